@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// CustomAction binds a key to a shell command, configured under actions.
+type CustomAction struct {
+	// Key as it is written in the help: "o", "O", "ctrl+g", "alt+x", ...
+	Key string `json:"key"`
+	// Shell command; {session}, {cwd}, {template} and {file} are replaced
+	// with shell-quoted values for the highlighted row.
+	Command string `json:"cmd"`
+	// Shown in the help overlay instead of the command.
+	Description string `json:"description,omitempty"`
+	// "sessions" (default) or "templates": the list the key works in.
+	View string `json:"view,omitempty"`
+	// Hand the terminal to the command, for editors and other programs
+	// that need it, and come back when it exits.
+	Interactive bool `json:"interactive,omitempty"`
+}
+
+// Views custom actions can be bound in.
+const (
+	actionViewSessions  = "sessions"
+	actionViewTemplates = "templates"
+)
+
+// builtinKeys are the keys lazytmux handles itself in each view, which
+// custom actions can't be bound to.
+var builtinKeys = map[string][]string{
+	actionViewSessions: {
+		"up", "k", "down", "j", "g", "G", "pgup", "pgdown", "ctrl+u", "ctrl+d",
+		"enter", " ", "alt+enter", "m", "n", "c", "t", "p", "o", "O", "*", "i",
+		"I", "P", "F", "f", "T", "/", "s", "l", "u", "A", "b", "N", "B", "W",
+		"w", "C", "S", "v", ":", "U", "X", "Q", "@", "M", "'", "r", "d", "D",
+		"ctrl+r", "F5", "a", "?", "h", "q", "ctrl+c",
+	},
+	actionViewTemplates: {
+		"up", "k", "down", "j", "pgup", "pgdown", "ctrl+u", "ctrl+d", "enter",
+		" ", "n", "e", "d", "r", "R", "y", "c", "f", "i", "I", "p", "<", ">",
+		"s", "*", "?", "h", "q", "esc", "ctrl+c",
+	},
+}
+
+// builtinKey reports whether lazytmux handles key itself in view.
+func builtinKey(view, key string) bool {
+	for _, k := range builtinKeys[view] {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (a CustomAction) view() string {
+	if a.View == "" {
+		return actionViewSessions
+	}
+	return a.View
+}
+
+func (a CustomAction) description() string {
+	if a.Description != "" {
+		return a.Description
+	}
+	return a.Command
+}
+
+// actionDoneMsg reports how a custom action went.
+type actionDoneMsg struct {
+	action CustomAction
+	output string
+	err    error
+}
+
+// findAction returns the custom action bound to key in view, if any.
+func findAction(view, key string) (CustomAction, bool) {
+	for _, a := range config.Actions {
+		if a.view() == view && a.Key == key {
+			return a, true
+		}
+	}
+	return CustomAction{}, false
+}
+
+// actionShortcuts lists the custom actions of a view for the help overlay,
+// none in read-only mode.
+func actionShortcuts(view string) [][]string {
+	var shortcuts [][]string
+	if readOnly {
+		return nil
+	}
+	for _, a := range config.Actions {
+		if a.view() == view {
+			shortcuts = append(shortcuts, []string{a.Key, a.description()})
+		}
+	}
+	return shortcuts
+}
+
+// expandAction fills in the placeholders of an action's command. Values are
+// quoted so that names with spaces or quotes stay one argument.
+func expandAction(command string, values map[string]string) string {
+	var pairs []string
+	for _, name := range []string{"session", "cwd", "template", "file"} {
+		pairs = append(pairs, "{"+name+"}", shellQuote(values[name]))
+	}
+	return strings.NewReplacer(pairs...).Replace(command)
+}
+
+// actionValues returns the placeholder values for the highlighted row, or
+// false if nothing is highlighted.
+func (m model) actionValues(view string) (map[string]string, bool) {
+	switch view {
+	case actionViewSessions:
+		if len(m.sessions) == 0 {
+			return nil, false
+		}
+		s := m.sessions[m.cursor]
+		return map[string]string{"session": s.Name, "cwd": s.Dir, "template": s.Template}, true
+	case actionViewTemplates:
+		if len(m.templates) == 0 || !m.templateVisible(m.templateCursor) {
+			return nil, false
+		}
+		t := m.templates[m.templateCursor]
+		return map[string]string{"template": t.Name, "cwd": t.StartDir(), "file": t.file}, true
+	}
+	return nil, false
+}
+
+// runAction runs a custom action for the highlighted row, in the background
+// or, for interactive ones, in the terminal with the TUI suspended. Actions
+// run any command they like, so none run in read-only mode.
+func (m *model) runAction(a CustomAction) tea.Cmd {
+	if m.denyReadOnly("running custom actions") {
+		return nil
+	}
+	values, ok := m.actionValues(a.view())
+	if !ok {
+		m.setMessage("Nothing selected", "warning")
+		return nil
+	}
+	return runActionWith(a, values)
+}
+
+// runActionWith runs an action with the given placeholder values.
+func runActionWith(a CustomAction, values map[string]string) tea.Cmd {
+	command := expandAction(a.Command, values)
+	env := append(os.Environ(), "LAZYTMUX_SESSION="+values["session"], "LAZYTMUX_TEMPLATE="+values["template"])
+	dir := ""
+	if info, err := os.Stat(values["cwd"]); err == nil && info.IsDir() {
+		dir = values["cwd"]
+	}
+
+	if a.Interactive {
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Env, cmd.Dir = env, dir
+		return tea.ExecProcess(cmd, func(err error) tea.Msg {
+			return actionDoneMsg{action: a, err: err}
+		})
+	}
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		cmd.Env, cmd.Dir = env, dir
+		out, err := cmd.CombinedOutput()
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %s", hookTimeout)
+		}
+		return actionDoneMsg{action: a, output: strings.TrimSpace(string(out)), err: err}
+	}
+}
+
+// actionProblems lists what is wrong with the configured actions, for doctor.
+func actionProblems(actions []CustomAction) []string {
+	var problems []string
+	seen := map[string]bool{}
+	for i, a := range actions {
+		switch {
+		case a.Key == "":
+			problems = append(problems, fmt.Sprintf("action %d has no key", i+1))
+		case seen[a.view()+" "+a.Key]:
+			problems = append(problems, fmt.Sprintf("action %d: key %q is bound twice in %s", i+1, a.Key, a.view()))
+		case builtinKey(a.view(), a.Key):
+			problems = append(problems, fmt.Sprintf("action %d: key %q is lazytmux's own in %s", i+1, a.Key, a.view()))
+		}
+		seen[a.view()+" "+a.Key] = true
+		if strings.TrimSpace(a.Command) == "" {
+			problems = append(problems, fmt.Sprintf("action %d has no cmd", i+1))
+		}
+		switch a.View {
+		case "", actionViewSessions, actionViewTemplates:
+		default:
+			problems = append(problems, fmt.Sprintf("action %d: view %q should be sessions or templates", i+1, a.View))
+		}
+	}
+	return problems
+}
+
+// dropBuiltinActions takes the actions bound to keys lazytmux handles itself
+// out of the config, so that a typo can't take q or Enter away, and
+// returns what it dropped.
+func dropBuiltinActions(cfg *Config) []string {
+	var dropped []string
+	var kept []CustomAction
+	for i, a := range cfg.Actions {
+		if builtinKey(a.view(), a.Key) {
+			dropped = append(dropped, fmt.Sprintf("action %d: key %q is lazytmux's own in %s", i+1, a.Key, a.view()))
+			continue
+		}
+		kept = append(kept, a)
+	}
+	cfg.Actions = kept
+	return dropped
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// switchKeys returns the keys the switch on msg.String() in the case of
+// the key handler for mode matches.
+func switchKeys(t *testing.T, file *ast.File, mode string) []string {
+	var keys []string
+	ast.Inspect(file, func(n ast.Node) bool {
+		clause, ok := n.(*ast.CaseClause)
+		if !ok || len(clause.List) != 1 {
+			return true
+		}
+		if id, ok := clause.List[0].(*ast.Ident); !ok || id.Name != mode {
+			return true
+		}
+		for _, stmt := range clause.Body {
+			sw, ok := stmt.(*ast.SwitchStmt)
+			if !ok {
+				continue
+			}
+			call, ok := sw.Tag.(*ast.CallExpr)
+			if !ok {
+				continue
+			}
+			if sel, ok := call.Fun.(*ast.SelectorExpr); !ok || sel.Sel.Name != "String" {
+				continue
+			}
+			for _, c := range sw.Body.List {
+				for _, e := range c.(*ast.CaseClause).List {
+					if lit, ok := e.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+						key, _ := strconv.Unquote(lit.Value)
+						keys = append(keys, key)
+					}
+				}
+			}
+		}
+		return true
+	})
+	if len(keys) == 0 {
+		t.Fatalf("found no key switch for %s", mode)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// TestBuiltinKeys keeps builtinKeys in step with the keys the views handle.
+func TestBuiltinKeys(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "main.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for mode, view := range map[string]string{"browsing": actionViewSessions, "templateBrowsing": actionViewTemplates} {
+		for _, key := range switchKeys(t, file, mode) {
+			if !builtinKey(view, key) {
+				t.Errorf("%s handles %q, which is missing from builtinKeys[%q]", mode, key, view)
+			}
+		}
+	}
+}
+
+func TestDropBuiltinActions(t *testing.T) {
+	cfg := Config{Actions: []CustomAction{
+		{Key: "q", Command: "true"},
+		{Key: "ctrl+o", Command: "true"},
+		{Key: "e", Command: "true", View: actionViewTemplates},
+		{Key: "e", Command: "true"},
+	}}
+	dropped := dropBuiltinActions(&cfg)
+	if len(dropped) != 2 {
+		t.Errorf("dropped %q, want the q and template e actions", dropped)
+	}
+	var kept []string
+	for _, a := range cfg.Actions {
+		kept = append(kept, a.view()+" "+a.Key)
+	}
+	if len(kept) != 2 || kept[0] != "sessions ctrl+o" || kept[1] != "sessions e" {
+		t.Errorf("kept %q, want ctrl+o and e in the session list", kept)
+	}
+}
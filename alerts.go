@@ -0,0 +1,40 @@
+package main
+
+import "strings"
+
+// tmux flags windows that rang the bell, showed activity or fell silent
+// since a client last looked at them, for windows with monitor-bell (on by
+// default), monitor-activity or monitor-silence set. The session list adds
+// them up per session from #{session_alerts}, which lists each flagged
+// window index followed by '#' for activity, '!' for a bell and '~' for
+// silence, like "1#!,3~".
+
+const (
+	bellIndicator     = "🔔"
+	activityIndicator = "⚡"
+	silenceIndicator  = "💤"
+)
+
+// parseAlerts sets the alert flags of a session from #{session_alerts}.
+func (s *Session) parseAlerts(alerts string) {
+	for _, window := range strings.Split(alerts, ",") {
+		s.Activity = s.Activity || strings.Contains(window, "#")
+		s.Bell = s.Bell || strings.Contains(window, "!")
+		s.Silence = s.Silence || strings.Contains(window, "~")
+	}
+}
+
+// alertMarks returns the indicators of the session's alerts, bell first.
+func (s Session) alertMarks() string {
+	var marks []string
+	if s.Bell {
+		marks = append(marks, bellIndicator)
+	}
+	if s.Activity {
+		marks = append(marks, activityIndicator)
+	}
+	if s.Silence {
+		marks = append(marks, silenceIndicator)
+	}
+	return strings.Join(marks, " ")
+}
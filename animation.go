@@ -0,0 +1,58 @@
+package main
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Moving the cursor pops the row it lands on for half a second. The frames
+// only tick while a pop is showing, so an idle TUI doesn't redraw.
+// "animations": "reduced" shows the pop as a single frame, and "off" drops
+// it, for slow terminals, remote sessions and batteries.
+
+// Time between the frames of the pop, and between showing it and taking
+// it away with reduced motion.
+const (
+	animationFrame   = 50 * time.Millisecond
+	reducedPopLength = 150 * time.Millisecond
+)
+
+func animationTick() tea.Cmd {
+	interval := animationFrame
+	if config.Animations == "reduced" {
+		interval = reducedPopLength
+	}
+	return tea.Tick(interval, func(t time.Time) tea.Msg {
+		return animationTickMsg(t)
+	})
+}
+
+// startAnimation starts the frames of a pop that was just set off, unless
+// they already tick. With animations off it takes the pop away instead.
+func (m *model) startAnimation() tea.Cmd {
+	if config.Animations == "off" {
+		m.popAnimation = 0
+		return nil
+	}
+	if m.popAnimation <= 0 || m.animating {
+		return nil
+	}
+	m.animating = true
+	return animationTick()
+}
+
+// stepAnimation shows the next frame of the pop and returns the tick for
+// the one after, or nil once it is over.
+func (m *model) stepAnimation() tea.Cmd {
+	m.animationTime = time.Since(m.startTime).Seconds()
+	m.popAnimation -= 0.05
+	if config.Animations == "reduced" || m.popAnimation < 0 {
+		m.popAnimation = 0
+	}
+	if m.popAnimation == 0 {
+		m.animating = false
+		return nil
+	}
+	return animationTick()
+}
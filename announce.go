@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Announcements describe what the TUI does in plain lines of text, for
+// screen readers and logs that can't follow the screen: the view or prompt
+// it switches to, the question it asks and the result it reports. They go
+// to a file descriptor, a file, or with "osc" to the terminal as OSC 777
+// notifications. Set with --announce or the announce config option.
+
+// announcer is where announcements go; nil when they are off.
+var announcer *os.File
+
+// announceOSC sends announcements as terminal notifications instead.
+var announceOSC bool
+
+// modeNames say in words what each mode shows or asks for.
+var modeNames = map[mode]string{
+	browsing:            "Session list",
+	creating:            "New session name",
+	renaming:            "Rename session",
+	confirming:          "Confirm",
+	templateBrowsing:    "Template list",
+	templateCreating:    "New template name",
+	templateEditing:     "Template editor",
+	paneEditing:         "Pane command",
+	paneBrowsing:        "Pane list",
+	templateVariables:   "Template variables",
+	templateReplacing:   "Search and replace in templates",
+	replaceReviewing:    "Review replacements",
+	auditBrowsing:       "Audit log",
+	templateImporting:   "Import template",
+	templateRenaming:    "Rename template",
+	templateDuplicating: "Duplicate template",
+	templateFiltering:   "Filter templates",
+	sessionDetails:      "Session details",
+	metaEditing:         "Edit session details",
+	idleReviewing:       "Idle sessions to kill, Space keeps one, Enter kills the rest",
+	processBrowsing:     "Process tree",
+	searchEntering:      "Search every pane for",
+	searchBrowsing:      "Matching lines, Enter attaches to the pane",
+	processFinding:      "Find the session running",
+	logViewing:          "Pane log",
+	layoutImporting:     "Window to import the layout of",
+	templateTesting:     "Test session of the template",
+	editorWindowNaming:  "Window name",
+	templateStarting:    "Session name for template",
+	sessionFiltering:    "Filter sessions",
+	changelogViewing:    "Release notes",
+	commandEntering:     "tmux command",
+	noteEntering:        "Note",
+	broadcastEntering:   "Command for every pane",
+	bufferBrowsing:      "Paste buffers",
+	bufferSaving:        "Save buffer to file",
+	windowBrowsing:      "Window list",
+	windowCreating:      "New window name",
+	windowRenaming:      "Rename window",
+	windowMoving:        "Move window to session",
+	windowSwapping:      "Swap window with",
+	paneJoining:         "Join pane into window",
+	clientBrowsing:      "Client list",
+	attachChoosing:      "Attach options: n normally, d detaching others, r read-only",
+	shareChoosing:       "Share session",
+	shareGuestEntering:  "User to share with",
+}
+
+// confirmQuestions say what a confirmation is about, %s being its target.
+var confirmQuestions = map[action]string{
+	actionDelete:         "Delete session %s?",
+	actionKillAll:        "Kill all sessions?",
+	actionDeleteTemplate: "Delete template %s?",
+	actionKillPane:       "Close pane %s?",
+	actionBroadcast:      "Send the command to every pane of %s?",
+	actionWorktrees:      "Create sessions for the worktrees?",
+	actionKillWindow:     "Kill window %s?",
+	actionKillClient:     "Kill client %s?",
+	actionUnshare:        "Stop sharing %s?",
+}
+
+// setAnnounce opens where announcements go: "off", "stderr", "fd:N", "osc"
+// or a file to append to.
+func setAnnounce(target string) error {
+	target = strings.TrimSpace(target)
+	switch {
+	case target == "" || target == "off":
+		return nil
+	case target == "osc":
+		announceOSC = true
+		return nil
+	case target == "stderr":
+		announcer = os.Stderr
+		return nil
+	case strings.HasPrefix(target, "fd:"):
+		fd, err := strconv.Atoi(target[3:])
+		if err != nil || fd < 1 {
+			return fmt.Errorf("announce: %q is not a file descriptor", target)
+		}
+		announcer = os.NewFile(uintptr(fd), target)
+		if _, err := announcer.Stat(); err != nil {
+			announcer = nil
+			return fmt.Errorf("announce: file descriptor %d is not open", fd)
+		}
+		return nil
+	}
+	f, err := os.OpenFile(expandHome(target), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("announce: %v", err)
+	}
+	announcer = f
+	return nil
+}
+
+func announceEnabled() bool {
+	return announcer != nil || announceOSC
+}
+
+// announce passes one line of text on.
+func announce(text string) {
+	text = printable(text)
+	switch {
+	case announcer != nil:
+		fmt.Fprintln(announcer, text)
+	case announceOSC:
+		osc := fmt.Sprintf("\x1b]777;notify;lazytmux;%s\x07", strings.ReplaceAll(text, ";", ","))
+		if os.Getenv("TMUX") != "" {
+			// Let tmux pass it on to the outer terminal.
+			osc = "\x1bPtmux;" + strings.ReplaceAll(osc, "\x1b", "\x1b\x1b") + "\x1b\\"
+		}
+		os.Stdout.WriteString(osc)
+	}
+}
+
+// announceChanges reports what an update changed: the mode, with the
+// question when asking for confirmation, and a new message.
+func announceChanges(before, after model) {
+	if after.mode != before.mode {
+		text := modeNames[after.mode]
+		if after.mode == confirming {
+			if q, ok := confirmQuestions[after.confirmAction]; ok {
+				if strings.Contains(q, "%s") {
+					q = fmt.Sprintf(q, after.confirmTarget)
+				}
+				text += ": " + q + " y or n"
+			}
+		}
+		announce(text)
+	}
+	if after.message != "" && (after.message != before.message || after.messageType != before.messageType) {
+		kind := after.messageType
+		if kind == "" {
+			kind = "info"
+		}
+		announce(strings.ToUpper(kind[:1]) + kind[1:] + ": " + after.message)
+	}
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// apiRequest is one line sent to the control socket.
+type apiRequest struct {
+	Command  string            `json:"command"`            // list, templates, create, kill or snapshot
+	Template string            `json:"template,omitempty"` // create: template name or prefix
+	Spec     *SessionTemplate  `json:"spec,omitempty"`     // create: a whole template instead of a saved one
+	Name     string            `json:"name,omitempty"`     // session to create, kill or snapshot
+	Vars     map[string]string `json:"vars,omitempty"`     // create: placeholder values
+	Exists   string            `json:"exists,omitempty"`   // create: attach, fail (default) or suffix
+}
+
+// apiResponse is the line sent back for every request.
+type apiResponse struct {
+	OK     bool        `json:"ok"`
+	Error  string      `json:"error,omitempty"`
+	Result interface{} `json:"result,omitempty"`
+}
+
+type createdJSON struct {
+	Session string `json:"session"`
+	Created bool   `json:"created"` // false if exists=attach found it running
+}
+
+type paneJSON struct {
+	ID         string `json:"id"`
+	Index      string `json:"index"` // "window.pane"
+	Command    string `json:"command"`
+	Title      string `json:"title,omitempty"`
+	Size       string `json:"size"`
+	Dead       bool   `json:"dead,omitempty"`
+	ExitStatus int    `json:"exit_status,omitempty"`
+}
+
+// snapshotJSON is a session with the panes running in it.
+type snapshotJSON struct {
+	sessionJSON
+	Panes []paneJSON `json:"panes"`
+}
+
+// getAPISocket returns where the control socket lives: LAZYTMUX_SOCKET,
+// else lazytmux.sock in XDG_RUNTIME_DIR or, failing that, the config dir.
+func getAPISocket() string {
+	if path := os.Getenv("LAZYTMUX_SOCKET"); path != "" {
+		return path
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "lazytmux.sock")
+	}
+	return filepath.Join(getConfigDir(), "lazytmux.sock")
+}
+
+// listenAPI starts answering requests on the control socket in the
+// background. A socket left behind by a lazytmux that died is replaced;
+// one that still answers is not. Closing the listener removes the socket.
+func listenAPI() (net.Listener, error) {
+	path := getAPISocket()
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("%s is already being served", path)
+	}
+	os.Remove(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	// Anyone who can talk to the socket can run commands as us, so it is
+	// created private rather than made so after it is already listening.
+	old := syscall.Umask(0077)
+	l, err := net.Listen("unix", path)
+	syscall.Umask(old)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveAPIConn(conn)
+		}
+	}()
+	return l, nil
+}
+
+// serveAPIConn answers requests, one JSON object per line each way, until
+// the client hangs up.
+func serveAPIConn(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	enc := json.NewEncoder(conn)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var req apiRequest
+		resp := apiResponse{}
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			resp.Error = fmt.Sprintf("invalid request: %v", err)
+		} else {
+			resp = handleAPIRequest(req)
+		}
+		if err := enc.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+func handleAPIRequest(req apiRequest) apiResponse {
+	var result interface{}
+	var err error
+	switch req.Command {
+	case "list":
+		result = sessionsJSON(cliSessions(false))
+	case "templates":
+		result = loadTemplates()
+	case "create":
+		if readOnly {
+			err = errors.New("read-only mode: creating sessions is disabled")
+		} else {
+			result, err = apiCreate(req)
+		}
+	case "kill":
+		if readOnly {
+			err = errors.New("read-only mode: killing sessions is disabled")
+		} else {
+			err = killByName(req.Name, cliSessions(false))
+		}
+	case "snapshot":
+		result, err = apiSnapshot(req.Name)
+	default:
+		err = fmt.Errorf("unknown command %q (want list, templates, create, kill or snapshot)", req.Command)
+	}
+	if err != nil {
+		return apiResponse{Error: err.Error()}
+	}
+	return apiResponse{OK: true, Result: result}
+}
+
+// apiCreate is `lazytmux start` for the socket, without attaching.
+func apiCreate(req apiRequest) (createdJSON, error) {
+	var source SessionTemplate
+	if req.Spec != nil {
+		source = *req.Spec
+		if problems := templateProblems(source); len(problems) > 0 {
+			return createdJSON{}, fmt.Errorf("invalid template: %s", strings.Join(problems, "; "))
+		}
+	} else {
+		saved := findTemplateByPrefix(req.Template, loadTemplates())
+		if req.Template == "" || saved == nil {
+			return createdJSON{}, fmt.Errorf("template '%s' not found", req.Template)
+		}
+		source = *saved
+	}
+	template, err := source.withVars(req.Vars)
+	if err != nil {
+		return createdJSON{}, err
+	}
+	name := sanitizeSessionName(req.Name)
+	if name == "" {
+		name = sanitizeSessionName(template.Name)
+	}
+	if err := checkSessionName(name, nil); err != nil {
+		return createdJSON{}, err
+	}
+	exists := req.Exists
+	if exists == "" {
+		exists = existsFail
+	}
+
+	// New sessions go to the first server managed, whichever one the TUI
+	// is looking at.
+	srv := managedServers()[0]
+	name, created, err := srv.claimSession(name, template.StartDir(), exists)
+	if err != nil {
+		return createdJSON{}, err
+	}
+	if created {
+		if err := srv.applyTemplate(name, template); err != nil {
+			_ = srv.killSession(name)
+			return createdJSON{}, fmt.Errorf("failed to create session from template: %v", err)
+		}
+		if req.Spec == nil {
+			recordTemplateUse(template.Name)
+		}
+	}
+	return createdJSON{Session: name, Created: created}, nil
+}
+
+// apiSnapshot describes every session, or just the named one, down to its
+// panes.
+func apiSnapshot(name string) ([]snapshotJSON, error) {
+	sessions := cliSessions(false)
+	out := []snapshotJSON{}
+	for i, s := range sessionsJSON(sessions) {
+		if name != "" && s.Name != name {
+			continue
+		}
+		snap := snapshotJSON{sessionJSON: s, Panes: []paneJSON{}}
+		for _, p := range serverNamed(sessions[i].Server).listSessionPanes(sessions[i].Name) {
+			snap.Panes = append(snap.Panes, paneJSON{
+				ID:         p.ID,
+				Index:      p.Index,
+				Command:    p.Command,
+				Title:      p.Title,
+				Size:       p.Size,
+				Dead:       p.Dead,
+				ExitStatus: p.ExitStatus,
+			})
+		}
+		out = append(out, snap)
+	}
+	if name != "" && len(out) == 0 {
+		return nil, fmt.Errorf("no session named '%s'", name)
+	}
+	return out, nil
+}
+
+// runServe answers the control socket without the TUI until interrupted.
+func runServe(args []string) int {
+	if len(args) != 0 {
+		fmt.Fprintf(os.Stderr, "Usage: %s serve\n", os.Args[0])
+		return 2
+	}
+	l, err := listenAPI()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Listening on %s\n", getAPISocket())
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	l.Close()
+	return 0
+}
+
+// runCall sends one request to a running `lazytmux serve` or TUI and
+// prints the result as JSON.
+func runCall(args []string) int {
+	fs := flag.NewFlagSet("call", flag.ContinueOnError)
+	exists := fs.String("exists", "", "create: what to do if the session already exists: attach, fail or suffix")
+	vars := varFlags{}
+	fs.Var(vars, "var", "create: value for a template `name=value` placeholder (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s call list\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s call templates\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s call create [--var name=value]... [--exists=attach|fail|suffix] <template> [session-name]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s call kill <session>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s call snapshot [session]\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	args, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(args) == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	req := apiRequest{Command: args[0]}
+	operands := args[1:]
+	switch req.Command {
+	case "list", "templates":
+		if len(operands) != 0 {
+			fs.Usage()
+			return 2
+		}
+	case "create":
+		if len(operands) < 1 || len(operands) > 2 {
+			fs.Usage()
+			return 2
+		}
+		req.Template = operands[0]
+		if len(operands) == 2 {
+			req.Name = operands[1]
+		}
+		req.Vars = vars
+		req.Exists = *exists
+	case "kill":
+		if len(operands) != 1 {
+			fs.Usage()
+			return 2
+		}
+		req.Name = operands[0]
+	case "snapshot":
+		if len(operands) > 1 {
+			fs.Usage()
+			return 2
+		}
+		if len(operands) == 1 {
+			req.Name = operands[0]
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q (want list, templates, create, kill or snapshot)\n", req.Command)
+		return 2
+	}
+
+	result, err := callAPI(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(result) == 0 {
+		return 0
+	}
+	var out bytes.Buffer
+	json.Indent(&out, result, "", "  ")
+	fmt.Println(out.String())
+	return 0
+}
+
+// callAPI sends req and returns the result as it came over the wire, so
+// that fields keep the server's order.
+func callAPI(req apiRequest) (json.RawMessage, error) {
+	path := getAPISocket()
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("nothing is serving %s; run '%s serve' or set \"api\": \"on\"", path, os.Args[0])
+	}
+	defer conn.Close()
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, err
+	}
+	var resp struct {
+		apiResponse
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("reading the response: %v", err)
+	}
+	if !resp.OK {
+		return nil, errors.New(resp.Error)
+	}
+	return resp.Result, nil
+}
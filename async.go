@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Every tmux command the TUI runs, reading what it shows as well as
+// changing sessions, windows and panes, runs as a tea.Cmd whose result
+// comes back as a message, so a slow tmux, or one at the other end of ssh,
+// never freezes the TUI. Update only asks for them. Each command is given
+// the server it goes to when it is asked for, as the next key may move the
+// cursor to a session on another server.
+
+// sessionsMsg carries the session list read in the background.
+type sessionsMsg struct {
+	sessions []Session
+	projects []Session
+	stats    serverStats
+	shares   map[string]Share
+}
+
+// doneMsg reports a command run in the background. done applies the
+// result to the model in Update and may return a command of its own.
+type doneMsg struct {
+	err  error
+	done func(m *model, err error) tea.Cmd
+}
+
+// readSessions reads everything the session list shows from tmux.
+func readSessions() sessionsMsg {
+	sessions := listTmuxSessions()
+	return sessionsMsg{
+		sessions: sessions,
+		projects: projectSessions(sessions),
+		stats:    loadServerStats(),
+		shares:   loadShares(),
+	}
+}
+
+func fetchSessions() tea.Msg {
+	return readSessions()
+}
+
+// loadSessions asks for the session list to be read again. Update starts
+// reading it once it is done with the message at hand, and the list
+// changes when the result comes back.
+func (m *model) loadSessions() {
+	m.reloadWanted = true
+}
+
+// selectLoaded moves the cursor to the named session once the list read
+// next has it, for sessions just created.
+func (m *model) selectLoaded(name string) {
+	m.selectSession(name)
+	m.pendingSelect = name
+	m.loadSessions()
+}
+
+// applySessions shows a session list that was read.
+func (m *model) applySessions(msg sessionsMsg) {
+	m.allSessions = msg.sessions
+	applyRules(m.allSessions, config.Rules)
+	applyMetadata(m.allSessions, m.metadata)
+	m.projects = msg.projects
+	m.stats = msg.stats
+	m.shares = msg.shares
+	m.showSessions()
+	if m.pendingSelect != "" {
+		m.selectSession(m.pendingSelect)
+		m.pendingSelect = ""
+	}
+}
+
+// startReload starts reading the session list if it was asked for and
+// isn't being read already.
+func (m *model) startReload() tea.Cmd {
+	if !m.reloadWanted || m.reloading {
+		return nil
+	}
+	m.reloadWanted = false
+	m.reloading = true
+	return fetchSessions
+}
+
+// viewMsg carries what the open view shows, read in the background by
+// refreshView; each of apply puts one list of it in the model.
+type viewMsg struct {
+	read  int
+	apply []func(m *model)
+}
+
+// refreshView asks for the panes, windows, clients or buffers shown to be
+// read from tmux again.
+func (m *model) refreshView() tea.Cmd {
+	srv := m.server()
+	var reads []func() func(m *model)
+	if m.showPanes {
+		session := m.paneSession
+		reads = append(reads, func() func(m *model) { return readPanes(srv, session) })
+	}
+	if m.showWindows {
+		session := m.windowSession
+		reads = append(reads, func() func(m *model) { return readWindows(srv, session) })
+	}
+	if m.showClients {
+		reads = append(reads, readClients)
+	}
+	if m.showBuffers {
+		cursor := m.bufferCursor
+		reads = append(reads, func() func(m *model) { return readBuffers(srv, cursor) })
+	}
+	if len(reads) == 0 {
+		return nil
+	}
+	m.viewReads++
+	msg := viewMsg{read: m.viewReads}
+	return func() tea.Msg {
+		for _, read := range reads {
+			msg.apply = append(msg.apply, read())
+		}
+		return msg
+	}
+}
+
+// applyView shows what a read of the view found, unless a later read was
+// asked for since, which may have been for another session or after a
+// change.
+func (m *model) applyView(msg viewMsg) {
+	if msg.read != m.viewReads {
+		return
+	}
+	m.viewShown = msg.read
+	for _, apply := range msg.apply {
+		apply(m)
+	}
+}
+
+// viewLoading reports whether the view waits for a read.
+func (m model) viewLoading() bool {
+	return m.viewShown != m.viewReads
+}
+
+// backgroundWork counts the commands of inBackground asked for whose
+// doneMsg Update hasn't seen yet, for macro replays to wait on.
+var backgroundWork atomic.Int64
+
+// inBackground runs work as a tea.Cmd, then done in Update with its error.
+// The command must be returned from Update, or replays would wait for it.
+func inBackground(work func() error, done func(m *model, err error) tea.Cmd) tea.Cmd {
+	backgroundWork.Add(1)
+	return func() tea.Msg { return doneMsg{err: work(), done: done} }
+}
+
+// changeInBackground runs work, a change to what the open view shows, and
+// reports it as done, or as failed with the error. The change goes to the
+// audit log as action on target unless action is "", and the view is read
+// again afterwards.
+func changeInBackground(work func() error, action, target, failed, done string) tea.Cmd {
+	return inBackground(work, func(m *model, err error) tea.Cmd {
+		if action != "" {
+			recordAudit(action, target, err)
+		}
+		if err != nil {
+			m.setMessage(fmt.Sprintf("%s: %v", failed, err), "error")
+		} else {
+			m.setMessage(done, "success")
+		}
+		return m.refreshView()
+	})
+}
+
+// killInBackground kills the named session on srv.
+func killInBackground(srv Server, name string) tea.Cmd {
+	return inBackground(func() error {
+		return srv.killSession(name)
+	}, func(m *model, err error) tea.Cmd {
+		recordAudit("kill-session", name, err)
+		if err != nil {
+			m.setMessage(fmt.Sprintf("Failed to delete session: %v", err), "error")
+		} else {
+			m.setMessage(fmt.Sprintf("Deleted session '%s'", name), "success")
+		}
+		return nil
+	})
+}
+
+// killManyInBackground kills the sessions one by one, keeping those in
+// kept, which are only named in the result.
+func killManyInBackground(kill []Session, kept []string) tea.Cmd {
+	var failed []string
+	return inBackground(func() error {
+		for _, s := range kill {
+			err := serverNamed(s.Server).killSession(s.Name)
+			recordAudit("kill-session", s.Name, err)
+			if err != nil {
+				failed = append(failed, s.Name)
+			}
+		}
+		return nil
+	}, func(m *model, err error) tea.Cmd {
+		if len(failed) > 0 {
+			m.setMessage(fmt.Sprintf("Failed to kill %s", strings.Join(failed, ", ")), "error")
+			return nil
+		}
+		msg := fmt.Sprintf("Killed %d session(s)", len(kill))
+		if len(kept) > 0 {
+			msg += ", kept " + strings.Join(kept, " and ")
+		}
+		m.setMessage(msg, "warning")
+		return nil
+	})
+}
+
+// killServerInBackground kills the tmux server with all its sessions.
+func killServerInBackground(srv Server) tea.Cmd {
+	return inBackground(srv.killAllSessions, func(m *model, err error) tea.Cmd {
+		recordAudit("kill-server", "all sessions", err)
+		if err != nil {
+			m.setMessage(fmt.Sprintf("Failed to kill all sessions: %v", err), "error")
+		} else {
+			m.setMessage("All sessions killed", "warning")
+		}
+		return nil
+	})
+}
+
+// renameInBackground renames a session on srv, and its bookmarks, star and
+// details with it.
+func renameInBackground(srv Server, old, new string) tea.Cmd {
+	return inBackground(func() error {
+		return srv.renameSession(old, new)
+	}, func(m *model, err error) tea.Cmd {
+		recordAudit("rename-session", old+" -> "+new, err)
+		if err != nil {
+			m.setMessage(fmt.Sprintf("Failed to rename session: %v", err), "error")
+			return nil
+		}
+		m.setMessage(fmt.Sprintf("Renamed '%s' to '%s'", old, new), "success")
+		if renameBookmarks(m.bookmarks, old, new) {
+			saveBookmarks(m.bookmarks)
+		}
+		if renameStar(m.favorites.Sessions, old, new) {
+			saveFavorites(m.favorites)
+		}
+		if renameMetadata(m.metadata, old, new) {
+			saveMetadata(m.metadata)
+		}
+		m.selectLoaded(new)
+		return nil
+	})
+}
+
+// createInBackground creates a plain session in dir, then selects it if
+// m.detachNew is set or attaches to it and quits. created describes it in
+// the status bar.
+func (m *model) createInBackground(name, dir, created string) tea.Cmd {
+	detach, srv := m.detachNew, m.server()
+	return inBackground(func() error {
+		return srv.createSessionIn(name, dir)
+	}, func(m *model, err error) tea.Cmd {
+		if err != nil {
+			m.setMessage(fmt.Sprintf("Failed to create session: %v", err), "error")
+			return nil
+		}
+		m.setMessage(created, "success")
+		if detach {
+			m.selectLoaded(name)
+			return nil
+		}
+		attachSession(srv, name)
+		return tea.Quit
+	})
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// How sessions are attached, chosen with the attach config option.
+const (
+	attachAuto     = "auto"     // exec on headless machines, terminal otherwise
+	attachTerminal = "terminal" // launch a terminal emulator running tmux attach
+	attachExec     = "exec"     // attach in the terminal lazytmux runs in after quitting
+	attachPrint    = "print"    // print the attach command after quitting
+	attachCopy     = "copy"     // copy the attach command to the clipboard after quitting
+)
+
+// terminalChosen is set when a terminal was picked with -t, which asks for
+// terminals to be launched even where auto wouldn't.
+var terminalChosen bool
+
+func attachMode() string {
+	switch config.Attach {
+	case attachTerminal, attachExec, attachPrint, attachCopy:
+		return config.Attach
+	}
+	if !terminalChosen && headless() != "" {
+		return attachExec
+	}
+	return attachTerminal
+}
+
+// headless says why no terminal emulator can be launched here, or returns ""
+// if one probably can.
+func headless() string {
+	switch {
+	case os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != "":
+		return "SSH session"
+	case runtime.GOOS != "darwin" && os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "":
+		return "no DISPLAY or WAYLAND_DISPLAY"
+	}
+	return ""
+}
+
+// spawnsTerminals reports whether attaching launches terminal emulators.
+// When it doesn't, no terminal has to be installed at all.
+func spawnsTerminals() bool {
+	return attachMode() == attachTerminal
+}
+
+// attachTarget is a session to attach to and the server it is on.
+type attachTarget struct {
+	server Server
+	name   string
+}
+
+// Sessions to attach to once the TUI has left the alternate screen.
+var deferredAttach []attachTarget
+
+// Ways to attach, set as the default with the attach_options config option
+// or picked for one attach with Alt+Enter.
+const (
+	attachNormal       = "normal"
+	attachDetachOthers = "detach-others" // tmux attach -d: detach every other client
+	attachReadOnly     = "read-only"     // tmux attach -r: watch without typing into it
+)
+
+// attachVariants overrides attach_options for particular sessions: those
+// picked with Alt+Enter and those whose template's after_create says
+// read-only.
+var attachVariants = map[string]string{}
+
+// attachFlags returns the flags for attaching to a session. tmux takes them
+// after the target as well, which is where they go.
+func attachFlags(name string) []string {
+	variant, ok := attachVariants[name]
+	if !ok {
+		variant = config.AttachOptions
+	}
+	switch variant {
+	case attachDetachOthers:
+		return []string{"-d"}
+	case attachReadOnly:
+		return []string{"-r"}
+	}
+	return nil
+}
+
+func attachCommand(t attachTarget) string {
+	return strings.Join(append([]string{t.server.tmuxShellPrefix(), "attach-session -t", shellQuote(t.name)}, attachFlags(t.name)...), " ")
+}
+
+// flushDeferredAttach attaches to, prints or copies the sessions collected
+// while the TUI was running. Only one session can take over the terminal, so
+// exec prints the commands for the others.
+func flushDeferredAttach() {
+	if len(deferredAttach) == 0 {
+		return
+	}
+	targets := deferredAttach
+	deferredAttach = nil
+	if attachMode() == attachExec {
+		for _, t := range targets[1:] {
+			fmt.Println(attachCommand(t))
+		}
+		if err := attachHere(targets[0].server, targets[0].name); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: could not attach to '%s': %v\n", targets[0].name, err)
+		}
+		return
+	}
+
+	var commands []string
+	for _, t := range targets {
+		commands = append(commands, attachCommand(t))
+	}
+	text := strings.Join(commands, "\n")
+	if attachMode() == attachCopy {
+		if err := copyToClipboard(text); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to copy to the clipboard: %v\n", err)
+		} else {
+			fmt.Fprintln(os.Stderr, "Copied to the clipboard:")
+		}
+	}
+	fmt.Println(text)
+}
+
+// copyToClipboard hands text to tmux when running inside it, which passes it
+// on to the outer terminal; that is the tmux of our own client, whichever
+// server --socket-name picked, and otherwise asks the terminal directly with
+// OSC 52. Both work over SSH.
+func copyToClipboard(text string) error {
+	if os.Getenv("TMUX") != "" {
+		return exec.Command("tmux", "set-buffer", "-w", "--", text).Run()
+	}
+	_, err := fmt.Fprintf(os.Stdout, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+	return err
+}
+
+// attachTargets returns the marked sessions, or else the highlighted one.
+func (m model) attachTargets() []attachTarget {
+	var targets []attachTarget
+	for _, s := range m.sessions {
+		if m.marked[s.Name] {
+			targets = append(targets, attachTarget{serverNamed(s.Server), s.Name})
+		}
+	}
+	if len(targets) == 0 && len(m.sessions) > 0 {
+		s := m.sessions[m.cursor]
+		targets = append(targets, attachTarget{serverNamed(s.Server), s.Name})
+	}
+	return targets
+}
+
+// startAttach attaches to the attach targets and quits.
+func (m model) startAttach() (tea.Model, tea.Cmd) {
+	if len(m.marked) > 0 {
+		if err := attachSessions(m.attachTargets()); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to place terminals: %v\n", err)
+		}
+		return m, tea.Quit
+	}
+	attachSession(m.server(), m.sessions[m.cursor].Name)
+	return m, tea.Quit
+}
+
+func (m model) renderAttachOptions() string {
+	targets := m.attachTargets()
+	title := fmt.Sprintf("🔗 Attach to '%s'", targets[0].name)
+	if len(targets) > 1 {
+		title = fmt.Sprintf("🔗 Attach to %d sessions", len(targets))
+	}
+	options := "[n] Normally • [d] Detach other clients • [r] Read-only • [Esc] Cancel"
+	inputView := inputBoxStyle.Render(title + "\n\n" + options)
+	return lipgloss.Place(m.width, 5, lipgloss.Center, lipgloss.Top, inputView)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// auditEntry is one line of the audit log.
+type auditEntry struct {
+	Time    time.Time `json:"time"`
+	User    string    `json:"user"`
+	Action  string    `json:"action"`
+	Target  string    `json:"target"`
+	Outcome string    `json:"outcome"` // "ok" or the error
+}
+
+// getAuditLogFile returns where destructive operations are recorded. The
+// audit_log setting can point several users at a shared file.
+func getAuditLogFile() string {
+	if config.AuditLog != "" {
+		return expandHome(config.AuditLog)
+	}
+	return filepath.Join(getConfigDir(), "audit.log")
+}
+
+// recordAudit appends an entry for a destructive operation. The log is only
+// ever appended to, one JSON object per line. Failing to write it must not
+// stop the operation, so errors are dropped.
+func recordAudit(action, target string, err error) {
+	if config.AuditLog == "off" {
+		return
+	}
+	entry := auditEntry{
+		Time:    time.Now(),
+		User:    currentUser,
+		Action:  action,
+		Target:  target,
+		Outcome: "ok",
+	}
+	if err != nil {
+		entry.Outcome = err.Error()
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(entry)
+
+	file := getAuditLogFile()
+	os.MkdirAll(filepath.Dir(file), 0755)
+	f, ferr := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if ferr != nil {
+		return
+	}
+	defer f.Close()
+	f.Write(buf.Bytes())
+}
+
+// readAuditLog returns the logged entries, newest first. Lines that cannot
+// be parsed are skipped.
+func readAuditLog() ([]auditEntry, error) {
+	f, err := os.Open(getAuditLogFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []auditEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry auditEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, scanner.Err()
+}
+
+func (m model) renderAuditView(tableWidth int) string {
+	var content strings.Builder
+
+	title := tableHeaderStyle.Width(tableWidth).Render(fmt.Sprintf("📜 AUDIT LOG (%s)", getAuditLogFile()))
+	content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, title))
+	content.WriteString("\n\n")
+
+	if len(m.auditEntries) == 0 {
+		emptyMsg := lipgloss.NewStyle().
+			Foreground(mutedColor).
+			Italic(true).
+			Render("Nothing recorded yet. Kills, renames and deletions show up here.")
+		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, emptyMsg))
+		content.WriteString("\n\n")
+	} else {
+		timeW, userW, actionW := 16, tableWidth/8, tableWidth/6
+		targetW := tableWidth / 4
+		outcomeW := tableWidth - timeW - userW - actionW - targetW
+		cell := func(width int, s string) string {
+			return lipgloss.NewStyle().Width(width).MaxWidth(width).Padding(0, 1).Render(s)
+		}
+
+		header := lipgloss.JoinHorizontal(lipgloss.Top,
+			cell(timeW, "TIME"), cell(userW, "USER"), cell(actionW, "ACTION"), cell(targetW, "TARGET"), cell(outcomeW, "OUTCOME"))
+		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, lipgloss.NewStyle().Bold(true).Render(header)))
+		content.WriteString("\n")
+
+		// One line per entry; scroll so the cursor stays visible.
+		visible := max(5, m.height-12)
+		start := 0
+		if m.auditCursor >= visible {
+			start = m.auditCursor - visible + 1
+		}
+		end := min(len(m.auditEntries), start+visible)
+		for i := start; i < end; i++ {
+			entry := m.auditEntries[i]
+			outcome := lipgloss.NewStyle().Foreground(successColor).Render(entry.Outcome)
+			if entry.Outcome != "ok" {
+				outcome = lipgloss.NewStyle().Foreground(dangerColor).Render(entry.Outcome)
+			}
+			row := lipgloss.JoinHorizontal(lipgloss.Top,
+				cell(timeW, entry.Time.Local().Format("01/02 15:04:05")),
+				cell(userW, entry.User),
+				cell(actionW, entry.Action),
+				cell(targetW, entry.Target),
+				cell(outcomeW, outcome))
+			if i == m.auditCursor {
+				row = lipgloss.NewStyle().Background(primaryColor).Foreground(lipgloss.Color("15")).Render(row)
+			}
+			content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, row))
+			content.WriteString("\n")
+		}
+		content.WriteString("\n")
+	}
+
+	if m.message != "" {
+		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, m.renderMessage()))
+		content.WriteString("\n")
+	}
+
+	hints := fmt.Sprintf("%d entries • [↑/↓] Scroll • [g/G] Newest/Oldest • [Esc] Back", len(m.auditEntries))
+	statusBar := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("16")).
+		Padding(0, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderTop(true).
+		BorderForeground(primaryColor).
+		Render(hints)
+	content.WriteString("\n")
+	content.WriteString(lipgloss.Place(tableWidth, 1, lipgloss.Center, lipgloss.Top, statusBar))
+
+	return baseStyle.Render(content.String())
+}
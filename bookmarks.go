@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Bookmarks tie a letter to a session, or to a window of one, for jumping
+// straight to a few workspaces you keep coming back to: M and a letter marks
+// the highlighted session (or window, in the window view), ' and the letter
+// attaches to it from anywhere in the session list. Windows are remembered
+// by index, which survives renames.
+
+func getBookmarksFile() string {
+	return filepath.Join(getConfigDir(), "bookmarks.json")
+}
+
+// loadBookmarks returns the bookmarks by register, each "session" or
+// "session:index".
+func loadBookmarks() map[string]string {
+	bookmarks := map[string]string{}
+	data, err := ioutil.ReadFile(getBookmarksFile())
+	if err != nil {
+		return bookmarks
+	}
+	json.Unmarshal(data, &bookmarks)
+	return bookmarks
+}
+
+func saveBookmarks(bookmarks map[string]string) error {
+	data, err := json.MarshalIndent(bookmarks, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(getConfigDir(), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(getBookmarksFile(), data)
+}
+
+// splitBookmark splits a bookmark into its session and window index, which
+// is empty for bookmarks of a whole session.
+func splitBookmark(target string) (session, window string) {
+	if i := strings.LastIndex(target, ":"); i > 0 {
+		return target[:i], target[i+1:]
+	}
+	return target, ""
+}
+
+// sessionBookmarks lists the registers pointing into a session, for showing
+// next to its name.
+func sessionBookmarks(bookmarks map[string]string, session string) []string {
+	var registers []string
+	for _, register := range sortedKeys(bookmarks) {
+		if s, _ := splitBookmark(bookmarks[register]); s == session {
+			registers = append(registers, "'"+register)
+		}
+	}
+	return registers
+}
+
+// renameBookmarks follows a session to its new name, reporting whether any
+// bookmark changed.
+func renameBookmarks(bookmarks map[string]string, old, new string) bool {
+	changed := false
+	for register, target := range bookmarks {
+		session, window := splitBookmark(target)
+		if session != old {
+			continue
+		}
+		bookmarks[register] = new
+		if window != "" {
+			bookmarks[register] = new + ":" + window
+		}
+		changed = true
+	}
+	return changed
+}
+
+// bookmarkKey handles M and ' in the session and window lists and the
+// register letter that follows them. It reports false for keys that are not
+// its business.
+func (m model) bookmarkKey(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	key := msg.String()
+	if m.bookmarkPending != "" {
+		pending := m.bookmarkPending
+		m.bookmarkPending = ""
+		m.message = ""
+		switch {
+		case pending == "M" && isRegister(key):
+			m.setBookmark(key)
+		case pending == "'" && isRegister(key):
+			return m.jumpToBookmark(key)
+		}
+		return m, nil, true
+	}
+	if m.mode != browsing && m.mode != windowBrowsing {
+		return m, nil, false
+	}
+
+	switch key {
+	case "M":
+		if m.mode == browsing && len(m.sessions) == 0 || m.mode == windowBrowsing && len(m.liveWindows) == 0 {
+			return m, nil, false
+		}
+		if m.mode == browsing && m.refuseOnProject(key) {
+			return m, nil, true
+		}
+		m.bookmarkPending = "M"
+		m.setMessage("Bookmark into register: press a letter", "info")
+		return m, nil, true
+	case "'":
+		if len(m.bookmarks) == 0 {
+			m.setMessage("No bookmarks yet, set one with M and a letter", "info")
+			return m, nil, true
+		}
+		var list []string
+		for _, register := range sortedKeys(m.bookmarks) {
+			list = append(list, register+"="+m.bookmarks[register])
+		}
+		m.bookmarkPending = "'"
+		m.setMessage("Jump to: "+strings.Join(list, " "), "info")
+		return m, nil, true
+	}
+	return m, nil, false
+}
+
+// setBookmark points a register at the highlighted session or window.
+func (m *model) setBookmark(register string) {
+	target := ""
+	if m.mode == windowBrowsing {
+		target = fmt.Sprintf("%s:%s", m.windowSession, m.liveWindows[m.windowCursor].Index)
+	} else {
+		target = m.sessions[m.cursor].Name
+	}
+	previous := m.bookmarks[register]
+	m.bookmarks[register] = target
+	if err := saveBookmarks(m.bookmarks); err != nil {
+		m.setMessage(fmt.Sprintf("Failed to save bookmark: %v", err), "error")
+		return
+	}
+	if previous != "" && previous != target {
+		m.setMessage(fmt.Sprintf("Bookmarked %s as '%s (was %s)", target, register, previous), "success")
+	} else {
+		m.setMessage(fmt.Sprintf("Bookmarked %s as '%s", target, register), "success")
+	}
+}
+
+// jumpToBookmark attaches to the session a register points at, at its
+// window if it has one, and quits.
+func (m model) jumpToBookmark(register string) (tea.Model, tea.Cmd, bool) {
+	target, ok := m.bookmarks[register]
+	if !ok {
+		m.setMessage(fmt.Sprintf("Nothing bookmarked as '%s", register), "warning")
+		return m, nil, true
+	}
+	session, window := splitBookmark(target)
+	var found []Session
+	for _, s := range m.allSessions {
+		if s.Name == session {
+			found = append(found, s)
+		}
+	}
+	switch {
+	case len(found) == 0:
+		m.setMessage(fmt.Sprintf("Bookmark '%s points at '%s', which is not running", register, session), "warning")
+		return m, nil, true
+	case len(found) > 1:
+		m.setMessage(fmt.Sprintf("Bookmark '%s points at '%s', which runs on %d servers", register, session, len(found)), "warning")
+		return m, nil, true
+	}
+	srv := serverNamed(found[0].Server)
+	if window == "" {
+		attachSession(srv, session)
+		return m, tea.Quit, true
+	}
+	return m, inBackground(func() error {
+		return srv.selectWindow(fmt.Sprintf("=%s:%s", session, window))
+	}, func(m *model, err error) tea.Cmd {
+		if err != nil {
+			m.setMessage(fmt.Sprintf("Bookmark '%s: %v", register, err), "warning")
+			return nil
+		}
+		attachSession(srv, session)
+		return tea.Quit
+	}), true
+}
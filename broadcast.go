@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// sessionPaneIDs returns the ids of every pane in every window of a session.
+func (s Server) sessionPaneIDs(session string) ([]string, error) {
+	out, err := s.command("list-panes", "-s", "-t", "="+session, "-F", "#{pane_id}").Output()
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// broadcast types command into each pane and presses Enter. It keeps going
+// past panes that fail and returns the number it reached.
+func (s Server) broadcast(panes []string, command string) (int, error) {
+	sent := 0
+	var failed []string
+	for _, id := range panes {
+		err := s.command("send-keys", "-t", id, "-l", command).Run()
+		if err == nil {
+			err = s.command("send-keys", "-t", id, "Enter").Run()
+		}
+		if err != nil {
+			failed = append(failed, id)
+			continue
+		}
+		sent++
+	}
+	if len(failed) > 0 {
+		return sent, fmt.Errorf("could not reach pane %s", strings.Join(failed, ", "))
+	}
+	return sent, nil
+}
+
+func (m *model) startBroadcast() {
+	ti := textinput.New()
+	ti.Placeholder = "e.g. git pull"
+	ti.CharLimit = 500
+	ti.Focus()
+	m.input = ti
+	m.mode = broadcastEntering
+}
+
+func (m model) renderBroadcastPrompt() string {
+	prompt := fmt.Sprintf("📣 Command for every pane of '%s'\n%s\n", m.sessions[m.cursor].Name, m.input.View())
+	prompt += lipgloss.NewStyle().Foreground(mutedColor).Render("[Enter] Continue • [Esc] Cancel")
+	inputView := inputBoxStyle.Render(prompt)
+	return lipgloss.Place(m.width, 5, lipgloss.Center, lipgloss.Top, inputView)
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Buffer is a tmux paste buffer.
+type Buffer struct {
+	Name    string
+	Size    int
+	Created time.Time
+	Sample  string // start of the contents, already escaped by tmux
+}
+
+// How many lines of the selected buffer the preview shows.
+const bufferPreviewLines = 8
+
+func (s Server) listBuffers() []Buffer {
+	format := strings.Join([]string{"#{buffer_name}", "#{buffer_size}", "#{buffer_created}", "#{buffer_sample}"}, "\t")
+	out, err := s.command("list-buffers", "-F", format).Output()
+	if err != nil {
+		return []Buffer{}
+	}
+
+	buffers := []Buffer{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		parts := strings.SplitN(line, "\t", 4)
+		if len(parts) < 4 {
+			continue
+		}
+		size, _ := strconv.Atoi(parts[1])
+		created, _ := strconv.ParseInt(parts[2], 10, 64)
+		buffers = append(buffers, Buffer{
+			Name:    parts[0],
+			Size:    size,
+			Created: time.Unix(created, 0),
+			Sample:  parts[3],
+		})
+	}
+	return buffers
+}
+
+func (s Server) showBuffer(name string) (string, error) {
+	out, err := s.command("show-buffer", "-b", name).Output()
+	return string(out), err
+}
+
+func (s Server) deleteBuffer(name string) error {
+	return s.command("delete-buffer", "-b", name).Run()
+}
+
+// saveBuffer writes a buffer to a file, refusing to overwrite one.
+func (s Server) saveBuffer(name, path string) error {
+	text, err := s.showBuffer(name)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(text); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// loadBufferText adds text as a new buffer; tmux names it.
+func (s Server) loadBufferText(text string) error {
+	cmd := s.command("load-buffer", "-")
+	cmd.Stdin = strings.NewReader(text)
+	return cmd.Run()
+}
+
+// readClipboard returns the system clipboard through whichever helper is
+// installed. Over SSH there usually is none.
+func readClipboard() (string, error) {
+	var tools [][]string
+	switch {
+	case runtime.GOOS == "darwin":
+		tools = [][]string{{"pbpaste"}}
+	case os.Getenv("WAYLAND_DISPLAY") != "":
+		tools = [][]string{{"wl-paste", "--no-newline"}}
+	}
+	tools = append(tools, []string{"xclip", "-selection", "clipboard", "-o"}, []string{"xsel", "--clipboard", "--output"})
+	for _, tool := range tools {
+		if _, err := exec.LookPath(tool[0]); err != nil {
+			continue
+		}
+		out, err := exec.Command(tool[0], tool[1:]...).Output()
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", tool[0], err)
+		}
+		return string(out), nil
+	}
+	return "", errors.New("no clipboard tool found (wl-paste, xclip, xsel or pbpaste)")
+}
+
+// readBuffers reads the buffer list, and the preview of the buffer at
+// cursor, for refreshView.
+func readBuffers(srv Server, cursor int) func(m *model) {
+	buffers := srv.listBuffers()
+	cursor = min(cursor, max(0, len(buffers)-1))
+	preview := ""
+	if len(buffers) > 0 {
+		if text, err := srv.showBuffer(buffers[cursor].Name); err == nil {
+			preview = bufferPreview(text)
+		}
+	}
+	return func(m *model) {
+		if !m.showBuffers {
+			return
+		}
+		m.buffers = buffers
+		m.bufferCursor = cursor
+		m.bufferPreview = preview
+	}
+}
+
+// bufferPreview is the start of a buffer as the preview shows it.
+func bufferPreview(text string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) > bufferPreviewLines {
+		lines = append(lines[:bufferPreviewLines], fmt.Sprintf("… %d more lines", len(lines)-bufferPreviewLines))
+	}
+	for i, line := range lines {
+		lines[i] = printable(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// printable drops control characters, which would garble the TUI, and
+// expands tabs.
+func printable(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return ' '
+		}
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func formatSize(bytes int) string {
+	switch {
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(bytes)/(1<<20))
+	case bytes >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(bytes)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", bytes)
+}
+
+func (m model) renderBufferView(tableWidth int) string {
+	var content strings.Builder
+
+	title := tableHeaderStyle.Width(tableWidth).Render("📋 PASTE BUFFERS")
+	content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, title))
+	content.WriteString("\n\n")
+
+	if len(m.buffers) == 0 {
+		text := "No paste buffers. Copy something in tmux or press 'c' to load the clipboard."
+		if m.viewLoading() {
+			text = "Reading the paste buffers..."
+		}
+		emptyMsg := lipgloss.NewStyle().
+			Foreground(mutedColor).
+			Italic(true).
+			Render(text)
+		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, emptyMsg))
+		content.WriteString("\n\n")
+	} else {
+		nameW, sizeW, createdW := tableWidth/5, 10, 16
+		sampleW := tableWidth - nameW - sizeW - createdW
+		cell := func(width int, s string) string {
+			return lipgloss.NewStyle().Width(width).MaxWidth(width).MaxHeight(1).Padding(0, 1).Render(s)
+		}
+
+		header := lipgloss.JoinHorizontal(lipgloss.Top,
+			cell(nameW, "NAME"), cell(sizeW, "SIZE"), cell(createdW, "CREATED"), cell(sampleW, "CONTENTS"))
+		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, lipgloss.NewStyle().Bold(true).Render(header)))
+		content.WriteString("\n")
+
+		// Leave room for the preview below the list.
+		visible := max(3, m.height-bufferPreviewLines-16)
+		start := 0
+		if m.bufferCursor >= visible {
+			start = m.bufferCursor - visible + 1
+		}
+		end := min(len(m.buffers), start+visible)
+		for i := start; i < end; i++ {
+			b := m.buffers[i]
+			row := lipgloss.JoinHorizontal(lipgloss.Top,
+				cell(nameW, b.Name),
+				cell(sizeW, formatSize(b.Size)),
+				cell(createdW, b.Created.Local().Format("01/02 15:04:05")),
+				cell(sampleW, b.Sample))
+			if i == m.bufferCursor {
+				row = lipgloss.NewStyle().Background(primaryColor).Foreground(lipgloss.Color("15")).Render(row)
+			}
+			content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, row))
+			content.WriteString("\n")
+		}
+		content.WriteString("\n")
+
+		// Long lines are cut rather than wrapped so the preview keeps its height.
+		lines := strings.Split(m.bufferPreview, "\n")
+		for i, line := range lines {
+			lines[i] = rightTruncate(line, tableWidth-8)
+		}
+		preview := lipgloss.NewStyle().
+			Width(tableWidth-4).
+			MaxWidth(tableWidth).
+			Padding(0, 1).
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(mutedColor).
+			Render(strings.Join(lines, "\n"))
+		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, preview))
+		content.WriteString("\n")
+	}
+
+	if m.mode == bufferSaving {
+		prompt := fmt.Sprintf("💾 Save '%s' to\n\n%s\n\n[Enter] Save • [Esc] Cancel", m.buffers[m.bufferCursor].Name, m.input.View())
+		inputView := inputBoxStyle.Render(prompt)
+		content.WriteString(lipgloss.Place(m.width, 7, lipgloss.Center, lipgloss.Top, inputView))
+		content.WriteString("\n")
+	}
+
+	if m.message != "" {
+		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, m.renderMessage()))
+		content.WriteString("\n")
+	}
+
+	hints := "[w] Save to file • [c] Load clipboard • [d] Delete • [Esc] Back"
+	if readOnly {
+		hints = "[w] Save to file • [c] Load clipboard • [Esc] Back • 🔒 Read-only"
+	}
+	statusBar := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("16")).
+		Padding(0, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderTop(true).
+		BorderForeground(primaryColor).
+		Render(hints)
+	content.WriteString("\n")
+	content.WriteString(lipgloss.Place(tableWidth, 1, lipgloss.Center, lipgloss.Top, statusBar))
+
+	return baseStyle.Render(content.String())
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/newcharhuso/tmux-navigator/pkg/lazytmux"
+)
+
+// readTemplate decodes a single JSON or YAML template from path, an HTTPS
+// URL, or stdin if path is "-".
+func readTemplate(path string) (SessionTemplate, error) {
+	data, err := readSource(path)
+	if err != nil {
+		return SessionTemplate{}, err
+	}
+
+	template, err := decodeTemplate(data)
+	if err != nil {
+		return SessionTemplate{}, fmt.Errorf("invalid template: %v", err)
+	}
+	if problems := templateProblems(template); len(problems) > 0 {
+		return SessionTemplate{}, fmt.Errorf("invalid template: %s", strings.Join(problems, "; "))
+	}
+	return template, nil
+}
+
+// parseInterspersed parses flags that may appear before, between or after
+// positional arguments and returns the positionals in order.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// varFlags collects repeated --var name=value flags.
+type varFlags map[string]string
+
+func (v varFlags) String() string {
+	var pairs []string
+	for _, k := range sortedKeys(v) {
+		pairs = append(pairs, k+"="+v[k])
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (v varFlags) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("expected name=value, got %q", s)
+	}
+	v[strings.TrimSpace(name)] = value
+	return nil
+}
+
+const (
+	existsFail   = lazytmux.ExistsFail
+	existsAttach = lazytmux.ExistsAttach
+	existsSuffix = lazytmux.ExistsSuffix
+)
+
+// exitExists is the exit code of apply, start and new when the session name
+// is taken, so scripts can tell it apart from real failures.
+const exitExists = 3
+
+// claimFailed reports an error of claimSession and returns the exit code.
+func claimFailed(err error) int {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	if errors.Is(err, errSessionExists) {
+		return exitExists
+	}
+	return 1
+}
+
+// claimSession creates an empty session for a template according to the
+// exists policy and returns the name that was used. created is false when
+// the policy chose to reuse a session that was already there.
+func (s Server) claimSession(name, dir, policy string) (sessionName string, created bool, err error) {
+	return s.builder().Claim(context.Background(), name, dir, policy)
+}
+
+// runApply instantiates a template read from a file or stdin without
+// storing it: lazytmux apply [--exists=attach|fail|suffix] <file|-> [session-name]
+func runApply(args []string) int {
+	fs := flag.NewFlagSet("apply", flag.ContinueOnError)
+	exists := fs.String("exists", existsFail, "What to do if the session already exists: attach, fail or suffix")
+	vars := varFlags{}
+	fs.Var(vars, "var", "Value for a template `name=value` placeholder (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s apply [--exists=attach|fail|suffix] [--var name=value]... <file|-> [session-name]\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	args, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(args) < 1 || len(args) > 2 {
+		fs.Usage()
+		return 2
+	}
+
+	template, err := readTemplate(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if template, err = template.withVars(vars); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v (pass --var name=value)\n", err)
+		return 1
+	}
+
+	sessionName := sanitizeSessionName(template.Name)
+	if len(args) == 2 {
+		sessionName = sanitizeSessionName(args[1])
+	}
+	if err := checkSessionName(sessionName, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	srv := currentServer()
+	sessionName, created, err := srv.claimSession(sessionName, template.StartDir(), *exists)
+	if err != nil {
+		return claimFailed(err)
+	}
+	if created {
+		if err := srv.applyTemplate(sessionName, template); err != nil {
+			// Don't leave a half-built session behind for the next run to trip over.
+			_ = srv.killSession(sessionName)
+			fmt.Fprintf(os.Stderr, "Error: failed to create session from template: %v\n", err)
+			return 1
+		}
+	}
+	fmt.Println(sessionName)
+	return 0
+}
+
+// runLayout opens every session of a configured layout in its own terminal,
+// creating missing sessions (from a template of the same name if there is
+// one) and placing the terminals through the window manager.
+func runLayout(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s layout <name>\n", os.Args[0])
+		return 2
+	}
+	sessions, ok := config.Layouts[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: no layout named '%s' in %s\n", args[0], getConfigFile())
+		return 1
+	}
+	if spawnsTerminals() {
+		if err := validateTerminal(terminalCmd); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+
+	templates := loadTemplates()
+	srv := currentServer()
+	var targets []attachTarget
+	for _, name := range sessions {
+		targets = append(targets, attachTarget{srv, name})
+		if srv.sessionExists(name) {
+			continue
+		}
+		var err error
+		if template := findTemplateByPrefix(name, templates); template != nil && len(template.Placeholders()) == 0 {
+			if err = srv.createSessionFromTemplate(name, *template); err == nil {
+				recordTemplateUse(template.Name)
+				_, err = runHook(template.OnAttach, name, *template)
+			}
+		} else {
+			err = srv.createSession(name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to create session '%s': %v\n", name, err)
+			return 1
+		}
+	}
+
+	if err := attachSessions(targets); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not place some terminals: %v\n", err)
+	}
+	flushDeferredAttach()
+	return 0
+}
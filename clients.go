@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Client is a terminal attached to the tmux server.
+type Client struct {
+	Name     string // the client's tty, e.g. "/dev/pts/3"
+	Session  string
+	Server   string // name of its server when several are listed
+	Width    int
+	Height   int
+	Activity time.Time
+	Term     string
+	ReadOnly bool
+	// Smallest is set when other clients share the session and this one is
+	// the smallest, so the session is shrunk to its size.
+	Smallest bool
+}
+
+// listClients lists the clients of every managed server.
+func listClients() []Client {
+	clients := []Client{}
+	for _, s := range managedServers() {
+		clients = append(clients, listServerClients(s)...)
+	}
+	sort.SliceStable(clients, func(i, j int) bool {
+		return clients[i].Session < clients[j].Session
+	})
+	markSmallest(clients)
+	return clients
+}
+
+func listServerClients(server Server) []Client {
+	format := strings.Join([]string{
+		"#{client_name}",
+		"#{client_width}",
+		"#{client_height}",
+		"#{client_activity}",
+		"#{client_termname}",
+		"#{client_readonly}",
+		"#{session_name}",
+	}, "\t")
+	out, err := server.command("list-clients", "-F", format).Output()
+	if err != nil {
+		return []Client{}
+	}
+
+	clients := []Client{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		parts := strings.SplitN(line, "\t", 7)
+		if len(parts) < 7 {
+			continue
+		}
+		width, _ := strconv.Atoi(parts[1])
+		height, _ := strconv.Atoi(parts[2])
+		activity, _ := strconv.ParseInt(parts[3], 10, 64)
+		clients = append(clients, Client{
+			Name:     parts[0],
+			Width:    width,
+			Height:   height,
+			Activity: time.Unix(activity, 0),
+			Term:     parts[4],
+			ReadOnly: parts[5] == "1",
+			Session:  parts[6],
+			Server:   server.Name,
+		})
+	}
+	return clients
+}
+
+// markSmallest flags, per session, the client that holds the others back.
+// Nothing is flagged for sessions whose clients all have the same size.
+func markSmallest(clients []Client) {
+	bySession := map[string][]int{}
+	for i, c := range clients {
+		key := c.Server + "\x00" + c.Session
+		bySession[key] = append(bySession[key], i)
+	}
+	for _, idx := range bySession {
+		smallest, differ := idx[0], false
+		for _, i := range idx[1:] {
+			c, s := clients[i], clients[smallest]
+			if c.Width != s.Width || c.Height != s.Height {
+				differ = true
+			}
+			if c.Width*c.Height < s.Width*s.Height {
+				smallest = i
+			}
+		}
+		clients[smallest].Smallest = differ
+	}
+}
+
+// detachClient detaches a client, leaving its terminal running.
+func (s Server) detachClient(name string) error {
+	return s.run("detach-client", "-t", name)
+}
+
+// killClient detaches a client and hangs up the process it runs in, which
+// usually closes the terminal.
+func (s Server) killClient(name string) error {
+	return s.run("detach-client", "-P", "-t", name)
+}
+
+// formatIdle describes how long ago a client was last used.
+func formatIdle(since time.Time) string {
+	d := time.Since(since)
+	switch {
+	case since.Unix() <= 0:
+		return "-"
+	case d < 10*time.Second:
+		return "active"
+	case d < time.Minute:
+		return fmt.Sprintf("%ds ago", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+}
+
+// readClients reads the clients of every server for refreshView.
+func readClients() func(m *model) {
+	clients := listClients()
+	return func(m *model) {
+		if !m.showClients {
+			return
+		}
+		m.clients = clients
+		if m.clientCursor >= len(m.clients) && len(m.clients) > 0 {
+			m.clientCursor = len(m.clients) - 1
+		}
+	}
+}
+
+func (m model) renderClientView(tableWidth int) string {
+	var content strings.Builder
+
+	title := tableHeaderStyle.Width(tableWidth).Render("🖥️ ATTACHED CLIENTS")
+	content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, title))
+	content.WriteString("\n\n")
+
+	if len(m.clients) == 0 {
+		text := "No clients are attached to any session."
+		if m.viewLoading() {
+			text = "Reading the clients..."
+		}
+		emptyMsg := lipgloss.NewStyle().
+			Foreground(mutedColor).
+			Italic(true).
+			Render(text)
+		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, emptyMsg))
+		content.WriteString("\n\n")
+	} else {
+		sessionW, sizeW, idleW, termW := tableWidth/4, 18, 10, 17
+		nameW := tableWidth - sessionW - sizeW - idleW - termW
+		cell := func(width int, s string) string {
+			return lipgloss.NewStyle().Width(width).MaxWidth(width).MaxHeight(1).Padding(0, 1).Render(s)
+		}
+
+		header := lipgloss.JoinHorizontal(lipgloss.Top,
+			cell(sessionW, "SESSION"), cell(nameW, "CLIENT"), cell(sizeW, "SIZE"), cell(idleW, "ACTIVITY"), cell(termW, "TERMINAL"))
+		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, lipgloss.NewStyle().Bold(true).Render(header)))
+		content.WriteString("\n")
+
+		for i, c := range m.clients {
+			name := c.Name
+			if c.ReadOnly {
+				name += " (read-only)"
+			}
+			size := fmt.Sprintf("%dx%d", c.Width, c.Height)
+			if c.Smallest {
+				size += " smallest"
+			}
+			row := lipgloss.JoinHorizontal(lipgloss.Top,
+				cell(sessionW, c.Session),
+				cell(nameW, name),
+				cell(sizeW, size),
+				cell(idleW, formatIdle(c.Activity)),
+				cell(termW, c.Term))
+			switch {
+			case i == m.clientCursor && m.mode == clientBrowsing:
+				row = lipgloss.NewStyle().Background(primaryColor).Foreground(lipgloss.Color("15")).Render(row)
+			case c.Smallest:
+				row = lipgloss.NewStyle().Foreground(warningColor).Render(row)
+			}
+			content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, row))
+			content.WriteString("\n")
+		}
+		content.WriteString("\n")
+	}
+
+	if m.mode == confirming && m.confirmAction == actionKillClient {
+		confirmText := fmt.Sprintf("⚠️  KILL CLIENT %s?\n\nThe terminal it runs in will be hung up!\n\n[y] Yes  [n] No", m.confirmTarget)
+		confirmView := confirmBoxStyle.Render(confirmText)
+		content.WriteString(lipgloss.Place(m.width, 7, lipgloss.Center, lipgloss.Center, confirmView))
+	}
+
+	if m.message != "" {
+		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, m.renderMessage()))
+		content.WriteString("\n")
+	}
+
+	hints := "[d] Detach • [x] Kill • [Esc] Back"
+	if readOnly {
+		hints = "[Esc] Back • 🔒 Read-only"
+	}
+	statusBar := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("16")).
+		Padding(0, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderTop(true).
+		BorderForeground(primaryColor).
+		Render(hints)
+	content.WriteString("\n")
+	content.WriteString(lipgloss.Place(tableWidth, 1, lipgloss.Center, lipgloss.Top, statusBar))
+
+	return baseStyle.Render(content.String())
+}
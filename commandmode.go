@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// How many commands the : history keeps.
+const maxCommandHistory = 500
+
+// How much of a command's output fits in the message area.
+const maxCommandOutputLines = 6
+
+// tmuxCommandMsg reports what a : command printed.
+type tmuxCommandMsg struct {
+	command string
+	output  string
+	err     error
+}
+
+func getCommandHistoryFile() string {
+	return filepath.Join(getConfigDir(), "command_history")
+}
+
+// loadCommandHistory returns earlier : commands, oldest first.
+func loadCommandHistory() []string {
+	data, err := ioutil.ReadFile(getCommandHistoryFile())
+	if err != nil {
+		return nil
+	}
+	var history []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			history = append(history, line)
+		}
+	}
+	return history
+}
+
+// addCommandHistory appends command unless it repeats the last one, and
+// trims the history to maxCommandHistory.
+func addCommandHistory(history []string, command string) []string {
+	if len(history) > 0 && history[len(history)-1] == command {
+		return history
+	}
+	history = append(history, command)
+	if len(history) > maxCommandHistory {
+		history = history[len(history)-maxCommandHistory:]
+	}
+	os.MkdirAll(getConfigDir(), 0755)
+	writeFileAtomic(getCommandHistoryFile(), []byte(strings.Join(history, "\n")+"\n"))
+	return history
+}
+
+// runTmuxCommand hands command to the tmux parser of server, so quoting,
+// {} blocks and ; work as at the tmux prompt.
+func runTmuxCommand(server Server, command string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		cmd := server.CommandContext(ctx, "source-file", "-")
+		cmd.Stdin = strings.NewReader(command + "\n")
+		var out bytes.Buffer
+		cmd.Stdout, cmd.Stderr = &out, &out
+		err := cmd.Run()
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out")
+		}
+		// Errors come back as "-:1: message", the position in our stdin.
+		output := strings.TrimSpace(out.String())
+		output = strings.TrimPrefix(output, "-:1: ")
+		return tmuxCommandMsg{command: command, output: output, err: err}
+	}
+}
+
+func (m *model) startCommandMode() {
+	ti := textinput.New()
+	ti.Prompt = ":"
+	ti.Placeholder = "tmux command, e.g. set -g mouse on"
+	ti.CharLimit = 500
+	ti.Focus()
+	m.input = ti
+	m.commandHistory = loadCommandHistory()
+	m.historyIndex = len(m.commandHistory)
+	m.mode = commandEntering
+}
+
+// browseCommandHistory replaces the input with an older (-1) or newer (+1)
+// command. Past the newest one the input is empty again.
+func (m *model) browseCommandHistory(delta int) {
+	i := m.historyIndex + delta
+	if i < 0 || i > len(m.commandHistory) {
+		return
+	}
+	m.historyIndex = i
+	if i == len(m.commandHistory) {
+		m.input.SetValue("")
+	} else {
+		m.input.SetValue(m.commandHistory[i])
+	}
+	m.input.CursorEnd()
+}
+
+// showCommandResult puts a command's output, or its error, in the message
+// area, cut to a few lines.
+func (m *model) showCommandResult(msg tmuxCommandMsg) {
+	lines := strings.Split(msg.output, "\n")
+	if len(lines) > maxCommandOutputLines {
+		more := len(lines) - maxCommandOutputLines
+		lines = append(lines[:maxCommandOutputLines], fmt.Sprintf("… %d more lines", more))
+	}
+	output := strings.Join(lines, "\n")
+	switch {
+	case msg.err != nil && output != "":
+		m.setMessage(output, "error")
+	case msg.err != nil:
+		m.setMessage(fmt.Sprintf("%s: %v", msg.command, msg.err), "error")
+	case output != "":
+		m.setMessage(output, "info")
+	default:
+		m.setMessage(fmt.Sprintf("Ran %s", msg.command), "success")
+	}
+}
+
+func (m model) renderCommandPrompt() string {
+	hint := lipgloss.NewStyle().Foreground(mutedColor).Render("[Enter] Run • [↑/↓] History • [Esc] Cancel")
+	inputView := inputBoxStyle.Render(m.input.View() + "\n" + hint)
+	return lipgloss.Place(m.width, 4, lipgloss.Center, lipgloss.Top, inputView)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Config holds user settings from ~/.config/lazytmux/config.json. Every
+// field is optional; the zero value keeps the built-in behavior.
+type Config struct {
+	// How Enter attaches: "terminal" launches a terminal emulator, "exec"
+	// attaches in the current terminal on exit, "print" prints the tmux
+	// attach command on exit and "copy" also copies it to the clipboard.
+	// "auto" (default) is exec over SSH or without a display, else terminal.
+	Attach string `json:"attach,omitempty"`
+	// Window manager used to place attached terminals: "auto" (default),
+	// "hyprland", "sway", "i3" or "none".
+	WindowManager string `json:"window_manager,omitempty"`
+	// Where the terminal for a given session should be placed.
+	Placements map[string]Placement `json:"placements,omitempty"`
+	// Named groups of sessions opened together with `lazytmux layout <name>`.
+	Layouts map[string][]string `json:"layouts,omitempty"`
+	// File kills, renames and template deletions are logged to, or "off".
+	// Defaults to audit.log in the config directory.
+	AuditLog string `json:"audit_log,omitempty"`
+	// Initial order of the template browser: "name" (default), "recent"
+	// or "frequent".
+	TemplateOrder string `json:"template_order,omitempty"`
+	// What Enter does after creating a session: "attach" (default) attaches
+	// and quits, "stay" keeps the TUI open. Alt+Enter always does the other.
+	AfterCreate string `json:"after_create,omitempty"`
+	// Tag, color or protect sessions by name, directory or template.
+	Rules []SessionRule `json:"rules,omitempty"`
+	// "off" stops the TUI from installing tmux hooks that tell it about
+	// sessions created, closed or attached elsewhere.
+	EventHooks string `json:"event_hooks,omitempty"`
+	// "on" makes the TUI answer JSON commands on the control socket while
+	// it runs, like `lazytmux serve` does without the TUI.
+	API string `json:"api,omitempty"`
+	// "on" shows the last line of each session's active pane from the
+	// start, as l does.
+	LastOutput string `json:"last_output,omitempty"`
+	// "on" checks GitHub for a newer release once a day and announces it
+	// in a banner. Off by default.
+	UpdateCheck string `json:"update_check,omitempty"`
+	// How Enter attaches: "normal" (default), "detach-others" detaches
+	// every other client of the session and "read-only" only watches.
+	// Alt+Enter picks one for a single attach.
+	AttachOptions string `json:"attach_options,omitempty"`
+	// Keys bound to shell commands in the session or template list.
+	Actions []CustomAction `json:"actions,omitempty"`
+	// "compact" lists sessions one line each without borders, fitting more
+	// on the screen; "detailed" (default) draws a box around every cell.
+	// v switches between them and saves the choice here.
+	Density string `json:"density,omitempty"`
+	// Retries and parallelism for building sessions from templates.
+	Creation CreationPolicy `json:"creation,omitempty"`
+	// The tmux server to manage instead of the default one, by socket name
+	// (tmux -L) or path (tmux -S). --socket-name and --socket-path override
+	// them for one run.
+	SocketName string `json:"socket_name,omitempty"`
+	SocketPath string `json:"socket_path,omitempty"`
+	// Servers picked by name with --profile.
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+	// Servers whose sessions are listed together, each marked with its
+	// server, unless a server is picked with a flag or the options above.
+	Servers []Server `json:"servers,omitempty"`
+	// Shell command run in the terminal about to attach, just before it
+	// does, with LAZYTMUX_SESSION set.
+	PreAttach string `json:"pre_attach,omitempty"`
+	// "on" stops the attach when pre_attach fails.
+	PreAttachBlocking string `json:"pre_attach_blocking,omitempty"`
+	// Command O opens the highlighted session's directory with, {cwd}
+	// standing for it, else it is appended. Defaults to xdg-open, or open
+	// on macOS.
+	OpenCommand string `json:"open_command,omitempty"`
+	// "on" hands the terminal to open_command, for editors that run in it.
+	OpenInteractive string `json:"open_interactive,omitempty"`
+	// "on" shows the CPU and memory used by each session from the start,
+	// as u does.
+	Resources string `json:"resources,omitempty"`
+	// "off" stops showing the git branch and uncommitted changes of each
+	// session's directory.
+	GitStatus string `json:"git_status,omitempty"`
+	// What characters tmux rejects in session names, like '.' and ':',
+	// become: "_" (default), any other string, or "drop" to remove them.
+	NameReplacement string `json:"name_replacement,omitempty"`
+	// "on" also replaces everything outside ASCII in session names.
+	NameASCII string `json:"name_ascii,omitempty"`
+	// "on" also shows a desktop notification when a command watched with
+	// T finishes.
+	WatchNotify string `json:"watch_notify,omitempty"`
+	// Directory L in the pane view logs panes to. Defaults to logs in the
+	// config directory.
+	LogDir string `json:"log_dir,omitempty"`
+	// "reduced" shows the pop of the row the cursor lands on as a single
+	// frame, "off" doesn't show it.
+	Animations string `json:"animations,omitempty"`
+	// How often auto-refresh reloads the session list, like "10s" or
+	// "1m", or "0" to only reload it on Ctrl+R. Defaults to 5 seconds, 30
+	// while the event hooks are in place.
+	RefreshInterval string `json:"refresh_interval,omitempty"`
+	// "off" leaves the mouse to the terminal instead of clicking and
+	// scrolling the lists.
+	Mouse string `json:"mouse,omitempty"`
+	// "on" keeps the session T in the template editor builds a template
+	// into when the preview is left, instead of killing it.
+	KeepTestSessions string `json:"keep_test_sessions,omitempty"`
+	// When sessions count as idle, and whether to offer killing them.
+	Idle IdlePolicy `json:"idle,omitempty"`
+	// Globs of project directories, like "~/code/*", listed after the
+	// sessions until they have one; Enter creates it in the directory.
+	ProjectRoots []string `json:"project_roots,omitempty"`
+	// Template project sessions are built from, rooted in the project.
+	// Plain sessions by default.
+	ProjectTemplate string `json:"project_template,omitempty"`
+	// Where to describe mode changes and results in plain text, for screen
+	// readers and logs: "stderr", "fd:N", "osc" for OSC 777 notifications,
+	// a file to append to, or "off" (default).
+	Announce string `json:"announce,omitempty"`
+}
+
+// Placement describes where the window manager should put a terminal.
+type Placement struct {
+	Workspace string `json:"workspace,omitempty"`
+	Split     string `json:"split,omitempty"` // "h" or "v", i3/sway only
+	Floating  bool   `json:"floating,omitempty"`
+	X         int    `json:"x,omitempty"`
+	Y         int    `json:"y,omitempty"`
+	Width     int    `json:"width,omitempty"`
+	Height    int    `json:"height,omitempty"`
+}
+
+var config Config
+
+func getConfigFile() string {
+	return filepath.Join(getConfigDir(), "config.json")
+}
+
+// saveConfigValue sets one key of the config file, leaving the others as
+// they are, including any this version doesn't know about.
+func saveConfigValue(key string, value interface{}) error {
+	raw := map[string]json.RawMessage{}
+	data, err := ioutil.ReadFile(getConfigFile())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+	}
+	if raw[key], err = json.Marshal(value); err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(getConfigDir(), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(getConfigFile(), append(out, '\n'))
+}
+
+// loadConfig reads the config file. A missing file is not an error.
+func loadConfig() (Config, error) {
+	var cfg Config
+	data, err := ioutil.ReadFile(getConfigFile())
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	err = json.Unmarshal(data, &cfg)
+	return cfg, err
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/newcharhuso/tmux-navigator/pkg/lazytmux"
+)
+
+// CreationPolicy tunes how templates are turned into sessions, for busy or
+// remote tmux servers that now and then fail a single command.
+type CreationPolicy struct {
+	// How many windows get their panes built at the same time. 1 (default)
+	// builds them one after the other.
+	Concurrency int `json:"concurrency,omitempty"`
+	// How often a failed new-window or split-window is tried again before
+	// the session is given up. Defaults to 2; 0 never retries.
+	Retries *int `json:"retries,omitempty"`
+	// How long to wait before the first retry, e.g. "250ms" (default). Each
+	// further retry waits twice as long as the one before.
+	Backoff string `json:"backoff,omitempty"`
+}
+
+const (
+	defaultCreateRetries = 2
+	defaultCreateBackoff = 250 * time.Millisecond
+)
+
+func (p CreationPolicy) concurrency() int {
+	if p.Concurrency < 1 {
+		return 1
+	}
+	return p.Concurrency
+}
+
+func (p CreationPolicy) retries() int {
+	if p.Retries == nil || *p.Retries < 0 {
+		return defaultCreateRetries
+	}
+	return *p.Retries
+}
+
+func (p CreationPolicy) backoff() time.Duration {
+	if d, err := time.ParseDuration(p.Backoff); err == nil && d >= 0 {
+		return d
+	}
+	return defaultCreateBackoff
+}
+
+// creationProblems lists what is wrong with the creation settings, for
+// doctor.
+func creationProblems(p CreationPolicy) []string {
+	var problems []string
+	if p.Concurrency < 0 {
+		problems = append(problems, "creation: concurrency should be 1 or more")
+	}
+	if p.Retries != nil && *p.Retries < 0 {
+		problems = append(problems, "creation: retries should be 0 or more")
+	}
+	if p.Backoff != "" {
+		if d, err := time.ParseDuration(p.Backoff); err != nil || d < 0 {
+			problems = append(problems, "creation: backoff should be a duration like 250ms or 1s")
+		}
+	}
+	return problems
+}
+
+// builder returns the template engine for s, tuned by config.Creation.
+// Waiting panes run the wait subcommand of this very binary.
+func (s Server) builder() *lazytmux.Builder {
+	b := lazytmux.NewBuilder(s)
+	b.Owner = currentUser
+	b.Concurrency = config.Creation.concurrency()
+	b.Retries = config.Creation.retries()
+	b.Backoff = config.Creation.backoff()
+	if self, err := os.Executable(); err == nil {
+		b.WaitProgram = self
+	}
+	return b
+}
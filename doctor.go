@@ -0,0 +1,562 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type checkStatus int
+
+const (
+	checkOK checkStatus = iota
+	checkWarn
+	checkFail
+)
+
+type checkResult struct {
+	name   string
+	status checkStatus
+	detail string
+	fix    string
+}
+
+// Minimum tmux version we test against; older servers mostly work but
+// lack some of the format variables we rely on.
+const minTmuxMajor, minTmuxMinor = 3, 0
+
+var supportedTerminals = []string{"kitty", "alacritty", "gnome-terminal", "xterm", "konsole", "terminator", "tilix"}
+
+// runDoctor checks the environment lazytmux depends on and prints a report
+// with a suggested fix for every problem. It returns the process exit code.
+func runDoctor() int {
+	checks := []func() checkResult{
+		checkTmuxBinary,
+		checkTmuxServer,
+		checkSocketDir,
+		checkTerminal,
+		checkConfigDir,
+		checkConfigFile,
+		checkTemplates,
+	}
+
+	failed := 0
+	warned := 0
+	for _, check := range checks {
+		res := check()
+		var mark string
+		switch res.status {
+		case checkOK:
+			mark = "✓"
+		case checkWarn:
+			mark = "!"
+			warned++
+		default:
+			mark = "✗"
+			failed++
+		}
+		fmt.Printf("%s %-12s %s\n", mark, res.name, res.detail)
+		if res.fix != "" && res.status != checkOK {
+			for _, line := range strings.Split(res.fix, "\n") {
+				fmt.Printf("  → %s\n", line)
+			}
+		}
+	}
+
+	fmt.Println()
+	switch {
+	case failed > 0:
+		fmt.Printf("%d problem(s), %d warning(s) found\n", failed, warned)
+		return 1
+	case warned > 0:
+		fmt.Printf("No problems found, %d warning(s)\n", warned)
+	default:
+		fmt.Println("Everything looks good")
+	}
+	return 0
+}
+
+func checkTmuxBinary() checkResult {
+	res := checkResult{name: "tmux"}
+	path, err := exec.LookPath("tmux")
+	if err != nil {
+		res.status = checkFail
+		res.detail = "tmux not found in PATH"
+		res.fix = "Install tmux with your package manager (e.g. apt install tmux, brew install tmux)"
+		return res
+	}
+
+	out, err := tmuxCommand("-V").Output()
+	if err != nil {
+		res.status = checkFail
+		res.detail = fmt.Sprintf("%s does not run: %v", path, err)
+		res.fix = "Reinstall tmux or make sure the binary in PATH is executable"
+		return res
+	}
+
+	version := strings.TrimSpace(string(out))
+	major, minor, ok := parseTmuxVersion(version)
+	if !ok {
+		res.status = checkWarn
+		res.detail = fmt.Sprintf("%s (could not parse version)", version)
+		return res
+	}
+	if major < minTmuxMajor || (major == minTmuxMajor && minor < minTmuxMinor) {
+		res.status = checkWarn
+		res.detail = fmt.Sprintf("%s is older than %d.%d", version, minTmuxMajor, minTmuxMinor)
+		res.fix = "Upgrade tmux; some template features may not work as expected"
+		return res
+	}
+	res.detail = fmt.Sprintf("%s (%s)", version, path)
+	return res
+}
+
+// parseTmuxVersion extracts major/minor from "tmux 3.3a", "tmux next-3.4", etc.
+func parseTmuxVersion(s string) (int, int, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "tmux ")
+	s = strings.TrimPrefix(s, "next-")
+	s = strings.TrimPrefix(s, "master")
+	parts := strings.SplitN(s, ".", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	digits := strings.TrimRightFunc(parts[1], func(r rune) bool { return r < '0' || r > '9' })
+	minor, err := strconv.Atoi(digits)
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
+func checkTmuxServer() checkResult {
+	res := checkResult{name: "server"}
+	if _, err := exec.LookPath("tmux"); err != nil {
+		res.status = checkFail
+		res.detail = "skipped, tmux is not installed"
+		return res
+	}
+
+	var stderr bytes.Buffer
+	cmd := tmuxCommand("display-message", "-p", "#{socket_path}")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err == nil {
+		res.detail = fmt.Sprintf("reachable at %s", strings.TrimSpace(string(out)))
+		return res
+	}
+
+	msg := strings.TrimSpace(stderr.String())
+	switch {
+	case strings.Contains(msg, "no server running"), strings.Contains(msg, "no current client"):
+		// display-message without a client still fails on some versions,
+		// fall back to asking for the session list.
+		if listErr := tmuxCommand("list-sessions").Run(); listErr == nil {
+			res.detail = "reachable"
+			return res
+		}
+		res.status = checkWarn
+		res.detail = "no tmux server running"
+		res.fix = "This is fine; one is started when you create a session (or run: tmux new-session -d)"
+	case strings.Contains(msg, "Permission denied"), strings.Contains(msg, "error connecting"):
+		res.status = checkFail
+		res.detail = msg
+		res.fix = "Check ownership of the tmux socket directory (see the socket check)\nIf the server is stale, remove the socket file and start tmux again"
+	default:
+		res.status = checkFail
+		if msg == "" {
+			msg = err.Error()
+		}
+		res.detail = msg
+		res.fix = "Try running 'tmux list-sessions' directly to see the full error"
+	}
+	return res
+}
+
+func tmuxSocketDir() string {
+	tmpDir := os.Getenv("TMUX_TMPDIR")
+	if tmpDir == "" {
+		tmpDir = "/tmp"
+	}
+	return filepath.Join(tmpDir, fmt.Sprintf("tmux-%d", os.Getuid()))
+}
+
+func checkSocketDir() checkResult {
+	res := checkResult{name: "socket dir"}
+	dir := tmuxSocketDir()
+	info, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		res.status = checkWarn
+		res.detail = fmt.Sprintf("%s does not exist yet", dir)
+		res.fix = "tmux creates it when the first server starts"
+		return res
+	}
+	if err != nil {
+		res.status = checkFail
+		res.detail = err.Error()
+		return res
+	}
+	if !info.IsDir() {
+		res.status = checkFail
+		res.detail = fmt.Sprintf("%s is not a directory", dir)
+		res.fix = fmt.Sprintf("Remove it: rm %s", dir)
+		return res
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		res.status = checkFail
+		res.detail = fmt.Sprintf("%s has mode %04o, tmux refuses to use it", dir, perm)
+		res.fix = fmt.Sprintf("Run: chmod 700 %s", dir)
+		return res
+	}
+	res.detail = dir
+	return res
+}
+
+func checkTerminal() checkResult {
+	res := checkResult{name: "terminal"}
+	if !spawnsTerminals() {
+		res.detail = fmt.Sprintf("not needed, attaching with %s", attachMode())
+		if reason := headless(); reason != "" && config.Attach != attachMode() {
+			res.detail += " (" + reason + ")"
+		}
+		return res
+	}
+	if err := validateTerminal(terminalCmd); err != nil {
+		res.status = checkFail
+		res.detail = err.Error()
+
+		var available []string
+		for _, term := range supportedTerminals {
+			if _, err := exec.LookPath(term); err == nil {
+				available = append(available, term)
+			}
+		}
+		if len(available) > 0 {
+			res.fix = fmt.Sprintf("Use one of the installed terminals: lazytmux -t %s\n(or set LAYTMUX_TERMINAL)", available[0])
+		} else {
+			res.fix = "Install one of: " + strings.Join(supportedTerminals, ", ")
+		}
+		return res
+	}
+	res.detail = terminalCmd
+	return res
+}
+
+func checkConfigDir() checkResult {
+	res := checkResult{name: "config dir"}
+	dir := getConfigDir()
+	info, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		res.status = checkWarn
+		res.detail = fmt.Sprintf("%s does not exist yet", dir)
+		res.fix = "It is created automatically when you save your first template"
+		return res
+	}
+	if err != nil {
+		res.status = checkFail
+		res.detail = err.Error()
+		return res
+	}
+	if !info.IsDir() {
+		res.status = checkFail
+		res.detail = fmt.Sprintf("%s is not a directory", dir)
+		res.fix = fmt.Sprintf("Move it out of the way: mv %s %s.bak", dir, dir)
+		return res
+	}
+
+	f, err := ioutil.TempFile(dir, ".doctor-")
+	if err != nil {
+		res.status = checkFail
+		res.detail = fmt.Sprintf("%s is not writable", dir)
+		res.fix = fmt.Sprintf("Fix ownership: chown -R $USER %s && chmod u+rwx %s", dir, dir)
+		return res
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	res.detail = dir
+	return res
+}
+
+func checkConfigFile() checkResult {
+	res := checkResult{name: "config"}
+	file := getConfigFile()
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		res.detail = "no config file, using defaults"
+		return res
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		res.status = checkFail
+		res.detail = fmt.Sprintf("%s: %v", file, err)
+		res.fix = fmt.Sprintf("Fix the file by hand or move it away: mv %s %s.bak", file, file)
+		return res
+	}
+
+	var problems []string
+	switch cfg.Attach {
+	case "", attachAuto, attachTerminal, attachExec, attachPrint, attachCopy:
+	default:
+		problems = append(problems, fmt.Sprintf("attach %q should be auto, terminal, exec, print or copy", cfg.Attach))
+	}
+	switch cfg.AttachOptions {
+	case "", attachNormal, attachDetachOthers, attachReadOnly:
+	default:
+		problems = append(problems, fmt.Sprintf("attach_options %q should be normal, detach-others or read-only", cfg.AttachOptions))
+	}
+	switch cfg.API {
+	case "", "on", "off":
+	default:
+		problems = append(problems, fmt.Sprintf("api %q should be on or off", cfg.API))
+	}
+	switch cfg.UpdateCheck {
+	case "", "on", "off":
+	default:
+		problems = append(problems, fmt.Sprintf("update_check %q should be on or off", cfg.UpdateCheck))
+	}
+	switch cfg.LastOutput {
+	case "", "on", "off":
+	default:
+		problems = append(problems, fmt.Sprintf("last_output %q should be on or off", cfg.LastOutput))
+	}
+	switch cfg.PreAttachBlocking {
+	case "", "on", "off":
+	default:
+		problems = append(problems, fmt.Sprintf("pre_attach_blocking %q should be on or off", cfg.PreAttachBlocking))
+	}
+	switch cfg.NameASCII {
+	case "", "on", "off":
+	default:
+		problems = append(problems, fmt.Sprintf("name_ascii %q should be on or off", cfg.NameASCII))
+	}
+	if cfg.NameReplacement != "drop" && strings.ContainsAny(cfg.NameReplacement, ".:/\\") {
+		problems = append(problems, fmt.Sprintf("name_replacement %q contains characters tmux rejects", cfg.NameReplacement))
+	}
+	switch cfg.Resources {
+	case "", "on", "off":
+	default:
+		problems = append(problems, fmt.Sprintf("resources %q should be on or off", cfg.Resources))
+	}
+	switch cfg.WatchNotify {
+	case "", "on", "off":
+	default:
+		problems = append(problems, fmt.Sprintf("watch_notify %q should be on or off", cfg.WatchNotify))
+	}
+	switch cfg.Animations {
+	case "", "on", "reduced", "off":
+	default:
+		problems = append(problems, fmt.Sprintf("animations %q should be on, reduced or off", cfg.Animations))
+	}
+	if cfg.RefreshInterval != "" {
+		if _, err := parseRefreshInterval(cfg.RefreshInterval); err != nil {
+			problems = append(problems, fmt.Sprintf("refresh_interval %q should be a duration of a second or more like 10s, or 0", cfg.RefreshInterval))
+		}
+	}
+	switch cfg.Mouse {
+	case "", "on", "off":
+	default:
+		problems = append(problems, fmt.Sprintf("mouse %q should be on or off", cfg.Mouse))
+	}
+	switch cfg.KeepTestSessions {
+	case "", "on", "off":
+	default:
+		problems = append(problems, fmt.Sprintf("keep_test_sessions %q should be on or off", cfg.KeepTestSessions))
+	}
+	switch cfg.OpenInteractive {
+	case "", "on", "off":
+	default:
+		problems = append(problems, fmt.Sprintf("open_interactive %q should be on or off", cfg.OpenInteractive))
+	}
+	switch cfg.GitStatus {
+	case "", "on", "off":
+	default:
+		problems = append(problems, fmt.Sprintf("git_status %q should be on or off", cfg.GitStatus))
+	}
+	switch cfg.Density {
+	case "", "compact", "detailed":
+	default:
+		problems = append(problems, fmt.Sprintf("density %q should be compact or detailed", cfg.Density))
+	}
+	if fd, ok := strings.CutPrefix(cfg.Announce, "fd:"); ok {
+		if n, err := strconv.Atoi(fd); err != nil || n < 1 {
+			problems = append(problems, fmt.Sprintf("announce %q should name a file descriptor, like fd:3", cfg.Announce))
+		}
+	}
+	switch cfg.WindowManager {
+	case "", "auto", "none", "hyprland", "sway", "i3":
+	default:
+		problems = append(problems, fmt.Sprintf("window_manager %q should be auto, none, hyprland, sway or i3", cfg.WindowManager))
+	}
+	for name, p := range cfg.Placements {
+		if p.Split != "" && p.Split != "h" && p.Split != "v" {
+			problems = append(problems, fmt.Sprintf("placement %s: split %q should be h or v", name, p.Split))
+		}
+	}
+	problems = append(problems, ruleProblems(cfg.Rules)...)
+	problems = append(problems, actionProblems(cfg.Actions)...)
+	problems = append(problems, creationProblems(cfg.Creation)...)
+	problems = append(problems, idleProblems(cfg.Idle)...)
+	problems = append(problems, serverProblems(cfg)...)
+	problems = append(problems, projectProblems(cfg, loadTemplates())...)
+	if len(problems) > 0 {
+		res.status = checkFail
+		res.detail = fmt.Sprintf("%d problem(s) in %s", len(problems), file)
+		res.fix = strings.Join(problems, "\n")
+		return res
+	}
+	res.detail = file
+	return res
+}
+
+func checkTemplates() checkResult {
+	res := checkResult{name: "templates"}
+	dir := getTemplatesDir()
+	templates, errs := loadTemplateDir()
+	if len(errs) > 0 {
+		var details []string
+		for _, err := range errs {
+			details = append(details, err.Error())
+		}
+		res.status = checkFail
+		res.detail = fmt.Sprintf("%d file(s) in %s could not be loaded", len(errs), dir)
+		res.fix = strings.Join(details, "\n") + "\nFix the files by hand or move them out of " + dir
+		return res
+	}
+	if len(templates) == 0 {
+		res.detail = "no templates saved yet"
+		return res
+	}
+
+	var problems []string
+	for _, t := range templates {
+		for _, p := range templateProblems(t) {
+			problems = append(problems, fmt.Sprintf("%s (%s): %s", t.Name, filepath.Base(t.file), p))
+		}
+	}
+	if len(problems) > 0 {
+		res.status = checkFail
+		res.detail = fmt.Sprintf("%d problem(s) in %s", len(problems), dir)
+		res.fix = strings.Join(problems, "\n")
+		return res
+	}
+
+	var missing []string
+	for _, t := range templates {
+		for _, p := range templateMissingCommands(t) {
+			missing = append(missing, fmt.Sprintf("%s (%s): %s", t.Name, filepath.Base(t.file), p))
+		}
+	}
+	if len(missing) > 0 {
+		res.status = checkWarn
+		res.detail = fmt.Sprintf("%d pane command(s) not found on PATH", len(missing))
+		res.fix = strings.Join(missing, "\n") + "\nInstall the programs or fix the commands; the panes would start with an error"
+		return res
+	}
+	res.detail = fmt.Sprintf("%d template(s) OK", len(templates))
+	return res
+}
+
+// templateProblems returns a human readable list of everything that would
+// prevent a template from being instantiated correctly.
+func templateProblems(t SessionTemplate) []string {
+	var problems []string
+	if strings.TrimSpace(t.Name) == "" {
+		problems = append(problems, "template has no name")
+	}
+	if strings.ContainsAny(t.Name, ".:") {
+		problems = append(problems, "name contains '.' or ':' which tmux does not allow in session names")
+	}
+
+	switch t.PaneBorderStatus {
+	case "", "top", "bottom", "off":
+	default:
+		problems = append(problems, fmt.Sprintf("pane_border_status %q should be top, bottom or off", t.PaneBorderStatus))
+	}
+	switch t.AfterCreate {
+	case "", "attach", "stay", "read-only":
+	default:
+		problems = append(problems, fmt.Sprintf("after_create %q should be attach, stay or read-only", t.AfterCreate))
+	}
+
+	for k := range t.Env {
+		if !isEnvName(k) {
+			problems = append(problems, fmt.Sprintf("env name %q is not a valid variable name", k))
+		}
+	}
+
+	problems = append(problems, paneProblems(t.Panes)...)
+	for i, w := range t.Windows {
+		label := w.Name
+		if label == "" {
+			label = fmt.Sprintf("#%d", i+2)
+		}
+		for _, p := range paneProblems(w.Panes) {
+			problems = append(problems, fmt.Sprintf("window %s: %s", label, p))
+		}
+	}
+	return problems
+}
+
+func paneProblems(panes []Pane) []string {
+	var problems []string
+	// Panes are created in order, so a parent has to appear before its children.
+	seen := map[int]bool{}
+	for i, p := range panes {
+		if seen[p.ID] {
+			problems = append(problems, fmt.Sprintf("duplicate pane id %d", p.ID))
+		}
+		switch p.Position {
+		case "main", "left", "right", "up", "down", "":
+		default:
+			problems = append(problems, fmt.Sprintf("pane %d has unknown position %q", p.ID, p.Position))
+		}
+		if p.Type != "" && p.Type != paneTypeEditor {
+			problems = append(problems, fmt.Sprintf("pane %d has unknown type %q, only editor is known", p.ID, p.Type))
+		}
+		if i > 0 && !seen[p.Parent] {
+			problems = append(problems, fmt.Sprintf("pane %d refers to parent %d which is not defined before it", p.ID, p.Parent))
+		}
+		if p.SplitPercent < 0 || p.SplitPercent > 99 {
+			problems = append(problems, fmt.Sprintf("pane %d has split_percent %d, expected 1-99", p.ID, p.SplitPercent))
+		}
+		seen[p.ID] = true
+	}
+	for _, p := range panes {
+		if p.WaitFor != "" && !seen[p.WaitForPane] {
+			problems = append(problems, fmt.Sprintf("pane %d waits for pane %d which is not in the same window", p.ID, p.WaitForPane))
+		}
+		if p.WaitFor != "" && p.WaitForPane == p.ID {
+			problems = append(problems, fmt.Sprintf("pane %d waits for itself", p.ID))
+		}
+		if p.WaitForPane != 0 && p.WaitFor == "" {
+			problems = append(problems, fmt.Sprintf("pane %d has wait_for_pane but no wait_for text", p.ID))
+		}
+		if p.WaitForPort < 0 || p.WaitForPort > 65535 {
+			problems = append(problems, fmt.Sprintf("pane %d has wait_for_port %d, expected 1-65535", p.ID, p.WaitForPort))
+		}
+	}
+	return problems
+}
+
+func isEnvName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_', r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/newcharhuso/tmux-navigator/pkg/lazytmux"
+)
+
+// Panes of type "editor" run the user's editor on the pane's directory, so
+// a template that opens an editor works on machines with different editors.
+// lazytmux.WithEditors fills in their commands.
+const paneTypeEditor = lazytmux.PaneTypeEditor
+
+// editorExportWarning says which editor the template's editor panes were
+// exported with, as exports can't pick one where they run; "" when it has
+// none.
+func editorExportWarning(template SessionTemplate) string {
+	windows := append([]TemplateWindow{{Panes: template.Panes}}, template.Windows...)
+	for _, w := range windows {
+		for _, p := range w.Panes {
+			if p.Type == paneTypeEditor {
+				return fmt.Sprintf("editor panes run %s, the editor set when exporting", lazytmux.EditorProgram(template.Env))
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/newcharhuso/tmux-navigator/pkg/lazytmux"
+)
+
+// TestExportsFillInEditors checks that exported templates run the editor
+// in editor panes, not an empty shell or its bare arguments.
+func TestExportsFillInEditors(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "")
+	template := SessionTemplate{Template: lazytmux.Template{
+		Name: "code",
+		Root: t.TempDir(),
+		Env:  map[string]string{"EDITOR": "hx"},
+		Panes: []Pane{
+			{ID: 1, Position: "main", Type: paneTypeEditor},
+			{ID: 2, Position: "right", Parent: 1, SplitPercent: 30, Type: paneTypeEditor, Command: "main.go"},
+		},
+	}}
+
+	script, warnings := templateScript(template)
+	for _, want := range []string{shellQuote("hx ."), shellQuote("hx main.go")} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script doesn't run %s:\n%s", want, script)
+		}
+	}
+	if !containsWarning(warnings, "editor panes run hx") {
+		t.Errorf("script warnings %q don't name the editor", warnings)
+	}
+
+	workspace, warnings := tmuxpWorkspace(template)
+	out, err := json.Marshal(workspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"hx ."`, `"hx main.go"`} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("tmuxp workspace doesn't run %s: %s", want, out)
+		}
+	}
+	if !containsWarning(warnings, "editor panes run hx") {
+		t.Errorf("tmuxp warnings %q don't name the editor", warnings)
+	}
+}
+
+func containsWarning(warnings []string, text string) bool {
+	for _, w := range warnings {
+		if strings.Contains(w, text) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package main
+
+import "fmt"
+
+// 1 to 5 in the template editor lay the panes out like tmux's own layouts,
+// even-horizontal, even-vertical, main-vertical, main-horizontal and tiled,
+// keeping their commands in reading order. + and - then add a pane or take
+// the last one away and lay them out the same way again, so a 2x3 grid is
+// 5 and + until there are six.
+
+// layoutKeys are the layouts 1 to 5 pick.
+var layoutKeys = map[string]string{
+	"1": "even-horizontal",
+	"2": "even-vertical",
+	"3": "main-vertical",
+	"4": "main-horizontal",
+	"5": "tiled",
+}
+
+// applyLayout lays the edited panes out as the named tmux layout with n
+// panes, adding empty ones or dropping the last ones in reading order.
+func (m *model) applyLayout(layout string, n int) {
+	panes := firstPanes(m.currentTemplate.Panes, n)
+	m.currentTemplate.Panes = applyPreset(layoutPreset{name: layout, layout: layout, panes: n}, panes)
+	m.editorLayout = layout
+	m.paneCursor = min(m.paneCursor, len(m.currentTemplate.Panes)-1)
+	m.calculatePaneLayout()
+	m.setMessage(fmt.Sprintf("Layout: %s with %s (+/- to change)", layout, plural(n, "pane")), "info")
+}
+
+// firstPanes returns the first n panes in reading order, or all of them.
+func firstPanes(panes []Pane, n int) []Pane {
+	if n >= len(panes) {
+		return panes
+	}
+	var kept []Pane
+	for _, i := range readingOrder(panes)[:n] {
+		kept = append(kept, panes[i])
+	}
+	return kept
+}
+
+// resizeLayout adds a pane to the layout, or takes one away for a
+// negative change, if the panes are laid out as a tmux layout.
+func (m *model) resizeLayout(change int) {
+	n := len(m.currentTemplate.Panes) + change
+	if n < 1 {
+		return
+	}
+	// Few panes can look like more than one layout, so the one picked last
+	// wins while the panes are still laid out like it.
+	layout := m.editorLayout
+	if candidate, _ := layoutPanes(layout, len(m.currentTemplate.Panes)); layout == "" || !sameGeometry(candidate, m.currentTemplate.Panes) {
+		layout = detectLayout(m.currentTemplate.Panes)
+	}
+	if layout == "" {
+		m.setMessage("Pick a layout with 1-5 first, these panes are arranged by hand", "info")
+		return
+	}
+	m.recordEdit("the layout")
+	m.applyLayout(layout, n)
+}
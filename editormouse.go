@@ -0,0 +1,167 @@
+package main
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// In the template editor a click on a pane of the layout preview selects
+// it, and dragging the border a pane was split off along moves it, setting
+// the pane's split percentage and laying the panes out again as the mouse
+// goes.
+
+// Size of the layout preview in the template editor, in characters.
+const (
+	editorCanvasRows = 12
+	editorCanvasCols = 48
+)
+
+// Lines of the template editor above the layout preview: the title and
+// the blank lines under it.
+const editorCanvasTop = 3
+
+// editorBounds is the part of the grid the panes take up, which the
+// preview is scaled to.
+func editorBounds(panes []Pane) (maxRow, maxCol int) {
+	maxRow, maxCol = 1, 1
+	for _, p := range panes {
+		maxRow = max(maxRow, p.Row+p.Height)
+		maxCol = max(maxCol, p.Col+p.Width)
+	}
+	return maxRow, maxCol
+}
+
+// canvasRect is where a pane is drawn on the preview, rows r0 to r1 and
+// columns c0 to c1, the ends excluded.
+func canvasRect(pane Pane, maxRow, maxCol int) (r0, r1, c0, c1 int) {
+	return scaledRect(pane, maxRow, maxCol, editorCanvasRows, editorCanvasCols)
+}
+
+// scaledRect is canvasRect for a drawing of pr rows by pc columns.
+func scaledRect(pane Pane, maxRow, maxCol, pr, pc int) (r0, r1, c0, c1 int) {
+	r0 = pane.Row * pr / maxRow
+	r1 = (pane.Row + pane.Height) * pr / maxRow
+	c0 = pane.Col * pc / maxCol
+	c1 = (pane.Col + pane.Width) * pc / maxCol
+
+	// Leave room for a box.
+	if r1 <= r0+1 {
+		r1 = min(r0+3, pr)
+	}
+	if c1 <= c0+2 {
+		c1 = min(c0+6, pc)
+	}
+	return max(r0, 0), min(r1, pr), max(c0, 0), min(c1, pc)
+}
+
+// addCanvas notes that the template editor, with its layout preview, is
+// about to be written to content.
+func (c *clickMap) addCanvas(content *strings.Builder) {
+	if c == nil {
+		return
+	}
+	top := strings.Count(content.String(), "\n") + inputBoxStyle.GetMarginTop() + inputBoxStyle.GetBorderTopSize() + inputBoxStyle.GetPaddingTop() + editorCanvasTop
+	left := inputBoxStyle.GetMarginLeft() + inputBoxStyle.GetBorderLeftSize() + inputBoxStyle.GetPaddingLeft()
+	c.canvas = clickZone{top: top, bottom: top + editorCanvasRows, left: left, right: left + editorCanvasCols, row: -1}
+}
+
+// onCanvas turns a point of the screen into a cell of the layout preview.
+func (c *clickMap) onCanvas(x, y int) (row, col int, ok bool) {
+	if c == nil || c.canvas.right == 0 {
+		return 0, 0, false
+	}
+	y += c.shift
+	z := c.canvas
+	return y - z.top, x - z.left, y >= z.top && y < z.bottom && x >= z.left && x < z.right
+}
+
+// paneAt returns the index of the pane drawn at a cell of the preview, the
+// one drawn last where they overlap, or -1.
+func paneAt(panes []Pane, row, col int) int {
+	maxRow, maxCol := editorBounds(panes)
+	for i := len(panes) - 1; i >= 0; i-- {
+		r0, r1, c0, c1 := canvasRect(panes[i], maxRow, maxCol)
+		if row >= r0 && row < r1 && col >= c0 && col < c1 {
+			return i
+		}
+	}
+	return -1
+}
+
+// borderAt returns the index of the pane split off along the border drawn
+// at a cell of the preview, or 0 when there is none there.
+func borderAt(panes []Pane, row, col int) int {
+	lines := layoutGeometry(append([]Pane(nil), panes...))
+	maxRow, maxCol := editorBounds(panes)
+	for i := len(lines) - 1; i > 0; i-- {
+		l := lines[i]
+		if l.to == 0 {
+			continue
+		}
+		// Each side of the border has its own line of box drawing.
+		if l.vertical {
+			at := l.at * editorCanvasCols / maxCol
+			if (col == at || col == at-1) && row >= l.from*editorCanvasRows/maxRow && row < l.to*editorCanvasRows/maxRow {
+				return i
+			}
+		} else {
+			at := l.at * editorCanvasRows / maxRow
+			if (row == at || row == at-1) && col >= l.from*editorCanvasCols/maxCol && col < l.to*editorCanvasCols/maxCol {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+// dragSplit moves the border pane i was split off along to a cell of the
+// preview, and lays the panes out again.
+func (m *model) dragSplit(i, row, col int) {
+	panes := m.currentTemplate.Panes
+	maxRow, maxCol := editorBounds(panes)
+	line := layoutGeometry(panes)[i]
+	if line.end <= line.start {
+		return
+	}
+	// The grid line nearest to the cell.
+	at := (row*maxRow + editorCanvasRows/2) / editorCanvasRows
+	if line.vertical {
+		at = (col*maxCol + editorCanvasCols/2) / editorCanvasCols
+	}
+	size := line.end - at
+	if panes[i].Position == "left" || panes[i].Position == "up" {
+		size = at - line.start
+	}
+	m.setSplit(i, size*100/(line.end-line.start))
+}
+
+// updateEditorMouse selects the pane clicked in the layout preview and
+// drags the border pressed on.
+func (m model) updateEditorMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if msg.Button != tea.MouseButtonLeft && msg.Action != tea.MouseActionRelease {
+		return m, nil
+	}
+	row, col, on := m.clicks.onCanvas(msg.X, msg.Y)
+	switch msg.Action {
+	case tea.MouseActionPress:
+		if !on {
+			return m, nil
+		}
+		if i := paneAt(m.currentTemplate.Panes, row, col); i >= 0 {
+			m.paneCursor = i
+		}
+		m.dragging = borderAt(m.currentTemplate.Panes, row, col)
+		m.clicks.dragFrom = m.saveEditorState("resizing a pane")
+	case tea.MouseActionMotion:
+		if m.dragging > 0 && m.dragging < len(m.currentTemplate.Panes) {
+			m.dragSplit(m.dragging, row, col)
+		}
+	case tea.MouseActionRelease:
+		if m.dragging > 0 && !samePanes(m.clicks.dragFrom.panes, m.currentTemplate.Panes) {
+			m.pushEdit(m.clicks.dragFrom)
+		}
+		m.dragging = 0
+	}
+	return m, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The template editor has a tab for each window of the template, [ and ]
+// going from one to the next, each with its own layout preview. n adds a
+// window after the shown one, r names it and x deletes it. The shown window
+// trades places with the first one in the template while it is edited, so
+// everything that edits the panes works on it as it is, and is put back in
+// order before the template is saved or tested.
+
+// swapFront swaps window k, the first one being 0, with the first window.
+func (t *SessionTemplate) swapFront(k int) {
+	if k <= 0 || k > len(t.Windows) {
+		return
+	}
+	w := &t.Windows[k-1]
+	t.WindowName, w.Name = w.Name, t.WindowName
+	t.Panes, w.Panes = w.Panes, t.Panes
+}
+
+// editedTemplate is the template being edited with its windows in order.
+func (m model) editedTemplate() SessionTemplate {
+	t := m.currentTemplate
+	t.Windows = append([]TemplateWindow(nil), t.Windows...)
+	t.swapFront(m.editorWindow)
+	return t
+}
+
+// windowLabel names window k of the template in order, for the tabs.
+func windowLabel(t SessionTemplate, k int) string {
+	name := t.WindowName
+	if k > 0 {
+		name = t.Windows[k-1].Name
+	}
+	if name == "" {
+		return fmt.Sprintf("%d", k+1)
+	}
+	return fmt.Sprintf("%d %s", k+1, name)
+}
+
+// showWindow shows window k in the editor.
+func (m *model) showWindow(k int) {
+	m.currentTemplate.swapFront(m.editorWindow)
+	m.editorWindow = k
+	m.currentTemplate.swapFront(k)
+	m.paneCursor = 0
+	m.movingPane = 0
+	m.calculatePaneLayout()
+}
+
+// nextWindow shows the window step tabs away.
+func (m *model) nextWindow(step int) {
+	k := m.editorWindow + step
+	if k < 0 || k > len(m.currentTemplate.Windows) {
+		return
+	}
+	m.showWindow(k)
+	m.setMessage(fmt.Sprintf("Window %s", windowLabel(m.editedTemplate(), k)), "info")
+}
+
+// addWindow adds a window of one empty pane after the shown one.
+func (m *model) addWindow() {
+	m.recordEdit("adding a window")
+	k := m.editorWindow
+	m.showWindow(0)
+	pane := Pane{ID: 1, Position: "main", SplitPercent: 50, Width: layoutGridW, Height: layoutGridH}
+	windows := append([]TemplateWindow(nil), m.currentTemplate.Windows[:k]...)
+	windows = append(windows, TemplateWindow{Panes: []Pane{pane}})
+	m.currentTemplate.Windows = append(windows, m.currentTemplate.Windows[k:]...)
+	m.showWindow(k + 1)
+	m.setMessage(fmt.Sprintf("Added window %d, r to name it", k+2), "success")
+}
+
+// deleteWindow deletes the shown window, unless it is the only one.
+func (m *model) deleteWindow() {
+	if len(m.currentTemplate.Windows) == 0 {
+		m.setMessage("A template needs a window, delete its panes instead", "info")
+		return
+	}
+	m.recordEdit("deleting a window")
+	k := m.editorWindow
+	label := windowLabel(m.editedTemplate(), k)
+	m.showWindow(0)
+	t := &m.currentTemplate
+	if k == 0 {
+		t.WindowName, t.Panes = t.Windows[0].Name, t.Windows[0].Panes
+		t.Windows = t.Windows[1:]
+	} else {
+		t.Windows = append(t.Windows[:k-1:k-1], t.Windows[k:]...)
+	}
+	m.showWindow(min(k, len(t.Windows)))
+	m.setMessage(fmt.Sprintf("Deleted window %s (Ctrl+Z to undo)", label), "success")
+}
+
+// startWindowNaming asks for the name of the shown window.
+func (m *model) startWindowNaming() {
+	ti := textinput.New()
+	ti.Placeholder = "Window name, empty for tmux's"
+	ti.CharLimit = 50
+	ti.SetValue(m.currentTemplate.WindowName)
+	ti.CursorEnd()
+	ti.Focus()
+	m.input = ti
+	m.mode = editorWindowNaming
+}
+
+// finishWindowNaming names the shown window as typed.
+func (m *model) finishWindowNaming() {
+	m.mode = templateEditing
+	name := strings.TrimSpace(m.input.Value())
+	if name == m.currentTemplate.WindowName {
+		return
+	}
+	m.recordEdit("renaming a window")
+	m.currentTemplate.WindowName = name
+	m.setMessage(fmt.Sprintf("Named window %d '%s'", m.editorWindow+1, name), "success")
+}
+
+// renderWindowTabs shows the windows of the template, the shown one
+// highlighted, or nothing for a template of one window.
+func (m model) renderWindowTabs() string {
+	t := m.editedTemplate()
+	if len(t.Windows) == 0 {
+		return ""
+	}
+	tab := lipgloss.NewStyle().Padding(0, 1).Foreground(mutedColor)
+	shown := tab.Copy().Bold(true).Foreground(lipgloss.Color("16")).Background(accentColor)
+	var tabs []string
+	for k := 0; k <= len(t.Windows); k++ {
+		style := tab
+		if k == m.editorWindow {
+			style = shown
+		}
+		tabs = append(tabs, style.Render(windowLabel(t, k)))
+	}
+	return strings.Join(tabs, " ")
+}
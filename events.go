@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// While the TUI runs, tmux hooks signal a wait-for channel on every event
+// that changes the session list, and the TUI waits on that channel on each
+// server, reloading as soon as it is signalled. The hooks run inside tmux,
+// without a shell, and nothing is polled in the meantime; the timed
+// auto-refresh only has to catch what no hook reports, like the command a
+// pane runs, so it slows down while the hooks are in place.
+
+// tmux events that change what the session list shows.
+var tmuxEvents = []string{
+	"session-created",
+	"session-closed",
+	"session-renamed",
+	"client-attached",
+	"client-detached",
+	"window-linked",
+	"window-unlinked",
+	"alert-bell",
+	"alert-activity",
+	"alert-silence",
+}
+
+// The wait-for channel the hooks signal.
+const eventChannel = "lazytmux-events"
+
+// Our hooks go into a fixed slot of each hook array, so registering them
+// again replaces them and hooks set by the user are left alone.
+const eventHookIndex = 147
+
+// How long to wait before waiting on a server again after it failed,
+// typically because it isn't running.
+const eventRetryInterval = 2 * time.Second
+
+// How long to wait before registering the hooks again after it failed.
+const hookRetryInterval = 30 * time.Second
+
+// How often sessions are reloaded anyway while the hooks are in place.
+const hookedRefreshInterval = 30 * time.Second
+
+// eventsMsg says the channel of a server was signalled, or failed.
+type eventsMsg struct {
+	server string
+	failed bool
+}
+
+// stopEvents ends the waits on the channel when the TUI exits.
+var eventsContext, stopEvents = context.WithCancel(context.Background())
+
+// Every lazytmux using the hooks of a server sets a user option of its own
+// there, named this followed by its pid, so the last one to quit is the one
+// that removes them. One that crashed leaves its option and with it the
+// hooks, which do no harm.
+const eventUserPrefix = "@lazytmux-events-"
+
+// eventUser is the option of this lazytmux.
+var eventUser = eventUserPrefix + strconv.Itoa(os.Getpid())
+
+func eventHook(event string) string {
+	return event + "[" + strconv.Itoa(eventHookIndex) + "]"
+}
+
+// registerEventHooks makes the managed tmux servers signal eventChannel on
+// every event in tmuxEvents. It fails when one of them isn't running.
+func registerEventHooks() error {
+	command := "wait-for -S " + eventChannel
+	args := []string{"set-option", "-g", eventUser, "1"}
+	for _, event := range tmuxEvents {
+		args = append(args, ";", "set-hook", "-g", eventHook(event), command)
+	}
+	var failed error
+	for _, s := range managedServers() {
+		if err := s.command(args...).Run(); err != nil {
+			failed = err
+		}
+	}
+	return failed
+}
+
+// registerHooks registers the hooks in the background when a server has
+// come up without them: at the first session seen after there were none,
+// as a new server starts without our hooks, or a while after the last
+// attempt failed.
+func (m *model) registerHooks() tea.Cmd {
+	if len(m.allSessions) == 0 {
+		m.hooksRegistered, m.hooksTried = false, time.Time{}
+		return nil
+	}
+	if m.hooksRegistered || m.hooksPending || time.Since(m.hooksTried) < hookRetryInterval {
+		return nil
+	}
+	m.hooksPending = true
+	m.hooksTried = time.Now()
+	return inBackground(registerEventHooks, func(m *model, err error) tea.Cmd {
+		m.hooksPending = false
+		m.hooksRegistered = err == nil
+		return nil
+	})
+}
+
+// unregisterEventHooks takes this lazytmux off the users of the hooks and
+// removes them from the servers no other lazytmux still uses them on.
+func unregisterEventHooks() {
+	var args []string
+	for _, event := range tmuxEvents {
+		if len(args) > 0 {
+			args = append(args, ";")
+		}
+		args = append(args, "set-hook", "-gu", eventHook(event))
+	}
+	for _, s := range managedServers() {
+		_ = s.command("set-option", "-gu", eventUser).Run()
+		out, err := s.command("show-options", "-g").Output()
+		if err != nil || strings.Contains(string(out), eventUserPrefix) {
+			continue
+		}
+		_ = s.command(args...).Run()
+	}
+}
+
+// waitForEvents blocks until the hooks of the server signal eventChannel.
+func waitForEvents(server Server) tea.Cmd {
+	return func() tea.Msg {
+		if eventsContext.Err() != nil {
+			return nil
+		}
+		cmd := server.command("wait-for", eventChannel)
+		err := cmd.Start()
+		if err == nil {
+			stop := context.AfterFunc(eventsContext, func() { cmd.Process.Kill() })
+			err = cmd.Wait()
+			stop()
+		}
+		if eventsContext.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			// Don't spin on a server that isn't running.
+			time.Sleep(eventRetryInterval)
+			return eventsMsg{server: server.Name, failed: true}
+		}
+		return eventsMsg{server: server.Name}
+	}
+}
+
+// watchEvents waits on every managed server.
+func watchEvents() tea.Cmd {
+	var cmds []tea.Cmd
+	for _, s := range managedServers() {
+		cmds = append(cmds, waitForEvents(s))
+	}
+	return tea.Batch(cmds...)
+}
+
+// eventHooksEnabled reports whether the config leaves the tmux hooks on.
+func eventHooksEnabled() bool {
+	return config.EventHooks != "off"
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// * stars the highlighted session or template: starred ones are pinned to
+// the top of their list and marked with a star. They are remembered by name,
+// so a session started again under the same name is starred again.
+
+const favoriteMark = "★"
+
+type favorites struct {
+	Sessions  []string `json:"sessions,omitempty"`
+	Templates []string `json:"templates,omitempty"`
+}
+
+func getFavoritesFile() string {
+	return filepath.Join(getConfigDir(), "favorites.json")
+}
+
+// loadFavorites reads the starred names. A missing or broken file just
+// means nothing is starred.
+func loadFavorites() favorites {
+	var f favorites
+	if data, err := ioutil.ReadFile(getFavoritesFile()); err == nil {
+		json.Unmarshal(data, &f)
+	}
+	return f
+}
+
+func saveFavorites(f favorites) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(getConfigDir(), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(getFavoritesFile(), data)
+}
+
+func starred(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// toggleStar stars or unstars name, reporting whether it is starred now.
+func toggleStar(names []string, name string) ([]string, bool) {
+	for i, n := range names {
+		if n == name {
+			return append(names[:i:i], names[i+1:]...), false
+		}
+	}
+	return append(names, name), true
+}
+
+// renameStar follows a starred name to its new one, reporting whether it
+// was starred.
+func renameStar(names []string, old, new string) bool {
+	for i, n := range names {
+		if n == old {
+			names[i] = new
+			return true
+		}
+	}
+	return false
+}
+
+// pinSessions moves the starred sessions to the top, keeping the order
+// within both groups.
+func pinSessions(sessions []Session, names []string) []Session {
+	sessions = append([]Session(nil), sessions...)
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return starred(names, sessions[i].Name) && !starred(names, sessions[j].Name)
+	})
+	return sessions
+}
+
+// pinTemplates moves the starred templates to the top in place.
+func pinTemplates(templates []SessionTemplate, names []string) {
+	sort.SliceStable(templates, func(i, j int) bool {
+		return starred(names, templates[i].Name) && !starred(names, templates[j].Name)
+	})
+}
+
+// starSession stars or unstars the highlighted session.
+func (m *model) starSession() {
+	if len(m.sessions) == 0 {
+		return
+	}
+	name := m.sessions[m.cursor].Name
+	var on bool
+	m.favorites.Sessions, on = toggleStar(m.favorites.Sessions, name)
+	m.saveStars(name, on)
+	m.showSessions()
+	m.selectSession(name)
+}
+
+// starTemplate stars or unstars the highlighted template.
+func (m *model) starTemplate() {
+	if m.templateCursor >= len(m.templates) {
+		return
+	}
+	name := m.templates[m.templateCursor].Name
+	var on bool
+	m.favorites.Templates, on = toggleStar(m.favorites.Templates, name)
+	m.saveStars(name, on)
+	m.sortTemplates()
+}
+
+func (m *model) saveStars(name string, on bool) {
+	if err := saveFavorites(m.favorites); err != nil {
+		m.setMessage(fmt.Sprintf("Failed to save favorites: %v", err), "error")
+		return
+	}
+	if on {
+		m.setMessage(fmt.Sprintf("Starred '%s'", name), "success")
+	} else {
+		m.setMessage(fmt.Sprintf("Unstarred '%s'", name), "info")
+	}
+}
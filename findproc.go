@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// f asks for a command, like "vite" or "postgres", and moves the cursor to
+// the session whose pane runs it in the foreground, going by tmux's
+// pane_current_command. Asking again moves on to the next such session.
+
+// runningPane is a pane whose foreground command matched.
+type runningPane struct {
+	Session string
+	Pane    string // "window.pane"
+	Command string
+}
+
+// panesRunning lists the panes of the sessions whose foreground command
+// contains name, ignoring case, in the order the sessions are given.
+func panesRunning(sessions []Session, name string) []runningPane {
+	name = strings.ToLower(name)
+	found := map[string][]runningPane{}
+	listed := map[string]bool{}
+	for _, s := range sessions {
+		server := serverNamed(s.Server)
+		if listed[server.Name] {
+			continue
+		}
+		listed[server.Name] = true
+		out, err := server.command("list-panes", "-a", "-F", "#{session_name}\t#{window_index}.#{pane_index}\t#{pane_current_command}").Output()
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+			fields := strings.Split(line, "\t")
+			if len(fields) != 3 || !strings.Contains(strings.ToLower(fields[2]), name) {
+				continue
+			}
+			found[fields[0]] = append(found[fields[0]], runningPane{Session: fields[0], Pane: fields[1], Command: fields[2]})
+		}
+	}
+	var panes []runningPane
+	for _, s := range sessions {
+		panes = append(panes, found[s.Name]...)
+		delete(found, s.Name)
+	}
+	return panes
+}
+
+// startFindProcess asks for the command to look for.
+func (m *model) startFindProcess() {
+	ti := textinput.New()
+	ti.Placeholder = "Command running in a pane, e.g. vite"
+	ti.CharLimit = 100
+	ti.SetValue(m.findProcess)
+	ti.Focus()
+	m.input = ti
+	m.mode = processFinding
+}
+
+// findProcessSession moves the cursor to a listed session with a pane
+// running the command typed.
+func (m *model) findProcessSession() tea.Cmd {
+	m.mode = browsing
+	name := strings.TrimSpace(m.input.Value())
+	if name == "" {
+		return nil
+	}
+	again := name == m.findProcess
+	m.findProcess = name
+	var listed []Session
+	for _, s := range m.sessions {
+		if !s.Project {
+			listed = append(listed, s)
+		}
+	}
+	var panes []runningPane
+	return inBackground(func() error {
+		panes = panesRunning(listed, name)
+		return nil
+	}, func(m *model, err error) tea.Cmd {
+		m.showRunning(name, panes, again)
+		return nil
+	})
+}
+
+// showRunning moves the cursor to the first session with panes running
+// name, or with again to the one after the highlighted one.
+func (m *model) showRunning(name string, panes []runningPane, again bool) {
+	if len(panes) == 0 {
+		m.setMessage(fmt.Sprintf("No pane is running '%s'", name), "info")
+		return
+	}
+	var sessions []string
+	where := map[string][]string{}
+	for _, p := range panes {
+		if where[p.Session] == nil {
+			sessions = append(sessions, p.Session)
+		}
+		where[p.Session] = append(where[p.Session], p.Pane+" "+p.Command)
+	}
+	// A new command starts from the first session, the same one again
+	// moves on from the highlighted one.
+	n := 0
+	for i, s := range sessions {
+		if again && len(m.sessions) > 0 && s == m.sessions[m.cursor].Name {
+			n = (i + 1) % len(sessions)
+		}
+	}
+	next := sessions[n]
+	m.selectSession(next)
+	msg := fmt.Sprintf("'%s' runs in %s, pane %s", name, next, strings.Join(where[next], ", "))
+	if len(sessions) > 1 {
+		msg += fmt.Sprintf(" (%d of %d sessions, f and Enter for the next)", n+1, len(sessions))
+	}
+	m.setMessage(msg, "success")
+}
+
+func (m model) renderFindProcessPrompt() string {
+	prompt := fmt.Sprintf("🔍 Find the session running\n%s\n", m.input.View())
+	prompt += lipgloss.NewStyle().Foreground(mutedColor).Render("[Enter] Find • [Esc] Cancel")
+	inputView := inputBoxStyle.Render(prompt)
+	return lipgloss.Place(m.width, 5, lipgloss.Center, lipgloss.Top, inputView)
+}
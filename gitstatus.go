@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// How long the git state of a directory is shown before it is looked up
+// again.
+const gitStatusTTL = 10 * time.Second
+
+// How long git may take for one directory before it is given up.
+const gitStatusTimeout = 2 * time.Second
+
+// gitState is the branch and dirtiness of the repository a directory is in.
+type gitState struct {
+	Branch string // "" when the directory is not in a repository
+	Dirty  bool   // uncommitted changes, untracked files included
+}
+
+// gitStatusMsg carries the git state of directories, keyed by directory.
+type gitStatusMsg map[string]gitState
+
+func gitStatusEnabled() bool {
+	return config.GitStatus != "off"
+}
+
+// readGitState asks git about dir. Directories outside a repository, and
+// those git takes too long for, have no branch.
+func readGitState(dir string) gitState {
+	ctx, cancel := context.WithTimeout(context.Background(), gitStatusTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "git", "-C", dir, "status", "--porcelain", "--branch").Output()
+	if err != nil {
+		return gitState{}
+	}
+	lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+	return gitState{Branch: parseBranchLine(lines[0]), Dirty: len(lines) > 1}
+}
+
+// parseBranchLine reads the branch from the "## " line of git status
+// --porcelain --branch.
+func parseBranchLine(line string) string {
+	line = strings.TrimPrefix(line, "## ")
+	switch {
+	case strings.HasPrefix(line, "No commits yet on "):
+		return strings.TrimPrefix(line, "No commits yet on ")
+	case strings.HasPrefix(line, "HEAD (no branch)"):
+		return "(detached)"
+	}
+	if branch, _, ok := strings.Cut(line, "..."); ok {
+		return branch
+	}
+	branch, _, _ := strings.Cut(line, " ")
+	return branch
+}
+
+// checkGitStatus looks up the directories in the background, so git on a
+// large repository doesn't hold up the TUI.
+func checkGitStatus(dirs []string) tea.Cmd {
+	return func() tea.Msg {
+		out := gitStatusMsg{}
+		for _, dir := range dirs {
+			out[dir] = readGitState(dir)
+		}
+		return out
+	}
+}
+
+// refreshGitStatus starts looking up the directories of the listed
+// sessions if what the git column shows has gone stale. It returns nil
+// otherwise. Sessions on other machines are left out.
+func (m *model) refreshGitStatus() tea.Cmd {
+	if !gitStatusEnabled() || m.checkingGit || time.Since(m.gitCheckedAt) < gitStatusTTL {
+		return nil
+	}
+	seen := map[string]bool{}
+	var dirs []string
+	for _, s := range m.sessions {
+		if s.Dir == "" || seen[s.Dir] || serverNamed(s.Server).remote() {
+			continue
+		}
+		seen[s.Dir] = true
+		dirs = append(dirs, s.Dir)
+	}
+	if len(dirs) == 0 {
+		return nil
+	}
+	m.checkingGit = true
+	return checkGitStatus(dirs)
+}
+
+// gitView reports whether the session table shows the git column: when any
+// listed session is in a repository.
+func (m model) gitView() bool {
+	for _, s := range m.sessions {
+		if m.gitStates[s.Dir].Branch != "" {
+			return true
+		}
+	}
+	return false
+}
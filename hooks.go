@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/newcharhuso/tmux-navigator/pkg/lazytmux"
+)
+
+// Hooks that hang (waiting for a password prompt, say) must not block the UI forever.
+const hookTimeout = lazytmux.HookTimeout
+
+// runHook runs a template hook through sh in the template's root directory
+// with the template environment plus LAZYTMUX_SESSION and LAZYTMUX_TEMPLATE.
+// The combined output is returned in both cases.
+func runHook(command, sessionName string, template SessionTemplate) (string, error) {
+	return lazytmux.RunHook(context.Background(), command, sessionName, template.Template)
+}
+
+// lastLine returns the last non-empty line of s.
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if l := strings.TrimSpace(lines[i]); l != "" {
+			return l
+		}
+	}
+	return ""
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// A session is idle while nobody is attached and none of its panes printed
+// anything, going by the later of tmux's session_activity and
+// session_last_attached. With idle.after set the session list marks those
+// idle longer with ⌛ and how long; I lists them for review and kills the
+// ones left checked. With idle.action "kill" that review opens on its own
+// at startup, and `lazytmux idle --kill` kills them without asking, for
+// cron. Other users' and protected sessions are never idle.
+
+// IdlePolicy says when sessions count as idle and what happens to them.
+type IdlePolicy struct {
+	// How long a session has to be idle, e.g. "72h" or "3d". Unset turns
+	// idle detection off.
+	After string `json:"after,omitempty"`
+	// "flag" (default) only marks idle sessions; "kill" also opens the
+	// review to kill them when the TUI starts.
+	Action string `json:"action,omitempty"`
+}
+
+// parseIdleAfter reads a duration, also taking whole days like "3d".
+func parseIdleAfter(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// idleAfter returns how long sessions have to be idle, or 0 when idle
+// detection is off.
+func (p IdlePolicy) idleAfter() time.Duration {
+	if p.After == "" {
+		return 0
+	}
+	d, _ := parseIdleAfter(p.After)
+	return d
+}
+
+// idleFor returns how long the session has been idle, 0 while attached.
+func (s Session) idleFor(now time.Time) time.Duration {
+	if s.Attached || s.Project {
+		return 0
+	}
+	last := s.CreatedAt
+	for _, t := range []time.Time{s.LastActivity, s.LastAttached} {
+		if t.After(last) {
+			last = t
+		}
+	}
+	if last.IsZero() || now.Before(last) {
+		return 0
+	}
+	return now.Sub(last)
+}
+
+// idleSessions returns the sessions idle for at least after that may be
+// killed: our own and unprotected.
+func idleSessions(sessions []Session, after time.Duration, now time.Time) []Session {
+	var idle []Session
+	if after <= 0 {
+		return nil
+	}
+	for _, s := range sessions {
+		if s.mine() && !s.Protected && s.idleFor(now) >= after {
+			idle = append(idle, s)
+		}
+	}
+	return idle
+}
+
+// idleProblems lists what is wrong with the idle settings, for doctor.
+func idleProblems(p IdlePolicy) []string {
+	var problems []string
+	if p.After != "" {
+		if _, err := parseIdleAfter(p.After); err != nil {
+			problems = append(problems, "idle: after should be a duration like 72h or 3d")
+		}
+	}
+	switch p.Action {
+	case "", "flag", "kill":
+	default:
+		problems = append(problems, fmt.Sprintf("idle: action %q should be flag or kill", p.Action))
+	}
+	if p.Action == "kill" && p.After == "" {
+		problems = append(problems, "idle: action kill needs after")
+	}
+	return problems
+}
+
+// startIdleReview lists the idle sessions for killing, all checked, and
+// reports whether there are any.
+func (m *model) startIdleReview() bool {
+	m.idleSessions = idleSessions(m.allSessions, config.Idle.idleAfter(), time.Now())
+	if len(m.idleSessions) == 0 {
+		return false
+	}
+	m.idleKeep = map[string]bool{}
+	m.idleCursor = 0
+	m.mode = idleReviewing
+	return true
+}
+
+// killIdleSessions kills the idle sessions still checked in the review.
+func (m *model) killIdleSessions() tea.Cmd {
+	var kill []Session
+	for _, s := range m.idleSessions {
+		if !m.idleKeep[s.Name] {
+			kill = append(kill, s)
+		}
+	}
+	sessions := m.allSessions
+	killed := 0
+	var failed []string
+	return inBackground(func() error {
+		for _, s := range kill {
+			if err := killByName(s.Name, sessions); err != nil {
+				failed = append(failed, s.Name)
+				continue
+			}
+			killed++
+		}
+		return nil
+	}, func(m *model, err error) tea.Cmd {
+		if len(failed) > 0 {
+			m.setMessage(fmt.Sprintf("Killed %s, failed to kill %s", plural(killed, "idle session"), strings.Join(failed, ", ")), "error")
+		} else {
+			m.setMessage(fmt.Sprintf("Killed %s", plural(killed, "idle session")), "success")
+		}
+		return nil
+	})
+}
+
+func (m model) renderIdleReview() string {
+	now := time.Now()
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("⌛ Sessions idle for more than %s\n\n", formatUptime(config.Idle.idleAfter())))
+	for i, s := range m.idleSessions {
+		check := "[x]"
+		if m.idleKeep[s.Name] {
+			check = "[ ]"
+		}
+		line := fmt.Sprintf("%s %s  idle %s", check, s.Name, formatUptime(s.idleFor(now)))
+		if s.Note != "" {
+			line += "  📌 " + s.Note
+		}
+		if i == m.idleCursor {
+			line = "▶ " + line
+		} else {
+			line = "  " + line
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("\n" + lipgloss.NewStyle().Foreground(mutedColor).Render("[Space] Keep/kill • [Enter] Kill checked • [Esc] Cancel"))
+	return lipgloss.Place(m.width, len(m.idleSessions)+6, lipgloss.Center, lipgloss.Top, inputBoxStyle.Render(b.String()))
+}
+
+// runIdle lists the idle sessions, or kills them with --kill.
+func runIdle(args []string, mineOnly bool) int {
+	fs := flag.NewFlagSet("idle", flag.ContinueOnError)
+	after := fs.String("after", config.Idle.After, "How long a session has to be idle, e.g. 72h or 3d")
+	kill := fs.Bool("kill", false, "Kill the idle sessions")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s idle [--after duration] [--kill]\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	args, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(args) != 0 {
+		fs.Usage()
+		return 2
+	}
+	if *after == "" {
+		fmt.Fprintln(os.Stderr, "Error: pass --after or set idle.after in the config")
+		return 2
+	}
+	d, err := parseIdleAfter(*after)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if *kill && readOnly {
+		fmt.Fprintln(os.Stderr, "Error: read-only mode: killing sessions is disabled")
+		return 1
+	}
+
+	sessions := cliSessions(mineOnly)
+	now := time.Now()
+	status := 0
+	for _, s := range idleSessions(sessions, d, now) {
+		if !*kill {
+			fmt.Printf("%s\tidle %s\n", s.Name, formatUptime(s.idleFor(now)))
+			continue
+		}
+		if err := killByName(s.Name, sessions); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			status = 1
+			continue
+		}
+		fmt.Printf("killed %s (idle %s)\n", s.Name, formatUptime(s.idleFor(now)))
+	}
+	return status
+}
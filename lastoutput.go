@@ -0,0 +1,58 @@
+package main
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// How long a captured last line is shown before it is captured again.
+const lastOutputTTL = 5 * time.Second
+
+// lastOutputMsg carries the last non-empty line of each session's active
+// pane, keyed by session name.
+type lastOutputMsg map[string]string
+
+// paneLastLine returns the last non-empty line visible in the active pane
+// of a session, or "" if there is none.
+func paneLastLine(server Server, session string) string {
+	out, err := server.command("capture-pane", "-p", "-J", "-t", "="+session+":").Output()
+	if err != nil {
+		return ""
+	}
+	return printable(lastLine(string(out)))
+}
+
+// captureLastOutput captures the sessions in the background, so a slow
+// tmux doesn't hold up the TUI.
+func captureLastOutput(sessions []Session) tea.Cmd {
+	return func() tea.Msg {
+		out := lastOutputMsg{}
+		for _, s := range sessions {
+			out[s.Name] = paneLastLine(serverNamed(s.Server), s.Name)
+		}
+		return out
+	}
+}
+
+// refreshLastOutput starts capturing the listed sessions if the column is
+// shown and what it shows has gone stale. It returns nil otherwise.
+func (m *model) refreshLastOutput() tea.Cmd {
+	if !m.showOutput || m.capturingOutput || time.Since(m.lastOutputAt) < lastOutputTTL {
+		return nil
+	}
+	var sessions []Session
+	for _, s := range m.sessions {
+		if !s.Project {
+			sessions = append(sessions, s)
+		}
+	}
+	m.capturingOutput = true
+	return captureLastOutput(sessions)
+}
+
+// outputView reports whether the session table shows the last output
+// column. The directory column takes precedence over it.
+func (m model) outputView() bool {
+	return m.showOutput && !m.pathView()
+}
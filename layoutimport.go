@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// i in the template editor picks a window of a running session and lays the
+// panes out like it, reading its #{window_layout}: the string tmux keeps a
+// window's arrangement in, like "bb62,159x48,0,0{79x48,0,0,1,79x48,80,0,2}".
+// Each row or column of cells in it becomes a chain of splits off the pane
+// in its first cell, sized like the cells, so a layout arranged by hand can
+// start a template. The panes keep their commands in reading order.
+
+// layoutCell is a cell of a tmux layout: a pane, or a row of cells side by
+// side, or a column of them stacked.
+type layoutCell struct {
+	width, height, x, y int
+	stacked             bool // children top to bottom, else left to right
+	children            []layoutCell
+}
+
+// parseWindowLayout reads a tmux layout string.
+func parseWindowLayout(layout string) (layoutCell, error) {
+	// The checksum in front is four hex digits.
+	if len(layout) > 5 && layout[4] == ',' {
+		if _, err := strconv.ParseUint(layout[:4], 16, 16); err == nil {
+			layout = layout[5:]
+		}
+	}
+	p := layoutParser{s: layout}
+	cell, err := p.cell()
+	if err == nil && p.i < len(p.s) {
+		err = fmt.Errorf("unexpected %q at %d", p.s[p.i:], p.i)
+	}
+	if err != nil {
+		return layoutCell{}, fmt.Errorf("bad layout %q: %w", layout, err)
+	}
+	return cell, nil
+}
+
+// layoutParser reads a layout string from position i on.
+type layoutParser struct {
+	s string
+	i int
+}
+
+// number reads a number followed by sep, unless sep is 0.
+func (p *layoutParser) number(sep byte) (int, error) {
+	start := p.i
+	for p.i < len(p.s) && p.s[p.i] >= '0' && p.s[p.i] <= '9' {
+		p.i++
+	}
+	if p.i == start {
+		return 0, fmt.Errorf("missing number at %d", start)
+	}
+	n, err := strconv.Atoi(p.s[start:p.i])
+	if err != nil {
+		return 0, err
+	}
+	if sep != 0 {
+		if p.i >= len(p.s) || p.s[p.i] != sep {
+			return 0, fmt.Errorf("missing %q at %d", sep, p.i)
+		}
+		p.i++
+	}
+	return n, nil
+}
+
+// cell reads WxH,X,Y followed by a pane number or the cells inside.
+func (p *layoutParser) cell() (layoutCell, error) {
+	var c layoutCell
+	var err error
+	if c.width, err = p.number('x'); err != nil {
+		return c, err
+	}
+	if c.height, err = p.number(','); err != nil {
+		return c, err
+	}
+	if c.x, err = p.number(','); err != nil {
+		return c, err
+	}
+	if c.y, err = p.number(0); err != nil {
+		return c, err
+	}
+	if p.i >= len(p.s) {
+		return c, fmt.Errorf("missing pane at %d", p.i)
+	}
+
+	switch p.s[p.i] {
+	case ',':
+		p.i++
+		_, err = p.number(0)
+		return c, err
+	case '{', '[':
+		c.stacked = p.s[p.i] == '['
+		end := byte('}')
+		if c.stacked {
+			end = ']'
+		}
+		for p.i < len(p.s) && p.s[p.i] != end {
+			p.i++ // the opening bracket, then the commas between cells
+			child, err := p.cell()
+			if err != nil {
+				return c, err
+			}
+			c.children = append(c.children, child)
+		}
+		if p.i >= len(p.s) {
+			return c, fmt.Errorf("missing %q", end)
+		}
+		p.i++
+		return c, nil
+	}
+	return c, fmt.Errorf("unexpected %q at %d", p.s[p.i], p.i)
+}
+
+// cellPanes turns a layout into a pane tree on the editor grid.
+func cellPanes(root layoutCell) []Pane {
+	panes := []Pane{{ID: 1, Position: "main", SplitPercent: 50}}
+	var split func(c layoutCell, id int)
+	split = func(c layoutCell, id int) {
+		// The pane holding the whole cell keeps the first one and splits
+		// off the rest, one cell after the other.
+		ids := []int{id}
+		for k := 1; k < len(c.children); k++ {
+			position, from, at, end := "right", c.children[k-1].x, c.children[k].x, c.x+c.width
+			if c.stacked {
+				position, from, at, end = "down", c.children[k-1].y, c.children[k].y, c.y+c.height
+			}
+			percent := 50
+			if end > from {
+				percent = max(1, min(99, (200*(end-at)+end-from)/(2*(end-from))))
+			}
+			next := len(panes) + 1
+			panes = append(panes, Pane{ID: next, Position: position, Parent: ids[k-1], SplitPercent: percent})
+			ids = append(ids, next)
+		}
+		for k, child := range c.children {
+			split(child, ids[k])
+		}
+	}
+	split(root, 1)
+	layoutGeometry(panes)
+	return panes
+}
+
+// windowLayout returns the layout string of a window.
+func (s Server) windowLayout(id string) (string, error) {
+	out, err := s.command("display-message", "-p", "-t", id, "#{window_layout}").Output()
+	if err != nil {
+		return "", fmt.Errorf("cannot read the layout of %s: %w", id, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// startLayoutImport offers the windows of every session to take the layout
+// of.
+func (m *model) startLayoutImport() tea.Cmd {
+	srv := m.server()
+	var windows []LiveWindow
+	return inBackground(func() error {
+		windows = srv.listAllWindows()
+		return nil
+	}, func(m *model, err error) tea.Cmd {
+		if m.mode != templateEditing {
+			return nil
+		}
+		if len(windows) == 0 {
+			m.setMessage("There is no running window to take the layout of", "warning")
+			return nil
+		}
+		m.importWindows = windows
+		m.importCursor = 0
+		m.mode = layoutImporting
+		return nil
+	})
+}
+
+// finishLayoutImport lays the edited panes out like the picked window.
+func (m *model) finishLayoutImport() tea.Cmd {
+	w, srv := m.importWindows[m.importCursor], m.server()
+	m.importWindows = nil
+	m.mode = templateEditing
+	var root layoutCell
+	return inBackground(func() error {
+		layout, err := srv.windowLayout(w.ID)
+		if err == nil {
+			root, err = parseWindowLayout(layout)
+		}
+		return err
+	}, func(m *model, err error) tea.Cmd {
+		if err != nil {
+			m.setMessage(fmt.Sprintf("Failed to import the layout: %v", err), "error")
+			return nil
+		}
+		if m.mode == templateEditing {
+			m.importLayout(root, fmt.Sprintf("%s:%s", w.Session, w.Index))
+		}
+		return nil
+	})
+}
+
+// importLayout lays the edited panes out like a layout read from window.
+func (m *model) importLayout(root layoutCell, window string) {
+	layout := cellPanes(root)
+	panes := firstPanes(m.currentTemplate.Panes, len(layout))
+	dropped := len(m.currentTemplate.Panes) - len(panes)
+	m.recordEdit("importing a layout")
+	m.currentTemplate.Panes = fillLayout(layout, panes)
+	m.paneCursor = 0
+	m.calculatePaneLayout()
+	text := fmt.Sprintf("Imported the layout of %s, %s", window, plural(len(layout), "pane"))
+	if dropped > 0 {
+		text += fmt.Sprintf(", %s left out (Ctrl+Z to undo)", plural(dropped, "pane"))
+	}
+	m.setMessage(text, "success")
+}
+
+// renderLayoutImport is the window picker of the layout import.
+func (m model) renderLayoutImport() string {
+	var lines []string
+	for i, w := range m.importWindows {
+		prefix := "  "
+		if i == m.importCursor {
+			prefix = "▶ "
+		}
+		lines = append(lines, fmt.Sprintf("%s%s:%s  %s (%s)", prefix, w.Session, w.Index, w.Name, plural(w.Panes, "pane")))
+	}
+	prompt := fmt.Sprintf("📐 Import the layout of\n\n%s\n\n[Enter] Import • [Esc] Cancel", strings.Join(lines, "\n"))
+	return lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, inputBoxStyle.Render(prompt)) + "\n"
+}
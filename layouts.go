@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Names of the tmux built-in layouts we can turn into a pane tree.
+var layoutNames = []string{"even-horizontal", "even-vertical", "main-vertical", "main-horizontal", "tiled"}
+
+// Share of the window given to the main pane in main-* layouts.
+const mainPanePercent = 60
+
+// layoutPanes builds a pane tree of n panes arranged like the named tmux
+// layout. Pane IDs start at 1 and the visual grid is filled in.
+func layoutPanes(layout string, n int) ([]Pane, error) {
+	if n < 1 {
+		n = 1
+	}
+	panes := []Pane{{ID: 1, Position: "main", SplitPercent: 50}}
+
+	switch layout {
+	case "even-horizontal":
+		panes = evenChain(panes, 1, n, "right")
+	case "even-vertical":
+		panes = evenChain(panes, 1, n, "down")
+	case "main-vertical", "main-horizontal":
+		if n > 1 {
+			direction, stack := "right", "down"
+			if layout == "main-horizontal" {
+				direction, stack = "down", "right"
+			}
+			panes = append(panes, Pane{ID: 2, Position: direction, Parent: 1, SplitPercent: 100 - mainPanePercent})
+			panes = evenChain(panes, 2, n-1, stack)
+		}
+	case "tiled":
+		cols := int(math.Ceil(math.Sqrt(float64(n))))
+		rows := (n + cols - 1) / cols
+		panes = evenChain(panes, 1, rows, "down")
+		rowHeads := make([]int, rows)
+		for r := range rowHeads {
+			rowHeads[r] = panes[r].ID
+		}
+		for r, head := range rowHeads {
+			// Spread the panes over the rows, earlier rows take the remainder.
+			inRow := n / rows
+			if r < n%rows {
+				inRow++
+			}
+			panes = evenChain(panes, head, inRow, "right")
+		}
+	case "", "main":
+		// Single pane; extra panes are ignored.
+	default:
+		return nil, fmt.Errorf("unknown layout %q", layout)
+	}
+
+	layoutGeometry(panes)
+	return panes, nil
+}
+
+// evenChain splits pane id into count equally sized panes along direction.
+// count includes the pane being split.
+func evenChain(panes []Pane, id, count int, direction string) []Pane {
+	nextID := 1
+	for _, p := range panes {
+		if p.ID >= nextID {
+			nextID = p.ID + 1
+		}
+	}
+	parent := id
+	for remaining := count; remaining > 1; remaining-- {
+		// The new pane takes the share of everything still to be created.
+		percent := 100 * (remaining - 1) / remaining
+		panes = append(panes, Pane{ID: nextID, Position: direction, Parent: parent, SplitPercent: percent})
+		parent = nextID
+		nextID++
+	}
+	return panes
+}
+
+// splitLine is the border a pane was split off its parent along, on the
+// editor grid.
+type splitLine struct {
+	vertical   bool // a border between columns, else between rows
+	at         int  // the column or row the border is on
+	from, to   int  // the rows or columns it runs along, to excluded
+	start, end int  // the parent's extent across the border before the split
+}
+
+// layoutGeometry fills in Row/Col/Width/Height of every pane on the editor
+// grid by replaying the splits in order, the same way addPane does. It
+// returns the border each pane was split off along, by pane index, the
+// first pane having none.
+func layoutGeometry(panes []Pane) []splitLine {
+	if len(panes) == 0 {
+		return nil
+	}
+	lines := make([]splitLine, len(panes))
+	index := map[int]int{}
+	for i := range panes {
+		p := &panes[i]
+		if i == 0 {
+			p.Row, p.Col, p.Width, p.Height = 0, 0, layoutGridW, layoutGridH
+			index[p.ID] = i
+			continue
+		}
+		pi, ok := index[p.Parent]
+		if !ok {
+			pi = 0
+		}
+		sel := &panes[pi]
+		split := p.SplitPercent
+		if split <= 0 {
+			split = 50
+		}
+
+		switch p.Position {
+		case "left", "right", "":
+			lines[i] = splitLine{vertical: true, from: sel.Row, to: sel.Row + sel.Height, start: sel.Col, end: sel.Col + sel.Width}
+			newW := max(1, sel.Width*split/100)
+			rem := max(1, sel.Width-newW)
+			p.Row, p.Height, p.Width = sel.Row, sel.Height, newW
+			if p.Position == "left" {
+				p.Col = sel.Col
+				sel.Col += newW
+				lines[i].at = sel.Col
+			} else {
+				p.Col = sel.Col + rem
+				lines[i].at = p.Col
+			}
+			sel.Width = rem
+		case "up", "down":
+			lines[i] = splitLine{from: sel.Col, to: sel.Col + sel.Width, start: sel.Row, end: sel.Row + sel.Height}
+			newH := max(1, sel.Height*split/100)
+			rem := max(1, sel.Height-newH)
+			p.Col, p.Width, p.Height = sel.Col, sel.Width, newH
+			if p.Position == "up" {
+				p.Row = sel.Row
+				sel.Row += newH
+				lines[i].at = sel.Row
+			} else {
+				p.Row = sel.Row + rem
+				lines[i].at = p.Row
+			}
+			sel.Height = rem
+		}
+		index[p.ID] = i
+	}
+	return lines
+}
+
+// isKnownLayout reports whether layoutPanes understands the layout name.
+func isKnownLayout(layout string) bool {
+	for _, l := range layoutNames {
+		if l == layout {
+			return true
+		}
+	}
+	return false
+}
+
+// readingOrder returns the indices of panes sorted top to bottom, left to
+// right on the editor grid. tmux numbers the panes of its built-in layouts
+// this way, while layoutPanes numbers them in split order.
+func readingOrder(panes []Pane) []int {
+	order := make([]int, len(panes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		pa, pb := panes[order[a]], panes[order[b]]
+		if pa.Row != pb.Row {
+			return pa.Row < pb.Row
+		}
+		return pa.Col < pb.Col
+	})
+	return order
+}
+
+// arrangePanes lays out the given panes like the named tmux layout, keeping
+// their command, title and directory in tmux's pane order. Layout names we
+// cannot reproduce, such as custom layout strings, fall back to tiled.
+func arrangePanes(layout string, contents []Pane) []Pane {
+	if !isKnownLayout(layout) {
+		layout = "tiled"
+	}
+	panes, _ := layoutPanes(layout, len(contents))
+	for n, i := range readingOrder(panes) {
+		panes[i].Command = contents[n].Command
+		panes[i].Title = contents[n].Title
+		panes[i].Dir = contents[n].Dir
+	}
+	return panes
+}
+
+// detectLayout returns the tmux layout whose arrangement matches panes, or
+// "" if the pane tree has no built-in equivalent.
+func detectLayout(panes []Pane) string {
+	for _, layout := range layoutNames {
+		candidate, _ := layoutPanes(layout, len(panes))
+		if sameGeometry(candidate, panes) {
+			return layout
+		}
+	}
+	return ""
+}
+
+// sameGeometry compares two sets of panes by their places on the grid.
+func sameGeometry(a, b []Pane) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	oa, ob := readingOrder(a), readingOrder(b)
+	for i := range oa {
+		pa, pb := a[oa[i]], b[ob[i]]
+		if pa.Row != pb.Row || pa.Col != pb.Col || pa.Width != pb.Width || pa.Height != pb.Height {
+			return false
+		}
+	}
+	return true
+}
+
+// layoutPreset is a canonical arrangement the template editor can apply in
+// one go. It grows to fit templates with more panes than it names.
+type layoutPreset struct {
+	name        string
+	layout      string
+	panes       int
+	mainPercent int // share of the main pane, main-* layouts only
+}
+
+var layoutPresets = []layoutPreset{
+	{"70/30 main left", "main-vertical", 2, 70},
+	{"3-row stack", "even-vertical", 3, 0},
+	{"2x2 grid", "tiled", 4, 0},
+	{"main + two stacked", "main-vertical", 3, mainPanePercent},
+}
+
+// placedLike returns p moved to the place of layout in its pane tree.
+func placedLike(p, layout Pane) Pane {
+	p.ID, p.Position, p.Parent, p.SplitPercent = layout.ID, layout.Position, layout.Parent, layout.SplitPercent
+	p.Row, p.Col, p.Width, p.Height = layout.Row, layout.Col, layout.Width, layout.Height
+	return p
+}
+
+// applyPreset rearranges panes into the preset, keeping everything but
+// their place and filling them in reading order. Missing panes are added
+// empty, and wait_for_pane follows the pane it referred to.
+func applyPreset(preset layoutPreset, panes []Pane) []Pane {
+	layout, _ := layoutPanes(preset.layout, max(preset.panes, len(panes)))
+	if preset.mainPercent > 0 && len(layout) > 1 {
+		layout[1].SplitPercent = 100 - preset.mainPercent
+		layoutGeometry(layout)
+	}
+	return fillLayout(layout, panes)
+}
+
+// fillLayout moves panes to the places of layout, which has at least as
+// many, in reading order. The places left over stay empty panes.
+func fillLayout(layout, panes []Pane) []Pane {
+	order := readingOrder(layout)
+	newID := map[int]int{}
+	out := make([]Pane, len(layout))
+	for i := range layout {
+		out[i] = layout[i]
+	}
+	for n, oi := range readingOrder(panes) {
+		old, i := panes[oi], order[n]
+		newID[old.ID] = layout[i].ID
+		out[i] = placedLike(old, layout[i])
+	}
+	for i := range out {
+		if out[i].WaitForPane != 0 {
+			out[i].WaitForPane = newID[out[i].WaitForPane]
+		}
+	}
+	return out
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A project can carry its own template in a .lazytmux.json at its top,
+// like tmuxinator's local configs. `lazytmux start` without a template, and
+// starting a project in the TUI, build the session from it with the
+// project as the root.
+const localTemplateFile = ".lazytmux.json"
+
+// findLocalTemplate returns the local template of dir or of the closest of
+// its parents that has one.
+func findLocalTemplate(dir string) (string, bool) {
+	dir, err := filepath.Abs(expandHome(dir))
+	if err != nil {
+		return "", false
+	}
+	for {
+		path := filepath.Join(dir, localTemplateFile)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// readLocalTemplate reads a local template. Its root is the directory the
+// file is in, or relative to it; without a name it is named after that
+// directory.
+func readLocalTemplate(path string) (SessionTemplate, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return SessionTemplate{}, err
+	}
+	template, err := decodeTemplate(data)
+	if err != nil {
+		return SessionTemplate{}, fmt.Errorf("invalid template %s: %v", path, err)
+	}
+	dir := filepath.Dir(path)
+	if strings.TrimSpace(template.Name) == "" {
+		template.Name = projectSessionName(dir)
+	}
+	switch root := expandHome(template.Root); {
+	case root == "":
+		template.Root = dir
+	case !filepath.IsAbs(root):
+		template.Root = filepath.Join(dir, root)
+	}
+	if problems := templateProblems(template); len(problems) > 0 {
+		return SessionTemplate{}, fmt.Errorf("invalid template %s: %s", path, strings.Join(problems, "; "))
+	}
+	return template, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Macros work like vim's registers: Q and a letter record the keys pressed
+// from then on, in any view and prompt, until Q is pressed again in the
+// session list; @ and the letter replays them, @@ the last one replayed.
+// Registers last until lazytmux quits. A replay feeds one key at a time and
+// waits for what it started in tmux, and for the list read after, before
+// the next, so that a key acts on what the ones before it did.
+
+// replayStepMsg has Update look whether the next key can be replayed.
+type replayStepMsg struct{}
+
+func replayStepDone() tea.Msg {
+	return replayStepMsg{}
+}
+
+func isRegister(key string) bool {
+	return len(key) == 1 && key[0] >= 'a' && key[0] <= 'z'
+}
+
+// macroKey handles Q and @ in the session list and the register letter that
+// follows them. It reports false for keys that are not its business.
+func (m model) macroKey(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	key := msg.String()
+	if m.macroPending != "" {
+		pending := m.macroPending
+		m.macroPending = ""
+		m.message = ""
+		switch {
+		case pending == "Q" && isRegister(key):
+			m.macroRecording = key
+			m.macroRegisters[key] = nil
+			m.setMessage(fmt.Sprintf("Recording @%s, press Q to stop", key), "info")
+		case pending == "@" && key == "@" && m.macroLast != "":
+			next, cmd := m.replayMacro(m.macroLast)
+			return next, cmd, true
+		case pending == "@" && isRegister(key):
+			next, cmd := m.replayMacro(key)
+			return next, cmd, true
+		}
+		return m, nil, true
+	}
+	if m.replaying && !m.replayFeeding {
+		// A key typed during a replay stops it.
+		m.replayKeys, m.replaying = nil, false
+		m.setMessage(fmt.Sprintf("Stopped replaying @%s", m.macroLast), "warning")
+		return m, nil, true
+	}
+	if m.mode != browsing || m.replaying {
+		return m, nil, false
+	}
+
+	switch key {
+	case "Q":
+		if m.macroRecording != "" {
+			n := len(m.macroRegisters[m.macroRecording])
+			m.setMessage(fmt.Sprintf("Recorded %d key(s) into @%s", n, m.macroRecording), "success")
+			m.macroRecording = ""
+			return m, nil, true
+		}
+		m.macroPending = "Q"
+		m.setMessage("Record into register: press a letter", "info")
+		return m, nil, true
+	case "@":
+		m.macroPending = "@"
+		m.setMessage("Replay register: press a letter, or @ for the last one", "info")
+		return m, nil, true
+	}
+	return m, nil, false
+}
+
+// recordKey adds a key to the register being recorded, if any. Keys of a
+// macro replayed meanwhile are recorded one by one.
+func (m *model) recordKey(msg tea.KeyMsg) {
+	if m.macroRecording != "" {
+		m.macroRegisters[m.macroRecording] = append(m.macroRegisters[m.macroRecording], msg)
+	}
+}
+
+// replayMacro starts replaying the keys of a register; Update feeds them.
+func (m model) replayMacro(register string) (tea.Model, tea.Cmd) {
+	keys := m.macroRegisters[register]
+	if len(keys) == 0 {
+		m.setMessage(fmt.Sprintf("Register @%s is empty", register), "warning")
+		return m, nil
+	}
+	if register == m.macroRecording {
+		m.setMessage(fmt.Sprintf("Register @%s is still being recorded", register), "warning")
+		return m, nil
+	}
+	m.macroLast = register
+	m.replaying = true
+	m.replayKeys = keys
+	return m, nil
+}
+
+// replayReady reports whether the next key of a replay can go: what the
+// keys before it started in tmux is done and nothing is still being read.
+func (m model) replayReady() bool {
+	return m.replaying && backgroundWork.Load() == 0 && !m.reloading && !m.reloadWanted && !m.viewLoading()
+}
+
+// replayStep feeds the next key of the replay through Update as if it
+// were typed again, or ends the replay after the last one.
+func (m model) replayStep() (model, tea.Cmd) {
+	if len(m.replayKeys) == 0 {
+		m.replaying = false
+		if m.message == "" {
+			m.setMessage(fmt.Sprintf("Replayed @%s", m.macroLast), "info")
+		}
+		return m, nil
+	}
+	key := m.replayKeys[0]
+	m.replayKeys = m.replayKeys[1:]
+	m.replayFeeding = true
+	next, cmd := m.Update(key)
+	after, ok := next.(model)
+	if !ok {
+		return m, cmd
+	}
+	after.replayFeeding = false
+	return after, tea.Batch(cmd, replayStepDone)
+}
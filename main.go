@@ -1,13 +1,15 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"io/ioutil"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -15,31 +17,66 @@ import (
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/newcharhuso/tmux-navigator/pkg/lazytmux"
 )
 
 type Session struct {
-	Name     string
-	Windows  int
-	Created  string
-	Attached bool
+	Name      string
+	Windows   int
+	Created   string    // formatted for the session table
+	CreatedAt time.Time // zero if tmux didn't say
+	Attached  bool
+	Owner     string
+	Dir       string // of the active pane
+	Command   string // running in the active pane
+	Template  string // the session was created from, if any
+	Server    string // name of its server when several are listed
+	Project   bool   // a project directory without a session, see projects.go
+	Note      string // attached in the detail panel, see metadata.go
+
+	// A window rang the bell, showed activity or fell silent, see alerts.go.
+	Bell     bool
+	Activity bool
+	Silence  bool
+
+	// Last output in any pane and last attach, zero if tmux didn't say.
+	LastActivity time.Time
+	LastAttached time.Time
+
+	// Set by the config rules, see applyRules.
+	Tags      []string
+	Color     string
+	Protected bool
 }
 
-type Pane struct {
-	ID           int    `json:"id"`
-	Command      string `json:"command"`
-	Position     string `json:"position"`      // "main", "left", "right", "up", "down"
-	Parent       int    `json:"parent"`        // ID of parent pane
-	SplitPercent int    `json:"split_percent"` // percentage for split (default 50)
-	Row          int    `json:"row"`           // Visual row position
-	Col          int    `json:"col"`           // Visual column position
-	Width        int    `json:"width"`         // Visual width
-	Height       int    `json:"height"`        // Visual height
-}
+// Pane, TemplateWindow and the fields of SessionTemplate are the template
+// types of pkg/lazytmux, whose engine builds the sessions.
+type Pane = lazytmux.Pane
+
+type TemplateWindow = lazytmux.Window
 
 type SessionTemplate struct {
-	Name        string `json:"name"`
-	Description string `json:"description,omitempty"` // Made optional
-	Panes       []Pane `json:"panes"`
+	lazytmux.Template
+
+	// File the template was loaded from; empty until it is first saved.
+	file string
+}
+
+// afterCreate returns what creating a session from the template does.
+func (t SessionTemplate) afterCreate() string {
+	if t.AfterCreate != "" {
+		return t.AfterCreate
+	}
+	return config.AfterCreate
+}
+
+// paneCount returns the number of panes across all windows of the template.
+func (t SessionTemplate) paneCount() int {
+	n := len(t.Panes)
+	for _, w := range t.Windows {
+		n += len(w.Panes)
+	}
+	return n
 }
 
 type mode int
@@ -55,6 +92,44 @@ const (
 	templateCreating
 	templateEditing
 	paneEditing
+	paneBrowsing
+	templateVariables
+	templateReplacing
+	replaceReviewing
+	auditBrowsing
+	templateImporting
+	templateRenaming
+	templateDuplicating
+	templateFiltering
+	templateStarting
+	sessionFiltering
+	changelogViewing
+	commandEntering
+	noteEntering
+	broadcastEntering
+	bufferBrowsing
+	bufferSaving
+	windowBrowsing
+	windowCreating
+	windowRenaming
+	windowMoving
+	windowSwapping
+	paneJoining
+	clientBrowsing
+	attachChoosing
+	shareChoosing
+	shareGuestEntering
+	sessionDetails
+	metaEditing
+	idleReviewing
+	processBrowsing
+	searchEntering
+	searchBrowsing
+	processFinding
+	logViewing
+	layoutImporting
+	templateTesting
+	editorWindowNaming
 )
 
 type action int
@@ -64,6 +139,12 @@ const (
 	actionDelete
 	actionKillAll
 	actionDeleteTemplate
+	actionKillPane
+	actionBroadcast
+	actionWorktrees
+	actionKillWindow
+	actionKillClient
+	actionUnshare
 )
 
 type tickMsg time.Time
@@ -76,6 +157,9 @@ type model struct {
 	cursor           int
 	templateCursor   int
 	paneCursor       int
+	presetIndex      int
+	editorLayout     string // tmux layout picked with 1-5 in the editor
+	editorWindow     int    // window shown in the editor, the first being 0
 	mode             mode
 	input            textinput.Model
 	commandInput     textinput.Model
@@ -87,19 +171,143 @@ type model struct {
 	showHelp         bool
 	confirmAction    action
 	confirmTarget    string
+	confirmServer    Server // the server of confirmTarget, for sessions
+	broadcastCommand string
+	broadcastPanes   []string
+	worktreeSessions map[string]string // name -> worktree path, waiting to be created
 	lastRefresh      time.Time
 	autoRefresh      bool
+	hooksRegistered  bool
+	hooksPending     bool      // a registration is on its way
+	hooksTried       time.Time // when the hooks were last registered
+	detachNew        bool
 	animationTime    float64
 	startTime        time.Time
 	lastCursor       int
 	popAnimation     float64
+	animating        bool      // the frames of popAnimation tick
+	clicks           *clickMap // where View drew what, see mouse.go
+	dragging         int       // index of the pane whose border is dragged in the editor, 0 for none
+	movingPane       int       // ID of the pane picked up with m in the editor, 0 for none
+	undoStack        []editorState
+	redoStack        []editorState
 	currentTemplate  SessionTemplate
 	editingPaneID    int
 	showTemplates    bool
 	previewMode      bool
+	listShare        int // percent of the width the template list takes next to the preview, 0 for the default
+	showPanes        bool
+	showWindows      bool
+	windowSession    string
+	liveWindows      []LiveWindow
+	windowCursor     int
+	joinTargets      []LiveWindow
+	joinCursor       int
+	importWindows    []LiveWindow // windows to import a layout from
+	importCursor     int
+	testSession      string   // scratch session T built the template into
+	testWindow       string   // its window shown in the editor
+	testCapture      []string // what it looked like
+	showClients      bool
+	compact          bool // one line per session, see Config.Density
+	macroRegisters   map[string][]tea.KeyMsg
+	macroRecording   string // register keys are recorded into
+	macroPending     string // "Q" or "@" while waiting for a register
+	macroLast        string
+	replaying        bool
+	replayKeys       []tea.KeyMsg      // keys of the replay still to feed
+	replayFeeding    bool              // the key at hand is replayed, not typed
+	bookmarks        map[string]string // register -> session or session:index
+	bookmarkPending  string            // "M" or "'" while waiting for a register
+	favorites        favorites
+	metadata         map[string]sessionMeta // by session name
+	detailSession    string
+	metaField        string // "note", "tags" or "color" while editing it
+	idleSessions     []Session
+	idleKeep         map[string]bool // idle sessions unchecked in the review
+	idleCursor       int
+	procSession      Session
+	procLines        []procLine
+	procCursor       int
+	searchQuery      string
+	searchMatches    []searchMatch
+	searchCursor     int
+	findProcess      string               // last command looked for with f
+	watches          map[string]paneWatch // by server name and pane id
+	checkingWatches  bool
+	reloadWanted     bool   // read the session list after this update
+	reloading        bool   // the session list is being read
+	pendingSelect    string // session to select once the list has it
+	viewReads        int    // reads of the open view asked for, see refreshView
+	viewShown        int    // the read the open view shows
+	sessionOffset    int    // first session row drawn, see viewport.go
+	templateOffset   int    // first visible template drawn
+	logFile          string // shown in the log viewer
+	logLines         []string
+	logScroll        int // lines scrolled up from the end of the log
+	clients          []Client
+	shares           map[string]Share // running shares by session
+	clientCursor     int
+	paneSession      string
+	livePanes        []LivePane
+	livePaneCursor   int
+	pendingTemplate  SessionTemplate
+	pendingSession   string
+	varNames         []string
+	varInputs        []textinput.Model
+	varCursor        int
+	varPreset        int // index into the pending template's presets, -1 for none
+	savingPreset     bool
+	presetInput      textinput.Model
+	marked           map[string]bool
+	editingTitle     bool
+	findInput        textinput.Model
+	replaceInput     textinput.Model
+	replaceMatches   []replaceMatch
+	replaceCursor    int
+	allSessions      []Session
+	projects         []Session // without a session yet, listed after allSessions
+	stats            serverStats
+	gitStates        map[string]gitState // by directory, see gitstatus.go
+	gitCheckedAt     time.Time
+	checkingGit      bool
+	dirInput         textinput.Model // directory field of the new session prompt
+	dirChoices       []string        // directories zoxide knows
+	dirCursor        int
+	mineOnly         bool
+	sessionFilter    string
+	previousFilter   string
+	sortByDir        bool
+	showAudit        bool
+	auditEntries     []auditEntry
+	auditCursor      int
+	showBuffers      bool
+	buffers          []Buffer
+	bufferCursor     int
+	bufferPreview    string
+	showOutput       bool
+	showResources    bool
+	measuring        bool
+	resources        map[string]resourceUsage // by session name
+	cpuSeen          map[int]time.Duration    // CPU time by pid at resourcesAt
+	resourcesAt      time.Time
+	lastOutput       map[string]string
+	lastOutputAt     time.Time
+	capturingOutput  bool
+	update           release
+	showChangelog    bool
+	changelogScroll  int
+	commandHistory   []string
+	historyIndex     int
+	tagsInput        textinput.Model
+	tagFilter        string
+	templateOrder    string
 }
 
 var terminalCmd string
+
+// readOnly disables every action that kills, renames or deletes something.
+var readOnly bool
 var (
 	primaryColor   = lipgloss.Color("1e66f5")
 	secondaryColor = lipgloss.Color("178299")
@@ -130,6 +338,16 @@ var (
 				BorderBottom(true).
 				BorderForeground(templateColor)
 
+	// Compact density drops the boxes, leaving one line per row.
+	compactHeaderStyle = lipgloss.NewStyle().
+				Foreground(primaryColor).
+				Bold(true).
+				Padding(0, 1)
+
+	compactRowStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("16")).
+			Padding(0, 1)
+
 	selectedRowStyle = lipgloss.NewStyle().
 				Foreground(lipgloss.Color("16")).
 				Bold(true).
@@ -225,9 +443,7 @@ func getDefaultTerminal() string {
 	}
 
 	// Try to detect available terminals in order of preference
-	terminals := []string{"kitty", "alacritty", "gnome-terminal", "xterm", "konsole", "terminator", "tilix"}
-
-	for _, term := range terminals {
+	for _, term := range supportedTerminals {
 		if _, err := exec.LookPath(term); err == nil {
 			return term
 		}
@@ -245,8 +461,13 @@ func validateTerminal(terminal string) error {
 	return nil
 }
 
-// Function to get terminal-specific arguments
-func getTerminalArgs(terminal string) []string {
+// Function to get terminal-specific arguments for attaching to srv
+func getTerminalArgs(srv Server, terminal string) []string {
+	return srv.withTmuxSocket(terminalArgs(terminal))
+}
+
+// terminalArgs is getTerminalArgs for the default tmux server.
+func terminalArgs(terminal string) []string {
 	switch terminal {
 	case "kitty":
 		return []string{"--detach", "-e", "tmux", "attach-session", "-t"}
@@ -272,91 +493,106 @@ func getConfigDir() string {
 	return filepath.Join(homeDir, ".config", "lazytmux")
 }
 
+// getTemplatesFile is where templates lived before they moved to one file
+// each in getTemplatesDir. It is only read to migrate them.
 func getTemplatesFile() string {
 	return filepath.Join(getConfigDir(), "templates.json")
 }
 
-func loadTemplates() []SessionTemplate {
-	templatesFile := getTemplatesFile()
-	if _, err := os.Stat(templatesFile); os.IsNotExist(err) {
-		return []SessionTemplate{}
-	}
-
-	data, err := ioutil.ReadFile(templatesFile)
-	if err != nil {
-		return []SessionTemplate{}
-	}
-
-	var templates []SessionTemplate
-	json.Unmarshal(data, &templates)
-	return templates
-}
-
-func saveTemplates(templates []SessionTemplate) error {
-	configDir := getConfigDir()
-	os.MkdirAll(configDir, 0755)
-
-	data, err := json.MarshalIndent(templates, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	return ioutil.WriteFile(getTemplatesFile(), data, 0644)
-}
-
 func tick() tea.Cmd {
 	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
 		return tickMsg(t)
 	})
 }
 
-func animationTick() tea.Cmd {
-	return tea.Tick(time.Millisecond*50, func(t time.Time) tea.Msg {
-		return animationTickMsg(t)
-	})
-}
-
 func refresh() tea.Cmd {
 	return func() tea.Msg {
 		return refreshMsg{}
 	}
 }
 
+// listTmuxSessions lists the sessions of every managed server.
 func listTmuxSessions() []Session {
-	out, err := exec.Command("tmux", "list-sessions", "-F", "#S:#{session_windows}:#{session_created}:#{session_attached}").Output()
+	sessions := []Session{}
+	for _, s := range managedServers() {
+		sessions = append(sessions, listServerSessions(s)...)
+	}
+	return sessions
+}
+
+func listServerSessions(server Server) []Session {
+	format := strings.Join([]string{"#S", "#{session_windows}", "#{session_created}", "#{session_attached}", "#{@owner}", "#{socket_path}", "#{pane_current_path}", "#{@template}", "#{pane_current_command}", "#{session_alerts}", "#{session_activity}", "#{session_last_attached}"}, "\t")
+	out, err := server.command("list-sessions", "-F", format).Output()
 	if err != nil {
 		return []Session{}
 	}
 	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
 	sessions := []Session{}
+	socketOwner := ""
 	for _, line := range lines {
 		if line != "" {
-			parts := strings.Split(line, ":")
-			if len(parts) >= 4 {
+			parts := strings.Split(line, "\t")
+			if len(parts) >= 9 {
 				windows := 1
 				if w, err := strconv.Atoi(parts[1]); err == nil {
 					windows = w
 				}
 
 				created := "unknown"
+				var createdAt time.Time
 				if ts, err := strconv.ParseInt(parts[2], 10, 64); err == nil {
-					created = time.Unix(ts, 0).Format("15:04 02/01")
+					createdAt = time.Unix(ts, 0)
+					created = createdAt.Format("15:04 02/01")
 				}
 
 				attached := parts[3] == "1"
 
-				sessions = append(sessions, Session{
-					Name:     parts[0],
-					Windows:  windows,
-					Created:  created,
-					Attached: attached,
-				})
+				// Sessions created by lazytmux carry @owner; for the rest,
+				// whoever owns the server socket owns the session.
+				owner := parts[4]
+				if owner == "" && !server.remote() {
+					if socketOwner == "" {
+						socketOwner = fileOwner(parts[5])
+					}
+					owner = socketOwner
+				}
+
+				session := Session{
+					Name:      parts[0],
+					Windows:   windows,
+					Created:   created,
+					CreatedAt: createdAt,
+					Attached:  attached,
+					Owner:     owner,
+					Dir:       parts[6],
+					Template:  parts[7],
+					Command:   parts[8],
+					Server:    server.Name,
+				}
+				// Fixtures recorded by older versions lack the alerts.
+				if len(parts) >= 10 {
+					session.parseAlerts(parts[9])
+				}
+				if len(parts) >= 12 {
+					session.LastActivity = unixTime(parts[10])
+					session.LastAttached = unixTime(parts[11])
+				}
+				sessions = append(sessions, session)
 			}
 		}
 	}
 	return sessions
 }
 
+// unixTime reads a tmux timestamp, zero if there is none.
+func unixTime(s string) time.Time {
+	ts, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || ts == 0 {
+		return time.Time{}
+	}
+	return time.Unix(ts, 0)
+}
+
 func generateNumericName(existing []Session) string {
 	names := map[int]bool{}
 	for _, s := range existing {
@@ -372,6 +608,104 @@ func generateNumericName(existing []Session) string {
 	return "0"
 }
 
+// startTemplateNaming opens the name prompt used to rename or duplicate a
+// template, prefilled with name.
+func (m *model) startTemplateNaming(name string) {
+	ti := textinput.New()
+	ti.Placeholder = "Template name"
+	ti.CharLimit = 50
+	ti.SetValue(name)
+	ti.CursorEnd()
+	ti.Focus()
+	m.input = ti
+}
+
+// startSessionNaming asks for the name of a session to create from
+// template, suggesting the template name or the first free variant of it.
+func (m *model) startSessionNaming(template SessionTemplate) {
+	ti := textinput.New()
+	ti.Placeholder = "Session name"
+	ti.CharLimit = 50
+	ti.SetValue(uniqueTemplateName(template.Name, m.allSessions, nil))
+	ti.CursorEnd()
+	ti.Focus()
+	m.input = ti
+	m.pendingTemplate = template
+	m.mode = templateStarting
+}
+
+// checkSessionName reports why name can't be used for a new session.
+func checkSessionName(name string, sessions []Session) error {
+	switch {
+	case name == "":
+		return errors.New("session name cannot be empty")
+	case strings.ContainsAny(name, ".:"):
+		return errors.New("names cannot contain '.' or ':'")
+	case nameExists(name, sessions, nil):
+		return fmt.Errorf("session '%s' already exists", name)
+	}
+	return nil
+}
+
+// createDetached reports whether the key confirming a new session asks to
+// keep the TUI open rather than attach. Alt+Enter does the opposite of Enter,
+// and Enter attaches unless afterCreate is "stay".
+func createDetached(key, afterCreate string) bool {
+	stay := afterCreate == "stay"
+	if key == "alt+enter" {
+		return !stay
+	}
+	return stay
+}
+
+// createHint describes the keys confirming a new session.
+func createHint(afterCreate string) string {
+	switch afterCreate {
+	case "stay":
+		return "[Enter] Create • [Alt+Enter] Attach"
+	case "read-only":
+		return "[Enter] Watch • [Alt+Enter] Create only"
+	}
+	return "[Enter] Attach • [Alt+Enter] Create only"
+}
+
+// selectSession moves the cursor to the named session if it is listed.
+func (m *model) selectSession(name string) {
+	for i, s := range m.sessions {
+		if s.Name == name {
+			m.cursor = i
+		}
+	}
+}
+
+// denyReadOnly reports whether read-only mode forbids an action, and tells
+// the user so if it does.
+func (m *model) denyReadOnly(action string) bool {
+	if !readOnly {
+		return false
+	}
+	m.setMessage(fmt.Sprintf("Read-only mode: %s is disabled", action), "warning")
+	return true
+}
+
+// hideDestructive drops the shortcuts for keys disabled in read-only mode.
+func hideDestructive(shortcuts [][]string, keys ...string) [][]string {
+	if !readOnly {
+		return shortcuts
+	}
+	var out [][]string
+	for _, shortcut := range shortcuts {
+		disabled := false
+		for _, k := range keys {
+			disabled = disabled || shortcut[0] == k
+		}
+		if !disabled {
+			out = append(out, shortcut)
+		}
+	}
+	return out
+}
+
 // Check if a name already exists in sessions or templates
 func nameExists(name string, sessions []Session, templates []SessionTemplate) bool {
 	for _, s := range sessions {
@@ -397,9 +731,16 @@ func findTemplateByPrefix(name string, templates []SessionTemplate) *SessionTemp
 	return nil
 }
 
-func attachSession(name string) {
-	args := getTerminalArgs(terminalCmd)
-	args = append(args, name)
+// attachSession attaches to the session called name on srv.
+func attachSession(srv Server, name string) {
+	if !spawnsTerminals() {
+		deferredAttach = append(deferredAttach, attachTarget{srv, name})
+		return
+	}
+	args := getTerminalArgs(srv, terminalCmd)
+	args = append(args, srv.remoteQuote(name))
+	args = append(args, attachFlags(name)...)
+	args = withPreAttach(args, name)
 
 	cmd := exec.Command(terminalCmd, args...)
 	if err := cmd.Start(); err != nil {
@@ -407,98 +748,78 @@ func attachSession(name string) {
 	}
 }
 
-func killSession(name string) error {
-	return exec.Command("tmux", "kill-session", "-t", name).Run()
+func (s Server) sessionExists(name string) bool {
+	return s.command("has-session", "-t", "="+name).Run() == nil
 }
 
-func killAllSessions() error {
-	return exec.Command("tmux", "kill-server").Run()
+// killSession kills the session named exactly name, never one it is a
+// prefix or pattern of, which tmux would otherwise fall back to once the
+// session is gone.
+func (s Server) killSession(name string) error {
+	return s.command("kill-session", "-t", "="+name).Run()
 }
 
-func renameSession(old, new string) error {
-	return exec.Command("tmux", "rename-session", "-t", old, new).Run()
+func (s Server) killAllSessions() error {
+	return s.command("kill-server").Run()
 }
 
-func createSession(name string) error {
-	return exec.Command("tmux", "new-session", "-ds", name).Run()
+// renameSession renames the session named exactly old.
+func (s Server) renameSession(old, new string) error {
+	return s.command("rename-session", "-t", "="+old, new).Run()
 }
 
-func createSessionFromTemplate(sessionName string, template SessionTemplate) error {
-	// Create base session
-	if err := createSession(sessionName); err != nil {
-		return err
-	}
-
-	if len(template.Panes) == 0 {
-		return nil
-	}
-
-	// Lookup initial (only) pane id
-	out, err := exec.Command("tmux", "list-panes", "-t", sessionName, "-F", "#{pane_id}").Output()
-	if err != nil {
-		return err
-	}
-	baseID := strings.TrimSpace(string(out))
-	idMap := map[int]string{}
-	idMap[template.Panes[0].ID] = baseID
-
-	// Command for first pane
-	if cmd := strings.TrimSpace(template.Panes[0].Command); cmd != "" {
-		_ = exec.Command("tmux", "send-keys", "-t", baseID, cmd, "C-m").Run()
-	}
-
-	// Create others in the given order, always selecting parent before split
-	for i := 1; i < len(template.Panes); i++ {
-		p := template.Panes[i]
-		parentID, ok := idMap[p.Parent]
-		if !ok {
-			// Fallback: split the first pane
-			parentID = baseID
-		}
+var errSessionExists = lazytmux.ErrSessionExists
 
-		args := []string{"split-window", "-t", parentID}
-		switch p.Position {
-		case "left", "right":
-			args = append(args, "-h")
-			if p.Position == "left" {
-				args = append(args, "-b") // place on the left of parent
-			}
-		case "up", "down":
-			args = append(args, "-v")
-			if p.Position == "up" {
-				args = append(args, "-b") // place above parent
-			}
-		default:
-			// default to vertical split
-			args = append(args, "-h")
-		}
+// createSession starts a detached session. tmux refuses duplicate names
+// itself, so this doubles as an atomic "create if missing" and reports
+// errSessionExists when another process won the race.
+func (s Server) createSession(name string) error {
+	return s.createSessionIn(name, "")
+}
 
-		if p.SplitPercent > 0 && p.SplitPercent != 50 {
-			args = append(args, "-p", strconv.Itoa(p.SplitPercent))
-		}
+// createSessionIn is createSession with a start directory for the first pane.
+func (s Server) createSessionIn(name, dir string) error {
+	return s.builder().NewSession(context.Background(), name, dir)
+}
 
-		// Print new pane id
-		args = append(args, "-P", "-F", "#{pane_id}")
+func (s Server) createSessionFromTemplate(sessionName string, template SessionTemplate) error {
+	return s.builder().Create(context.Background(), sessionName, template.Template)
+}
 
-		newOut, err := exec.Command("tmux", args...).Output()
-		if err != nil {
-			return err
-		}
-		newID := strings.TrimSpace(string(newOut))
-		idMap[p.ID] = newID
+// applyTemplate lays out windows and panes of template inside an existing,
+// freshly created session.
+func (s Server) applyTemplate(sessionName string, template SessionTemplate) error {
+	return s.builder().Apply(context.Background(), sessionName, template.Template)
+}
 
-		if cmd := strings.TrimSpace(p.Command); cmd != "" {
-			_ = exec.Command("tmux", "send-keys", "-t", newID, cmd, "C-m").Run()
-		}
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
+	return keys
+}
 
-	// Focus original pane
-	_ = exec.Command("tmux", "select-pane", "-t", baseID).Run()
-	return nil
+// expandHome replaces a leading ~ with the user's home directory.
+func expandHome(path string) string {
+	return lazytmux.ExpandHome(path)
+}
+
+// shellQuote wraps s in single quotes for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
 
 func (m model) Init() tea.Cmd {
-	return tea.Batch(textinput.Blink, tick(), animationTick())
+	cmds := []tea.Cmd{textinput.Blink, tick()}
+	if eventHooksEnabled() {
+		cmds = append(cmds, watchEvents())
+	}
+	if updateCheckEnabled() {
+		cmds = append(cmds, checkForUpdate())
+	}
+	return tea.Batch(cmds...)
 }
 
 func (m *model) setMessage(msg, msgType string) {
@@ -506,6 +827,21 @@ func (m *model) setMessage(msg, msgType string) {
 	m.messageType = msgType
 }
 
+func (m model) renderMessage() string {
+	var msgStyle lipgloss.Style
+	switch m.messageType {
+	case "success":
+		msgStyle = successMessageStyle
+	case "warning":
+		msgStyle = warningMessageStyle
+	case "error":
+		msgStyle = errorMessageStyle
+	default:
+		msgStyle = infoMessageStyle
+	}
+	return msgStyle.Render(m.message)
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -561,7 +897,32 @@ func (m *model) findPaneIndex(id int) int {
 	return -1
 }
 
+// Update handles a message and, when asked to, announces what it changed.
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.updateModel(msg)
+	after, ok := next.(model)
+	if !ok {
+		return next, cmd
+	}
+	if announceEnabled() && !after.replaying {
+		announceChanges(m, after)
+	}
+	if reload := after.startReload(); reload != nil {
+		cmd = tea.Batch(cmd, reload)
+	}
+	if animation := after.startAnimation(); animation != nil {
+		cmd = tea.Batch(cmd, animation)
+	}
+	after.followCursor()
+	if after.replayReady() {
+		var step tea.Cmd
+		after, step = after.replayStep()
+		cmd = tea.Batch(cmd, step)
+	}
+	return after, cmd
+}
+
+func (m model) updateModel(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 
 	switch msg := msg.(type) {
@@ -569,36 +930,169 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.height = msg.Height
 
+	case tea.MouseMsg:
+		return m.updateMouse(msg)
+
 	case animationTickMsg:
-		m.animationTime = time.Since(m.startTime).Seconds()
-		if m.popAnimation > 0 {
-			m.popAnimation -= 0.05
-			if m.popAnimation < 0 {
-				m.popAnimation = 0
-			}
+		if cmd := m.stepAnimation(); cmd != nil {
+			cmds = append(cmds, cmd)
 		}
-		cmds = append(cmds, animationTick())
 
 	case tickMsg:
-		if m.autoRefresh && time.Since(m.lastRefresh) > 5*time.Second {
-			m.sessions = listTmuxSessions()
+		if m.refreshDue(time.Now()) {
+			m.loadSessions()
 			m.lastRefresh = time.Now()
 		}
+		if eventHooksEnabled() {
+			if cmd := m.registerHooks(); cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+		}
+		// A read still on its way is not asked for again.
+		if !m.viewLoading() {
+			if cmd := m.refreshView(); cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+		}
+		if m.mode == logViewing {
+			m.reloadPaneLog()
+		}
+		if cmd := m.refreshLastOutput(); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+		if cmd := m.refreshGitStatus(); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+		if cmd := m.refreshResources(); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+		if cmd := m.refreshWatches(); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
 		cmds = append(cmds, tick())
 
+	case tmuxCommandMsg:
+		recordAudit("tmux-command", msg.command, msg.err)
+		m.showCommandResult(msg)
+		m.loadSessions()
+
+	case updateMsg:
+		m.update = release(msg)
+
+	case actionDoneMsg:
+		last := lastLine(msg.output)
+		switch {
+		case msg.err != nil && last != "":
+			m.setMessage(fmt.Sprintf("%s failed: %v: %s", msg.action.description(), msg.err, last), "error")
+		case msg.err != nil:
+			m.setMessage(fmt.Sprintf("%s failed: %v", msg.action.description(), msg.err), "error")
+		case last != "":
+			m.setMessage(last, "success")
+		default:
+			m.setMessage(fmt.Sprintf("Ran %s", msg.action.description()), "success")
+		}
+		// The command may well have changed sessions or templates.
+		m.loadSessions()
+		m.templates = loadTemplates()
+		m.sortTemplates()
+		m.clampTemplateCursor()
+
+	case lastOutputMsg:
+		m.lastOutput = msg
+		m.lastOutputAt = time.Now()
+		m.capturingOutput = false
+
+	case resourcesMsg:
+		m.resources = msg.usage
+		m.cpuSeen = msg.cpuTime
+		m.resourcesAt = msg.at
+		m.measuring = false
+
+	case sessionsMsg:
+		m.reloading = false
+		m.lastRefresh = time.Now()
+		m.applySessions(msg)
+
+	case doneMsg:
+		backgroundWork.Add(-1)
+		cmds = append(cmds, msg.done(&m, msg.err))
+		m.loadSessions()
+
+	case viewMsg:
+		m.applyView(msg)
+
+	case replayStepMsg:
+		// Update replays the next key if it can.
+
+	case watchMsg:
+		m.finishWatches(msg)
+
+	case searchMsg:
+		if m.mode == browsing {
+			m.showSearchResults(msg)
+		}
+
+	case gitStatusMsg:
+		m.gitStates = msg
+		m.gitCheckedAt = time.Now()
+		m.checkingGit = false
+
+	case eventsMsg:
+		if !msg.failed {
+			// With auto-refresh off the list only changes on Ctrl+R.
+			if m.autoRefresh {
+				m.loadSessions()
+				m.lastRefresh = time.Now()
+			}
+			if cmd := m.refreshView(); cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+		}
+		cmds = append(cmds, waitForEvents(serverNamed(msg.server)))
+
+	case shareDoneMsg:
+		if msg.share.Socket == "" {
+			recordAudit("share-session", "", msg.err)
+			m.setMessage(fmt.Sprintf("Failed to share: %v", msg.err), "error")
+			break
+		}
+		recordAudit("share-session", fmt.Sprintf("%s via %s", msg.share.Session, msg.share.Backend), nil)
+		m.shares[msg.share.Session] = msg.share
+		if err := saveShares(m.shares); err != nil {
+			m.setMessage(fmt.Sprintf("Shared, but failed to remember it: %v", err), "warning")
+			break
+		}
+		text := fmt.Sprintf("Shared '%s', your pair joins with: %s", msg.share.Session, msg.share.Command)
+		if copyToClipboard(msg.share.Command) == nil {
+			text += " (copied)"
+		}
+		m.setMessage(text, "success")
+
 	case refreshMsg:
-		m.sessions = listTmuxSessions()
+		m.loadSessions()
 		m.templates = loadTemplates()
+		m.sortTemplates()
+		m.clampTemplateCursor()
 		m.lastRefresh = time.Now()
 		m.setMessage("Sessions and templates refreshed", "success")
 
 	case tea.KeyMsg:
+		if next, cmd, ok := m.macroKey(msg); ok {
+			return next, cmd
+		}
+		if next, cmd, ok := m.bookmarkKey(msg); ok {
+			return next, cmd
+		}
+		m.recordKey(msg)
 		if m.message != "" {
 			m.message = ""
 		}
 
 		switch m.mode {
 		case browsing:
+			if m.refuseOnProject(msg.String()) {
+				break
+			}
 			switch msg.String() {
 			case "ctrl+c", "q":
 				return m, tea.Quit
@@ -626,10 +1120,36 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.cursor = len(m.sessions) - 1
 					m.popAnimation = 0.5
 				}
+			case "pgup":
+				m.pageSessions(-m.sessionRows())
+			case "pgdown":
+				m.pageSessions(m.sessionRows())
+			case "ctrl+u":
+				m.pageSessions(-m.sessionRows() / 2)
+			case "ctrl+d":
+				m.pageSessions(m.sessionRows() / 2)
 			case "enter", " ":
+				if m.onProject() {
+					return m.openProject("enter")
+				}
+				if len(m.attachTargets()) > 0 {
+					return m.startAttach()
+				}
+			case "alt+enter":
+				if m.onProject() {
+					return m.openProject("alt+enter")
+				}
+				if len(m.attachTargets()) > 0 {
+					m.mode = attachChoosing
+				}
+			case "m":
 				if len(m.sessions) > 0 {
-					attachSession(m.sessions[m.cursor].Name)
-					return m, tea.Quit
+					name := m.sessions[m.cursor].Name
+					if m.marked[name] {
+						delete(m.marked, name)
+					} else {
+						m.marked[name] = true
+					}
 				}
 			case "n", "c":
 				ti := textinput.New()
@@ -637,8 +1157,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				ti.Focus()
 				ti.CharLimit = 50
 				m.input = ti
+				m.startDirPicker()
 				m.mode = creating
 			case "r":
+				if m.denyReadOnly("renaming sessions") {
+					break
+				}
 				if len(m.sessions) > 0 {
 					ti := textinput.New()
 					ti.Placeholder = "Enter new session name"
@@ -649,17 +1173,73 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.mode = renaming
 				}
 			case "d":
+				if m.denyReadOnly("deleting sessions") {
+					break
+				}
 				if len(m.sessions) > 0 {
+					if m.sessions[m.cursor].Protected {
+						m.setMessage(fmt.Sprintf("Session '%s' is protected by a rule", m.sessions[m.cursor].Name), "warning")
+						break
+					}
 					m.confirmAction = actionDelete
 					m.confirmTarget = m.sessions[m.cursor].Name
+					m.confirmServer = m.server()
 					m.mode = confirming
 				}
 			case "D":
+				if m.denyReadOnly("deleting sessions") {
+					break
+				}
 				if len(m.sessions) > 0 {
 					m.confirmAction = actionKillAll
 					m.confirmTarget = ""
 					m.mode = confirming
 				}
+			case "*":
+				m.starSession()
+			case "i":
+				m.showDetails()
+			case "P":
+				cmds = append(cmds, m.showProcesses())
+			case "F":
+				m.startSearch()
+			case "f":
+				m.startFindProcess()
+			case "T":
+				if len(m.sessions) > 0 {
+					cmds = append(cmds, m.toggleWatch(m.sessions[m.cursor], ""))
+				}
+			case "I":
+				if m.denyReadOnly("killing idle sessions") {
+					break
+				}
+				if config.Idle.idleAfter() == 0 {
+					m.setMessage("Set idle.after in the config to find idle sessions", "info")
+				} else if !m.startIdleReview() {
+					m.setMessage("No idle sessions", "info")
+				}
+			case "O":
+				if cmd := m.openDirectory(); cmd != nil {
+					cmds = append(cmds, cmd)
+				}
+			case "o":
+				m.mineOnly = !m.mineOnly
+				m.showSessions()
+				if m.mineOnly {
+					m.setMessage("Showing only your sessions", "info")
+				} else {
+					m.setMessage("Showing sessions of all users", "info")
+				}
+			case "/":
+				m.startSessionFilter()
+			case "s":
+				m.sortByDir = !m.sortByDir
+				m.showSessions()
+				if m.sortByDir {
+					m.setMessage("Sorted by directory", "info")
+				} else {
+					m.setMessage("Sorted by name", "info")
+				}
 			case "ctrl+r", "F5":
 				cmds = append(cmds, refresh())
 			case "a":
@@ -672,58 +1252,848 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case "t":
 				m.showTemplates = true
 				m.templateCursor = 0
+				m.clampTemplateCursor()
 				m.mode = templateBrowsing
+			case "v":
+				m.compact = !m.compact
+				density := "detailed"
+				if m.compact {
+					density = "compact"
+				}
+				config.Density = density
+				if err := saveConfigValue("density", density); err != nil {
+					m.setMessage(fmt.Sprintf("Switched to %s view, but could not save it: %v", density, err), "warning")
+				} else {
+					m.setMessage(fmt.Sprintf("Switched to %s view", density), "info")
+				}
+			case "C":
+				m.clients, m.clientCursor = nil, 0
+				m.showClients = true
+				m.mode = clientBrowsing
+				cmds = append(cmds, m.refreshView())
+			case "w":
+				if len(m.sessions) > 0 {
+					m.windowSession = m.sessions[m.cursor].Name
+					m.liveWindows, m.windowCursor = nil, 0
+					m.showWindows = true
+					m.mode = windowBrowsing
+					cmds = append(cmds, m.refreshView())
+				}
+			case "p":
+				if len(m.sessions) > 0 {
+					m.paneSession = m.sessions[m.cursor].Name
+					m.livePanes, m.livePaneCursor = nil, 0
+					m.showPanes = true
+					m.mode = paneBrowsing
+					cmds = append(cmds, m.refreshView())
+				}
+			case "A":
+				entries, err := readAuditLog()
+				if err != nil {
+					m.setMessage(fmt.Sprintf("Failed to read audit log: %v", err), "error")
+				}
+				m.auditEntries = entries
+				m.auditCursor = 0
+				m.showAudit = true
+				m.mode = auditBrowsing
+			case "l":
+				m.showOutput = !m.showOutput
+				if m.showOutput {
+					// Capture right away rather than on the next tick.
+					m.lastOutputAt = time.Time{}
+					if cmd := m.refreshLastOutput(); cmd != nil {
+						cmds = append(cmds, cmd)
+					}
+				}
+			case "u":
+				m.showResources = !m.showResources
+				if m.showResources {
+					// Measure right away, starting over for the CPU.
+					m.cpuSeen, m.resourcesAt = nil, time.Time{}
+					if cmd := m.refreshResources(); cmd != nil {
+						cmds = append(cmds, cmd)
+					}
+				}
+			case "N":
+				if len(m.sessions) > 0 {
+					m.startNote()
+				}
+			case "S":
+				if len(m.sessions) == 0 || m.denyReadOnly("sharing sessions") {
+					break
+				}
+				if m.server().remote() {
+					m.setMessage("Sessions on other machines can't be shared from here", "warning")
+					break
+				}
+				name := m.sessions[m.cursor].Name
+				if _, ok := m.shares[name]; ok {
+					m.confirmAction = actionUnshare
+					m.confirmTarget = name
+					m.mode = confirming
+					break
+				}
+				m.mode = shareChoosing
+			case "W":
+				if len(m.sessions) == 0 {
+					break
+				}
+				trees, err := worktreeSessions(m.sessions[m.cursor].Dir, m.allSessions)
+				if err != nil {
+					m.setMessage(fmt.Sprintf("No worktrees: %v", err), "warning")
+					break
+				}
+				if len(trees) == 0 {
+					m.setMessage("Every worktree already has a session", "info")
+					break
+				}
+				m.worktreeSessions = trees
+				m.confirmAction = actionWorktrees
+				m.confirmTarget = ""
+				m.mode = confirming
+			case "B":
+				if m.denyReadOnly("broadcasting commands") {
+					break
+				}
+				if len(m.sessions) > 0 {
+					m.startBroadcast()
+				}
+			case ":":
+				if m.denyReadOnly("running tmux commands") {
+					break
+				}
+				m.startCommandMode()
+			case "U":
+				if m.update.Tag != "" {
+					m.changelogScroll = 0
+					m.showChangelog = true
+					m.mode = changelogViewing
+				}
+			case "X":
+				if m.update.Tag != "" {
+					m.dismissUpdate()
+				}
+			case "b":
+				m.buffers, m.bufferCursor, m.bufferPreview = nil, 0, ""
+				m.showBuffers = true
+				m.mode = bufferBrowsing
+				cmds = append(cmds, m.refreshView())
 			case "?", "h":
 				m.showHelp = !m.showHelp
+			default:
+				if a, ok := findAction(actionViewSessions, msg.String()); ok {
+					if cmd := m.runAction(a); cmd != nil {
+						cmds = append(cmds, cmd)
+					}
+				}
 			}
 
-		case templateBrowsing:
+		case auditBrowsing:
 			switch msg.String() {
 			case "ctrl+c", "q", "esc":
-				m.showTemplates = false
+				m.showAudit = false
 				m.mode = browsing
 			case "up", "k":
-				if m.templateCursor > 0 {
-					m.templateCursor--
-					m.popAnimation = 0.5
+				if m.auditCursor > 0 {
+					m.auditCursor--
 				}
 			case "down", "j":
-				if m.templateCursor < len(m.templates)-1 {
-					m.templateCursor++
-					m.popAnimation = 0.5
+				if m.auditCursor < len(m.auditEntries)-1 {
+					m.auditCursor++
 				}
-			case "enter", " ":
-				if len(m.templates) > 0 {
-					// Create session from template
-					template := m.templates[m.templateCursor]
-					sessionName := fmt.Sprintf("%s-%d", template.Name, time.Now().Unix())
+			case "g":
+				m.auditCursor = 0
+			case "G":
+				m.auditCursor = max(0, len(m.auditEntries)-1)
+			}
 
-					if err := createSessionFromTemplate(sessionName, template); err != nil {
-						m.setMessage(fmt.Sprintf("Failed to create session from template: %v", err), "error")
-					} else {
-						m.setMessage(fmt.Sprintf("Created session '%s' from template '%s'", sessionName, template.Name), "success")
-						attachSession(sessionName)
-						return m, tea.Quit
-					}
+		case commandEntering:
+			switch msg.String() {
+			case "enter":
+				command := strings.TrimSpace(m.input.Value())
+				m.mode = browsing
+				if command == "" {
+					break
 				}
-			case "n", "c":
-				// Create new template
-				m.currentTemplate = SessionTemplate{
-					Name:        "",
-					Description: "",
-					Panes: []Pane{{
-						ID:           1,
-						Command:      "",
-						Position:     "main",
-						Parent:       0,
-						SplitPercent: 50,
-						Row:          0,
-						Col:          0,
-						Width:        layoutGridW,
-						Height:       layoutGridH,
-					}},
+				m.commandHistory = addCommandHistory(m.commandHistory, command)
+				cmds = append(cmds, runTmuxCommand(m.server(), command))
+			case "esc":
+				m.mode = browsing
+			case "up":
+				m.browseCommandHistory(-1)
+			case "down":
+				m.browseCommandHistory(1)
+			default:
+				var cmd tea.Cmd
+				m.input, cmd = m.input.Update(msg)
+				cmds = append(cmds, cmd)
+			}
+
+		case broadcastEntering:
+			switch msg.String() {
+			case "enter":
+				command := strings.TrimSpace(m.input.Value())
+				m.mode = browsing
+				if command == "" || len(m.sessions) == 0 {
+					break
 				}
-				m.editingPaneID = 1
+				name, srv := m.sessions[m.cursor].Name, m.server()
+				var panes []string
+				cmds = append(cmds, inBackground(func() (err error) {
+					panes, err = srv.sessionPaneIDs(name)
+					return err
+				}, func(m *model, err error) tea.Cmd {
+					if err != nil || len(panes) == 0 {
+						m.setMessage(fmt.Sprintf("Failed to list the panes of '%s'", name), "error")
+						return nil
+					}
+					if m.mode != browsing {
+						return nil
+					}
+					m.broadcastCommand = command
+					m.broadcastPanes = panes
+					m.confirmAction = actionBroadcast
+					m.confirmTarget = name
+					m.mode = confirming
+					return nil
+				}))
+			case "esc":
+				m.mode = browsing
+			default:
+				var cmd tea.Cmd
+				m.input, cmd = m.input.Update(msg)
+				cmds = append(cmds, cmd)
+			}
+
+		case attachChoosing:
+			variant := map[string]string{"n": attachNormal, "d": attachDetachOthers, "r": attachReadOnly}[msg.String()]
+			switch {
+			case variant != "":
+				for _, t := range m.attachTargets() {
+					attachVariants[t.name] = variant
+				}
+				return m.startAttach()
+			case msg.String() == "esc":
+				m.mode = browsing
+			}
+
+		case shareChoosing:
+			switch msg.String() {
+			case "u":
+				m.startShareGuest()
+			case "t":
+				if tmateInstalled() {
+					m.mode = browsing
+					m.setMessage("Waiting for tmate to connect...", "info")
+					cmds = append(cmds, shareWithTmate(m.server(), m.sessions[m.cursor].Name))
+				}
+			case "esc":
+				m.mode = browsing
+			}
+
+		case shareGuestEntering:
+			switch msg.String() {
+			case "enter":
+				guest := strings.TrimSpace(m.input.Value())
+				m.mode = browsing
+				if guest == "" || len(m.sessions) == 0 {
+					break
+				}
+				cmds = append(cmds, shareWithUser(m.server(), m.sessions[m.cursor].Name, guest))
+			case "esc":
+				m.mode = browsing
+			default:
+				var cmd tea.Cmd
+				m.input, cmd = m.input.Update(msg)
+				cmds = append(cmds, cmd)
+			}
+
+		case processBrowsing:
+			switch msg.String() {
+			case "up", "k":
+				if m.procCursor > 0 {
+					m.procCursor--
+				}
+			case "down", "j":
+				if m.procCursor < len(m.procLines)-1 {
+					m.procCursor++
+				}
+			case "g":
+				m.procCursor = 0
+			case "G":
+				m.procCursor = max(0, len(m.procLines)-1)
+			case "r", "ctrl+r", "F5":
+				cmds = append(cmds, m.reloadProcesses())
+			case "d":
+				m.mode = browsing
+				if m.denyReadOnly("deleting sessions") {
+					break
+				}
+				if m.procSession.Protected {
+					m.setMessage(fmt.Sprintf("Session '%s' is protected by a rule", m.procSession.Name), "warning")
+					break
+				}
+				m.confirmAction = actionDelete
+				m.confirmTarget = m.procSession.Name
+				m.mode = confirming
+			case "esc", "q", "P":
+				m.mode = browsing
+			}
+
+		case searchEntering:
+			switch msg.String() {
+			case "enter":
+				cmds = append(cmds, m.runSearch())
+			case "esc":
+				m.mode = browsing
+			default:
+				var cmd tea.Cmd
+				m.input, cmd = m.input.Update(msg)
+				cmds = append(cmds, cmd)
+			}
+
+		case logViewing:
+			switch msg.String() {
+			case "up", "k":
+				if m.logScroll < len(m.logLines)-m.logRows() {
+					m.logScroll++
+				}
+			case "down", "j":
+				if m.logScroll > 0 {
+					m.logScroll--
+				}
+			case "g":
+				m.logScroll = max(0, len(m.logLines)-m.logRows())
+			case "G":
+				m.logScroll = 0
+			case "esc", "q", "V":
+				m.mode = paneBrowsing
+			}
+
+		case processFinding:
+			switch msg.String() {
+			case "enter":
+				cmds = append(cmds, m.findProcessSession())
+			case "esc":
+				m.mode = browsing
+			default:
+				var cmd tea.Cmd
+				m.input, cmd = m.input.Update(msg)
+				cmds = append(cmds, cmd)
+			}
+
+		case searchBrowsing:
+			switch msg.String() {
+			case "up", "k":
+				if m.searchCursor > 0 {
+					m.searchCursor--
+				}
+			case "down", "j":
+				if m.searchCursor < len(m.searchMatches)-1 {
+					m.searchCursor++
+				}
+			case "g":
+				m.searchCursor = 0
+			case "G":
+				m.searchCursor = max(0, len(m.searchMatches)-1)
+			case "enter":
+				cmds = append(cmds, m.jumpToMatch())
+			case "/", "F":
+				m.startSearch()
+			case "esc", "q":
+				m.mode = browsing
+			}
+
+		case idleReviewing:
+			switch msg.String() {
+			case "up", "k":
+				if m.idleCursor > 0 {
+					m.idleCursor--
+				}
+			case "down", "j":
+				if m.idleCursor < len(m.idleSessions)-1 {
+					m.idleCursor++
+				}
+			case " ", "x":
+				name := m.idleSessions[m.idleCursor].Name
+				m.idleKeep[name] = !m.idleKeep[name]
+			case "enter":
+				cmds = append(cmds, m.killIdleSessions())
+				m.mode = browsing
+			case "esc", "q":
+				m.mode = browsing
+			}
+
+		case sessionDetails:
+			switch msg.String() {
+			case "e":
+				m.startMetaEdit("note")
+			case "t":
+				m.startMetaEdit("tags")
+			case "c":
+				m.startMetaEdit("color")
+			case "esc", "i", "q":
+				m.mode = browsing
+			}
+
+		case metaEditing:
+			cmds = append(cmds, m.updateMetaEdit(msg))
+
+		case noteEntering:
+			switch msg.String() {
+			case "enter":
+				note := strings.TrimSpace(m.input.Value())
+				m.mode = browsing
+				if note == "" || len(m.sessions) == 0 {
+					break
+				}
+				name, srv := m.sessions[m.cursor].Name, m.server()
+				cmds = append(cmds, inBackground(func() error {
+					return srv.addNote(name, note)
+				}, func(m *model, err error) tea.Cmd {
+					if err != nil {
+						m.setMessage(fmt.Sprintf("Failed to add note: %v", err), "error")
+					} else {
+						m.setMessage(fmt.Sprintf("Added a note to '%s'", name), "success")
+					}
+					return nil
+				}))
+			case "esc":
+				m.mode = browsing
+			default:
+				var cmd tea.Cmd
+				m.input, cmd = m.input.Update(msg)
+				cmds = append(cmds, cmd)
+			}
+
+		case changelogViewing:
+			switch msg.String() {
+			case "ctrl+c", "q", "esc":
+				m.showChangelog = false
+				m.mode = browsing
+			case "up", "k":
+				m.scrollChangelog(-1)
+			case "down", "j":
+				m.scrollChangelog(1)
+			case "pgup":
+				m.scrollChangelog(-m.changelogHeight())
+			case "pgdown", " ":
+				m.scrollChangelog(m.changelogHeight())
+			case "X":
+				m.dismissUpdate()
+				m.showChangelog = false
+				m.mode = browsing
+			}
+
+		case bufferBrowsing:
+			switch msg.String() {
+			case "ctrl+c", "q", "esc":
+				m.showBuffers = false
+				m.mode = browsing
+			case "up", "k":
+				if m.bufferCursor > 0 {
+					m.bufferCursor--
+					m.bufferPreview = ""
+					cmds = append(cmds, m.refreshView())
+				}
+			case "down", "j":
+				if m.bufferCursor < len(m.buffers)-1 {
+					m.bufferCursor++
+					m.bufferPreview = ""
+					cmds = append(cmds, m.refreshView())
+				}
+			case "d":
+				if m.denyReadOnly("deleting buffers") {
+					break
+				}
+				if len(m.buffers) > 0 {
+					name, srv := m.buffers[m.bufferCursor].Name, m.server()
+					cmds = append(cmds, changeInBackground(func() error { return srv.deleteBuffer(name) },
+						"delete-buffer", name, "Failed to delete buffer", fmt.Sprintf("Deleted buffer '%s'", name)))
+				}
+			case "w":
+				if len(m.buffers) > 0 {
+					ti := textinput.New()
+					ti.Placeholder = "File to write"
+					ti.CharLimit = 200
+					ti.SetValue("~/" + m.buffers[m.bufferCursor].Name + ".txt")
+					ti.CursorEnd()
+					ti.Focus()
+					m.input = ti
+					m.mode = bufferSaving
+				}
+			case "c":
+				srv := m.server()
+				var text string
+				cmds = append(cmds, inBackground(func() (err error) {
+					text, err = readClipboard()
+					if err == nil && text == "" {
+						err = errors.New("the clipboard is empty")
+					}
+					if err == nil {
+						err = srv.loadBufferText(text)
+					}
+					return err
+				}, func(m *model, err error) tea.Cmd {
+					if err != nil {
+						m.setMessage(fmt.Sprintf("Failed to load the clipboard: %v", err), "error")
+						return nil
+					}
+					m.bufferCursor = 0
+					m.setMessage(fmt.Sprintf("Loaded %s from the clipboard", formatSize(len(text))), "success")
+					return m.refreshView()
+				}))
+			}
+
+		case bufferSaving:
+			switch msg.String() {
+			case "enter":
+				path := expandHome(strings.TrimSpace(m.input.Value()))
+				if path == "" {
+					break
+				}
+				name, srv := m.buffers[m.bufferCursor].Name, m.server()
+				m.mode = bufferBrowsing
+				cmds = append(cmds, inBackground(func() error {
+					return srv.saveBuffer(name, path)
+				}, func(m *model, err error) tea.Cmd {
+					if err != nil {
+						m.setMessage(fmt.Sprintf("Failed to save buffer: %v", err), "error")
+					} else {
+						m.setMessage(fmt.Sprintf("Saved buffer '%s' to %s", name, path), "success")
+					}
+					return nil
+				}))
+			case "esc":
+				m.mode = bufferBrowsing
+			default:
+				var cmd tea.Cmd
+				m.input, cmd = m.input.Update(msg)
+				cmds = append(cmds, cmd)
+			}
+
+		case clientBrowsing:
+			switch msg.String() {
+			case "ctrl+c", "q", "esc":
+				m.showClients = false
+				m.mode = browsing
+			case "up", "k":
+				if m.clientCursor > 0 {
+					m.clientCursor--
+				}
+			case "down", "j":
+				if m.clientCursor < len(m.clients)-1 {
+					m.clientCursor++
+				}
+			case "d":
+				if m.denyReadOnly("detaching clients") {
+					break
+				}
+				if len(m.clients) > 0 {
+					c := m.clients[m.clientCursor]
+					srv := serverNamed(c.Server)
+					cmds = append(cmds, changeInBackground(func() error { return srv.detachClient(c.Name) },
+						"detach-client", fmt.Sprintf("%s from %s", c.Name, c.Session),
+						"Failed to detach client", fmt.Sprintf("Detached %s from '%s'", c.Name, c.Session)))
+				}
+			case "x":
+				if m.denyReadOnly("killing clients") {
+					break
+				}
+				if len(m.clients) > 0 {
+					m.confirmAction = actionKillClient
+					m.confirmTarget = m.clients[m.clientCursor].Name
+					m.mode = confirming
+				}
+			}
+
+		case windowBrowsing:
+			switch msg.String() {
+			case "ctrl+c", "q", "esc":
+				m.showWindows = false
+				m.mode = browsing
+			case "up", "k":
+				if m.windowCursor > 0 {
+					m.windowCursor--
+				}
+			case "down", "j":
+				if m.windowCursor < len(m.liveWindows)-1 {
+					m.windowCursor++
+				}
+			case "enter", " ":
+				if len(m.liveWindows) > 0 {
+					srv, id, session := m.server(), m.liveWindows[m.windowCursor].ID, m.windowSession
+					cmds = append(cmds, inBackground(func() error {
+						return srv.selectWindow(id)
+					}, func(m *model, err error) tea.Cmd {
+						if err != nil {
+							m.setMessage(fmt.Sprintf("Failed to select window: %v", err), "error")
+							return nil
+						}
+						attachSession(srv, session)
+						return tea.Quit
+					}))
+				}
+			case "n":
+				m.startWindowInput(windowCreating, "Window name (empty for the default)", "")
+			case "r":
+				if m.denyReadOnly("renaming windows") {
+					break
+				}
+				if len(m.liveWindows) > 0 {
+					m.startWindowInput(windowRenaming, "Enter new window name", m.liveWindows[m.windowCursor].Name)
+				}
+			case "x":
+				if m.denyReadOnly("killing windows") {
+					break
+				}
+				if len(m.liveWindows) > 0 {
+					m.confirmAction = actionKillWindow
+					m.confirmTarget = m.liveWindows[m.windowCursor].ID
+					m.mode = confirming
+				}
+			case "m":
+				if m.denyReadOnly("moving windows") {
+					break
+				}
+				if len(m.liveWindows) > 0 {
+					m.startWindowInput(windowMoving, "Session to move the window to", "")
+				}
+			case "s":
+				if m.denyReadOnly("swapping windows") {
+					break
+				}
+				if len(m.liveWindows) > 0 {
+					m.startWindowInput(windowSwapping, "e.g. 2 or api:1", "")
+				}
+			}
+
+		case windowCreating, windowRenaming, windowMoving, windowSwapping:
+			switch msg.String() {
+			case "enter":
+				cmds = append(cmds, m.submitWindowInput())
+			case "esc":
+				m.mode = windowBrowsing
+			default:
+				var cmd tea.Cmd
+				m.input, cmd = m.input.Update(msg)
+				cmds = append(cmds, cmd)
+			}
+
+		case editorWindowNaming:
+			switch msg.String() {
+			case "enter":
+				m.finishWindowNaming()
+			case "esc":
+				m.mode = templateEditing
+			default:
+				var cmd tea.Cmd
+				m.input, cmd = m.input.Update(msg)
+				cmds = append(cmds, cmd)
+			}
+
+		case templateTesting:
+			switch msg.String() {
+			case "r":
+				if m.testCapture != nil {
+					cmds = append(cmds, m.recaptureTest())
+				}
+			case "ctrl+c", "q", "esc":
+				cmds = append(cmds, m.endTest())
+			}
+
+		case layoutImporting:
+			switch msg.String() {
+			case "up", "k":
+				if m.importCursor > 0 {
+					m.importCursor--
+				}
+			case "down", "j":
+				if m.importCursor < len(m.importWindows)-1 {
+					m.importCursor++
+				}
+			case "enter":
+				cmds = append(cmds, m.finishLayoutImport())
+			case "esc", "q":
+				m.importWindows = nil
+				m.mode = templateEditing
+			}
+
+		case paneJoining:
+			switch msg.String() {
+			case "up", "k":
+				if m.joinCursor > 0 {
+					m.joinCursor--
+				}
+			case "down", "j":
+				if m.joinCursor < len(m.joinTargets)-1 {
+					m.joinCursor++
+				}
+			case "enter":
+				cmds = append(cmds, m.finishJoin(false))
+			case "tab":
+				cmds = append(cmds, m.finishJoin(true))
+			case "esc", "q":
+				m.joinTargets = nil
+				m.mode = paneBrowsing
+			}
+
+		case paneBrowsing:
+			switch msg.String() {
+			case "ctrl+c", "q", "esc":
+				m.showPanes = false
+				m.mode = browsing
+			case "up", "k":
+				if m.livePaneCursor > 0 {
+					m.livePaneCursor--
+				}
+			case "down", "j":
+				if m.livePaneCursor < len(m.livePanes)-1 {
+					m.livePaneCursor++
+				}
+			case "enter", " ":
+				if len(m.livePanes) > 0 {
+					srv, id, session := m.server(), m.livePanes[m.livePaneCursor].ID, m.paneSession
+					cmds = append(cmds, inBackground(func() error {
+						return srv.selectPane(id)
+					}, func(m *model, err error) tea.Cmd {
+						if err != nil {
+							m.setMessage(fmt.Sprintf("Failed to select pane: %v", err), "error")
+							return nil
+						}
+						attachSession(srv, session)
+						return tea.Quit
+					}))
+				}
+			case "b":
+				if m.denyReadOnly("breaking panes out") {
+					break
+				}
+				if len(m.livePanes) > 0 {
+					pane := m.livePanes[m.livePaneCursor]
+					if windowPaneCount(m.livePanes, pane) < 2 {
+						m.setMessage(fmt.Sprintf("Pane %s already has a window of its own", pane.Index), "warning")
+						break
+					}
+					srv, session := m.server(), m.paneSession
+					cmds = append(cmds, changeInBackground(func() error { return srv.breakPane(pane.ID, session) },
+						"break-pane", fmt.Sprintf("%s:%s", session, pane.Index),
+						"Failed to break pane out", fmt.Sprintf("Moved pane %s to a window of its own", pane.Index)))
+				}
+			case "L":
+				if m.denyReadOnly("logging panes") {
+					break
+				}
+				if len(m.livePanes) > 0 {
+					srv, session, pane := m.server(), m.paneSession, m.livePanes[m.livePaneCursor]
+					var text string
+					cmds = append(cmds, inBackground(func() (err error) {
+						text, err = srv.togglePaneLog(session, pane)
+						return err
+					}, func(m *model, err error) tea.Cmd {
+						if err != nil {
+							m.setMessage(fmt.Sprintf("Failed to log pane: %v", err), "error")
+						} else {
+							m.setMessage(text, "success")
+						}
+						return m.refreshView()
+					}))
+				}
+			case "V":
+				if len(m.livePanes) > 0 {
+					m.showPaneLog()
+				}
+			case "T":
+				if len(m.livePanes) > 0 {
+					for _, s := range m.allSessions {
+						if s.Name == m.paneSession {
+							cmds = append(cmds, m.toggleWatch(s, m.livePanes[m.livePaneCursor].ID))
+						}
+					}
+				}
+			case "J":
+				if m.denyReadOnly("joining panes") {
+					break
+				}
+				if len(m.livePanes) > 0 {
+					cmds = append(cmds, m.startJoin())
+				}
+			case "R":
+				if m.denyReadOnly("respawning panes") {
+					break
+				}
+				if len(m.livePanes) > 0 {
+					pane := m.livePanes[m.livePaneCursor]
+					if !pane.Dead {
+						m.setMessage("Pane is still running, close it first", "warning")
+						break
+					}
+					srv := m.server()
+					cmds = append(cmds, changeInBackground(func() error { return srv.respawnPane(pane.ID) },
+						"", "", "Failed to respawn pane", fmt.Sprintf("Respawned pane %s", pane.Index)))
+				}
+			case "x":
+				if m.denyReadOnly("closing panes") {
+					break
+				}
+				if len(m.livePanes) > 0 {
+					pane := m.livePanes[m.livePaneCursor]
+					if !pane.Dead {
+						m.confirmAction = actionKillPane
+						m.confirmTarget = pane.ID
+						m.mode = confirming
+						break
+					}
+					srv := m.server()
+					cmds = append(cmds, changeInBackground(func() error { return srv.killPane(pane.ID) },
+						"kill-pane", fmt.Sprintf("%s:%s", m.paneSession, pane.Index),
+						"Failed to close pane", fmt.Sprintf("Closed pane %s", pane.Index)))
+				}
+			}
+
+		case templateBrowsing:
+			switch msg.String() {
+			case "ctrl+c", "q", "esc":
+				m.showTemplates = false
+				m.mode = browsing
+			case "up", "k":
+				if m.moveTemplateCursor(-1) {
+					m.popAnimation = 0.5
+				}
+			case "down", "j":
+				if m.moveTemplateCursor(1) {
+					m.popAnimation = 0.5
+				}
+			case "pgup":
+				m.pageTemplates(-m.templateRows())
+			case "pgdown":
+				m.pageTemplates(m.templateRows())
+			case "ctrl+u":
+				m.pageTemplates(-m.templateRows() / 2)
+			case "ctrl+d":
+				m.pageTemplates(m.templateRows() / 2)
+			case "enter", " ":
+				if m.visibleTemplates() > 0 {
+					m.startSessionNaming(m.templates[m.templateCursor])
+				}
+			case "n", "c":
+				// Create new template
+				m.currentTemplate = SessionTemplate{Template: lazytmux.Template{
+					Name:        "",
+					Description: "",
+					Panes: []Pane{{
+						ID:           1,
+						Command:      "",
+						Position:     "main",
+						Parent:       0,
+						SplitPercent: 50,
+						Row:          0,
+						Col:          0,
+						Width:        layoutGridW,
+						Height:       layoutGridH,
+					}},
+				}}
+				m.editingPaneID = 1
 
 				ti := textinput.New()
 				ti.Placeholder = "Enter template name"
@@ -736,44 +2106,133 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				desc.CharLimit = 100
 				m.descriptionInput = desc
 
+				tags := textinput.New()
+				tags.Placeholder = "e.g. work, go (optional)"
+				tags.CharLimit = 100
+				tags.SetValue(m.tagFilter)
+				m.tagsInput = tags
+
 				m.mode = templateCreating
 			case "e":
-				if len(m.templates) > 0 {
+				if m.denyReadOnly("editing templates") {
+					break
+				}
+				if m.selectedTemplateVisible() {
 					m.currentTemplate = m.templates[m.templateCursor]
+					m.editorWindow = 0
 					m.editingPaneID = 1
 					m.paneCursor = 0
 					m.calculatePaneLayout()
+					m.startEditHistory()
 					m.mode = templateEditing
 				}
 			case "d":
-				if len(m.templates) > 0 {
+				if m.denyReadOnly("deleting templates") {
+					break
+				}
+				if m.selectedTemplateVisible() {
 					m.confirmAction = actionDeleteTemplate
 					m.confirmTarget = m.templates[m.templateCursor].Name
 					m.mode = confirming
 				}
+			case "r":
+				if m.denyReadOnly("renaming templates") {
+					break
+				}
+				if m.selectedTemplateVisible() {
+					m.startTemplateNaming(m.templates[m.templateCursor].Name)
+					m.mode = templateRenaming
+				}
+			case "y":
+				if m.selectedTemplateVisible() {
+					name := m.templates[m.templateCursor].Name + "-copy"
+					m.startTemplateNaming(uniqueTemplateName(name, m.allSessions, m.templates))
+					m.mode = templateDuplicating
+				}
+			case "R":
+				if m.denyReadOnly("replacing in templates") {
+					break
+				}
+				if m.visibleTemplates() > 0 {
+					m.startReplace()
+				}
+			case "i":
+				templates, imported, skipped := importProjects(findTmuxinatorProjects(), parseTmuxinator, m.templates)
+				templates, tmuxpImported, tmuxpSkipped := importProjects(findTmuxpProjects(), parseTmuxp, templates)
+				imported = append(imported, tmuxpImported...)
+				skipped = append(skipped, tmuxpSkipped...)
+				if len(imported) == 0 && len(skipped) == 0 {
+					m.setMessage("No tmuxinator or tmuxp projects found", "warning")
+					break
+				}
+				if len(imported) == 0 {
+					m.setMessage(fmt.Sprintf("Nothing imported (%d skipped)", len(skipped)), "warning")
+					break
+				}
+				if err := saveTemplates(templates); err != nil {
+					m.setMessage(fmt.Sprintf("Failed to save templates: %v", err), "error")
+					break
+				}
+				m.templates = templates
+				if len(skipped) > 0 {
+					m.setMessage(fmt.Sprintf("Imported %d project(s), %d skipped", len(imported), len(skipped)), "warning")
+				} else {
+					m.setMessage(fmt.Sprintf("Imported %d project(s)", len(imported)), "success")
+				}
+			case "I":
+				ti := textinput.New()
+				ti.Placeholder = "Path or https:// URL of a JSON or YAML template"
+				ti.Focus()
+				ti.CharLimit = 500
+				m.input = ti
+				m.mode = templateImporting
+			case "f":
+				m.startTagFilter()
+			case "*":
+				m.starTemplate()
+			case "s":
+				m.templateOrder = nextTemplateOrder(m.templateOrder)
+				m.sortTemplates()
+				m.setMessage(fmt.Sprintf("Templates ordered by %s", m.templateOrder), "info")
 			case "p":
 				m.previewMode = !m.previewMode
+			case "<":
+				m.resizePreview(-listShareStep)
+			case ">":
+				m.resizePreview(listShareStep)
 			case "?", "h":
 				m.showHelp = !m.showHelp
+			default:
+				if a, ok := findAction(actionViewTemplates, msg.String()); ok {
+					if cmd := m.runAction(a); cmd != nil {
+						cmds = append(cmds, cmd)
+					}
+				}
 			}
 
 		case templateCreating:
 			var cmd tea.Cmd
-			if m.input.Focused() {
+			switch {
+			case m.input.Focused():
 				m.input, cmd = m.input.Update(msg)
-				cmds = append(cmds, cmd)
-			} else {
+			case m.descriptionInput.Focused():
 				m.descriptionInput, cmd = m.descriptionInput.Update(msg)
-				cmds = append(cmds, cmd)
+			default:
+				m.tagsInput, cmd = m.tagsInput.Update(msg)
 			}
+			cmds = append(cmds, cmd)
 
 			switch msg.String() {
 			case "tab":
-				if m.input.Focused() {
+				switch {
+				case m.input.Focused():
 					m.input.Blur()
 					m.descriptionInput.Focus()
-				} else {
+				case m.descriptionInput.Focused():
 					m.descriptionInput.Blur()
+					m.tagsInput.Focus()
+				default:
+					m.tagsInput.Blur()
 					m.input.Focus()
 				}
 			case "enter":
@@ -785,7 +2244,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 
 					// Check for duplicate names
-					if nameExists(name, m.sessions, m.templates) {
+					if nameExists(name, m.allSessions, m.templates) {
 						m.setMessage("Name already exists", "error")
 						break
 					}
@@ -801,13 +2260,14 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 
 					// Check for duplicate names again
-					if nameExists(name, m.sessions, m.templates) {
+					if nameExists(name, m.allSessions, m.templates) {
 						m.setMessage("Name already exists", "error")
 						break
 					}
 
 					m.currentTemplate.Name = name
 					m.currentTemplate.Description = strings.TrimSpace(m.descriptionInput.Value())
+					m.currentTemplate.Tags = parseTags(m.tagsInput.Value())
 					m.calculatePaneLayout()
 
 					m.templates = append(m.templates, m.currentTemplate)
@@ -817,6 +2277,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						m.setMessage(fmt.Sprintf("Template '%s' created", m.currentTemplate.Name), "success")
 						m.mode = templateBrowsing
 						m.templateCursor = len(m.templates) - 1
+						m.clampTemplateCursor()
 					}
 				}
 			case "esc":
@@ -826,8 +2287,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case templateEditing:
+			if m.movingPane != 0 && m.moveKey(msg.String()) {
+				break
+			}
 			switch msg.String() {
 			case "ctrl+c", "q", "esc":
+				m.movingPane = 0
 				m.mode = templateBrowsing
 			case "up", "k":
 				if m.paneCursor > 0 {
@@ -848,18 +2313,83 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					cmd.CharLimit = 100
 					m.commandInput = cmd
 
+					m.editingTitle = false
+					m.mode = paneEditing
+				}
+			case "t":
+				if len(m.currentTemplate.Panes) > 0 {
+					m.editingPaneID = m.currentTemplate.Panes[m.paneCursor].ID
+
+					title := textinput.New()
+					title.Placeholder = "Enter pane label (e.g. editor, server, logs)"
+					title.SetValue(m.currentTemplate.Panes[m.paneCursor].Title)
+					title.Focus()
+					title.CharLimit = 40
+					m.commandInput = title
+
+					m.editingTitle = true
 					m.mode = paneEditing
 				}
 			case "H":
+				m.recordEdit("adding a pane")
 				m.addPane("left")
 			case "L":
+				m.recordEdit("adding a pane")
 				m.addPane("right")
 			case "J":
+				m.recordEdit("adding a pane")
 				m.addPane("down")
 			case "K":
+				m.recordEdit("adding a pane")
 				m.addPane("up")
+			case "y":
+				m.recordEdit("duplicating a pane")
+				m.duplicatePane()
+			case "l":
+				m.recordEdit("the layout preset")
+				m.applyNextPreset()
+			case "1", "2", "3", "4", "5":
+				m.recordEdit("the layout")
+				m.applyLayout(layoutKeys[msg.String()], len(m.currentTemplate.Panes))
+			case "i":
+				cmds = append(cmds, m.startLayoutImport())
+			case "T":
+				cmds = append(cmds, m.testTemplate())
+			case "[":
+				m.nextWindow(-1)
+			case "]":
+				m.nextWindow(1)
+			case "n":
+				m.addWindow()
+			case "r":
+				m.startWindowNaming()
+			case "x":
+				m.deleteWindow()
+			case "+":
+				m.resizeLayout(1)
+			case "-":
+				m.resizeLayout(-1)
+			case "shift+left":
+				m.swapPane("left")
+			case "shift+right":
+				m.swapPane("right")
+			case "shift+up":
+				m.swapPane("up")
+			case "shift+down":
+				m.swapPane("down")
+			case "m":
+				m.startMovePane()
+			case ">":
+				m.resizePane(resizeStep)
+			case "<":
+				m.resizePane(-resizeStep)
+			case "ctrl+z":
+				m.undoEdit()
+			case "ctrl+y":
+				m.redoEdit()
 			case "d":
 				if len(m.currentTemplate.Panes) > 1 && m.paneCursor < len(m.currentTemplate.Panes) {
+					m.recordEdit("deleting a pane")
 					m.currentTemplate.Panes = append(m.currentTemplate.Panes[:m.paneCursor], m.currentTemplate.Panes[m.paneCursor+1:]...)
 					if m.paneCursor >= len(m.currentTemplate.Panes) {
 						m.paneCursor = len(m.currentTemplate.Panes) - 1
@@ -868,14 +2398,18 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			case "s":
 				// Save template
+				edited := m.editedTemplate()
 				for i, template := range m.templates {
-					if template.Name == m.currentTemplate.Name {
-						m.templates[i] = m.currentTemplate
+					if template.Name == edited.Name {
+						m.templates[i] = edited
 						break
 					}
 				}
 				if err := saveTemplates(m.templates); err != nil {
 					m.setMessage(fmt.Sprintf("Failed to save template: %v", err), "error")
+				} else if missing := templateMissingCommands(edited); len(missing) > 0 {
+					m.setMessage(fmt.Sprintf("Template saved, but %s", strings.Join(missing, "; ")), "warning")
+					m.mode = templateBrowsing
 				} else {
 					m.setMessage("Template saved", "success")
 					m.mode = templateBrowsing
@@ -889,12 +2423,21 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			switch msg.String() {
 			case "enter":
-				// Update pane command
+				// Update pane command or label
+				value := strings.TrimSpace(m.commandInput.Value())
 				for i := range m.currentTemplate.Panes {
-					if m.currentTemplate.Panes[i].ID == m.editingPaneID {
-						m.currentTemplate.Panes[i].Command = strings.TrimSpace(m.commandInput.Value())
-						break
+					p := &m.currentTemplate.Panes[i]
+					if p.ID != m.editingPaneID {
+						continue
 					}
+					if m.editingTitle && p.Title != value {
+						m.recordEdit("the label")
+						p.Title = value
+					} else if !m.editingTitle && p.Command != value {
+						m.recordEdit("the command")
+						p.Command = value
+					}
+					break
 				}
 				m.mode = templateEditing
 			case "esc":
@@ -902,60 +2445,337 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case creating, renaming:
+			if m.mode == creating {
+				if ok, cmd := m.dirKey(msg); ok {
+					cmds = append(cmds, cmd)
+					break
+				}
+			}
 			var cmd tea.Cmd
 			m.input, cmd = m.input.Update(msg)
 			cmds = append(cmds, cmd)
 
 			switch msg.String() {
-			case "enter":
+			case "enter", "alt+enter":
 				val := strings.TrimSpace(m.input.Value())
 				if m.mode == creating {
+					m.detachNew = createDetached(msg.String(), config.AfterCreate)
+					dir := m.pickedDir()
+					if val == "" && dir != "" && !nameExists(projectSessionName(dir), m.allSessions, m.templates) {
+						val = projectSessionName(dir)
+					}
 					if val == "" {
-						val = generateNumericName(m.sessions)
+						val = generateNumericName(m.allSessions)
 					}
 
 					// Check if session name matches a template prefix
 					template := findTemplateByPrefix(val, m.templates)
+					if val = sanitizeSessionName(val); val == "" {
+						val = generateNumericName(m.allSessions)
+					}
+					if template != nil && dir != "" {
+						// The picked directory wins over the template's root.
+						t := *template
+						t.Root = dir
+						template = &t
+					}
 					if template != nil {
 						// Create session from template
-						if err := createSessionFromTemplate(val, *template); err != nil {
-							m.setMessage(fmt.Sprintf("Failed to create session from template: %v", err), "error")
-						} else {
-							m.setMessage(fmt.Sprintf("Created session '%s' from template '%s'", val, template.Name), "success")
-							attachSession(val)
-							return m, tea.Quit
+						m.detachNew = createDetached(msg.String(), template.afterCreate())
+						cmds = append(cmds, m.startTemplateSession(val, *template))
+						if m.mode == templateVariables {
+							m.input.SetValue("")
+							break
 						}
 					} else {
 						// Create regular session
-						if err := createSession(val); err != nil {
-							m.setMessage(fmt.Sprintf("Failed to create session: %v", err), "error")
-						} else {
-							m.setMessage(fmt.Sprintf("Created session '%s'", val), "success")
-							attachSession(val)
-							return m, tea.Quit
-						}
+						cmds = append(cmds, m.createInBackground(val, dir, fmt.Sprintf("Created session '%s'", val)))
 					}
-				} else if m.mode == renaming && val != "" {
+				} else if m.mode == renaming && sanitizeSessionName(val) != "" {
+					val = sanitizeSessionName(val)
 					// Check for duplicate names
-					if nameExists(val, m.sessions, m.templates) {
+					if nameExists(val, m.allSessions, m.templates) {
 						m.setMessage("Name already exists", "error")
 						break
 					}
 
-					oldName := m.sessions[m.cursor].Name
-					if err := renameSession(oldName, val); err != nil {
-						m.setMessage(fmt.Sprintf("Failed to rename session: %v", err), "error")
-					} else {
-						m.setMessage(fmt.Sprintf("Renamed '%s' to '%s'", oldName, val), "success")
+					cmds = append(cmds, renameInBackground(m.server(), m.sessions[m.cursor].Name, val))
+				}
+				m.mode = browsing
+				m.input.SetValue("")
+
+			case "esc":
+				m.mode = browsing
+				m.input.SetValue("")
+			}
+
+		case sessionFiltering:
+			switch msg.String() {
+			case "enter":
+				m.mode = browsing
+			case "esc":
+				m.sessionFilter = m.previousFilter
+				m.showSessions()
+				m.mode = browsing
+			default:
+				var cmd tea.Cmd
+				m.input, cmd = m.input.Update(msg)
+				cmds = append(cmds, cmd)
+				m.sessionFilter = strings.TrimSpace(m.input.Value())
+				m.showSessions()
+				m.cursor = 0
+			}
+
+		case templateFiltering:
+			switch msg.String() {
+			case "enter":
+				tag := strings.TrimPrefix(strings.TrimSpace(m.input.Value()), "#")
+				previous := m.tagFilter
+				m.tagFilter = tag
+				if tag != "" && m.visibleTemplates() == 0 {
+					m.tagFilter = previous
+					m.setMessage(fmt.Sprintf("No templates tagged '%s'", tag), "warning")
+					break
+				}
+				m.clampTemplateCursor()
+				m.mode = templateBrowsing
+			case "esc":
+				m.mode = templateBrowsing
+			default:
+				var cmd tea.Cmd
+				m.input, cmd = m.input.Update(msg)
+				cmds = append(cmds, cmd)
+			}
+
+		case templateRenaming, templateDuplicating:
+			switch msg.String() {
+			case "enter":
+				name := strings.TrimSpace(m.input.Value())
+				current := m.templates[m.templateCursor]
+				if m.mode == templateRenaming && name == current.Name {
+					m.mode = templateBrowsing
+					break
+				}
+				others := m.templates
+				if m.mode == templateRenaming {
+					others = append(append([]SessionTemplate(nil), m.templates[:m.templateCursor]...), m.templates[m.templateCursor+1:]...)
+				}
+				if err := checkTemplateName(name, m.allSessions, others); err != nil {
+					m.setMessage(fmt.Sprintf("Invalid name: %v", err), "error")
+					break
+				}
+				if m.mode == templateRenaming {
+					templates, err := renameTemplate(m.templates, m.templateCursor, name)
+					recordAudit("rename-template", current.Name+" -> "+name, err)
+					m.templates = templates
+					if err != nil {
+						m.setMessage(fmt.Sprintf("Failed to rename template: %v", err), "error")
+						break
+					}
+					renameUsage(current.Name, name)
+					if renameStar(m.favorites.Templates, current.Name, name) {
+						saveFavorites(m.favorites)
+					}
+					m.setMessage(fmt.Sprintf("Renamed template '%s' to '%s'", current.Name, name), "success")
+				} else {
+					templates, err := duplicateTemplate(m.templates, m.templateCursor, name)
+					if err != nil {
+						m.setMessage(fmt.Sprintf("Failed to duplicate template: %v", err), "error")
+						break
 					}
+					m.templates = templates
+					m.templateCursor++
+					m.setMessage(fmt.Sprintf("Duplicated '%s' as '%s'", current.Name, name), "success")
+				}
+				m.mode = templateBrowsing
+			case "esc":
+				m.mode = templateBrowsing
+			default:
+				var cmd tea.Cmd
+				m.input, cmd = m.input.Update(msg)
+				cmds = append(cmds, cmd)
+			}
+
+		case templateStarting:
+			switch msg.String() {
+			case "enter", "alt+enter":
+				name := sanitizeSessionName(m.input.Value())
+				m.detachNew = createDetached(msg.String(), m.pendingTemplate.afterCreate())
+				if err := checkSessionName(name, m.allSessions); err != nil {
+					m.setMessage(fmt.Sprintf("Invalid name: %v", err), "error")
+					break
+				}
+				cmds = append(cmds, m.startTemplateSession(name, m.pendingTemplate))
+				if m.mode == templateStarting {
+					m.mode = templateBrowsing
+				}
+			case "esc":
+				m.mode = templateBrowsing
+			default:
+				var cmd tea.Cmd
+				m.input, cmd = m.input.Update(msg)
+				cmds = append(cmds, cmd)
+			}
+
+		case templateImporting:
+			switch msg.String() {
+			case "enter":
+				src := strings.TrimSpace(m.input.Value())
+				if src == "" {
+					break
+				}
+				template, err := fetchTemplate(src, "")
+				if err != nil {
+					m.setMessage(fmt.Sprintf("Import failed: %v", err), "error")
+					break
+				}
+				templates, stored, _ := addImportedTemplate(template, m.templates, m.allSessions, existsSuffix)
+				if err := saveTemplates(templates); err != nil {
+					m.setMessage(fmt.Sprintf("Failed to save templates: %v", err), "error")
+					break
+				}
+				m.templates = templates
+				m.templateCursor = len(m.templates) - 1
+				m.clampTemplateCursor()
+				if stored != template.Name {
+					m.setMessage(fmt.Sprintf("'%s' already exists, imported as '%s'", template.Name, stored), "warning")
+				} else {
+					m.setMessage(fmt.Sprintf("Imported template '%s'", stored), "success")
+				}
+				m.mode = templateBrowsing
+			case "esc":
+				m.mode = templateBrowsing
+			default:
+				var cmd tea.Cmd
+				m.input, cmd = m.input.Update(msg)
+				cmds = append(cmds, cmd)
+			}
+
+		case templateReplacing:
+			var cmd tea.Cmd
+			if m.findInput.Focused() {
+				m.findInput, cmd = m.findInput.Update(msg)
+			} else {
+				m.replaceInput, cmd = m.replaceInput.Update(msg)
+			}
+			cmds = append(cmds, cmd)
+
+			switch msg.String() {
+			case "tab", "shift+tab", "up", "down":
+				if m.findInput.Focused() {
+					m.findInput.Blur()
+					m.replaceInput.Focus()
+				} else {
+					m.replaceInput.Blur()
+					m.findInput.Focus()
+				}
+			case "enter":
+				if m.findInput.Focused() {
+					m.findInput.Blur()
+					m.replaceInput.Focus()
+					break
+				}
+				find := m.findInput.Value()
+				if find == "" {
+					m.setMessage("Search text cannot be empty", "error")
+					break
+				}
+				m.replaceMatches = findReplaceMatches(m.templates, find, m.replaceInput.Value())
+				if len(m.replaceMatches) == 0 {
+					m.setMessage(fmt.Sprintf("No template contains '%s'", find), "warning")
+					break
+				}
+				m.replaceCursor = 0
+				m.mode = replaceReviewing
+			case "esc":
+				m.mode = templateBrowsing
+			}
+
+		case replaceReviewing:
+			switch msg.String() {
+			case "up", "k":
+				if m.replaceCursor > 0 {
+					m.replaceCursor--
+				}
+			case "down", "j":
+				if m.replaceCursor < len(m.replaceMatches)-1 {
+					m.replaceCursor++
+				}
+			case " ", "x":
+				m.replaceMatches[m.replaceCursor].selected = !m.replaceMatches[m.replaceCursor].selected
+			case "a":
+				all := true
+				for _, match := range m.replaceMatches {
+					all = all && match.selected
+				}
+				for i := range m.replaceMatches {
+					m.replaceMatches[i].selected = !all
+				}
+			case "enter":
+				templates, changed := applyReplaceMatches(m.templates, m.replaceMatches)
+				if changed == 0 {
+					m.setMessage("No matches selected", "warning")
+					break
+				}
+				if err := saveTemplates(templates); err != nil {
+					m.setMessage(fmt.Sprintf("Failed to save templates: %v", err), "error")
+					break
 				}
-				m.sessions = listTmuxSessions()
-				m.mode = browsing
-				m.input.SetValue("")
+				m.templates = templates
+				m.replaceMatches = nil
+				m.setMessage(fmt.Sprintf("Replaced in %d field(s)", changed), "success")
+				m.mode = templateBrowsing
+			case "esc":
+				m.mode = templateReplacing
+			case "ctrl+c", "q":
+				m.replaceMatches = nil
+				m.mode = templateBrowsing
+			}
+
+		case templateVariables:
+			if m.savingPreset {
+				cmds = append(cmds, m.updatePresetInput(msg))
+				break
+			}
+			var cmd tea.Cmd
+			m.varInputs[m.varCursor], cmd = m.varInputs[m.varCursor].Update(msg)
+			cmds = append(cmds, cmd)
 
+			switch msg.String() {
+			case "tab", "down":
+				m.focusVariable((m.varCursor + 1) % len(m.varInputs))
+			case "shift+tab", "up":
+				m.focusVariable((m.varCursor + len(m.varInputs) - 1) % len(m.varInputs))
+			case "ctrl+p":
+				m.cyclePreset()
+			case "ctrl+s":
+				m.startSavingPreset()
+			case "enter":
+				if m.varCursor < len(m.varInputs)-1 {
+					m.focusVariable(m.varCursor + 1)
+					break
+				}
+				values := map[string]string{}
+				for i, name := range m.varNames {
+					values[name] = m.varInputs[i].Value()
+				}
+				template, err := m.pendingTemplate.withVars(values)
+				if err != nil {
+					m.setMessage(fmt.Sprintf("Failed to fill in template: %v", err), "error")
+					break
+				}
+				cmds = append(cmds, m.startTemplateSession(m.pendingSession, template))
+				if m.showTemplates {
+					m.mode = templateBrowsing
+				} else {
+					m.mode = browsing
+				}
 			case "esc":
-				m.mode = browsing
-				m.input.SetValue("")
+				if m.showTemplates {
+					m.mode = templateBrowsing
+				} else {
+					m.mode = browsing
+				}
 			}
 
 		case confirming:
@@ -963,41 +2783,107 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case "y", "enter":
 				switch m.confirmAction {
 				case actionDelete:
-					if err := killSession(m.confirmTarget); err != nil {
-						m.setMessage(fmt.Sprintf("Failed to delete session: %v", err), "error")
-					} else {
-						m.setMessage(fmt.Sprintf("Deleted session '%s'", m.confirmTarget), "success")
-					}
+					cmds = append(cmds, killInBackground(m.confirmServer, m.confirmTarget))
 				case actionKillAll:
-					if err := killAllSessions(); err != nil {
-						m.setMessage(fmt.Sprintf("Failed to kill all sessions: %v", err), "error")
-					} else {
-						m.setMessage("All sessions killed", "warning")
+					if kill, kept := killableSessions(m.allSessions); len(kept) > 0 || sharedServer(m.allSessions) || len(dashboardServers) > 0 {
+						// Never take teammates' or protected sessions down with
+						// the server, nor other servers with this one.
+						cmds = append(cmds, killManyInBackground(kill, kept))
+						break
 					}
+					cmds = append(cmds, killServerInBackground(m.server()))
 				case actionDeleteTemplate:
 					for i, template := range m.templates {
 						if template.Name == m.confirmTarget {
+							err := deleteTemplateFile(template)
+							recordAudit("delete-template", template.Name, err)
+							if err != nil {
+								m.setMessage(fmt.Sprintf("Failed to delete template: %v", err), "error")
+								break
+							}
 							m.templates = append(m.templates[:i], m.templates[i+1:]...)
+							m.setMessage(fmt.Sprintf("Deleted template '%s'", m.confirmTarget), "success")
 							break
 						}
 					}
-					if err := saveTemplates(m.templates); err != nil {
-						m.setMessage(fmt.Sprintf("Failed to delete template: %v", err), "error")
-					} else {
-						m.setMessage(fmt.Sprintf("Deleted template '%s'", m.confirmTarget), "success")
+					if m.visibleTemplates() == 0 {
+						m.tagFilter = ""
 					}
-					if m.templateCursor >= len(m.templates) && len(m.templates) > 0 {
-						m.templateCursor = len(m.templates) - 1
+					m.clampTemplateCursor()
+				case actionBroadcast:
+					srv, panes, command, name := m.server(), m.broadcastPanes, m.broadcastCommand, m.confirmTarget
+					sent := 0
+					cmds = append(cmds, inBackground(func() (err error) {
+						sent, err = srv.broadcast(panes, command)
+						return err
+					}, func(m *model, err error) tea.Cmd {
+						recordAudit("broadcast", fmt.Sprintf("%s: %s", name, command), err)
+						if err != nil {
+							m.setMessage(fmt.Sprintf("Sent to %d of %d panes: %v", sent, len(panes), err), "error")
+						} else {
+							m.setMessage(fmt.Sprintf("Sent to %d pane(s) of '%s'", sent, name), "success")
+						}
+						return nil
+					}))
+					m.broadcastPanes = nil
+				case actionWorktrees:
+					srv, trees := m.server(), m.worktreeSessions
+					var created []string
+					cmds = append(cmds, inBackground(func() (err error) {
+						created, err = srv.createWorktreeSessions(trees)
+						return err
+					}, func(m *model, err error) tea.Cmd {
+						if err != nil {
+							m.setMessage(fmt.Sprintf("Failed to create worktree sessions: %v", err), "error")
+						} else {
+							m.setMessage(fmt.Sprintf("Created %s", strings.Join(created, ", ")), "success")
+						}
+						return nil
+					}))
+					m.worktreeSessions = nil
+				case actionUnshare:
+					share, name := m.shares[m.confirmTarget], m.confirmTarget
+					cmds = append(cmds, inBackground(func() error {
+						return stopShare(share)
+					}, func(m *model, err error) tea.Cmd {
+						recordAudit("unshare-session", name, err)
+						if err != nil {
+							m.setMessage(fmt.Sprintf("Failed to stop sharing: %v", err), "error")
+							return nil
+						}
+						delete(m.shares, name)
+						saveShares(m.shares)
+						m.setMessage(fmt.Sprintf("Stopped sharing '%s'", name), "success")
+						return nil
+					}))
+				case actionKillClient:
+					srv, name := m.server(), m.confirmTarget
+					cmds = append(cmds, changeInBackground(func() error { return srv.killClient(name) },
+						"kill-client", name, "Failed to kill client", fmt.Sprintf("Killed client %s", name)))
+				case actionKillWindow:
+					target := m.windowSession
+					for _, w := range m.liveWindows {
+						if w.ID == m.confirmTarget {
+							target = fmt.Sprintf("%s:%s", m.windowSession, w.Index)
+						}
 					}
+					srv, id := m.server(), m.confirmTarget
+					cmds = append(cmds, changeInBackground(func() error { return srv.killWindow(id) },
+						"kill-window", target, "Failed to kill window", fmt.Sprintf("Killed window %s", target)))
+				case actionKillPane:
+					srv, id := m.server(), m.confirmTarget
+					cmds = append(cmds, changeInBackground(func() error { return srv.killPane(id) },
+						"kill-pane", fmt.Sprintf("%s pane %s", m.paneSession, id), "Failed to close pane", "Closed pane"))
 				}
-				m.sessions = listTmuxSessions()
-				if m.cursor >= len(m.sessions) && len(m.sessions) > 0 {
-					m.cursor = len(m.sessions) - 1
-				} else if len(m.sessions) == 0 {
-					m.cursor = 0
-				}
+				m.loadSessions()
 				if m.showTemplates {
 					m.mode = templateBrowsing
+				} else if m.showPanes {
+					m.mode = paneBrowsing
+				} else if m.showWindows {
+					m.mode = windowBrowsing
+				} else if m.showClients {
+					m.mode = clientBrowsing
 				} else {
 					m.mode = browsing
 				}
@@ -1005,6 +2891,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case "n", "esc":
 				if m.showTemplates {
 					m.mode = templateBrowsing
+				} else if m.showPanes {
+					m.mode = paneBrowsing
+				} else if m.showWindows {
+					m.mode = windowBrowsing
+				} else if m.showClients {
+					m.mode = clientBrowsing
 				} else {
 					m.mode = browsing
 				}
@@ -1140,6 +3032,37 @@ func (m *model) addPane(direction string) {
 	m.calculatePaneLayout()
 }
 
+// duplicatePane splits the selected pane the same way it was split off its
+// own parent, and gives the new pane the selected pane's command, directory
+// and other settings.
+func (m *model) duplicatePane() {
+	if len(m.currentTemplate.Panes) == 0 {
+		return
+	}
+	if m.paneCursor < 0 || m.paneCursor >= len(m.currentTemplate.Panes) {
+		m.paneCursor = 0
+	}
+	src := m.currentTemplate.Panes[m.paneCursor]
+	direction := src.Position
+	if direction != "left" && direction != "up" && direction != "down" {
+		direction = "right"
+	}
+	m.addPane(direction)
+
+	dup := &m.currentTemplate.Panes[len(m.currentTemplate.Panes)-1]
+	*dup = placedLike(src, *dup)
+}
+
+// applyNextPreset rearranges the edited panes into the next layout preset.
+func (m *model) applyNextPreset() {
+	preset := layoutPresets[m.presetIndex%len(layoutPresets)]
+	m.presetIndex++
+	m.currentTemplate.Panes = applyPreset(preset, m.currentTemplate.Panes)
+	m.paneCursor = 0
+	m.calculatePaneLayout()
+	m.setMessage(fmt.Sprintf("Layout: %s (l for the next one)", preset.name), "info")
+}
+
 func (m model) View() string {
 	if m.width == 0 {
 		return "Loading..."
@@ -1147,33 +3070,131 @@ func (m model) View() string {
 
 	var content strings.Builder
 	tableWidth := min(m.width-4, 100)
+	m.clicks.start(m.mode)
 
 	if m.showTemplates {
 		return m.renderTemplateView(tableWidth)
 	}
+	if m.mode == logViewing {
+		return m.renderLogView(tableWidth)
+	}
+	if m.showPanes {
+		return m.renderPaneView(tableWidth)
+	}
+	if m.showWindows {
+		return m.renderWindowView(tableWidth)
+	}
+	if m.mode == processBrowsing {
+		return m.renderProcessView(tableWidth)
+	}
+	if m.mode == searchBrowsing {
+		return m.renderSearchView(tableWidth)
+	}
+	if m.showClients {
+		return m.renderClientView(tableWidth)
+	}
+	if m.showAudit {
+		return m.renderAuditView(tableWidth)
+	}
+	if m.showBuffers {
+		return m.renderBufferView(tableWidth)
+	}
+	if m.showChangelog {
+		return m.renderChangelogView(tableWidth)
+	}
 
 	// Regular session view
 	if len(m.sessions) == 0 {
+		emptyText := "No tmux sessions found. Press 'n' to create a new session or 't' for templates."
+		if m.sessionFilter != "" {
+			emptyText = fmt.Sprintf("No sessions match %q. Press '/' to change the filter.", m.sessionFilter)
+		}
 		emptyMsg := lipgloss.NewStyle().
 			Foreground(mutedColor).
 			Italic(true).
-			Render("No tmux sessions found. Press 'n' to create a new session or 't' for templates.")
+			Render(emptyText)
 		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, emptyMsg))
 		content.WriteString("\n\n")
 	} else {
-		nameHeader := tableHeaderStyle.Width(tableWidth * 2 / 5).Render("SESSION NAME")
-		statusHeader := tableHeaderStyle.Width(tableWidth / 6).Render("STATUS")
-		windowsHeader := tableHeaderStyle.Width(tableWidth / 6).Render("WINDOWS")
-		createdHeader := tableHeaderStyle.Width(tableWidth / 6).Render("CREATED")
+		// On shared servers the name column gives up room for the owner.
+		shared := sharedServer(m.allSessions)
+		servers := len(dashboardServers) > 0
+		nameWidth := tableWidth * 2 / 5
+		if shared {
+			nameWidth -= tableWidth / 6
+		}
+		if servers {
+			nameWidth -= tableWidth / 8
+		}
+		// The git column takes room from the name and from the window count,
+		// or the directory, which need less of it.
+		gitView := m.gitView()
+		windowsWidth, narrowed := tableWidth/6, 0
+		if gitView {
+			narrowed = max(tableWidth/6-max(tableWidth/9, 11), 0)
+			windowsWidth -= narrowed
+			nameWidth = max(nameWidth-(tableWidth/7-narrowed), 16)
+		}
+		resourcesWidth := max(tableWidth/7, 12)
+		if m.showResources {
+			nameWidth = max(nameWidth-resourcesWidth, 16)
+		}
 
-		headerRow := lipgloss.JoinHorizontal(lipgloss.Top, nameHeader, statusHeader, windowsHeader, createdHeader)
+		headerStyle := tableHeaderStyle
+		if m.compact {
+			headerStyle = compactHeaderStyle
+		}
+		nameHeader := headerStyle.Width(nameWidth).Render("SESSION NAME")
+		statusHeader := headerStyle.Width(tableWidth / 6).Render("STATUS")
+		windowsHeader := headerStyle.Width(windowsWidth).Render("WINDOWS")
+		createdHeader := headerStyle.Width(tableWidth / 6).Render("CREATED")
+
+		// The directory, or the last output, takes the place of the window
+		// count and creation time.
+		pathView, outputView := m.pathView(), m.outputView()
+		dirWidth := tableWidth/3 - narrowed
+
+		headers := []string{nameHeader}
+		if servers {
+			headers = append(headers, headerStyle.Width(tableWidth/8).Render("SERVER"))
+		}
+		if shared {
+			headers = append(headers, headerStyle.Width(tableWidth/6).Render("OWNER"))
+		}
+		headers = append(headers, statusHeader)
+		if gitView {
+			headers = append(headers, headerStyle.Width(tableWidth/7).Render("GIT"))
+		}
+		if m.showResources {
+			headers = append(headers, headerStyle.Width(resourcesWidth).Render("CPU MEM"))
+		}
+		if pathView {
+			headers = append(headers, headerStyle.Width(dirWidth).Render("DIRECTORY"))
+		} else if outputView {
+			headers = append(headers, headerStyle.Width(dirWidth).Render("LAST OUTPUT"))
+		} else {
+			headers = append(headers, windowsHeader, createdHeader)
+		}
+		headerRow := lipgloss.JoinHorizontal(lipgloss.Top, headers...)
 		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, headerRow))
 		content.WriteString("\n")
 
+		now := time.Now()
+		rows := m.sessionRows()
+		offset := scrollOffset(m.sessionOffset, m.cursor, rows, len(m.sessions))
 		for i, session := range m.sessions {
+			if i < offset || i >= offset+rows {
+				continue
+			}
 			isSelected := m.cursor == i && m.mode == browsing
 
 			rowStyle := selectedRowStyle.Copy().Padding(0, 1)
+			if m.compact {
+				rowStyle = compactRowStyle.Copy()
+				if isSelected {
+					rowStyle = rowStyle.Background(primaryColor).Foreground(lipgloss.Color("15")).Bold(true)
+				}
+			}
 
 			if isSelected && m.popAnimation > 0 {
 				scale := 1.0 + (m.popAnimation * 0.2)
@@ -1184,23 +3205,104 @@ func (m model) View() string {
 			if isSelected {
 				nameText = "▶ " + session.Name
 			}
+			if m.marked[session.Name] {
+				nameText = nameText[:len(nameText)-len(session.Name)] + "✓ " + session.Name
+			}
+			if starred(m.favorites.Sessions, session.Name) && !session.Project {
+				nameText += " " + favoriteMark
+			}
+			if session.Protected {
+				nameText += " 🔒"
+			}
+			if session.Note != "" {
+				nameText += " 📌"
+			}
+			if marks := session.alertMarks(); marks != "" {
+				nameText += " " + marks
+			}
+			if after := config.Idle.idleAfter(); after > 0 && session.mine() && session.idleFor(now) >= after {
+				nameText += " ⌛" + formatUptime(session.idleFor(now))
+			}
+			if _, ok := m.shares[session.Name]; ok {
+				nameText += " 👥"
+			}
+			if m.watched(session.Name) {
+				nameText += " 👀"
+			}
+			for _, register := range sessionBookmarks(m.bookmarks, session.Name) {
+				nameText += " " + register
+			}
+			for _, tag := range session.Tags {
+				nameText += " #" + tag
+			}
 
 			statusText := detachedIndicator + " Detached"
 			if session.Attached {
 				statusText = attachedIndicator + " Active"
 			}
+			windows := fmt.Sprintf("%d", session.Windows)
+			if session.Project {
+				statusText = "📁 Project"
+				windows = ""
+			}
 
-			nameCell := rowStyle.Copy().Width(tableWidth * 2 / 5).Render(nameText)
+			nameStyle := rowStyle.Copy().Width(nameWidth)
+			if session.Color != "" {
+				nameStyle = nameStyle.Foreground(lipgloss.Color(session.Color))
+			}
+			nameCell := nameStyle.Render(nameText)
 			statusCell := rowStyle.Copy().Width(tableWidth / 6).Render(statusText)
-			windowsCell := rowStyle.Copy().Width(tableWidth / 6).Render(fmt.Sprintf("%d", session.Windows))
+			windowsCell := rowStyle.Copy().Width(windowsWidth).Render(windows)
 			createdCell := rowStyle.Copy().Width(tableWidth / 6).Render(session.Created)
 
-			row := lipgloss.JoinHorizontal(lipgloss.Top, nameCell, statusCell, windowsCell, createdCell)
+			cells := []string{nameCell}
+			if servers {
+				cells = append(cells, rowStyle.Copy().Width(tableWidth/8).Render(session.Server))
+			}
+			if shared {
+				ownerStyle := rowStyle.Copy().Width(tableWidth / 6)
+				if !session.mine() {
+					ownerStyle = ownerStyle.Foreground(warningColor)
+				}
+				cells = append(cells, ownerStyle.Render(session.Owner))
+			}
+			cells = append(cells, statusCell)
+			if gitView {
+				git := m.gitStates[session.Dir]
+				gitStyle := rowStyle.Copy().Width(tableWidth / 7)
+				text := rightTruncate(git.Branch, tableWidth/7-4)
+				if git.Dirty {
+					text += " *"
+					gitStyle = gitStyle.Foreground(warningColor)
+				}
+				cells = append(cells, gitStyle.Render(text))
+			}
+			if m.showResources {
+				text := ""
+				if usage, ok := m.resources[session.Name]; ok && !session.Project {
+					text = usage.format()
+				}
+				cells = append(cells, rowStyle.Copy().Width(resourcesWidth).Render(text))
+			}
+			if pathView {
+				// Leave room for the cell padding.
+				dir := leftTruncate(shortDir(session.Dir), dirWidth-2)
+				cells = append(cells, rowStyle.Copy().Width(dirWidth).Render(dir))
+			} else if outputView {
+				line := rightTruncate(m.lastOutput[session.Name], dirWidth-2)
+				cells = append(cells, rowStyle.Copy().Width(dirWidth).Render(line))
+			} else {
+				cells = append(cells, windowsCell, createdCell)
+			}
+			row := lipgloss.JoinHorizontal(lipgloss.Top, cells...)
+			m.clicks.addRow(&content, row, i)
 			content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, row))
 			content.WriteString("\n")
 		}
-		content.WriteString("\n")
+		content.WriteString(m.renderScrollPosition(offset, rows, len(m.sessions)))
 	}
+	content.WriteString(m.renderStatsFooter())
+	content.WriteString("\n\n")
 
 	if m.mode == creating || m.mode == renaming {
 		var inputPrompt string
@@ -1209,45 +3311,154 @@ func (m model) View() string {
 		} else {
 			inputPrompt = "🔄 Rename session:"
 		}
-		inputView := inputBoxStyle.Render(fmt.Sprintf("%s\n%s", inputPrompt, m.input.View()))
+		inputPrompt += "\n" + m.input.View() + namePreview(m.input.Value())
+		if m.mode == creating {
+			if len(m.dirChoices) > 0 {
+				inputPrompt += m.renderDirPicker()
+			}
+			inputPrompt += "\n" + lipgloss.NewStyle().Foreground(mutedColor).Render(createHint(config.AfterCreate))
+		}
+		inputView := inputBoxStyle.Render(inputPrompt)
 		content.WriteString(lipgloss.Place(m.width, 4, lipgloss.Center, lipgloss.Top, inputView))
 		content.WriteString("\n")
 	}
 
+	if m.mode == sessionFiltering {
+		content.WriteString(m.renderSessionFilter())
+		content.WriteString("\n")
+	}
+
+	if m.mode == commandEntering {
+		content.WriteString(m.renderCommandPrompt())
+		content.WriteString("\n")
+	}
+
+	if m.mode == broadcastEntering {
+		content.WriteString(m.renderBroadcastPrompt())
+		content.WriteString("\n")
+	}
+
+	if m.mode == searchEntering {
+		content.WriteString(m.renderSearchPrompt())
+		content.WriteString("\n")
+	}
+
+	if m.mode == processFinding {
+		content.WriteString(m.renderFindProcessPrompt())
+		content.WriteString("\n")
+	}
+
+	if m.mode == attachChoosing {
+		content.WriteString(m.renderAttachOptions())
+		content.WriteString("\n")
+	}
+
+	if m.mode == shareChoosing || m.mode == shareGuestEntering {
+		content.WriteString(m.renderSharePrompt())
+		content.WriteString("\n")
+	}
+
+	if m.mode == noteEntering {
+		content.WriteString(m.renderNotePrompt())
+		content.WriteString("\n")
+	}
+
+	if m.mode == idleReviewing {
+		content.WriteString(m.renderIdleReview())
+		content.WriteString("\n")
+	}
+
+	if m.mode == sessionDetails || m.mode == metaEditing {
+		content.WriteString(m.renderDetails())
+		content.WriteString("\n")
+	}
+
+	if m.mode == templateVariables {
+		content.WriteString(m.renderVariableForm())
+		content.WriteString("\n")
+	}
+
 	if m.mode == confirming {
 		var confirmText string
 		switch m.confirmAction {
 		case actionDelete:
 			confirmText = fmt.Sprintf("⚠️  DELETE SESSION '%s'?\n\nThis action cannot be undone!\n\n[y] Yes  [n] No", m.confirmTarget)
+			for _, s := range m.allSessions {
+				if s.Name == m.confirmTarget && !s.mine() {
+					confirmText = fmt.Sprintf("⚠️  DELETE SESSION '%s'?\n\nIt belongs to %s, not you!\nThis action cannot be undone!\n\n[y] Yes  [n] No", m.confirmTarget, s.Owner)
+				}
+				if s.Name == m.confirmTarget && s.Note != "" {
+					confirmText = strings.Replace(confirmText, "\n\n", fmt.Sprintf("\n\nNote: %s\n", s.Note), 1)
+				}
+			}
 		case actionKillAll:
 			confirmText = fmt.Sprintf("💀 KILL ALL %d SESSIONS?\n\nThis will destroy ALL sessions!\nThis action cannot be undone!\n\n[y] Yes  [n] No", len(m.sessions))
+			if kill, kept := killableSessions(m.allSessions); len(kept) > 0 || sharedServer(m.allSessions) {
+				keptText := "Sessions of other users are kept."
+				if len(kept) > 0 {
+					keptText = "Keeping " + strings.Join(kept, " and ") + "."
+				}
+				confirmText = fmt.Sprintf("💀 KILL %d SESSIONS?\n\n%s\nThis action cannot be undone!\n\n[y] Yes  [n] No", len(kill), keptText)
+			}
+		case actionWorktrees:
+			names := sortedKeys(m.worktreeSessions)
+			if len(names) > 5 {
+				names = append(names[:5], fmt.Sprintf("and %d more", len(names)-5))
+			}
+			confirmText = fmt.Sprintf("🌳 CREATE %d WORKTREE SESSION(S)?\n\n%s\n\n[y] Yes  [n] No",
+				len(m.worktreeSessions), strings.Join(names, "\n"))
+		case actionUnshare:
+			share := m.shares[m.confirmTarget]
+			confirmText = fmt.Sprintf("👥 STOP SHARING '%s'?\n\nYour pair will be disconnected.\nThe session keeps running.\n\n[y] Yes  [n] No", share.Session)
+		case actionBroadcast:
+			confirmText = fmt.Sprintf("📣 SEND TO ALL %d PANES OF '%s'?\n\n%s\n\n[y] Yes  [n] No",
+				len(m.broadcastPanes), m.confirmTarget, rightTruncate(printable(m.broadcastCommand), 60))
 		}
 		confirmView := confirmBoxStyle.Render(confirmText)
 		content.WriteString(lipgloss.Place(m.width, 7, lipgloss.Center, lipgloss.Center, confirmView))
 	}
 
+	if m.update.Tag != "" {
+		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, m.renderUpdateBanner()))
+		content.WriteString("\n")
+	}
+
 	if m.message != "" {
-		var msgStyle lipgloss.Style
-		switch m.messageType {
-		case "success":
-			msgStyle = successMessageStyle
-		case "warning":
-			msgStyle = warningMessageStyle
-		case "error":
-			msgStyle = errorMessageStyle
-		default:
-			msgStyle = infoMessageStyle
-		}
-		statusMsg := msgStyle.Render(m.message)
+		statusMsg := m.renderMessage()
 		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, statusMsg))
 		content.WriteString("\n")
 	}
 
 	var statusItems []string
-	statusItems = append(statusItems, fmt.Sprintf("📊 Sessions: %d", len(m.sessions)))
+	projects := 0
+	for _, s := range m.sessions {
+		if s.Project {
+			projects++
+		}
+	}
+	statusItems = append(statusItems, fmt.Sprintf("📊 Sessions: %d", len(m.sessions)-projects))
+	if projects > 0 {
+		statusItems = append(statusItems, fmt.Sprintf("📁 Projects: %d", projects))
+	}
 	statusItems = append(statusItems, fmt.Sprintf("📋 Templates: %d", len(m.templates)))
-	if m.autoRefresh {
-		statusItems = append(statusItems, "🔄 Auto-refresh: ON")
+	statusItems = append(statusItems, m.refreshStatus())
+	if readOnly {
+		statusItems = append(statusItems, "🔒 Read-only")
+	}
+	if label := serverLabel(); label != "" {
+		statusItems = append(statusItems, "🔌 "+label)
+	}
+	if m.mineOnly {
+		statusItems = append(statusItems, fmt.Sprintf("👤 Only %s (%d hidden)", currentUser, len(m.allSessions)-len(ownSessions(m.allSessions))))
+	}
+	if m.sessionFilter != "" {
+		statusItems = append(statusItems, fmt.Sprintf("🔍 %q", m.sessionFilter))
+	}
+	if m.sortByDir {
+		statusItems = append(statusItems, "📁 By directory")
+	}
+	if m.macroRecording != "" {
+		statusItems = append(statusItems, "⏺ Recording @"+m.macroRecording)
 	}
 	statusItems = append(statusItems, "❓ Press ? for help")
 
@@ -1260,7 +3471,9 @@ func (m model) View() string {
 		BorderForeground(primaryColor).
 		Render(statusBarText)
 	content.WriteString("\n")
-	content.WriteString(lipgloss.Place(tableWidth, 1, lipgloss.Center, lipgloss.Top, statusBar))
+	statusBar = lipgloss.Place(tableWidth, 1, lipgloss.Center, lipgloss.Top, statusBar)
+	m.clicks.addStatusBar(&content, statusBar, statusItems)
+	content.WriteString(statusBar)
 
 	if m.showHelp {
 		helpContent := strings.Builder{}
@@ -1270,9 +3483,41 @@ func (m model) View() string {
 			{"↓/j", "Move down"},
 			{"g", "Go to top"},
 			{"G", "Go to bottom"},
-			{"Enter/Space", "Attach to session"},
+			{"PgUp/PgDn", "Move a page (Ctrl+U/D half)"},
+			{"Enter/Space", "Attach to session (or all marked), start a 📁 project"},
+			{"Alt+Enter", "Attach detaching others or read-only"},
+			{"m", "Mark session for multi-attach"},
 			{"n/c", "Create new session"},
 			{"t", "Browse templates"},
+			{"p", "Show panes of session"},
+			{"o", "Show only my sessions"},
+			{"O", "Open the session's directory"},
+			{"*", "Star the session, pinning it to the top"},
+			{"i", "Show details, edit its note, tags and color"},
+			{"I", "Review idle sessions for killing"},
+			{"P", "Show the process tree of the session"},
+			{"F", "Search the contents of every pane"},
+			{"f", "Find the session running a command"},
+			{"T", "Tell me when the running command finishes"},
+			{"/", "Filter by name or directory"},
+			{"s", "Sort by name or directory"},
+			{"l", "Show the last line of output"},
+			{"u", "Show CPU and memory use"},
+			{"A", "Browse audit log"},
+			{"b", "Browse paste buffers"},
+			{"N", "Add a note to the session"},
+			{"B", "Send a command to every pane"},
+			{"W", "Create sessions for git worktrees"},
+			{"w", "Manage the session's windows"},
+			{"C", "Show attached clients"},
+			{"S", "Share with a pair, or stop sharing"},
+			{"v", "Toggle compact or detailed rows"},
+			{":", "Run a tmux command"},
+			{"U/X", "Show or dismiss a new release"},
+			{"Q", "Record keys into a register"},
+			{"@", "Replay a register (@@ the last)"},
+			{"M", "Bookmark the session into a register"},
+			{"'", "Attach to a bookmarked session"},
 			{"r", "Rename session"},
 			{"d", "Delete session"},
 			{"D", "Delete ALL sessions"},
@@ -1281,6 +3526,8 @@ func (m model) View() string {
 			{"?/h", "Toggle this help"},
 			{"q/Ctrl+C", "Quit"},
 		}
+		shortcuts = hideDestructive(shortcuts, "r", "d", "D", "B", "S", ":")
+		shortcuts = append(shortcuts, actionShortcuts(actionViewSessions)...)
 		for _, shortcut := range shortcuts {
 			key := lipgloss.NewStyle().
 				Foreground(accentColor).
@@ -1301,7 +3548,9 @@ func (m model) View() string {
 		content.WriteString(lipgloss.Place(m.width, m.height-10, lipgloss.Right, lipgloss.Top, helpBox))
 	}
 
-	return baseStyle.Render(content.String())
+	frame := baseStyle.Render(content.String())
+	m.clicks.finish(frame, m.height)
+	return frame
 }
 
 func (m model) renderTemplateView(tableWidth int) string {
@@ -1320,8 +3569,24 @@ func (m model) renderTemplateView(tableWidth int) string {
 		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, emptyMsg))
 		content.WriteString("\n\n")
 	} else {
-		// Template list
+		// Template list, counting the visible templates to draw only those
+		// scrolled to.
+		rows, total := m.templateRows(), m.visibleTemplates()
+		offset := scrollOffset(m.templateOffset, m.templatePosition(), rows, total)
+		// With the preview next to it, rows are drawn into list first and
+		// the two joined side by side, see preview.go.
+		listWidth, previewWidth := m.templateSplit(tableWidth)
+		var list strings.Builder
+		listTop, listZones := strings.Count(content.String(), "\n"), m.clicks.mark()
+		position := -1
 		for i, template := range m.templates {
+			if !m.templateVisible(i) {
+				continue
+			}
+			position++
+			if position < offset || position >= offset+rows {
+				continue
+			}
 			isSelected := m.templateCursor == i && (m.mode == templateBrowsing)
 
 			rowStyle := selectedTemplateStyle.Copy().Padding(0, 1)
@@ -1344,44 +3609,115 @@ func (m model) renderTemplateView(tableWidth int) string {
 			} else {
 				nameText = "  " + template.Name
 			}
+			if starred(m.favorites.Templates, template.Name) {
+				nameText += " " + favoriteMark
+			}
 
-			paneCount := fmt.Sprintf("%d panes", len(template.Panes))
-			if len(template.Panes) == 1 {
+			paneCount := fmt.Sprintf("%d panes", template.paneCount())
+			if template.paneCount() == 1 {
 				paneCount = "1 pane"
 			}
+			if len(template.Windows) > 0 {
+				paneCount = fmt.Sprintf("%d win, %s", len(template.Windows)+1, paneCount)
+			}
 
 			description := template.Description
-			if len(description) > 40 {
-				description = description[:40] + "..."
-			}
 			if description == "" {
 				description = "(no description)"
 			}
+			if len(template.Tags) > 0 {
+				description += " #" + strings.Join(template.Tags, " #")
+			}
+			if len(description) > 40 {
+				description = description[:40] + "..."
+			}
 
-			nameCell := rowStyle.Copy().Width(tableWidth / 3).Render(nameText)
-			paneCell := rowStyle.Copy().Width(tableWidth / 6).Render(paneCount)
-			descCell := rowStyle.Copy().Width(tableWidth / 2).Render(description)
+			nameCell := rowStyle.Copy().Width(listWidth / 3).Render(nameText)
+			paneCell := rowStyle.Copy().Width(listWidth / 6).Render(paneCount)
+			descCell := rowStyle.Copy().Width(listWidth / 2).Render(description)
 
 			row := lipgloss.JoinHorizontal(lipgloss.Top, nameCell, paneCell, descCell)
+			if previewWidth > 0 {
+				m.clicks.addRow(&list, row, i)
+				list.WriteString(lipgloss.PlaceHorizontal(listWidth, lipgloss.Left, row))
+				list.WriteString("\n")
+				continue
+			}
+			m.clicks.addRow(&content, row, i)
 			content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, row))
 			content.WriteString("\n")
 		}
+		if previewWidth > 0 {
+			// The preview takes what the rows, borders included, leave and
+			// the height the list could fill.
+			rowLines := strings.TrimSuffix(list.String(), "\n")
+			previewWidth = m.width - 4 - lipgloss.Width(rowLines) - 1
+			preview := m.renderTemplatePreview(previewWidth, max(lipgloss.Height(rowLines), rows*3))
+			split := lipgloss.JoinHorizontal(lipgloss.Top, rowLines, " ", preview)
+			left := (m.width - lipgloss.Width(split)) / 2
+			m.clicks.placeSince(listZones, listTop, left, listWidth)
+			content.WriteString(lipgloss.PlaceHorizontal(m.width, lipgloss.Center, split))
+			content.WriteString("\n")
+		}
+		content.WriteString(m.renderScrollPosition(offset, rows, total))
 	}
 
 	// Handle different modes
 	switch m.mode {
 	case templateCreating:
 		var inputPrompt string
-		inputPrompt = "📝 Create Template\n\nName: " + m.input.View() + "\nDescription: " + m.descriptionInput.View() + "\n\n[Tab] Switch fields • [Enter] Save • [Esc] Cancel"
+		inputPrompt = "📝 Create Template\n\nName: " + m.input.View() + "\nDescription: " + m.descriptionInput.View() + "\nTags: " + m.tagsInput.View() + "\n\n[Tab] Switch fields • [Enter] Save • [Esc] Cancel"
 		inputView := inputBoxStyle.Render(inputPrompt)
-		content.WriteString(lipgloss.Place(m.width, 8, lipgloss.Center, lipgloss.Top, inputView))
+		content.WriteString(lipgloss.Place(m.width, 9, lipgloss.Center, lipgloss.Top, inputView))
+
+	case templateFiltering:
+		content.WriteString(m.renderTagFilter())
 
 	case templateEditing:
 		editView := m.renderTemplateEditor()
+		m.clicks.addCanvas(&content)
 		content.WriteString(editView)
 
+	case layoutImporting:
+		content.WriteString(m.renderLayoutImport())
+
+	case templateTesting:
+		content.WriteString(m.renderTemplateTest())
+
+	case editorWindowNaming:
+		inputView := inputBoxStyle.Render(fmt.Sprintf("🪟 Name window %d\n\nName: ", m.editorWindow+1) + m.input.View() + "\n\n[Enter] Save • [Esc] Cancel")
+		content.WriteString(lipgloss.Place(m.width, 6, lipgloss.Center, lipgloss.Top, inputView))
+
+	case templateVariables:
+		content.WriteString(m.renderVariableForm())
+
+	case templateRenaming, templateDuplicating:
+		prompt := "🔄 Rename template"
+		if m.mode == templateDuplicating {
+			prompt = "📑 Duplicate template"
+		}
+		inputView := inputBoxStyle.Render(prompt + "\n\nName: " + m.input.View() + "\n\n[Enter] Save • [Esc] Cancel")
+		content.WriteString(lipgloss.Place(m.width, 6, lipgloss.Center, lipgloss.Top, inputView))
+
+	case templateStarting:
+		prompt := fmt.Sprintf("🚀 New session from '%s'\n\nName: %s%s\n\n%s • [Esc] Cancel", m.pendingTemplate.Name, m.input.View(), namePreview(m.input.Value()), createHint(m.pendingTemplate.afterCreate()))
+		content.WriteString(lipgloss.Place(m.width, 6, lipgloss.Center, lipgloss.Top, inputBoxStyle.Render(prompt)))
+
+	case templateImporting:
+		inputView := inputBoxStyle.Render("📥 Import template\n\nFrom: " + m.input.View() + "\n\n[Enter] Import • [Esc] Cancel")
+		content.WriteString(lipgloss.Place(m.width, 6, lipgloss.Center, lipgloss.Top, inputView))
+
+	case templateReplacing:
+		content.WriteString(m.renderReplaceForm())
+
+	case replaceReviewing:
+		content.WriteString(m.renderReplaceMatches(tableWidth))
+
 	case paneEditing:
 		inputPrompt := fmt.Sprintf("✏️ Edit Pane Command\n\n%s", m.commandInput.View())
+		if m.editingTitle {
+			inputPrompt = fmt.Sprintf("🏷️ Edit Pane Label\n\n%s", m.commandInput.View())
+		}
 		inputView := inputBoxStyle.Render(inputPrompt)
 		content.WriteString(lipgloss.Place(m.width, 4, lipgloss.Center, lipgloss.Top, inputView))
 
@@ -1396,18 +3732,7 @@ func (m model) renderTemplateView(tableWidth int) string {
 
 	// Status and help for templates
 	if m.message != "" {
-		var msgStyle lipgloss.Style
-		switch m.messageType {
-		case "success":
-			msgStyle = successMessageStyle
-		case "warning":
-			msgStyle = warningMessageStyle
-		case "error":
-			msgStyle = errorMessageStyle
-		default:
-			msgStyle = infoMessageStyle
-		}
-		statusMsg := msgStyle.Render(m.message)
+		statusMsg := m.renderMessage()
 		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, statusMsg))
 		content.WriteString("\n")
 	}
@@ -1415,9 +3740,18 @@ func (m model) renderTemplateView(tableWidth int) string {
 	// Template status bar
 	var statusItems []string
 	statusItems = append(statusItems, fmt.Sprintf("📋 Templates: %d", len(m.templates)))
+	if m.tagFilter != "" {
+		statusItems = append(statusItems, fmt.Sprintf("🏷️ #%s (%d shown)", m.tagFilter, m.visibleTemplates()))
+	}
+	if m.templateOrder == orderRecent || m.templateOrder == orderFrequent {
+		statusItems = append(statusItems, "🕘 By "+m.templateOrder)
+	}
 	if m.previewMode {
 		statusItems = append(statusItems, "👁️ Preview: ON")
 	}
+	if readOnly {
+		statusItems = append(statusItems, "🔒 Read-only")
+	}
 	statusItems = append(statusItems, "❓ Press ? for help")
 
 	statusBarText := strings.Join(statusItems, " • ")
@@ -1429,7 +3763,9 @@ func (m model) renderTemplateView(tableWidth int) string {
 		BorderForeground(templateColor).
 		Render(statusBarText)
 	content.WriteString("\n")
-	content.WriteString(lipgloss.Place(tableWidth, 1, lipgloss.Center, lipgloss.Top, statusBar))
+	statusBar = lipgloss.Place(tableWidth, 1, lipgloss.Center, lipgloss.Top, statusBar)
+	m.clicks.addStatusBar(&content, statusBar, statusItems)
+	content.WriteString(statusBar)
 
 	if m.showHelp {
 		helpContent := strings.Builder{}
@@ -1440,24 +3776,49 @@ func (m model) renderTemplateView(tableWidth int) string {
 			shortcuts = [][]string{
 				{"↑/k", "Move up"},
 				{"↓/j", "Move down"},
+				{"PgUp/PgDn", "Move a page (Ctrl+U/D half)"},
 				{"Enter/Space", "Create session from template"},
 				{"n/c", "Create new template"},
 				{"e", "Edit template"},
 				{"d", "Delete template"},
+				{"r", "Rename template"},
+				{"y", "Duplicate template"},
+				{"R", "Search and replace in all templates"},
+				{"i", "Import tmuxinator and tmuxp projects"},
+				{"I", "Import a template from a file or URL"},
+				{"f", "Filter by tag"},
+				{"*", "Star the template, pinning it to the top"},
+				{"s", "Order by name, recent or frequent use"},
 				{"p", "Toggle preview"},
+				{"</>", "Narrow or widen the list next to the preview"},
 				{"Esc", "Back to sessions"},
 				{"?/h", "Toggle help"},
 			}
+			shortcuts = hideDestructive(shortcuts, "e", "d", "r", "R")
+			shortcuts = append(shortcuts, actionShortcuts(actionViewTemplates)...)
 		} else if m.mode == templateEditing {
 			shortcuts = [][]string{
 				{"↑/k", "Move up panes"},
 				{"↓/j", "Move down panes"},
 				{"Enter/e", "Edit pane command"},
+				{"t", "Edit pane label"},
 				{"H", "Add pane left of selected"},
 				{"J", "Add pane down of selected"},
 				{"K", "Add pane up of selected"},
 				{"L", "Add pane right of selected"},
+				{"y", "Duplicate pane"},
+				{"l", "Apply the next layout preset"},
+				{"1-5", "Lay out like a tmux layout"},
+				{"+/-", "Add or drop a pane, same layout"},
+				{"i", "Import the layout of a window"},
+				{"T", "Test in a scratch session"},
+				{"[/]", "Previous or next window"},
+				{"n/r/x", "Add, name or delete a window"},
+				{"</>", "Shrink or grow the pane"},
+				{"Shift+←/→/↑/↓", "Swap with the pane on that side"},
+				{"m", "Move the pane next to another"},
 				{"d", "Delete pane"},
+				{"Ctrl+Z/Y", "Undo or redo a change"},
 				{"s", "Save template"},
 				{"Esc", "Back to templates"},
 			}
@@ -1484,7 +3845,9 @@ func (m model) renderTemplateView(tableWidth int) string {
 		content.WriteString(lipgloss.Place(m.width, m.height-15, lipgloss.Right, lipgloss.Top, helpBox))
 	}
 
-	return baseStyle.Render(content.String())
+	frame := baseStyle.Render(content.String())
+	m.clicks.finish(frame, m.height)
+	return frame
 }
 
 // Replace your existing renderTemplateEditor() with this version.
@@ -1498,28 +3861,14 @@ func (m model) renderTemplateEditor() string {
 		Foreground(templateColor).
 		Bold(true).
 		Render(title))
-	content.WriteString("\n\n")
-
-	// Determine layout bounds (dynamic - scale to content)
-	maxRow, maxCol := 1, 1
-	for _, p := range m.currentTemplate.Panes {
-		if rr := p.Row + p.Height; rr > maxRow {
-			maxRow = rr
-		}
-		if cc := p.Col + p.Width; cc > maxCol {
-			maxCol = cc
-		}
-	}
-	if maxRow <= 0 {
-		maxRow = 1
-	}
-	if maxCol <= 0 {
-		maxCol = 1
+	if tabs := m.renderWindowTabs(); tabs != "" {
+		content.WriteString("  " + tabs)
 	}
+	content.WriteString("\n\n")
 
-	// Preview canvas size (characters). Adjust these if you want a bigger/smaller editor.
-	const pr = 12 // preview rows (height)
-	const pc = 48 // preview cols (width)
+	maxRow, maxCol := editorBounds(m.currentTemplate.Panes)
+	const pr = editorCanvasRows
+	const pc = editorCanvasCols
 
 	// Initialize the canvas with spaces
 	grid := make([][]rune, pr)
@@ -1530,44 +3879,9 @@ func (m model) renderTemplateEditor() string {
 		}
 	}
 
-	// Helper to keep coords sane
-	clip := func(v, lo, hi int) int {
-		if v < lo {
-			return lo
-		}
-		if v > hi {
-			return hi
-		}
-		return v
-	}
-
 	// Draw each pane as a box on the canvas
 	for idx, pane := range m.currentTemplate.Panes {
-		// Map template coordinates -> preview coordinates (inclusive/exclusive)
-		r0 := pane.Row * pr / maxRow
-		r1 := (pane.Row + pane.Height) * pr / maxRow
-		c0 := pane.Col * pc / maxCol
-		c1 := (pane.Col + pane.Width) * pc / maxCol
-
-		// Ensure minimum size to draw a box
-		if r1 <= r0+1 {
-			r1 = clip(r0+3, 0, pr)
-		}
-		if c1 <= c0+2 {
-			c1 = clip(c0+6, 0, pc)
-		}
-		if r0 < 0 {
-			r0 = 0
-		}
-		if c0 < 0 {
-			c0 = 0
-		}
-		if r1 > pr {
-			r1 = pr
-		}
-		if c1 > pc {
-			c1 = pc
-		}
+		r0, r1, c0, c1 := canvasRect(pane, maxRow, maxCol)
 
 		// Choose border style for selected pane (double lines) vs others (single)
 		var (
@@ -1614,26 +3928,40 @@ func (m model) renderTemplateEditor() string {
 			grid[r1-1][c1-1] = br
 		}
 
-		// Fill interior with spaces (already spaces) and write the command on the first interior line
+		// Fill interior with spaces (already spaces) and write the label (or
+		// the command) on the first interior line
 		cmd := strings.TrimSpace(pane.Command)
+		if pane.Type == paneTypeEditor {
+			cmd = strings.TrimSpace("$EDITOR " + cmd)
+		}
 		if cmd == "" {
 			cmd = "(empty)"
 		}
-		rText := r0 + 1
+		lines := []string{cmd}
+		if pane.Title != "" {
+			// The label replaces the command; show the command below it if there is room.
+			lines = []string{pane.Title, cmd}
+		}
 		cTextStart := c0 + 1
 		maxTextWidth := (c1 - 1) - (c0 + 1) // interior width
 		if maxTextWidth < 0 {
 			maxTextWidth = 0
 		}
-		// Truncate command to fit
-		cmdRunes := []rune(cmd)
-		if len(cmdRunes) > maxTextWidth {
-			cmdRunes = cmdRunes[:maxTextWidth]
-		}
-		for i, rr := range cmdRunes {
-			c := cTextStart + i
-			if rText >= 0 && rText < pr && c >= 0 && c < pc {
-				grid[rText][c] = rr
+		for n, line := range lines {
+			rText := r0 + 1 + n
+			if rText >= r1-1 {
+				break
+			}
+			// Truncate text to fit
+			textRunes := []rune(line)
+			if len(textRunes) > maxTextWidth {
+				textRunes = textRunes[:maxTextWidth]
+			}
+			for i, rr := range textRunes {
+				c := cTextStart + i
+				if rText >= 0 && rText < pr && c >= 0 && c < pc {
+					grid[rText][c] = rr
+				}
 			}
 		}
 
@@ -1658,8 +3986,15 @@ func (m model) renderTemplateEditor() string {
 	}
 	content.WriteString("\n")
 
+	// Diagnostics: pane commands that won't start
+	if missing := missingCommands(m.currentTemplate.Panes); len(missing) > 0 {
+		diag := "⚠ " + strings.Join(missing, "\n⚠ ")
+		content.WriteString(lipgloss.NewStyle().Foreground(warningColor).Render(diag))
+		content.WriteString("\n\n")
+	}
+
 	// Add editor command hints (compact)
-	hints := "Commands: [H/J/K/L] Split selected • [Enter/e] Edit command • [d] Delete pane • [s] Save • [Esc] Back"
+	hints := "Commands: [H/J/K/L] Split selected • [Enter/e] Edit command • [t] Label • [d] Delete pane • [</>] Resize • [Shift+arrows] Swap • [m] Move • [1-5] tmux layouts • [+/-] Panes • [i] Import layout • [T] Test • [[/]] Window • [n/r/x] Add/name/delete window • [Ctrl+Z/Y] Undo/redo • [s] Save • [Esc] Back"
 	content.WriteString(hints)
 
 	// Use same box style as before for consistency
@@ -1667,18 +4002,57 @@ func (m model) renderTemplateEditor() string {
 }
 
 func main() {
+	if isTmuxShim() {
+		os.Exit(runTmuxShim(os.Args[1:]))
+	}
+
 	// Define command line flags
 	var (
 		terminal    = flag.String("t", "", "Terminal emulator to use (e.g., kitty, alacritty, gnome-terminal)")
 		showHelp    = flag.Bool("h", false, "Show help")
 		showVersion = flag.Bool("v", false, "Show version")
+		readOnlyArg = flag.Bool("read-only", false, "Disable killing, renaming and deleting sessions, panes and templates")
+		mineOnly    = flag.Bool("mine", false, "Only show sessions owned by you")
+		recordArg   = flag.String("record", "", "Record the output of every tmux command into a fixture file")
+		replayArg   = flag.String("replay", "", "Answer tmux commands from a fixture file instead of running tmux")
+		socketName  = flag.String("socket-name", "", "Manage the tmux server on this socket name (tmux -L)")
+		socketPath  = flag.String("socket-path", "", "Manage the tmux server on this socket path (tmux -S)")
+		profile     = flag.String("profile", "", "Manage the tmux server of a profile from the config file")
+		announceArg = flag.String("announce", "", "Describe mode changes and results in plain text: stderr, fd:N, osc or a file")
 	)
 
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] [command]\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "A modern TUI for managing tmux sessions and templates.\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nCommands:\n")
+		fmt.Fprintf(os.Stderr, "  list                    List sessions, or templates with --templates (--format text|json|names)\n")
+		fmt.Fprintf(os.Stderr, "  new [name]              Create an empty session (--dir, --attach)\n")
+		fmt.Fprintf(os.Stderr, "  start <template> [name] Create a session from a saved template (--var, --preset, --exists, --attach)\n")
+		fmt.Fprintf(os.Stderr, "  start [dir] [name]      Create a session from the .lazytmux.json of a directory or its parents\n")
+		fmt.Fprintf(os.Stderr, "  attach <session>        Attach to a session by exact, prefix or fuzzy name\n")
+		fmt.Fprintf(os.Stderr, "  switch [query]          Pick a session in a bare list and switch to it, for display-popup -E\n")
+		fmt.Fprintf(os.Stderr, "  kill <session>...       Kill sessions by name (not in read-only mode or if protected)\n")
+		fmt.Fprintf(os.Stderr, "  kill --match pattern    Kill sessions matching a glob (--regex for a regular expression, --dry-run to list them)\n")
+		fmt.Fprintf(os.Stderr, "  idle [--after 3d]       List sessions idle for longer than idle.after (--kill to kill them)\n")
+		fmt.Fprintf(os.Stderr, "  doctor                  Check tmux, terminal and config setup and suggest fixes\n")
+		fmt.Fprintf(os.Stderr, "  apply <file|-> [name]   Create a session from a template file (or stdin) without saving it\n")
+		fmt.Fprintf(os.Stderr, "                          --exists=attach|fail|suffix decides what happens on a name clash\n")
+		fmt.Fprintf(os.Stderr, "  layout <name>           Open every session of a configured layout in placed terminals\n")
+		fmt.Fprintf(os.Stderr, "  import-tmuxinator [file...]\n")
+		fmt.Fprintf(os.Stderr, "                          Convert tmuxinator projects (default: all in ~/.config/tmuxinator) into templates\n")
+		fmt.Fprintf(os.Stderr, "  import-tmuxp [file...]  Convert tmuxp workspaces (default: all in ~/.tmuxp) into templates\n")
+		fmt.Fprintf(os.Stderr, "  export-tmuxp <template> Print a template as a tmuxp workspace (--format yaml|json)\n")
+		fmt.Fprintf(os.Stderr, "  export-script <template>\n")
+		fmt.Fprintf(os.Stderr, "                          Print a template as a shell script of tmux commands\n")
+		fmt.Fprintf(os.Stderr, "  import-script <file...> Turn shell scripts of tmux commands into templates\n")
+		fmt.Fprintf(os.Stderr, "  import <file|url|->     Save a template from a file, HTTPS URL or stdin\n")
+		fmt.Fprintf(os.Stderr, "                          --exists=fail|suffix|replace decides what happens on a name clash\n")
+		fmt.Fprintf(os.Stderr, "  wait [--pane id --text t] [--port n]\n")
+		fmt.Fprintf(os.Stderr, "                          Block until a pane shows some text and/or a local port is open\n")
+		fmt.Fprintf(os.Stderr, "  serve                   Answer JSON commands on the control socket without the TUI\n")
+		fmt.Fprintf(os.Stderr, "  call <command> [args]   Send list, templates, create, kill or snapshot to the control socket\n")
 		fmt.Fprintf(os.Stderr, "\nTerminal Detection:\n")
 		fmt.Fprintf(os.Stderr, "  1. Command line flag (-t)\n")
 		fmt.Fprintf(os.Stderr, "  2. LAYTMUX_TERMINAL environment variable\n")
@@ -1688,6 +4062,8 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  %s                          # Auto-detect terminal\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -t alacritty             # Use alacritty\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  LAZYTMUX_TERMINAL=kitty %s  # Use environment variable\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s apply - < layout.json    # Create a session from stdin\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --replay bug.jsonl       # Show the UI as recorded with --record bug.jsonl\n", os.Args[0])
 	}
 
 	flag.Parse()
@@ -1698,27 +4074,116 @@ func main() {
 	}
 
 	if *showVersion {
-		fmt.Println("lazytmux version 0.0.1")
+		fmt.Println("lazytmux version " + version)
 		os.Exit(0)
 	}
 
+	readOnly = *readOnlyArg
+
+	if *recordArg != "" && *replayArg != "" {
+		fmt.Fprintf(os.Stderr, "Error: --record and --replay can't be used together\n")
+		os.Exit(1)
+	}
+	if *recordArg != "" {
+		if err := useFixture(fixtureRecord, *recordArg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *replayArg != "" {
+		if err := useFixture(fixtureReplay, *replayArg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// Determine which terminal to use
 	if *terminal != "" {
 		terminalCmd = *terminal
+		terminalChosen = true
 	} else {
 		terminalCmd = getDefaultTerminal()
 	}
 
-	// Validate the terminal
-	if err := validateTerminal(terminalCmd); err != nil {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring %s: %v\n", getConfigFile(), err)
+	}
+	config = cfg
+	for _, problem := range dropBuiltinActions(&config) {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring %s\n", problem)
+	}
+
+	if err := selectServer(*socketName, *socketPath, *profile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	announceTo := config.Announce
+	if *announceArg != "" {
+		announceTo = *announceArg
+	}
+	if err := setAnnounce(announceTo); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if flag.NArg() > 0 {
+		switch flag.Arg(0) {
+		case "list", "ls":
+			os.Exit(runList(flag.Args()[1:], *mineOnly))
+		case "new":
+			os.Exit(runNew(flag.Args()[1:]))
+		case "start":
+			os.Exit(runStart(flag.Args()[1:]))
+		case "attach", "a":
+			os.Exit(runAttach(flag.Args()[1:], *mineOnly))
+		case "switch":
+			os.Exit(runSwitch(flag.Args()[1:], *mineOnly))
+		case "kill":
+			os.Exit(runKill(flag.Args()[1:], *mineOnly))
+		case "doctor":
+			os.Exit(runDoctor())
+		case "apply":
+			os.Exit(runApply(flag.Args()[1:]))
+		case "layout":
+			os.Exit(runLayout(flag.Args()[1:]))
+		case "import-tmuxinator":
+			os.Exit(runImportTmuxinator(flag.Args()[1:]))
+		case "import-tmuxp":
+			os.Exit(runImportTmuxp(flag.Args()[1:]))
+		case "export-tmuxp":
+			os.Exit(runExportTmuxp(flag.Args()[1:]))
+		case "export-script":
+			os.Exit(runExportScript(flag.Args()[1:]))
+		case "import-script":
+			os.Exit(runImportScript(flag.Args()[1:]))
+		case "import":
+			os.Exit(runImportTemplate(flag.Args()[1:]))
+		case "wait":
+			os.Exit(runWait(flag.Args()[1:]))
+		case "serve":
+			os.Exit(runServe(flag.Args()[1:]))
+		case "call":
+			os.Exit(runCall(flag.Args()[1:]))
+		case "idle":
+			os.Exit(runIdle(flag.Args()[1:], *mineOnly))
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown command: %s\n", flag.Arg(0))
+			fmt.Fprintf(os.Stderr, "Run '%s -h' for usage.\n", os.Args[0])
+			os.Exit(2)
+		}
+	}
+
+	// Validate the terminal, unless attaching never launches one
+	if err := validateTerminal(terminalCmd); err != nil && spawnsTerminals() {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		fmt.Fprintf(os.Stderr, "Run '%s -h' for help on terminal detection.\n", os.Args[0])
 
 		// Show available terminals
 		fmt.Fprintf(os.Stderr, "\nTrying to find available terminals...\n")
-		terminals := []string{"kitty", "alacritty", "gnome-terminal", "xterm", "konsole", "terminator", "tilix"}
 		found := false
-		for _, term := range terminals {
+		for _, term := range supportedTerminals {
 			if _, err := exec.LookPath(term); err == nil {
 				fmt.Fprintf(os.Stderr, "  ✓ %s (available)\n", term)
 				found = true
@@ -1730,29 +4195,69 @@ func main() {
 		os.Exit(1)
 	}
 
-	fmt.Printf("Using terminal: %s\n", terminalCmd)
+	if spawnsTerminals() {
+		fmt.Printf("Using terminal: %s\n", terminalCmd)
+	}
 
-	sessions := listTmuxSessions()
 	templates := loadTemplates()
 
 	m := model{
-		sessions:       sessions,
 		templates:      templates,
 		cursor:         0,
 		templateCursor: 0,
 		paneCursor:     0,
 		mode:           browsing,
 		lastRefresh:    time.Now(),
-		autoRefresh:    true,
+		autoRefresh:    !manualRefresh(),
 		startTime:      time.Now(),
 		lastCursor:     -1,
 		popAnimation:   0,
 		showTemplates:  false,
 		previewMode:    true,
+		listShare:      loadViewState().ListShare,
+		marked:         map[string]bool{},
+		mineOnly:       *mineOnly,
+		templateOrder:  config.TemplateOrder,
+		showOutput:     config.LastOutput == "on",
+		showResources:  config.Resources == "on",
+		compact:        config.Density == "compact",
+		macroRegisters: map[string][]tea.KeyMsg{},
+		clicks:         &clickMap{lastRow: -1},
+		bookmarks:      loadBookmarks(),
+		favorites:      loadFavorites(),
+		metadata:       loadMetadata(),
+	}
+	m.applySessions(readSessions())
+	m.sortTemplates()
+	if config.Idle.Action == "kill" && !readOnly {
+		m.startIdleReview()
+	}
+	if eventHooksEnabled() {
+		m.hooksRegistered, m.hooksTried = registerEventHooks() == nil, time.Now()
+	}
+
+	var api net.Listener
+	if config.API == "on" {
+		if api, err = listenAPI(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: control socket not started: %v\n", err)
+		}
 	}
 
-	p := tea.NewProgram(m, tea.WithAltScreen())
-	if err := p.Start(); err != nil {
+	options := []tea.ProgramOption{tea.WithAltScreen()}
+	if mouseEnabled() {
+		options = append(options, tea.WithMouseCellMotion())
+	}
+	p := tea.NewProgram(m, options...)
+	err = p.Start()
+	if eventHooksEnabled() {
+		stopEvents()
+		unregisterEventHooks()
+	}
+	if api != nil {
+		api.Close()
+	}
+	flushDeferredAttach()
+	if err != nil {
 		fmt.Println("Error:", err)
 		os.Exit(1)
 	}
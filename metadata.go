@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// i shows the details of the highlighted session, where a note, tags and a
+// color can be attached to it: "running the migration, do not kill". They
+// are kept by session name in metadata.json, so they come back with a
+// session started again under the same name, and add to what the config
+// rules set.
+
+type sessionMeta struct {
+	Note  string   `json:"note,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+	Color string   `json:"color,omitempty"`
+}
+
+func (s sessionMeta) empty() bool {
+	return s.Note == "" && len(s.Tags) == 0 && s.Color == ""
+}
+
+func getMetadataFile() string {
+	return filepath.Join(getConfigDir(), "metadata.json")
+}
+
+// loadMetadata returns the metadata by session name. A missing or broken
+// file just means there is none.
+func loadMetadata() map[string]sessionMeta {
+	meta := map[string]sessionMeta{}
+	if data, err := ioutil.ReadFile(getMetadataFile()); err == nil {
+		json.Unmarshal(data, &meta)
+	}
+	return meta
+}
+
+func saveMetadata(meta map[string]sessionMeta) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(getConfigDir(), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(getMetadataFile(), data)
+}
+
+// applyMetadata adds the stored note, tags and color to the sessions after
+// the rules ran; a stored color wins over theirs.
+func applyMetadata(sessions []Session, meta map[string]sessionMeta) {
+	for i := range sessions {
+		s := &sessions[i]
+		md, ok := meta[s.Name]
+		if !ok || s.Project {
+			continue
+		}
+		s.Note = md.Note
+		for _, tag := range md.Tags {
+			if !containsString(s.Tags, tag) {
+				s.Tags = append(s.Tags, tag)
+			}
+		}
+		if md.Color != "" {
+			s.Color = md.Color
+		}
+	}
+}
+
+// renameMetadata moves the metadata of a renamed session, reporting
+// whether it had any.
+func renameMetadata(meta map[string]sessionMeta, old, new string) bool {
+	md, ok := meta[old]
+	if !ok {
+		return false
+	}
+	delete(meta, old)
+	meta[new] = md
+	return true
+}
+
+var metaLabels = map[string]string{"note": "Note", "tags": "Tags", "color": "Color"}
+
+// showDetails opens the detail panel of the highlighted session.
+func (m *model) showDetails() {
+	if len(m.sessions) == 0 {
+		return
+	}
+	m.detailSession = m.sessions[m.cursor].Name
+	m.mode = sessionDetails
+}
+
+// startMetaEdit asks for a new value of field: "note", "tags" or "color".
+func (m *model) startMetaEdit(field string) {
+	md := m.metadata[m.detailSession]
+	ti := textinput.New()
+	ti.CharLimit = 300
+	switch field {
+	case "note":
+		ti.Placeholder = "What should you know about this session?"
+		ti.SetValue(md.Note)
+	case "tags":
+		ti.Placeholder = "Tags, separated by spaces or commas"
+		ti.SetValue(strings.Join(md.Tags, " "))
+	case "color":
+		ti.Placeholder = `A color like "1" or "#ff5f87", empty for none`
+		ti.CharLimit = 20
+		ti.SetValue(md.Color)
+	}
+	ti.Focus()
+	m.input = ti
+	m.metaField = field
+	m.mode = metaEditing
+}
+
+// updateMetaEdit handles keys while a field of the detail panel is edited.
+func (m *model) updateMetaEdit(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "enter":
+		value := strings.TrimSpace(m.input.Value())
+		md := m.metadata[m.detailSession]
+		switch m.metaField {
+		case "note":
+			md.Note = value
+		case "tags":
+			md.Tags = parseTags(value)
+		case "color":
+			md.Color = value
+		}
+		if md.empty() {
+			delete(m.metadata, m.detailSession)
+		} else {
+			m.metadata[m.detailSession] = md
+		}
+		if err := saveMetadata(m.metadata); err != nil {
+			m.setMessage(fmt.Sprintf("Failed to save session details: %v", err), "error")
+		} else {
+			m.setMessage(fmt.Sprintf("Saved the %s of '%s'", m.metaField, m.detailSession), "success")
+		}
+		m.loadSessions()
+		m.mode = sessionDetails
+	case "esc":
+		m.mode = sessionDetails
+	default:
+		var cmd tea.Cmd
+		m.input, cmd = m.input.Update(msg)
+		return cmd
+	}
+	return nil
+}
+
+func (m model) renderDetails() string {
+	var s Session
+	for _, have := range m.allSessions {
+		if have.Name == m.detailSession {
+			s = have
+		}
+	}
+	md := m.metadata[m.detailSession]
+	label := lipgloss.NewStyle().Foreground(mutedColor).Width(10)
+	row := func(name, value string) string {
+		if value == "" {
+			value = "-"
+		}
+		return label.Render(name) + value + "\n"
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("ℹ️  %s\n\n", m.detailSession))
+	b.WriteString(row("Directory", shortDir(s.Dir)))
+	b.WriteString(row("Command", s.Command))
+	b.WriteString(row("Template", s.Template))
+	b.WriteString(row("Windows", fmt.Sprintf("%d", s.Windows)))
+	b.WriteString(row("Created", s.Created))
+	b.WriteString(row("Owner", s.Owner))
+	var ruled []string
+	for _, tag := range s.Tags {
+		if !containsString(md.Tags, tag) {
+			ruled = append(ruled, tag)
+		}
+	}
+	tags := strings.Join(md.Tags, " ")
+	if len(ruled) > 0 {
+		tags = strings.TrimSpace(tags + " (rules: " + strings.Join(ruled, " ") + ")")
+	}
+	b.WriteString(row("Tags", tags))
+	color := md.Color
+	if color != "" {
+		color = lipgloss.NewStyle().Foreground(lipgloss.Color(color)).Render("■ ") + color
+	}
+	b.WriteString(row("Color", color))
+	b.WriteString(row("Note", md.Note))
+	if m.mode == metaEditing {
+		b.WriteString(fmt.Sprintf("\n%s %s\n", label.Render(metaLabels[m.metaField]), m.input.View()))
+		b.WriteString(lipgloss.NewStyle().Foreground(mutedColor).Render("[Enter] Save • [Esc] Back"))
+	} else {
+		b.WriteString("\n" + lipgloss.NewStyle().Foreground(mutedColor).Render("[e] Note • [t] Tags • [c] Color • [Esc] Back"))
+	}
+	return lipgloss.Place(m.width, 16, lipgloss.Center, lipgloss.Top, inputBoxStyle.Render(b.String()))
+}
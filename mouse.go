@@ -0,0 +1,244 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The session list and the template browser take the mouse: a click
+// selects a row, a double click attaches to it, or starts the template,
+// the wheel moves the cursor, in the other lists too, and a click on an
+// item of the status bar does what its key does, like Ctrl+R on the refresh
+// time. View notes where it drew the rows and items, so clicks land on
+// what is on the screen, however rows wrap. "mouse": "off" leaves the mouse
+// to the terminal, for selecting text without holding Shift.
+
+// Two clicks on a row this close together are a double click.
+const doubleClickTime = 400 * time.Millisecond
+
+// clickZone is a part of the screen a click does something on.
+type clickZone struct {
+	top, bottom int // lines, bottom excluded
+	left, right int // columns, right excluded, or 0 and 0 for whole lines
+	row         int // session or template index, or -1
+	key         tea.KeyMsg
+}
+
+// move shifts the zone down and right, leaving whole lines whole.
+func (z *clickZone) move(down, right int) {
+	z.top += down
+	z.bottom += down
+	if z.right > 0 {
+		z.left += right
+		z.right += right
+	}
+}
+
+// clickMap is where the last View drew the clickable parts of the screen.
+// The model only holds a pointer to it, so View can fill it in.
+type clickMap struct {
+	zones []clickZone
+	mode  mode // the view they were drawn for
+	shift int  // lines of the frame above the top of the screen
+	// The layout preview of the template editor, if drawn.
+	canvas clickZone
+	// The editor before the border being dragged was pressed on.
+	dragFrom editorState
+	// The row clicked last, for double clicks.
+	lastRow   int
+	lastClick time.Time
+}
+
+// statusKeys are the keys clicks on status bar items stand for, by the
+// start of the item, in the session list and the template browser.
+var statusKeys = map[mode][][2]string{
+	browsing: {
+		{"📋 Templates", "t"},
+		{"🔄 Refreshed", "ctrl+r"},
+		{"⏸ Refreshed", "ctrl+r"},
+		{"👤 Only", "o"},
+		{"🔍", "/"},
+		{"📁 By directory", "s"},
+		{"❓", "?"},
+	},
+	templateBrowsing: {
+		{"🏷️", "f"},
+		{"🕘 By", "s"},
+		{"👁️ Preview", "p"},
+		{"❓", "?"},
+	},
+}
+
+// wheelModes are the views the wheel moves the cursor of.
+var wheelModes = map[mode]bool{
+	browsing: true, templateBrowsing: true, paneBrowsing: true, windowBrowsing: true,
+	bufferBrowsing: true, clientBrowsing: true, auditBrowsing: true, processBrowsing: true,
+	searchBrowsing: true, logViewing: true, changelogViewing: true,
+}
+
+// mouseEnabled reports whether the config leaves the mouse on.
+func mouseEnabled() bool {
+	return config.Mouse != "off"
+}
+
+// keyFor makes the key message a click stands for.
+func keyFor(key string) tea.KeyMsg {
+	switch key {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "up":
+		return tea.KeyMsg{Type: tea.KeyUp}
+	case "down":
+		return tea.KeyMsg{Type: tea.KeyDown}
+	case "ctrl+r":
+		return tea.KeyMsg{Type: tea.KeyCtrlR}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+}
+
+// start forgets the zones of the last frame, for a view of mode.
+func (c *clickMap) start(view mode) {
+	if c == nil {
+		return
+	}
+	c.zones = c.zones[:0]
+	c.mode = view
+	c.canvas = clickZone{}
+}
+
+// addRow notes that a row of the list was drawn at the end of content.
+func (c *clickMap) addRow(content *strings.Builder, row string, index int) {
+	if c == nil {
+		return
+	}
+	top := strings.Count(content.String(), "\n")
+	c.zones = append(c.zones, clickZone{top: top, bottom: top + lipgloss.Height(row), row: index})
+}
+
+// mark returns where the zones noted from now on start, for placeSince.
+func (c *clickMap) mark() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.zones)
+}
+
+// placeSince moves the rows noted since mark, which were drawn apart from
+// the frame, down to line top and into the width columns from left, for
+// a list drawn next to something else.
+func (c *clickMap) placeSince(mark, top, left, width int) {
+	if c == nil {
+		return
+	}
+	for i := mark; i < len(c.zones); i++ {
+		c.zones[i].top += top
+		c.zones[i].bottom += top
+		c.zones[i].left, c.zones[i].right = left, left+width
+	}
+}
+
+// addStatusBar notes where the items of the status bar about to be written
+// to content are, centered in width columns.
+func (c *clickMap) addStatusBar(content *strings.Builder, bar string, items []string) {
+	if c == nil {
+		return
+	}
+	lines := strings.Split(bar, "\n")
+	if len(lines) < 2 {
+		return
+	}
+	// The items are on the line under the top border.
+	top := strings.Count(content.String(), "\n") + 1
+	text := ansiEscape.ReplaceAllString(lines[1], "")
+	for _, item := range items {
+		for _, sk := range statusKeys[c.mode] {
+			if !strings.HasPrefix(item, sk[0]) {
+				continue
+			}
+			if i := strings.Index(text, item); i >= 0 {
+				left := lipgloss.Width(text[:i])
+				c.zones = append(c.zones, clickZone{top: top, bottom: top + 1, left: left, right: left + lipgloss.Width(item), row: -1, key: keyFor(sk[1])})
+			}
+			break
+		}
+	}
+}
+
+// finish notes how the frame was drawn: with baseStyle's padding around
+// it, and its top cut off when it is taller than the screen.
+func (c *clickMap) finish(frame string, height int) {
+	if c == nil {
+		return
+	}
+	top, left := baseStyle.GetPaddingTop(), baseStyle.GetPaddingLeft()
+	for i := range c.zones {
+		c.zones[i].move(top, left)
+	}
+	c.canvas.move(top, left)
+	c.shift = max(0, lipgloss.Height(frame)-height)
+}
+
+// at returns the zone under a point of the screen.
+func (c *clickMap) at(x, y int) (clickZone, bool) {
+	if c == nil {
+		return clickZone{}, false
+	}
+	y += c.shift
+	for _, z := range c.zones {
+		if y >= z.top && y < z.bottom && (z.right == 0 || x >= z.left && x < z.right) {
+			return z, true
+		}
+	}
+	return clickZone{}, false
+}
+
+// updateMouse handles the mouse, mostly by turning it into keys.
+func (m model) updateMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if m.mode == templateEditing {
+		return m.updateEditorMouse(msg)
+	}
+	if msg.Action != tea.MouseActionPress || m.showHelp && msg.Button == tea.MouseButtonLeft {
+		return m, nil
+	}
+	switch msg.Button {
+	case tea.MouseButtonWheelUp, tea.MouseButtonWheelDown:
+		if !wheelModes[m.mode] {
+			return m, nil
+		}
+		if msg.Button == tea.MouseButtonWheelUp {
+			return m.updateModel(keyFor("up"))
+		}
+		return m.updateModel(keyFor("down"))
+	case tea.MouseButtonLeft:
+	default:
+		return m, nil
+	}
+
+	zone, ok := m.clicks.at(msg.X, msg.Y)
+	if !ok || m.clicks.mode != m.mode || m.mode != browsing && m.mode != templateBrowsing {
+		return m, nil
+	}
+	if zone.row < 0 {
+		return m.updateModel(zone.key)
+	}
+	now := time.Now()
+	double := zone.row == m.clicks.lastRow && now.Sub(m.clicks.lastClick) < doubleClickTime
+	m.clicks.lastRow, m.clicks.lastClick = zone.row, now
+	if double {
+		m.clicks.lastRow = -1
+		return m.updateModel(keyFor("enter"))
+	}
+	if m.mode == browsing && zone.row != m.cursor {
+		m.lastCursor = m.cursor
+		m.cursor = zone.row
+		m.popAnimation = 0.5
+	}
+	if m.mode == templateBrowsing && zone.row != m.templateCursor {
+		m.templateCursor = zone.row
+		m.popAnimation = 0.5
+	}
+	return m, nil
+}
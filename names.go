@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Session names, typed in or taken from directories, projects and branches,
+// are cleaned of the characters tmux rejects or mangles before they are
+// used: '.' and ':', which tmux reads as target separators, '/' and control
+// characters, and with name_ascii on anything outside ASCII. Each run of
+// them becomes name_replacement, "_" unless set; the prompts show the final
+// name as you type whenever it differs.
+
+// nameReplacement returns what rejected characters become.
+func nameReplacement() string {
+	switch config.NameReplacement {
+	case "":
+		return "_"
+	case "drop":
+		return ""
+	}
+	return config.NameReplacement
+}
+
+// badNameRune reports whether r can't be part of a session name.
+func badNameRune(r rune) bool {
+	switch {
+	case r == '.' || r == ':' || r == '/' || r == '\\':
+		return true
+	case unicode.IsControl(r) || r == unicode.ReplacementChar:
+		return true
+	case r > unicode.MaxASCII:
+		return config.NameASCII == "on"
+	}
+	return false
+}
+
+// sanitizeSessionName returns name with every run of characters tmux can't
+// take replaced, trimmed of replacements at either end.
+func sanitizeSessionName(name string) string {
+	replacement := nameReplacement()
+	var b strings.Builder
+	replaced := false
+	for _, r := range strings.TrimSpace(name) {
+		if badNameRune(r) {
+			replaced = true
+			continue
+		}
+		if replaced && b.Len() > 0 {
+			b.WriteString(replacement)
+		}
+		replaced = false
+		b.WriteRune(r)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// namePreview shows the name a typed one becomes, or nothing when it is
+// used as typed.
+func namePreview(typed string) string {
+	typed = strings.TrimSpace(typed)
+	final := sanitizeSessionName(typed)
+	if typed == "" || final == typed {
+		return ""
+	}
+	return "\n" + lipgloss.NewStyle().Foreground(mutedColor).Render("→ "+final)
+}
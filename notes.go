@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Name of the window quick notes are shown in.
+const notesWindow = "notes"
+
+// getNotesFile is where the notes of a session are kept. The notes window
+// follows it, so notes outlive the window and even the session.
+func getNotesFile(session string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '/' || r == os.PathSeparator {
+			return '_'
+		}
+		return r
+	}, session)
+	return filepath.Join(getConfigDir(), "notes", name+".txt")
+}
+
+// addNote appends a timestamped note to the session's notes and makes sure
+// the session has a window showing them.
+func (s Server) addNote(session, note string) error {
+	file := getNotesFile(session)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(f, "[%s] %s\n", time.Now().Format("2006-01-02 15:04"), note)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return s.ensureNotesWindow(session, file)
+}
+
+// ensureNotesWindow adds a background window to the session that follows
+// the notes file, unless there is one already.
+func (s Server) ensureNotesWindow(session, file string) error {
+	out, err := s.command("list-windows", "-t", "="+session, "-F", "#{window_name}").Output()
+	if err != nil {
+		return err
+	}
+	for _, name := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if name == notesWindow {
+			return nil
+		}
+	}
+	return s.command("new-window", "-d", "-t", "="+session+":", "-n", notesWindow,
+		"tail -n +1 -F "+shellQuote(file)).Run()
+}
+
+func (m *model) startNote() {
+	ti := textinput.New()
+	ti.Placeholder = "What should you remember about this session?"
+	ti.CharLimit = 300
+	ti.Focus()
+	m.input = ti
+	m.mode = noteEntering
+}
+
+func (m model) renderNotePrompt() string {
+	prompt := fmt.Sprintf("📝 Note for '%s'\n%s\n", m.sessions[m.cursor].Name, m.input.View())
+	prompt += lipgloss.NewStyle().Foreground(mutedColor).Render("[Enter] Add to its notes window • [Esc] Cancel")
+	inputView := inputBoxStyle.Render(prompt)
+	return lipgloss.Place(m.width, 5, lipgloss.Center, lipgloss.Top, inputView)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"runtime"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultOpenCommand opens directories in the desktop's file manager.
+func defaultOpenCommand() string {
+	if runtime.GOOS == "darwin" {
+		return "open"
+	}
+	return "xdg-open"
+}
+
+// openDirectory opens the directory of the highlighted session's active
+// pane with open_command, as it is right now rather than at the last
+// refresh. Projects open their own directory.
+func (m *model) openDirectory() tea.Cmd {
+	if len(m.sessions) == 0 {
+		return nil
+	}
+	s := m.sessions[m.cursor]
+	server := serverNamed(s.Server)
+	if server.remote() {
+		m.setMessage("The directory is on another machine", "warning")
+		return nil
+	}
+	dir := s.Dir
+	return inBackground(func() error {
+		if !s.Project {
+			out, err := server.command("display-message", "-p", "-t", "="+s.Name+":", "#{pane_current_path}").Output()
+			if current := strings.TrimSpace(string(out)); err == nil && current != "" {
+				dir = current
+			}
+		}
+		return nil
+	}, func(m *model, err error) tea.Cmd {
+		if dir == "" {
+			m.setMessage("The session has no directory", "warning")
+			return nil
+		}
+
+		command := config.OpenCommand
+		if strings.TrimSpace(command) == "" {
+			command = defaultOpenCommand()
+		}
+		if !strings.Contains(command, "{cwd}") {
+			command += " {cwd}"
+		}
+		a := CustomAction{
+			Key:         "O",
+			Command:     command,
+			Description: "Open " + shortDir(dir),
+			Interactive: config.OpenInteractive == "on",
+		}
+		return runActionWith(a, map[string]string{"session": s.Name, "cwd": dir, "template": s.Template})
+	})
+}
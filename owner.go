@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// currentUser is the name sessions created by lazytmux are tagged with.
+var currentUser = func() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return strconv.Itoa(os.Getuid())
+}()
+
+// fileOwner returns the user name owning path, or "" if it cannot be found.
+func fileOwner(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ""
+	}
+	uid := strconv.FormatUint(uint64(stat.Uid), 10)
+	if u, err := user.LookupId(uid); err == nil {
+		return u.Username
+	}
+	return uid
+}
+
+// mine reports whether the session belongs to the user running lazytmux.
+func (s Session) mine() bool {
+	return s.Owner == "" || s.Owner == currentUser
+}
+
+// sharedServer reports whether sessions of other users may be in the list:
+// when running as root or when any session is owned by someone else.
+func sharedServer(sessions []Session) bool {
+	if os.Geteuid() == 0 {
+		return true
+	}
+	for _, s := range sessions {
+		if !s.mine() {
+			return true
+		}
+	}
+	return false
+}
+
+// ownSessions returns the sessions that belong to the current user.
+func ownSessions(sessions []Session) []Session {
+	var own []Session
+	for _, s := range sessions {
+		if s.mine() {
+			own = append(own, s)
+		}
+	}
+	return own
+}
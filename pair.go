@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Sessions are shared through a second tmux server, on a socket the guest
+// may use, whose only pane attaches to the real session. With no prefix and
+// no status line that server is invisible, so host and guest both work in
+// the session itself. tmate runs the same relay and hands out an ssh address
+// instead.
+
+// Ways to share a session.
+const (
+	shareSocket = "socket"
+	shareTmate  = "tmate"
+)
+
+// Share is a session being shared, as remembered in shares.json.
+type Share struct {
+	Session string    `json:"session"`
+	Backend string    `json:"backend"`
+	Socket  string    `json:"socket"`
+	Guest   string    `json:"guest,omitempty"` // user allowed on the socket
+	Command string    `json:"command"`         // what the guest runs to join
+	Started time.Time `json:"started"`
+}
+
+// shareDoneMsg reports a share that was set up, or why it wasn't.
+type shareDoneMsg struct {
+	share Share
+	err   error
+}
+
+func getSharesFile() string {
+	return filepath.Join(getConfigDir(), "shares.json")
+}
+
+// binary is the program that serves the share's socket.
+func (s Share) binary() string {
+	if s.Backend == shareTmate {
+		return "tmate"
+	}
+	return "tmux"
+}
+
+// alive reports whether the relay server still runs. It stops by itself
+// when the shared session ends.
+func (s Share) alive() bool {
+	return exec.Command(s.binary(), "-S", s.Socket, "has-session").Run() == nil
+}
+
+// loadShares returns the shares that are still running, by session name.
+func loadShares() map[string]Share {
+	shares := map[string]Share{}
+	data, err := ioutil.ReadFile(getSharesFile())
+	if err != nil {
+		return shares
+	}
+	var list []Share
+	if json.Unmarshal(data, &list) != nil {
+		return shares
+	}
+	for _, s := range list {
+		if s.alive() {
+			shares[s.Session] = s
+		}
+	}
+	return shares
+}
+
+func saveShares(shares map[string]Share) error {
+	list := []Share{}
+	for _, name := range sortedShareNames(shares) {
+		list = append(list, shares[name])
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(getConfigDir(), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(getSharesFile(), data)
+}
+
+func sortedShareNames(shares map[string]Share) []string {
+	names := map[string]string{}
+	for name := range shares {
+		names[name] = ""
+	}
+	return sortedKeys(names)
+}
+
+// tmateInstalled reports whether sessions can be shared over tmate.
+func tmateInstalled() bool {
+	_, err := exec.LookPath("tmate")
+	return err == nil
+}
+
+// Prefix of the directories the relay sockets live in.
+const shareDirPrefix = "lazytmux-share-"
+
+// shareSocketPath makes a directory of its own for the relay socket of a
+// share and returns where the socket goes in it. The directory is private
+// and named at random, so nobody can put a socket there first or reach the
+// relay before the guest is let in.
+func shareSocketPath() (string, error) {
+	dir, err := os.MkdirTemp("", shareDirPrefix)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "relay.sock"), nil
+}
+
+// removeShareSocket removes the socket of a share and its directory.
+func removeShareSocket(socket string) {
+	os.Remove(socket)
+	if dir := filepath.Dir(socket); strings.HasPrefix(filepath.Base(dir), shareDirPrefix) {
+		os.Remove(dir)
+	}
+}
+
+// relayCommand attaches to the shared session on host from inside the
+// relay.
+func relayCommand(host Server, session string) (string, error) {
+	out, err := host.command("display-message", "-p", "#{socket_path}").Output()
+	if err != nil {
+		return "", fmt.Errorf("could not find the tmux socket: %v", err)
+	}
+	socket := strings.TrimSpace(string(out))
+	return fmt.Sprintf("env -u TMUX tmux -S %s attach-session -t %s", shellQuote(socket), shellQuote("="+session)), nil
+}
+
+// startRelay starts the relay server on socket and makes it transparent.
+func startRelay(binary, socket string, host Server, session string) error {
+	relay, err := relayCommand(host, session)
+	if err != nil {
+		return err
+	}
+	args := []string{"-S", socket, "new-session", "-d", "-s", session, relay,
+		";", "set-option", "-g", "prefix", "None",
+		";", "set-option", "-g", "prefix2", "None",
+		";", "set-option", "-g", "status", "off",
+		";", "set-option", "-g", "destroy-unattached", "off"}
+	if out, err := exec.Command(binary, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// grantSocket lets guest, and nobody else, through to the socket with ACL
+// entries on it and its directory, and on tmux 3.3 and later also puts them
+// on the server's own access list. Whoever reaches the socket types into
+// the session as us, so without setfacl there is no share rather than one
+// open to every user.
+func grantSocket(socket, guest string) error {
+	if _, err := exec.LookPath("setfacl"); err != nil {
+		return fmt.Errorf("setfacl is needed to let '%s' in, and only them", guest)
+	}
+	for path, perms := range map[string]string{filepath.Dir(socket): "x", socket: "rw"} {
+		if out, err := exec.Command("setfacl", "-m", "u:"+guest+":"+perms, path).CombinedOutput(); err != nil {
+			return fmt.Errorf("setfacl: %s", strings.TrimSpace(string(out)))
+		}
+	}
+	out, err := exec.Command("tmux", "-S", socket, "server-access", "-a", "-w", guest).CombinedOutput()
+	if err != nil && !strings.Contains(string(out), "unknown command") {
+		return fmt.Errorf("server-access: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// shareWithUser shares a session on host with another user of this
+// machine.
+func shareWithUser(host Server, session, guest string) tea.Cmd {
+	return func() tea.Msg {
+		if _, err := user.Lookup(guest); err != nil {
+			return shareDoneMsg{err: fmt.Errorf("no user named '%s'", guest)}
+		}
+		socket, err := shareSocketPath()
+		if err != nil {
+			return shareDoneMsg{err: err}
+		}
+		if err := startRelay("tmux", socket, host, session); err != nil {
+			removeShareSocket(socket)
+			return shareDoneMsg{err: err}
+		}
+		if err := grantSocket(socket, guest); err != nil {
+			exec.Command("tmux", "-S", socket, "kill-server").Run()
+			removeShareSocket(socket)
+			return shareDoneMsg{err: err}
+		}
+		return shareDoneMsg{share: Share{
+			Session: session,
+			Backend: shareSocket,
+			Socket:  socket,
+			Guest:   guest,
+			Command: fmt.Sprintf("tmux -S %s attach", shellQuote(socket)),
+			Started: time.Now(),
+		}}
+	}
+}
+
+// shareWithTmate shares a session on host over tmate and waits for its ssh
+// address.
+func shareWithTmate(host Server, session string) tea.Cmd {
+	return func() tea.Msg {
+		socket, err := shareSocketPath()
+		if err != nil {
+			return shareDoneMsg{err: err}
+		}
+		if err := startRelay("tmate", socket, host, session); err != nil {
+			removeShareSocket(socket)
+			return shareDoneMsg{err: err}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+		if err := exec.CommandContext(ctx, "tmate", "-S", socket, "wait", "tmate-ready").Run(); err != nil {
+			exec.Command("tmate", "-S", socket, "kill-server").Run()
+			removeShareSocket(socket)
+			return shareDoneMsg{err: fmt.Errorf("tmate did not connect: %v", err)}
+		}
+		out, err := exec.Command("tmate", "-S", socket, "display-message", "-p", "#{tmate_ssh}").Output()
+		if err != nil {
+			return shareDoneMsg{err: err}
+		}
+		return shareDoneMsg{share: Share{
+			Session: session,
+			Backend: shareTmate,
+			Socket:  socket,
+			Command: strings.TrimSpace(string(out)),
+			Started: time.Now(),
+		}}
+	}
+}
+
+// stopShare shuts the relay down. The shared session itself keeps running.
+func stopShare(s Share) error {
+	err := exec.Command(s.binary(), "-S", s.Socket, "kill-server").Run()
+	removeShareSocket(s.Socket)
+	if err != nil && s.alive() {
+		return err
+	}
+	return nil
+}
+
+func (m *model) startShareGuest() {
+	ti := textinput.New()
+	ti.Placeholder = "User name of your pair"
+	ti.CharLimit = 32
+	ti.Focus()
+	m.input = ti
+	m.mode = shareGuestEntering
+}
+
+func (m model) renderSharePrompt() string {
+	name := m.sessions[m.cursor].Name
+	var prompt string
+	switch m.mode {
+	case shareChoosing:
+		options := "[u] With a user of this machine"
+		if tmateInstalled() {
+			options += " • [t] Over tmate"
+		}
+		prompt = fmt.Sprintf("👥 Share '%s'\n\n%s • [Esc] Cancel", name, options)
+	case shareGuestEntering:
+		prompt = fmt.Sprintf("👥 Share '%s' with\n%s\n", name, m.input.View())
+		prompt += lipgloss.NewStyle().Foreground(mutedColor).Render("[Enter] Share • [Esc] Cancel")
+	}
+	inputView := inputBoxStyle.Render(prompt)
+	return lipgloss.Place(m.width, 5, lipgloss.Center, lipgloss.Top, inputView)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// L in the pane view starts or stops copying everything the highlighted
+// pane prints to a log file, with tmux pipe-pane, and V shows the end of
+// that log, following it as it grows. Logs are named after the session,
+// pane and start time, in log_dir. The file a pane logs to is kept in its
+// @lazytmux_log option, so logging survives lazytmux and shows up as 📝
+// next to the pane the next time.
+
+// How much of the end of a log the viewer reads.
+const logTail = 256 * 1024
+
+// ansiEscape matches the terminal control sequences in a raw pane log.
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// getLogDir is where pane logs go: log_dir, or logs in the config
+// directory.
+func getLogDir() string {
+	if config.LogDir != "" {
+		return expandHome(config.LogDir)
+	}
+	return filepath.Join(getConfigDir(), "logs")
+}
+
+// paneLogFile names a new log for a pane, like web-1.0-20240131-154502.log.
+func paneLogFile(session, index string, now time.Time) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '/' || r == os.PathSeparator {
+			return '_'
+		}
+		return r
+	}, session)
+	return filepath.Join(getLogDir(), fmt.Sprintf("%s-%s-%s.log", name, index, now.Format("20060102-150405")))
+}
+
+// togglePaneLog starts logging the pane to a new file, or stops it if it
+// is logging already, and returns what it did.
+func (s Server) togglePaneLog(session string, pane LivePane) (string, error) {
+	if pane.Logging {
+		if err := s.command("pipe-pane", "-t", pane.ID).Run(); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Stopped logging pane %s to %s", pane.Index, shortDir(pane.LogFile)), nil
+	}
+	if s.remote() {
+		return "", fmt.Errorf("the pane runs on another machine")
+	}
+	file := paneLogFile(session, pane.Index, time.Now())
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return "", err
+	}
+	if err := s.command("pipe-pane", "-o", "-t", pane.ID, "cat >> "+shellQuote(file)).Run(); err != nil {
+		return "", err
+	}
+	if err := s.command("set-option", "-p", "-t", pane.ID, "@lazytmux_log", file).Run(); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Logging pane %s to %s", pane.Index, shortDir(file)), nil
+}
+
+// readLogTail returns the last lines of a log as plain text, with the
+// escape sequences gone and each line as a carriage return left it.
+func readLogTail(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := info.Size() - logTail
+	if offset > 0 {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(ansiEscape.ReplaceAllString(string(data), ""), "\n")
+	if offset > 0 && len(lines) > 0 {
+		// The first line was cut off.
+		lines = lines[1:]
+	}
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if j := strings.LastIndex(line, "\r"); j >= 0 {
+			line = line[j+1:]
+		}
+		lines[i] = printable(line)
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines, nil
+}
+
+// showPaneLog opens the log of the highlighted pane.
+func (m *model) showPaneLog() {
+	pane := m.livePanes[m.livePaneCursor]
+	if pane.LogFile == "" {
+		m.setMessage(fmt.Sprintf("Pane %s has no log, L starts one", pane.Index), "info")
+		return
+	}
+	lines, err := readLogTail(pane.LogFile)
+	if err != nil {
+		m.setMessage(fmt.Sprintf("Failed to read the log: %v", err), "error")
+		return
+	}
+	m.logFile = pane.LogFile
+	m.logLines = lines
+	m.logScroll = 0
+	m.mode = logViewing
+}
+
+// reloadPaneLog reads the end of the shown log again.
+func (m *model) reloadPaneLog() {
+	if lines, err := readLogTail(m.logFile); err == nil {
+		m.logLines = lines
+	}
+}
+
+// logRows is how many lines of the log fit on the screen.
+func (m model) logRows() int {
+	return max(5, m.height-8)
+}
+
+func (m model) renderLogView(tableWidth int) string {
+	var content strings.Builder
+	title := tableHeaderStyle.Width(tableWidth).Render("📝 " + shortDir(m.logFile))
+	content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, title))
+	content.WriteString("\n\n")
+
+	// logScroll counts lines up from the end, so new output stays in view
+	// until you scroll back.
+	rows := m.logRows()
+	end := max(0, len(m.logLines)-m.logScroll)
+	start := max(0, end-rows)
+	muted := lipgloss.NewStyle().Foreground(mutedColor)
+	for _, line := range m.logLines[start:end] {
+		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, lipgloss.NewStyle().Width(tableWidth).Render(rightTruncate(line, tableWidth))))
+		content.WriteString("\n")
+	}
+	if len(m.logLines) == 0 {
+		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, muted.Italic(true).Render("Nothing logged yet.")))
+		content.WriteString("\n")
+	}
+	content.WriteString("\n")
+	where := "following"
+	if m.logScroll > 0 {
+		where = fmt.Sprintf("%d lines up", m.logScroll)
+	}
+	help := muted.Render(fmt.Sprintf("%s • [j/k] Scroll • [G] Follow • [Esc] Back", where))
+	content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, help))
+	return content.String()
+}
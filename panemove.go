@@ -0,0 +1,131 @@
+package main
+
+import "fmt"
+
+// Shift and an arrow in the template editor swap the selected pane with its
+// neighbour on that side: the two trade commands, labels and settings and
+// keep their places, so the splits that build the layout stay as they are.
+// m picks the selected pane up to move it: j/k then go to another pane and
+// H/J/K/L split that one for it, like adding a pane there. The panes that
+// were split off the moved one are handed to the pane it was split off, so
+// every pane still comes after the one it splits.
+
+// sideNames say where a pane is from another one, by split direction.
+var sideNames = map[string]string{"left": "left of", "right": "right of", "up": "above", "down": "below"}
+
+// moveKeys are where H/J/K/L put the pane being moved.
+var moveKeys = map[string]string{"H": "left", "L": "right", "J": "down", "K": "up"}
+
+// neighbour returns the index of the pane sharing the most of the side of
+// pane i towards direction, or -1 if none does.
+func neighbour(panes []Pane, i int, direction string) int {
+	p := panes[i]
+	best, bestOverlap := -1, 0
+	for j, q := range panes {
+		if j == i {
+			continue
+		}
+		var touches bool
+		var overlap int
+		switch direction {
+		case "left", "right":
+			touches = direction == "left" && q.Col+q.Width == p.Col || direction == "right" && p.Col+p.Width == q.Col
+			overlap = min(p.Row+p.Height, q.Row+q.Height) - max(p.Row, q.Row)
+		case "up", "down":
+			touches = direction == "up" && q.Row+q.Height == p.Row || direction == "down" && p.Row+p.Height == q.Row
+			overlap = min(p.Col+p.Width, q.Col+q.Width) - max(p.Col, q.Col)
+		}
+		if touches && overlap > bestOverlap {
+			best, bestOverlap = j, overlap
+		}
+	}
+	return best
+}
+
+// swapPane swaps the selected pane with its neighbour towards direction
+// and follows it there.
+func (m *model) swapPane(direction string) {
+	panes := m.currentTemplate.Panes
+	if m.paneCursor < 0 || m.paneCursor >= len(panes) {
+		return
+	}
+	i := m.paneCursor
+	j := neighbour(panes, i, direction)
+	if j < 0 {
+		m.setMessage(fmt.Sprintf("No pane %s this one", sideNames[direction]), "info")
+		return
+	}
+	m.recordEdit("swapping panes")
+	a, b := panes[i], panes[j]
+	panes[i], panes[j] = placedLike(b, a), placedLike(a, b)
+	// Waiting for a pane follows its command.
+	for k := range panes {
+		switch panes[k].WaitForPane {
+		case a.ID:
+			panes[k].WaitForPane = b.ID
+		case b.ID:
+			panes[k].WaitForPane = a.ID
+		}
+	}
+	m.paneCursor = j
+	m.setMessage(fmt.Sprintf("Swapped pane %d with pane %d", a.ID, b.ID), "info")
+}
+
+// startMovePane picks up the selected pane to move.
+func (m *model) startMovePane() {
+	if len(m.currentTemplate.Panes) < 2 || m.paneCursor <= 0 || m.paneCursor >= len(m.currentTemplate.Panes) {
+		m.setMessage("The first pane holds the others, swap it with Shift and an arrow instead", "info")
+		return
+	}
+	m.movingPane = m.currentTemplate.Panes[m.paneCursor].ID
+	m.setMessage(fmt.Sprintf("Moving pane %d: j/k to the pane to put it next to, then H/J/K/L, Esc to cancel", m.movingPane), "info")
+}
+
+// moveKey handles the keys that put down or drop the pane being moved, and
+// reports whether key was one of them.
+func (m *model) moveKey(key string) bool {
+	if direction, ok := moveKeys[key]; ok {
+		m.movePane(direction)
+		return true
+	}
+	if key == "esc" {
+		m.movingPane = 0
+		m.setMessage("Move cancelled", "info")
+		return true
+	}
+	return false
+}
+
+// movePane splits the selected pane in direction for the pane picked up.
+func (m *model) movePane(direction string) {
+	id := m.movingPane
+	m.movingPane = 0
+	from := m.findPaneIndex(id)
+	if from < 0 || m.paneCursor < 0 || m.paneCursor >= len(m.currentTemplate.Panes) {
+		return
+	}
+	target := m.currentTemplate.Panes[m.paneCursor].ID
+	if target == id {
+		m.setMessage("Pick another pane to put it next to", "info")
+		return
+	}
+	m.recordEdit("moving a pane")
+	moved := m.currentTemplate.Panes[from]
+	var panes []Pane
+	for _, p := range m.currentTemplate.Panes {
+		if p.ID == id {
+			continue
+		}
+		if p.Parent == id {
+			p.Parent = moved.Parent
+		}
+		panes = append(panes, p)
+	}
+	moved.Parent, moved.Position, moved.SplitPercent = target, direction, 50
+	panes = append(panes, moved)
+	layoutGeometry(panes)
+	m.currentTemplate.Panes = panes
+	m.paneCursor = len(panes) - 1
+	m.calculatePaneLayout()
+	m.setMessage(fmt.Sprintf("Moved pane %d %s pane %d", id, sideNames[direction], target), "success")
+}
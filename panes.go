@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// LivePane is a pane of a running session, as opposed to a template Pane.
+type LivePane struct {
+	ID         string // tmux pane id, e.g. "%3"
+	WindowID   string // tmux window id, e.g. "@1"
+	Index      string // "window.pane"
+	Command    string
+	Title      string
+	Size       string
+	Dead       bool
+	ExitStatus int
+	Logging    bool   // output is piped to LogFile
+	LogFile    string // last log started with L, see panelog.go
+}
+
+func (s Server) listSessionPanes(session string) []LivePane {
+	format := strings.Join([]string{
+		"#{pane_id}",
+		"#{window_index}.#{pane_index}",
+		"#{pane_current_command}",
+		"#{pane_dead}",
+		"#{pane_dead_status}",
+		"#{pane_width}x#{pane_height}",
+		"#{window_id}",
+		"#{pane_pipe}",
+		"#{@lazytmux_log}",
+		"#{pane_title}",
+	}, "\t")
+	out, err := s.command("list-panes", "-s", "-t", "="+session, "-F", format).Output()
+	if err != nil {
+		return []LivePane{}
+	}
+
+	panes := []LivePane{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		parts := strings.SplitN(line, "\t", 10)
+		if len(parts) < 10 {
+			continue
+		}
+		status, _ := strconv.Atoi(parts[4])
+		panes = append(panes, LivePane{
+			ID:         parts[0],
+			Index:      parts[1],
+			Command:    parts[2],
+			Dead:       parts[3] == "1",
+			ExitStatus: status,
+			Size:       parts[5],
+			WindowID:   parts[6],
+			Logging:    parts[7] == "1",
+			LogFile:    parts[8],
+			Title:      parts[9],
+		})
+	}
+	return panes
+}
+
+// readPanes reads the panes of a session for refreshView.
+func readPanes(srv Server, session string) func(m *model) {
+	panes := srv.listSessionPanes(session)
+	return func(m *model) {
+		if !m.showPanes || m.paneSession != session {
+			return
+		}
+		m.livePanes = panes
+		if m.livePaneCursor >= len(m.livePanes) && len(m.livePanes) > 0 {
+			m.livePaneCursor = len(m.livePanes) - 1
+		}
+	}
+}
+
+func (s Server) respawnPane(id string) error {
+	return s.command("respawn-pane", "-t", id).Run()
+}
+
+func (s Server) killPane(id string) error {
+	return s.command("kill-pane", "-t", id).Run()
+}
+
+// breakPane moves a pane out of its window into a new window at the end of
+// its session, leaving the current window selected.
+func (s Server) breakPane(id, session string) error {
+	return s.run("break-pane", "-d", "-s", id, "-t", "="+session+":")
+}
+
+// joinPane moves a pane into another window, below its active pane or, with
+// beside, to the right of it.
+func (s Server) joinPane(id, windowID string, beside bool) error {
+	split := "-v"
+	if beside {
+		split = "-h"
+	}
+	return s.run("join-pane", "-d", split, "-s", id, "-t", windowID)
+}
+
+// windowPaneCount counts the listed panes that share a window with pane.
+func windowPaneCount(panes []LivePane, pane LivePane) int {
+	n := 0
+	for _, p := range panes {
+		if p.WindowID == pane.WindowID {
+			n++
+		}
+	}
+	return n
+}
+
+// startJoin offers the windows the highlighted pane can be joined into:
+// every window on the server but its own.
+func (m *model) startJoin() tea.Cmd {
+	pane, srv := m.livePanes[m.livePaneCursor], m.server()
+	var windows []LiveWindow
+	return inBackground(func() error {
+		windows = srv.listAllWindows()
+		return nil
+	}, func(m *model, err error) tea.Cmd {
+		if m.mode != paneBrowsing {
+			return nil
+		}
+		m.joinTargets = nil
+		for _, w := range windows {
+			if w.ID != pane.WindowID {
+				m.joinTargets = append(m.joinTargets, w)
+			}
+		}
+		if len(m.joinTargets) == 0 {
+			m.setMessage("There is no other window to join the pane into", "warning")
+			return nil
+		}
+		m.joinCursor = 0
+		m.mode = paneJoining
+		return nil
+	})
+}
+
+// finishJoin joins the highlighted pane into the picked window.
+func (m *model) finishJoin(beside bool) tea.Cmd {
+	pane, srv := m.livePanes[m.livePaneCursor], m.server()
+	target := m.joinTargets[m.joinCursor]
+	label := fmt.Sprintf("%s:%s", target.Session, target.Index)
+	m.joinTargets = nil
+	m.mode = paneBrowsing
+	return changeInBackground(func() error { return srv.joinPane(pane.ID, target.ID, beside) },
+		"join-pane", fmt.Sprintf("%s:%s -> %s", m.paneSession, pane.Index, label),
+		"Failed to join pane", fmt.Sprintf("Joined pane %s into %s", pane.Index, label))
+}
+
+// selectPane makes id the active pane of the active window, so attaching
+// afterwards lands on it.
+func (s Server) selectPane(id string) error {
+	if err := s.command("select-window", "-t", id).Run(); err != nil {
+		return err
+	}
+	return s.command("select-pane", "-t", id).Run()
+}
+
+func (p LivePane) status() string {
+	if p.Dead {
+		return fmt.Sprintf("finished (exit %d)", p.ExitStatus)
+	}
+	return "running"
+}
+
+var hostname, _ = os.Hostname()
+
+func (m model) renderPaneView(tableWidth int) string {
+	var content strings.Builder
+
+	title := tableHeaderStyle.Width(tableWidth).Render(fmt.Sprintf("🔍 PANES OF '%s'", m.paneSession))
+	content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, title))
+	content.WriteString("\n\n")
+
+	if len(m.livePanes) == 0 {
+		text := "No panes found. The session may have been closed."
+		if m.viewLoading() {
+			text = "Reading the panes..."
+		}
+		emptyMsg := lipgloss.NewStyle().
+			Foreground(mutedColor).
+			Italic(true).
+			Render(text)
+		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, emptyMsg))
+		content.WriteString("\n\n")
+	} else {
+		for i, pane := range m.livePanes {
+			isSelected := m.livePaneCursor == i && m.mode == paneBrowsing
+
+			rowStyle := selectedRowStyle.Copy().Padding(0, 1)
+			if !isSelected {
+				rowStyle = lipgloss.NewStyle().
+					Foreground(lipgloss.Color("16")).
+					Padding(0, 1).
+					Border(lipgloss.RoundedBorder()).
+					BorderForeground(mutedColor)
+			}
+
+			indexText := "  " + pane.Index
+			if isSelected {
+				indexText = "▶ " + pane.Index
+			}
+
+			statusStyle := lipgloss.NewStyle().Foreground(successColor)
+			if pane.Dead {
+				statusStyle = statusStyle.Foreground(mutedColor)
+				if pane.ExitStatus != 0 {
+					statusStyle = statusStyle.Foreground(dangerColor)
+				}
+			}
+
+			command := pane.Command
+			// tmux defaults the title to the host name, which is just noise here.
+			if pane.Title != "" && pane.Title != command && pane.Title != hostname {
+				command = fmt.Sprintf("%s (%s)", command, pane.Title)
+			}
+
+			indexCell := rowStyle.Copy().Width(tableWidth / 6).Render(indexText)
+			commandCell := rowStyle.Copy().Width(tableWidth * 2 / 5).Render(command)
+			sizeCell := rowStyle.Copy().Width(tableWidth / 8).Render(pane.Size)
+			status := statusStyle.Render(pane.status())
+			if pane.Logging {
+				status += " 📝"
+			}
+			statusCell := rowStyle.Copy().Width(tableWidth / 4).Render(status)
+
+			row := lipgloss.JoinHorizontal(lipgloss.Top, indexCell, commandCell, sizeCell, statusCell)
+			content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, row))
+			content.WriteString("\n")
+		}
+		content.WriteString("\n")
+	}
+
+	if m.mode == paneJoining {
+		var lines []string
+		for i, w := range m.joinTargets {
+			prefix := "  "
+			if i == m.joinCursor {
+				prefix = "▶ "
+			}
+			lines = append(lines, fmt.Sprintf("%s%s:%s  %s (%d pane(s))", prefix, w.Session, w.Index, w.Name, w.Panes))
+		}
+		prompt := fmt.Sprintf("🧩 Join pane %s into\n\n%s\n\n[Enter] Below • [Tab] Beside • [Esc] Cancel",
+			m.livePanes[m.livePaneCursor].Index, strings.Join(lines, "\n"))
+		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, inputBoxStyle.Render(prompt)))
+		content.WriteString("\n")
+	}
+
+	if m.mode == confirming && m.confirmAction == actionKillPane {
+		confirmText := fmt.Sprintf("⚠️  CLOSE RUNNING PANE %s?\n\nThe process in it will be killed!\n\n[y] Yes  [n] No", m.confirmTarget)
+		confirmView := confirmBoxStyle.Render(confirmText)
+		content.WriteString(lipgloss.Place(m.width, 7, lipgloss.Center, lipgloss.Center, confirmView))
+	}
+
+	if m.message != "" {
+		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, m.renderMessage()))
+		content.WriteString("\n")
+	}
+
+	hints := "[Enter] Attach at pane • [T] Watch • [L/V] Log/view log • [R] Respawn • [x] Close • [b] Break out • [J] Join into • [Esc] Back"
+	if readOnly {
+		hints = "[Enter] Attach at pane • [T] Watch • [V] View log • [Esc] Back • 🔒 Read-only"
+	}
+	statusBar := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("16")).
+		Padding(0, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderTop(true).
+		BorderForeground(primaryColor).
+		Render(hints)
+	content.WriteString("\n")
+	content.WriteString(lipgloss.Place(tableWidth, 1, lipgloss.Center, lipgloss.Top, statusBar))
+
+	return baseStyle.Render(content.String())
+}
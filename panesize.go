@@ -0,0 +1,68 @@
+package main
+
+import "fmt"
+
+// < and > in the template editor shrink and grow the selected pane by a
+// step of the pane it was split off, moving the border between them. The
+// first pane was split off nothing, so it gives room to or takes it from
+// the last pane split off it. Dragging a border with the mouse sets the
+// same split percentages.
+
+// How much of its parent < and > move a pane's border by, in percent.
+const resizeStep = 5
+
+// How far a border can move towards either side of its parent.
+const (
+	minSplit = 5
+	maxSplit = 95
+)
+
+// setSplit gives pane i split percent of the pane it was split off, within
+// bounds, and lays the panes out again. It reports whether that changed
+// anything.
+func (m *model) setSplit(i, split int) bool {
+	panes := m.currentTemplate.Panes
+	split = max(minSplit, min(maxSplit, split))
+	if split == panes[i].SplitPercent {
+		return false
+	}
+	panes[i].SplitPercent = split
+	layoutGeometry(panes)
+	m.calculatePaneLayout()
+	m.setMessage(fmt.Sprintf("Pane %d takes %d%% of its split", panes[i].ID, split), "info")
+	return true
+}
+
+// resizePane grows the selected pane by a step, or shrinks it for a
+// negative step.
+func (m *model) resizePane(step int) {
+	panes := m.currentTemplate.Panes
+	if len(panes) < 2 || m.paneCursor < 0 || m.paneCursor >= len(panes) {
+		m.setMessage("Split the pane first, there is nothing to resize it against", "info")
+		return
+	}
+	i := m.paneCursor
+	if i == 0 {
+		// The first pane is what its children leave over.
+		i = -1
+		for j := len(panes) - 1; j > 0; j-- {
+			if panes[j].Parent == panes[0].ID {
+				i = j
+				break
+			}
+		}
+		if i < 0 {
+			m.setMessage("No pane is split off this one", "info")
+			return
+		}
+		step = -step
+	}
+	state := m.saveEditorState("resizing a pane")
+	split := panes[i].SplitPercent
+	if split <= 0 {
+		split = 50
+	}
+	if m.setSplit(i, split+step) {
+		m.pushEdit(state)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// shellBuiltins are commands the shell runs itself, so they are never on PATH.
+var shellBuiltins = map[string]bool{
+	".": true, ":": true, "[": true, "alias": true, "bg": true, "builtin": true,
+	"case": true, "cd": true, "command": true, "echo": true, "eval": true,
+	"exec": true, "exit": true, "export": true, "false": true, "fg": true,
+	"for": true, "if": true, "jobs": true, "printf": true, "pwd": true,
+	"read": true, "set": true, "source": true, "test": true, "time": true,
+	"trap": true, "true": true, "type": true, "ulimit": true, "umask": true,
+	"unset": true, "until": true, "wait": true, "while": true, "{": true, "(": true,
+}
+
+// commandName returns the program a pane command starts, skipping leading
+// VAR=value assignments, or "" if there is nothing to look up: builtins,
+// relative paths and names filled in from {{placeholders}}.
+func commandName(command string) string {
+	for _, field := range strings.Fields(command) {
+		if i := strings.Index(field, "="); i > 0 && isEnvName(field[:i]) {
+			continue
+		}
+		name := strings.Trim(field, `"'`)
+		switch {
+		case shellBuiltins[name], strings.Contains(name, "{{"), strings.Contains(name, "$"):
+			return ""
+		case strings.Contains(name, "/") && !strings.HasPrefix(name, "/") && !strings.HasPrefix(name, "~/"):
+			return "" // relative to the pane's directory, which may not exist yet
+		}
+		return expandHome(name)
+	}
+	return ""
+}
+
+// missingCommands lists the panes whose command starts a program that isn't
+// on PATH, such as a mistyped "nvmi .".
+func missingCommands(panes []Pane) []string {
+	var missing []string
+	for _, p := range panes {
+		name := commandName(p.Command)
+		if name == "" {
+			continue
+		}
+		if _, err := exec.LookPath(name); err != nil {
+			missing = append(missing, fmt.Sprintf("pane %d: %s not found", p.ID, name))
+		}
+	}
+	return missing
+}
+
+// templateMissingCommands is missingCommands for every window of a template.
+func templateMissingCommands(t SessionTemplate) []string {
+	missing := missingCommands(t.Panes)
+	for i, w := range t.Windows {
+		label := w.Name
+		if label == "" {
+			label = fmt.Sprintf("#%d", i+2)
+		}
+		for _, p := range missingCommands(w.Panes) {
+			missing = append(missing, fmt.Sprintf("window %s: %s", label, p))
+		}
+	}
+	return missing
+}
@@ -0,0 +1,514 @@
+package lazytmux
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tmux runs tmux commands on one server, killing them when ctx is done.
+// Server is the plain local one; lazytmux itself passes its own, which
+// also reaches servers over ssh.
+type Tmux interface {
+	CommandContext(ctx context.Context, args ...string) *exec.Cmd
+}
+
+// Server is a tmux server of this machine: the default one, or the one
+// on the named socket (tmux -L) or at the socket path (tmux -S).
+type Server struct {
+	SocketName string
+	SocketPath string
+}
+
+// CommandContext returns tmux with args, pointed at the server.
+func (s Server) CommandContext(ctx context.Context, args ...string) *exec.Cmd {
+	var argv []string
+	switch {
+	case s.SocketPath != "":
+		argv = append(argv, "-S", s.SocketPath)
+	case s.SocketName != "":
+		argv = append(argv, "-L", s.SocketName)
+	}
+	return exec.CommandContext(ctx, "tmux", append(argv, args...)...)
+}
+
+// ErrSessionExists is returned when a session of the name is already there.
+var ErrSessionExists = errors.New("session already exists")
+
+// What Instantiate and Builder.Claim do when the session already exists.
+const (
+	ExistsFail   = "fail"   // return an error (default)
+	ExistsAttach = "attach" // leave it as it is and report it
+	ExistsSuffix = "suffix" // create name-2, name-3, ... instead
+)
+
+// How many suffixed names ExistsSuffix tries before giving up.
+const maxSuffixAttempts = 100
+
+// Hooks that hang (waiting for a password prompt, say) must not block forever.
+const HookTimeout = 2 * time.Minute
+
+// permanentTmuxErrors are failures that trying again won't fix.
+var permanentTmuxErrors = []string{
+	"no space for new pane",
+	"can't find",
+	"unknown option",
+	"invalid",
+}
+
+// Builder turns templates into sessions on a tmux server. It is what
+// lazytmux itself creates sessions with; NewBuilder gives its defaults.
+type Builder struct {
+	Tmux Tmux
+	// Sessions are tagged with it in @owner, so lazytmux can tell whose
+	// they are.
+	Owner string
+	// How many windows get their panes built at the same time.
+	Concurrency int
+	// How often a failed new-window or split-window is tried again, and
+	// how long to wait before the first retry; each further retry waits
+	// twice as long.
+	Retries int
+	Backoff time.Duration
+	// The lazytmux program panes with wait_for or wait_for_port run to
+	// wait, as its wait subcommand does the waiting.
+	WaitProgram string
+}
+
+// NewBuilder returns a Builder for tmux with lazytmux's defaults: one
+// window at a time, two retries 250ms apart, owned by the current user,
+// waiting through the lazytmux found in PATH.
+func NewBuilder(tmux Tmux) *Builder {
+	owner := strconv.Itoa(os.Getuid())
+	if u, err := user.Current(); err == nil {
+		owner = u.Username
+	}
+	return &Builder{
+		Tmux:        tmux,
+		Owner:       owner,
+		Concurrency: 1,
+		Retries:     2,
+		Backoff:     250 * time.Millisecond,
+		WaitProgram: "lazytmux",
+	}
+}
+
+// NewSession creates an empty, detached session in dir, which may be ""
+// for tmux's default. It returns ErrSessionExists if the name is taken.
+func (b *Builder) NewSession(ctx context.Context, name, dir string) error {
+	args := []string{"new-session", "-ds", name}
+	if dir != "" {
+		args = append(args, "-c", dir)
+	}
+	// Tag the session in the same tmux invocation so it is never seen
+	// without an owner; set-option applies to the session just created.
+	args = append(args, ";", "set-option", "@owner", b.Owner)
+	var stderr bytes.Buffer
+	cmd := b.Tmux.CommandContext(ctx, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if strings.Contains(msg, "duplicate session") {
+			return ErrSessionExists
+		}
+		if msg != "" {
+			return errors.New(msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// sessionExists reports whether a session is named exactly name.
+func (b *Builder) sessionExists(ctx context.Context, name string) bool {
+	return b.Tmux.CommandContext(ctx, "has-session", "-t", "="+name).Run() == nil
+}
+
+// Claim creates an empty session for a template according to the exists
+// policy and returns the name that was used. created is false when the
+// policy chose to reuse a session that was already there.
+func (b *Builder) Claim(ctx context.Context, name, dir, policy string) (sessionName string, created bool, err error) {
+	switch policy {
+	case ExistsFail, ExistsAttach, ExistsSuffix:
+	default:
+		return "", false, fmt.Errorf("unknown --exists value %q (want attach, fail or suffix)", policy)
+	}
+
+	candidate := name
+	for attempt := 1; attempt <= maxSuffixAttempts; attempt++ {
+		err := b.NewSession(ctx, candidate, dir)
+		if err == nil {
+			return candidate, true, nil
+		}
+		if !errors.Is(err, ErrSessionExists) {
+			return "", false, err
+		}
+
+		switch policy {
+		case ExistsFail:
+			return "", false, fmt.Errorf("%w: %s", ErrSessionExists, candidate)
+		case ExistsAttach:
+			// It may have been killed between our attempt and now.
+			if b.sessionExists(ctx, candidate) {
+				return candidate, false, nil
+			}
+		case ExistsSuffix:
+			candidate = fmt.Sprintf("%s-%d", name, attempt+1)
+		}
+	}
+	return "", false, fmt.Errorf("could not find a free session name for '%s'", name)
+}
+
+// Create creates a session from template, killing it again if laying it
+// out fails.
+func (b *Builder) Create(ctx context.Context, sessionName string, template Template) error {
+	if err := b.NewSession(ctx, sessionName, template.StartDir()); err != nil {
+		return err
+	}
+	if err := b.Apply(ctx, sessionName, template); err != nil {
+		// Don't leave a half-built session behind, even when ctx is done.
+		_ = b.Tmux.CommandContext(context.Background(), "kill-session", "-t", "="+sessionName).Run()
+		return err
+	}
+	return nil
+}
+
+// Apply lays out windows and panes of template inside an existing,
+// freshly created session.
+func (b *Builder) Apply(ctx context.Context, sessionName string, template Template) error {
+	// Remembered for the config rules that match on the template.
+	_ = b.Tmux.CommandContext(ctx, "set-option", "-t", sessionName, "@template", template.Name).Run()
+
+	if _, err := RunHook(ctx, template.OnCreate, sessionName, template); err != nil {
+		return fmt.Errorf("on_create hook failed: %v", err)
+	}
+
+	if len(template.Panes) == 0 && len(template.Windows) == 0 {
+		return nil
+	}
+
+	// Lookup initial (only) pane id
+	out, err := b.Tmux.CommandContext(ctx, "list-panes", "-t", sessionName, "-F", "#{pane_id}").Output()
+	if err != nil {
+		return err
+	}
+	baseID := strings.TrimSpace(string(out))
+
+	// Panes created from here on inherit the session environment; the
+	// first pane already runs a shell, so it gets explicit exports.
+	if len(template.Env) > 0 {
+		exports := make([]string, 0, len(template.Env))
+		for _, k := range sortedKeys(template.Env) {
+			if err := b.Tmux.CommandContext(ctx, "set-environment", "-t", sessionName, k, template.Env[k]).Run(); err != nil {
+				return err
+			}
+			exports = append(exports, fmt.Sprintf("export %s=%s", k, shellQuote(template.Env[k])))
+		}
+		_ = b.Tmux.CommandContext(ctx, "send-keys", "-t", baseID, strings.Join(exports, "; "), "C-m").Run()
+	}
+
+	if template.WindowName != "" {
+		_ = b.Tmux.CommandContext(ctx, "rename-window", "-t", baseID, template.WindowName).Run()
+	}
+	// Windows are added one by one so they keep their order; their panes
+	// may then be built side by side, see Concurrency.
+	windowIDs := []string{baseID}
+	windowPanes := [][]Pane{template.Panes}
+	for _, w := range template.Windows {
+		args := []string{"new-window", "-d", "-t", sessionName + ":", "-P", "-F", "#{pane_id}"}
+		if w.Name != "" {
+			args = append(args, "-n", w.Name)
+		}
+		firstDir := ""
+		if len(w.Panes) > 0 {
+			firstDir = w.Panes[0].Dir
+		}
+		if dir := ResolveDir(template.Root, firstDir); dir != "" {
+			args = append(args, "-c", dir)
+		}
+		id, err := b.create(ctx, args...)
+		if err != nil {
+			return err
+		}
+		windowIDs = append(windowIDs, id)
+		windowPanes = append(windowPanes, w.Panes)
+	}
+
+	borderStatus := template.BorderStatus()
+	err = b.forEachWindow(len(windowIDs), func(i int) error {
+		panes := WithEditors(windowPanes[i], template.Root, template.Env)
+		return b.buildPanes(ctx, windowIDs[i], template.Root, panes, borderStatus)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Focus original window and pane
+	_ = b.Tmux.CommandContext(ctx, "select-window", "-t", baseID).Run()
+	_ = b.Tmux.CommandContext(ctx, "select-pane", "-t", baseID).Run()
+	return nil
+}
+
+// buildPanes recreates a pane tree inside the window that owns baseID. The
+// first pane of the tree maps onto baseID itself.
+func (b *Builder) buildPanes(ctx context.Context, baseID, root string, panes []Pane, borderStatus string) error {
+	if borderStatus != "" {
+		_ = b.Tmux.CommandContext(ctx, "set-option", "-w", "-t", baseID, "pane-border-status", borderStatus).Run()
+	}
+	if len(panes) == 0 {
+		return nil
+	}
+
+	idMap := map[int]string{}
+	idMap[panes[0].ID] = baseID
+	b.decoratePane(ctx, baseID, panes[0])
+
+	// Panes that wait for another one start last, once every pane they
+	// might refer to exists.
+	var waiting []Pane
+	start := func(paneID string, p Pane) error {
+		if p.Waits() {
+			waiting = append(waiting, p)
+			return nil
+		}
+		return b.runPaneCommand(ctx, paneID, p)
+	}
+
+	// Command for first pane
+	if err := start(baseID, panes[0]); err != nil {
+		return err
+	}
+
+	// Create others in the given order, always selecting parent before split
+	for i := 1; i < len(panes); i++ {
+		p := panes[i]
+		parentID, ok := idMap[p.Parent]
+		if !ok {
+			// Fallback: split the first pane
+			parentID = baseID
+		}
+
+		args := []string{"split-window", "-t", parentID}
+		switch p.Position {
+		case "left", "right":
+			args = append(args, "-h")
+			if p.Position == "left" {
+				args = append(args, "-b") // place on the left of parent
+			}
+		case "up", "down":
+			args = append(args, "-v")
+			if p.Position == "up" {
+				args = append(args, "-b") // place above parent
+			}
+		default:
+			// default to vertical split
+			args = append(args, "-h")
+		}
+
+		if p.SplitPercent > 0 && p.SplitPercent != 50 {
+			args = append(args, "-p", strconv.Itoa(p.SplitPercent))
+		}
+
+		if dir := ResolveDir(root, p.Dir); dir != "" {
+			args = append(args, "-c", dir)
+		}
+
+		// Print new pane id
+		args = append(args, "-P", "-F", "#{pane_id}")
+
+		newID, err := b.create(ctx, args...)
+		if err != nil {
+			return err
+		}
+		idMap[p.ID] = newID
+		b.decoratePane(ctx, newID, p)
+
+		if err := start(newID, p); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range waiting {
+		if strings.TrimSpace(p.Command) == "" {
+			continue
+		}
+		p.Command = b.waitCommand(p, idMap[p.WaitForPane])
+		if err := b.runPaneCommand(ctx, idMap[p.ID], p); err != nil {
+			return err
+		}
+	}
+
+	_ = b.Tmux.CommandContext(ctx, "select-pane", "-t", baseID).Run()
+	return nil
+}
+
+// runPaneCommand starts the pane's command. Normally it is typed into the
+// pane's shell; with RemainOnExit the command replaces the shell, so its
+// exit status stays visible once it finishes.
+func (b *Builder) runPaneCommand(ctx context.Context, paneID string, p Pane) error {
+	cmd := strings.TrimSpace(p.Command)
+	if cmd == "" {
+		return nil
+	}
+	if p.RemainOnExit {
+		if err := b.Tmux.CommandContext(ctx, "set-option", "-p", "-t", paneID, "remain-on-exit", "on").Run(); err != nil {
+			return err
+		}
+		return b.Tmux.CommandContext(ctx, "respawn-pane", "-k", "-t", paneID, cmd).Run()
+	}
+	_ = b.Tmux.CommandContext(ctx, "send-keys", "-t", paneID, cmd, "C-m").Run()
+	return nil
+}
+
+// decoratePane applies the optional title and border style of a template pane.
+func (b *Builder) decoratePane(ctx context.Context, paneID string, p Pane) {
+	if p.Title != "" {
+		_ = b.Tmux.CommandContext(ctx, "select-pane", "-t", paneID, "-T", p.Title).Run()
+	}
+	if p.BorderStyle != "" {
+		// Pane scoped options need tmux 3.2 or newer; older servers just ignore it.
+		_ = b.Tmux.CommandContext(ctx, "set-option", "-p", "-t", paneID, "pane-border-style", p.BorderStyle).Run()
+		_ = b.Tmux.CommandContext(ctx, "set-option", "-p", "-t", paneID, "pane-active-border-style", p.BorderStyle).Run()
+	}
+}
+
+// waitCommand returns the pane command prefixed with a call to the wait
+// subcommand of WaitProgram, so the pane itself does the waiting and the
+// caller is free to attach or exit right away. depID is the tmux id of the
+// WaitForPane pane.
+func (b *Builder) waitCommand(p Pane, depID string) string {
+	args := []string{shellQuote(b.WaitProgram), "wait"}
+	if p.WaitFor != "" {
+		args = append(args, "--pane", depID, "--text", shellQuote(p.WaitFor))
+	}
+	if p.WaitForPort > 0 {
+		args = append(args, "--port", strconv.Itoa(p.WaitForPort))
+	}
+	if p.WaitTimeout > 0 {
+		args = append(args, "--timeout", strconv.Itoa(p.WaitTimeout))
+	}
+	return strings.Join(args, " ") + " && " + strings.TrimSpace(p.Command)
+}
+
+// create runs a tmux command that creates a window or pane and returns
+// what it printed, retrying failures as Retries and Backoff say.
+func (b *Builder) create(ctx context.Context, args ...string) (string, error) {
+	wait := b.Backoff
+	for attempt := 0; ; attempt++ {
+		var stdout, stderr bytes.Buffer
+		cmd := b.Tmux.CommandContext(ctx, args...)
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		err := cmd.Run()
+		if err == nil {
+			return strings.TrimSpace(stdout.String()), nil
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if msg != "" {
+			err = errors.New(msg)
+		}
+		if attempt >= b.Retries || isPermanentTmuxError(msg) {
+			return "", err
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		wait *= 2
+	}
+}
+
+func isPermanentTmuxError(msg string) bool {
+	for _, permanent := range permanentTmuxErrors {
+		if strings.Contains(msg, permanent) {
+			return true
+		}
+	}
+	return false
+}
+
+// forEachWindow calls build for every window index, up to Concurrency at
+// a time, and returns the first error.
+func (b *Builder) forEachWindow(n int, build func(i int) error) error {
+	limit := b.Concurrency
+	if limit <= 1 {
+		for i := 0; i < n; i++ {
+			if err := build(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+	)
+	slots := make(chan struct{}, limit)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := build(i); err != nil {
+				once.Do(func() { first = err })
+			}
+		}(i)
+	}
+	wg.Wait()
+	return first
+}
+
+// RunHook runs a template hook through sh in the template's root directory
+// with the template environment plus LAZYTMUX_SESSION and LAZYTMUX_TEMPLATE.
+// The combined output is returned in both cases.
+func RunHook(ctx context.Context, command, sessionName string, template Template) (string, error) {
+	if strings.TrimSpace(command) == "" {
+		return "", nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, HookTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = template.StartDir()
+	cmd.Env = os.Environ()
+	for _, k := range sortedKeys(template.Env) {
+		cmd.Env = append(cmd.Env, k+"="+template.Env[k])
+	}
+	cmd.Env = append(cmd.Env, "LAZYTMUX_SESSION="+sessionName, "LAZYTMUX_TEMPLATE="+template.Name)
+
+	out, err := cmd.CombinedOutput()
+	output := strings.TrimSpace(string(out))
+	if ctx.Err() == context.DeadlineExceeded {
+		return output, fmt.Errorf("timed out after %s", HookTimeout)
+	}
+	if err != nil {
+		if last := lastLine(output); last != "" {
+			return output, fmt.Errorf("%v: %s", err, last)
+		}
+		return output, err
+	}
+	return output, nil
+}
+
+// lastLine returns the last non-empty line of s.
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if l := strings.TrimSpace(lines[i]); l != "" {
+			return l
+		}
+	}
+	return ""
+}
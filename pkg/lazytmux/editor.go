@@ -0,0 +1,73 @@
+package lazytmux
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Panes of type "editor" run the user's editor on the pane's directory, so
+// a template that opens an editor works on machines with different editors.
+const PaneTypeEditor = "editor"
+
+// editorSessionFiles are the session files editors restore from, by the
+// name of the editor program, with the flag that loads them.
+var editorSessionFiles = map[string][2]string{
+	"vim":  {"-S", "Session.vim"},
+	"nvim": {"-S", "Session.vim"},
+	"gvim": {"-S", "Session.vim"},
+	"mvim": {"-S", "Session.vim"},
+}
+
+// EditorProgram returns the editor command: $VISUAL or $EDITOR as the
+// template's env sets them, else as this process sees them, else vi.
+func EditorProgram(env map[string]string) string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if v := strings.TrimSpace(env[name]); v != "" {
+			return v
+		}
+	}
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if v := strings.TrimSpace(os.Getenv(name)); v != "" {
+			return v
+		}
+	}
+	return "vi"
+}
+
+// EditorCommand is the command of an editor pane starting in dir. The pane's
+// own command, if any, is passed on to the editor as arguments; else it
+// opens the directory, restoring the editor's session file when dir has one.
+func EditorCommand(p Pane, dir string, env map[string]string) string {
+	editor := EditorProgram(env)
+	if args := strings.TrimSpace(p.Command); args != "" {
+		return editor + " " + args
+	}
+	fields := strings.Fields(editor)
+	if len(fields) > 0 {
+		if restore, ok := editorSessionFiles[filepath.Base(fields[0])]; ok {
+			if _, err := os.Stat(filepath.Join(dir, restore[1])); err == nil {
+				return editor + " " + restore[0] + " " + restore[1]
+			}
+		}
+	}
+	return editor + " ."
+}
+
+// WithEditors returns panes with the commands of editor panes filled in.
+// Without a root or dir, panes start in the current directory.
+func WithEditors(panes []Pane, root string, env map[string]string) []Pane {
+	out := make([]Pane, len(panes))
+	copy(out, panes)
+	for i, p := range out {
+		if p.Type != PaneTypeEditor {
+			continue
+		}
+		dir := ResolveDir(root, p.Dir)
+		if dir == "" {
+			dir, _ = os.Getwd()
+		}
+		out[i].Command = EditorCommand(p, dir, env)
+	}
+	return out
+}
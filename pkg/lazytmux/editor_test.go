@@ -0,0 +1,64 @@
+package lazytmux
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEditorProgram(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		visual     string
+		editor     string
+		wantEditor string
+	}{
+		{"nothing set", nil, "", "", "vi"},
+		{"EDITOR", nil, "", "nano", "nano"},
+		{"VISUAL before EDITOR", nil, "code --wait", "nano", "code --wait"},
+		{"template EDITOR before VISUAL", map[string]string{"EDITOR": "hx"}, "code", "nano", "hx"},
+		{"template VISUAL before its EDITOR", map[string]string{"VISUAL": "nvim", "EDITOR": "hx"}, "", "", "nvim"},
+		{"blank template values are skipped", map[string]string{"VISUAL": "  "}, "", "emacs", "emacs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("VISUAL", tt.visual)
+			t.Setenv("EDITOR", tt.editor)
+			if got := EditorProgram(tt.env); got != tt.wantEditor {
+				t.Errorf("EditorProgram(%v) = %q, want %q", tt.env, got, tt.wantEditor)
+			}
+		})
+	}
+}
+
+func TestEditorCommand(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	plain := t.TempDir()
+	restorable := t.TempDir()
+	if err := os.WriteFile(filepath.Join(restorable, "Session.vim"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		editor string
+		pane   Pane
+		dir    string
+		want   string
+	}{
+		{"opens the directory", "vim", Pane{Type: PaneTypeEditor}, plain, "vim ."},
+		{"restores Session.vim", "vim", Pane{Type: PaneTypeEditor}, restorable, "vim -S Session.vim"},
+		{"restores with a full path", "/usr/bin/nvim", Pane{Type: PaneTypeEditor}, restorable, "/usr/bin/nvim -S Session.vim"},
+		{"other editors ignore Session.vim", "nano", Pane{Type: PaneTypeEditor}, restorable, "nano ."},
+		{"command is passed as arguments", "vim", Pane{Type: PaneTypeEditor, Command: " main.go +10 "}, restorable, "vim main.go +10"},
+		{"editor keeps its own arguments", "code --wait", Pane{Type: PaneTypeEditor, Command: "README.md"}, plain, "code --wait README.md"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("EDITOR", tt.editor)
+			if got := EditorCommand(tt.pane, tt.dir, nil); got != tt.want {
+				t.Errorf("EditorCommand(%+v, %s) = %q, want %q", tt.pane, tt.dir, got, tt.want)
+			}
+		})
+	}
+}
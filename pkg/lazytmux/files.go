@@ -0,0 +1,297 @@
+package lazytmux
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Templates live in TemplatesDir, one file each, so they can be kept in
+// git, shared and edited by hand without touching each other. Files may be
+// JSON or YAML, with the same field names.
+
+// TemplatesDir is where lazytmux keeps its templates.
+func TemplatesDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "lazytmux", "templates")
+}
+
+// TemplateFiles lists the template files in dir in name order.
+func TemplateFiles(dir string) []string {
+	var files []string
+	for _, pattern := range []string{"*.json", "*.yaml", "*.yml"} {
+		matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+		for _, m := range matches {
+			// Skip editor backups and lazytmux's temporary files.
+			if !strings.HasPrefix(filepath.Base(m), ".") {
+				files = append(files, m)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// ReadTemplateFile loads one template file. Templates without a name are
+// named after their file.
+func ReadTemplateFile(path string) (Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Template{}, err
+	}
+	template, err := DecodeTemplate(data)
+	if err != nil {
+		return Template{}, fmt.Errorf("%s: %v", filepath.Base(path), err)
+	}
+	if template.Name == "" {
+		template.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return template, nil
+}
+
+// LoadTemplates returns the templates saved in TemplatesDir. A file that
+// can't be read, or names a template another file already defines, is
+// left out and reported in the error, alongside the templates that could
+// be read.
+func LoadTemplates() ([]Template, error) {
+	var errs []error
+	templates := []Template{}
+	seen := map[string]string{}
+	for _, path := range TemplateFiles(TemplatesDir()) {
+		template, err := ReadTemplateFile(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if other, dup := seen[template.Name]; dup {
+			errs = append(errs, fmt.Errorf("%s: template '%s' is already defined in %s", filepath.Base(path), template.Name, filepath.Base(other)))
+			continue
+		}
+		seen[template.Name] = path
+		templates = append(templates, template)
+	}
+	return templates, errors.Join(errs...)
+}
+
+// DecodeTemplate reads a template in either format. Anything that does not
+// start with '{' is taken to be YAML, whose scalars are converted to the
+// type of the field they land in, so "split_percent: '30'" works just as
+// well as 30.
+func DecodeTemplate(data []byte) (Template, error) {
+	var template Template
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		err := json.Unmarshal(data, &template)
+		return template, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var doc yaml.Node
+	if err := dec.Decode(&doc); err == io.EOF {
+		return template, nil
+	} else if err != nil {
+		return Template{}, err
+	}
+	// Decoding stops after the first document; reading on catches what
+	// follows it but isn't a document, such as a line indented less than
+	// the first.
+	var next yaml.Node
+	if err := dec.Decode(&next); err != nil && err != io.EOF {
+		return Template{}, err
+	}
+	err := decodeYAML(&doc, reflect.ValueOf(&template).Elem(), "")
+	return template, err
+}
+
+// decodeYAML decodes a YAML node into v, naming struct fields after their
+// json tags.
+func decodeYAML(n *yaml.Node, v reflect.Value, path string) error {
+	fail := func(want string) error {
+		if path == "" {
+			return fmt.Errorf("expected %s", want)
+		}
+		return fmt.Errorf("%s: expected %s", path, want)
+	}
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+	switch n.Kind {
+	case 0:
+		return nil
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil
+		}
+		return decodeYAML(n.Content[0], v, path)
+	case yaml.AliasNode:
+		return decodeYAML(n.Alias, v, path)
+	}
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!null" {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decodeYAML(n, v.Elem(), path)
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			return fail("a mapping")
+		}
+		pairs, err := yamlPairs(n)
+		if err != nil {
+			return err
+		}
+		fields := map[string]int{}
+		for i := 0; i < v.NumField(); i++ {
+			if name, ok := jsonFieldName(v.Type().Field(i)); ok {
+				fields[name] = i
+			}
+		}
+		for _, p := range pairs {
+			if i, ok := fields[p[0].Value]; ok {
+				if err := decodeYAML(p[1], v.Field(i), join(p[0].Value)); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			return fail("a mapping")
+		}
+		pairs, err := yamlPairs(n)
+		if err != nil {
+			return err
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		for _, p := range pairs {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := decodeYAML(p[1], elem, join(p[0].Value)); err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(p[0].Value).Convert(v.Type().Key()), elem)
+		}
+	case reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			return fail("a list")
+		}
+		slice := reflect.MakeSlice(v.Type(), len(n.Content), len(n.Content))
+		for i, item := range n.Content {
+			if err := decodeYAML(item, slice.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.String:
+		if n.Kind != yaml.ScalarNode {
+			return fail("a string")
+		}
+		v.SetString(n.Value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(n.Value, 10, 64)
+		if n.Kind != yaml.ScalarNode || err != nil {
+			return fail("a number")
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(n.Value, 10, 64)
+		if n.Kind != yaml.ScalarNode || err != nil {
+			return fail("a positive number")
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(n.Value, 64)
+		if n.Kind != yaml.ScalarNode || err != nil {
+			return fail("a number")
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		switch strings.ToLower(n.Value) {
+		case "true", "yes", "on":
+			v.SetBool(true)
+		case "false", "no", "off":
+			v.SetBool(false)
+		default:
+			return fail("true or false")
+		}
+	default:
+		return fail(v.Kind().String())
+	}
+	return nil
+}
+
+// yamlPairs returns the key and value nodes of a mapping, with the keys
+// of "<<" merges that the mapping doesn't set itself.
+func yamlPairs(n *yaml.Node) ([][2]*yaml.Node, error) {
+	var pairs, merged [][2]*yaml.Node
+	set := map[string]bool{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		if key.Kind == yaml.ScalarNode && key.Tag == "!!merge" {
+			for value.Kind == yaml.AliasNode {
+				value = value.Alias
+			}
+			sources := []*yaml.Node{value}
+			if value.Kind == yaml.SequenceNode {
+				sources = value.Content
+			}
+			for _, source := range sources {
+				for source.Kind == yaml.AliasNode {
+					source = source.Alias
+				}
+				if source.Kind != yaml.MappingNode {
+					return nil, fmt.Errorf("line %d: << has to merge a mapping", value.Line)
+				}
+				more, err := yamlPairs(source)
+				if err != nil {
+					return nil, err
+				}
+				merged = append(merged, more...)
+			}
+			continue
+		}
+		if key.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("line %d: only plain keys are supported", key.Line)
+		}
+		pairs = append(pairs, [2]*yaml.Node{key, value})
+		set[key.Value] = true
+	}
+	for _, p := range merged {
+		if !set[p[0].Value] {
+			pairs = append(pairs, p)
+			set[p[0].Value] = true
+		}
+	}
+	return pairs, nil
+}
+
+// jsonFieldName returns the key a struct field is stored under.
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" {
+		return "", false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
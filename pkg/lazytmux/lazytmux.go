@@ -0,0 +1,168 @@
+// Package lazytmux creates tmux workspaces from lazytmux templates in other
+// Go programs, like deployment tools and project generators.
+//
+// It is the engine the lazytmux binary itself lays sessions out with, so a
+// template builds the same session here as from the TUI, with its hooks,
+// editor panes, waits and retries. Nothing but tmux has to be running.
+//
+//	tmpl := lazytmux.Template{
+//		Name: "billing",
+//		Root: "/srv/billing",
+//		Panes: []lazytmux.Pane{
+//			{ID: 1, Position: "main", Command: "make run"},
+//			{ID: 2, Position: "right", Parent: 1, SplitPercent: 40, Command: "make logs"},
+//		},
+//	}
+//	created, err := lazytmux.Instantiate(ctx, tmpl, lazytmux.Options{Exists: lazytmux.ExistsAttach})
+package lazytmux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options tune Instantiate. The zero value creates a session named after
+// the template on the default tmux server, with its default variables,
+// failing if one exists.
+type Options struct {
+	Name   string            // session to create, defaults to the template name
+	Vars   map[string]string // values for {{placeholders}}
+	Exists string            // ExistsFail, ExistsAttach or ExistsSuffix
+	// The tmux server to create the session on; defaults to Server{}.
+	Tmux Tmux
+}
+
+// Created is the session Instantiate made or found.
+type Created struct {
+	Session string `json:"session"`
+	Created bool   `json:"created"` // false if ExistsAttach found it running
+}
+
+// Session is a running session down to its panes.
+type Session struct {
+	Name     string      `json:"name"`
+	Windows  int         `json:"windows"`
+	Created  *time.Time  `json:"created,omitempty"`
+	Attached bool        `json:"attached"`
+	Owner    string      `json:"owner,omitempty"`
+	Dir      string      `json:"dir,omitempty"`
+	Command  string      `json:"command,omitempty"`
+	Template string      `json:"template,omitempty"` // the template it was created from
+	Panes    []PaneState `json:"panes"`
+}
+
+// PaneState is a pane running in a session.
+type PaneState struct {
+	ID         string `json:"id"`
+	Index      string `json:"index"` // "window.pane"
+	Command    string `json:"command"`
+	Title      string `json:"title,omitempty"`
+	Size       string `json:"size"`
+	Dead       bool   `json:"dead,omitempty"`
+	ExitStatus int    `json:"exit_status,omitempty"`
+}
+
+// Instantiate creates a session from tmpl, which doesn't have to be saved,
+// without attaching to it. A session that fails to build is killed again.
+func Instantiate(ctx context.Context, tmpl Template, opts Options) (Created, error) {
+	template, err := tmpl.WithVars(opts.Vars)
+	if err != nil {
+		return Created{}, err
+	}
+	name := opts.Name
+	if name == "" {
+		name = template.Name
+	}
+	if name == "" {
+		return Created{}, errors.New("no session name given")
+	}
+	exists := opts.Exists
+	if exists == "" {
+		exists = ExistsFail
+	}
+	var tmux Tmux = Server{}
+	if opts.Tmux != nil {
+		tmux = opts.Tmux
+	}
+
+	b := NewBuilder(tmux)
+	name, created, err := b.Claim(ctx, name, template.StartDir(), exists)
+	if err != nil {
+		return Created{}, err
+	}
+	if created {
+		if err := b.Apply(ctx, name, template); err != nil {
+			_ = tmux.CommandContext(context.Background(), "kill-session", "-t", "="+name).Run()
+			return Created{}, fmt.Errorf("failed to create session from template: %v", err)
+		}
+	}
+	return Created{Session: name, Created: created}, nil
+}
+
+// Snapshot describes the named session of the default tmux server and its
+// panes.
+func Snapshot(ctx context.Context, session string) (Session, error) {
+	return SnapshotOf(ctx, Server{}, session)
+}
+
+// SnapshotOf describes the named session of a tmux server and its panes.
+func SnapshotOf(ctx context.Context, tmux Tmux, session string) (Session, error) {
+	if session == "" {
+		return Session{}, errors.New("no session given")
+	}
+	// display-message falls back to the current session rather than fail
+	// when the target is gone, so it is checked for first.
+	if tmux.CommandContext(ctx, "has-session", "-t", "="+session).Run() != nil {
+		return Session{}, fmt.Errorf("no session named '%s'", session)
+	}
+	format := strings.Join([]string{"#S", "#{session_windows}", "#{session_created}", "#{session_attached}", "#{@owner}", "#{pane_current_path}", "#{pane_current_command}", "#{@template}"}, "\t")
+	out, err := tmux.CommandContext(ctx, "display-message", "-p", "-t", "="+session+":", format).Output()
+	if err != nil {
+		return Session{}, err
+	}
+	parts := strings.SplitN(strings.TrimRight(string(out), "\n"), "\t", 8)
+	if len(parts) < 8 {
+		return Session{}, fmt.Errorf("unexpected tmux output for '%s'", session)
+	}
+	s := Session{
+		Name:     parts[0],
+		Attached: parts[3] != "" && parts[3] != "0",
+		Owner:    parts[4],
+		Dir:      parts[5],
+		Command:  parts[6],
+		Template: parts[7],
+	}
+	s.Windows, _ = strconv.Atoi(parts[1])
+	if ts, err := strconv.ParseInt(parts[2], 10, 64); err == nil {
+		created := time.Unix(ts, 0)
+		s.Created = &created
+	}
+
+	format = strings.Join([]string{"#{pane_id}", "#{window_index}.#{pane_index}", "#{pane_current_command}", "#{pane_dead}", "#{pane_dead_status}", "#{pane_width}x#{pane_height}", "#{pane_title}"}, "\t")
+	out, err = tmux.CommandContext(ctx, "list-panes", "-s", "-t", "="+session, "-F", format).Output()
+	if err != nil {
+		return Session{}, err
+	}
+	s.Panes = []PaneState{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		parts := strings.SplitN(line, "\t", 7)
+		if len(parts) < 7 {
+			continue
+		}
+		status, _ := strconv.Atoi(parts[4])
+		s.Panes = append(s.Panes, PaneState{
+			ID:         parts[0],
+			Index:      parts[1],
+			Command:    parts[2],
+			Dead:       parts[3] == "1",
+			ExitStatus: status,
+			Size:       parts[5],
+			Title:      parts[6],
+		})
+	}
+	return s, nil
+}
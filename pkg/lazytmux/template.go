@@ -0,0 +1,272 @@
+package lazytmux
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Pane is one pane of a template window. Position is "main" for the first
+// pane and "left", "right", "up" or "down" of its Parent for the others.
+type Pane struct {
+	ID           int    `json:"id"`
+	Command      string `json:"command"`
+	Position     string `json:"position"`                 // "main", "left", "right", "up", "down"
+	Parent       int    `json:"parent"`                   // ID of parent pane
+	SplitPercent int    `json:"split_percent"`            // percentage for split (default 50)
+	Row          int    `json:"row"`                      // Visual row position
+	Col          int    `json:"col"`                      // Visual column position
+	Width        int    `json:"width"`                    // Visual width
+	Height       int    `json:"height"`                   // Visual height
+	Title        string `json:"title,omitempty"`          // Shown in the tmux pane border
+	BorderStyle  string `json:"border_style,omitempty"`   // tmux style for the border, e.g. "fg=red"
+	RemainOnExit bool   `json:"remain_on_exit,omitempty"` // Run command as the pane process and keep the pane when it exits
+	Dir          string `json:"dir,omitempty"`            // Working directory, relative paths are resolved against the template root
+	WaitForPane  int    `json:"wait_for_pane,omitempty"`  // ID of a pane in the same window whose output wait_for is looked for in
+	WaitFor      string `json:"wait_for,omitempty"`       // Hold the command until this text shows up in wait_for_pane
+	WaitForPort  int    `json:"wait_for_port,omitempty"`  // Hold the command until this port on localhost is open
+	WaitTimeout  int    `json:"wait_timeout,omitempty"`   // Seconds to wait before giving up (default 60)
+	Type         string `json:"type,omitempty"`           // "editor" runs $EDITOR, see editor.go
+}
+
+// Window is a window after the first one of a template.
+type Window struct {
+	Name  string `json:"name,omitempty"`
+	Panes []Pane `json:"panes"`
+}
+
+// Template is a lazytmux template, with the fields and JSON names of the
+// template files.
+type Template struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"` // Made optional
+	Tags        []string `json:"tags,omitempty"`        // Free-form labels the browser can filter by
+	WindowName  string   `json:"window_name,omitempty"` // Name of the first window
+	Panes       []Pane   `json:"panes"`                 // Panes of the first window
+	Windows     []Window `json:"windows,omitempty"`     // Additional windows, created in order
+	// Where tmux draws pane titles: "top", "bottom" or "off". Defaults to
+	// "top" as soon as any pane has a title.
+	PaneBorderStatus string `json:"pane_border_status,omitempty"`
+	// Environment set on the session before any pane command runs.
+	Env map[string]string `json:"env,omitempty"`
+	// Working directory of the session; panes without a dir start here.
+	Root string `json:"root,omitempty"`
+	// Default values for {{placeholders}} used in commands and paths.
+	Variables map[string]string `json:"variables,omitempty"`
+	// Named sets of variable values to start the template with.
+	Presets map[string]map[string]string `json:"presets,omitempty"`
+	// Shell commands run before the panes are laid out and right before
+	// lazytmux attaches to a freshly created session.
+	OnCreate string `json:"on_create,omitempty"`
+	OnAttach string `json:"on_attach,omitempty"`
+	// What creating a session from the template does: "attach", "stay" or
+	// "read-only". Overrides the after_create config option.
+	AfterCreate string `json:"after_create,omitempty"`
+}
+
+// Waits reports whether the pane delays its command until something else
+// is ready.
+func (p Pane) Waits() bool {
+	return p.WaitFor != "" || p.WaitForPort > 0
+}
+
+// BorderStatus returns the pane-border-status to apply to the template's
+// windows, or "" to leave the tmux default alone.
+func (t Template) BorderStatus() string {
+	if t.PaneBorderStatus != "" {
+		return t.PaneBorderStatus
+	}
+	hasTitle := func(panes []Pane) bool {
+		for _, p := range panes {
+			if p.Title != "" {
+				return true
+			}
+		}
+		return false
+	}
+	if hasTitle(t.Panes) {
+		return "top"
+	}
+	for _, w := range t.Windows {
+		if hasTitle(w.Panes) {
+			return "top"
+		}
+	}
+	return ""
+}
+
+// StartDir is the directory of the session's very first pane.
+func (t Template) StartDir() string {
+	if len(t.Panes) > 0 {
+		return ResolveDir(t.Root, t.Panes[0].Dir)
+	}
+	return ResolveDir(t.Root, "")
+}
+
+// Clone returns a copy of the template that shares no panes, windows or
+// maps with the original.
+func (t Template) Clone() Template {
+	out := t
+	out.Panes = append([]Pane(nil), t.Panes...)
+	if t.Windows != nil {
+		out.Windows = make([]Window, len(t.Windows))
+		for i, w := range t.Windows {
+			w.Panes = append([]Pane(nil), w.Panes...)
+			out.Windows[i] = w
+		}
+	}
+	if t.Env != nil {
+		out.Env = make(map[string]string, len(t.Env))
+		for k, v := range t.Env {
+			out.Env[k] = v
+		}
+	}
+	if t.Variables != nil {
+		out.Variables = make(map[string]string, len(t.Variables))
+		for k, v := range t.Variables {
+			out.Variables[k] = v
+		}
+	}
+	if t.Presets != nil {
+		out.Presets = make(map[string]map[string]string, len(t.Presets))
+		for name, values := range t.Presets {
+			out.Presets[name] = values
+		}
+	}
+	return out
+}
+
+var placeholderRe = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// placeholderFields returns pointers to every field of the template that may contain
+// {{placeholders}}, in a stable order.
+func (t *Template) placeholderFields() []*string {
+	fields := []*string{&t.Root, &t.WindowName}
+	addPanes := func(panes []Pane) {
+		for i := range panes {
+			fields = append(fields, &panes[i].Command, &panes[i].Dir, &panes[i].Title)
+		}
+	}
+	addPanes(t.Panes)
+	for i := range t.Windows {
+		fields = append(fields, &t.Windows[i].Name)
+		addPanes(t.Windows[i].Panes)
+	}
+	return fields
+}
+
+// Placeholders lists the placeholder names used by the template in order
+// of first appearance.
+func (t Template) Placeholders() []string {
+	var names []string
+	seen := map[string]bool{}
+	add := func(s string) {
+		for _, match := range placeholderRe.FindAllStringSubmatch(s, -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				names = append(names, match[1])
+			}
+		}
+	}
+	for _, f := range t.placeholderFields() {
+		add(*f)
+	}
+	for _, k := range sortedKeys(t.Env) {
+		add(t.Env[k])
+	}
+	return names
+}
+
+// WithVars returns a copy of the template with every placeholder replaced.
+// Unknown placeholders fall back to the template defaults; it is an error if
+// neither provides a value.
+func (t Template) WithVars(values map[string]string) (Template, error) {
+	var missing []string
+	lookup := func(name string) string {
+		if v, ok := values[name]; ok {
+			return v
+		}
+		if v, ok := t.Variables[name]; ok {
+			return v
+		}
+		missing = append(missing, name)
+		return ""
+	}
+	expand := func(s string) string {
+		return placeholderRe.ReplaceAllStringFunc(s, func(m string) string {
+			return lookup(placeholderRe.FindStringSubmatch(m)[1])
+		})
+	}
+
+	out := t.Clone()
+	for k, v := range out.Env {
+		out.Env[k] = expand(v)
+	}
+	for _, f := range out.placeholderFields() {
+		*f = expand(*f)
+	}
+
+	if len(missing) > 0 {
+		return Template{}, fmt.Errorf("no value for %s", strings.Join(uniqueStrings(missing), ", "))
+	}
+	return out, nil
+}
+
+// ExpandHome replaces a leading ~ with the user's home directory.
+func ExpandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+// ResolveDir returns the directory a pane should start in, or "" to let
+// tmux pick its default.
+func ResolveDir(root, dir string) string {
+	root = ExpandHome(root)
+	dir = ExpandHome(dir)
+	switch {
+	case dir == "":
+		dir = root
+	case !filepath.IsAbs(dir) && root != "":
+		dir = filepath.Join(root, dir)
+	}
+	if dir == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func uniqueStrings(list []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, s := range list {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// shellQuote wraps s in single quotes for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,100 @@
+package lazytmux
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWithVars(t *testing.T) {
+	template := Template{
+		Name:      "api",
+		Root:      "~/src/{{ project }}",
+		Env:       map[string]string{"BRANCH": "{{branch}}"},
+		Variables: map[string]string{"branch": "main"},
+		Panes:     []Pane{{ID: 1, Position: "main", Command: "git checkout {{branch}} && make {{target}}"}},
+	}
+	if got, want := template.Placeholders(), []string{"project", "branch", "target"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Placeholders() = %q, want %q", got, want)
+	}
+
+	out, err := template.WithVars(map[string]string{"project": "billing", "target": "run"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Root != "~/src/billing" || out.Env["BRANCH"] != "main" || out.Panes[0].Command != "git checkout main && make run" {
+		t.Errorf("WithVars filled in %+v", out)
+	}
+	if template.Panes[0].Command != "git checkout {{branch}} && make {{target}}" || template.Env["BRANCH"] != "{{branch}}" {
+		t.Errorf("WithVars changed the original template: %+v", template)
+	}
+
+	if _, err := template.WithVars(nil); err == nil || !strings.Contains(err.Error(), "project, target") {
+		t.Errorf("WithVars(nil) = %v, want an error naming project and target", err)
+	}
+}
+
+func TestResolveDir(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	tests := []struct {
+		root, dir, want string
+	}{
+		{"", "", ""},
+		{"/srv/api", "", "/srv/api"},
+		{"/srv/api", "web", "/srv/api/web"},
+		{"/srv/api", "/tmp", "/tmp"},
+		{"~/src", "api", filepath.Join(home, "src/api")},
+		{"", "~", home},
+	}
+	for _, tt := range tests {
+		if got := ResolveDir(tt.root, tt.dir); got != tt.want {
+			t.Errorf("ResolveDir(%q, %q) = %q, want %q", tt.root, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeTemplate(t *testing.T) {
+	yaml := `
+defaults: &pane
+  position: right
+  parent: 1
+name: api
+panes:
+  - id: 1
+    position: main
+    command: make run
+  - <<: *pane
+    id: 2
+    split_percent: "30"
+    remain_on_exit: yes
+`
+	template, err := DecodeTemplate([]byte(yaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Pane{
+		{ID: 1, Position: "main", Command: "make run"},
+		{ID: 2, Position: "right", Parent: 1, SplitPercent: 30, RemainOnExit: true},
+	}
+	if template.Name != "api" || !reflect.DeepEqual(template.Panes, want) {
+		t.Errorf("decoded %+v", template)
+	}
+
+	json := `{"name": "api", "panes": [{"id": 1, "position": "main"}]}`
+	if template, err := DecodeTemplate([]byte(json)); err != nil || template.Name != "api" || len(template.Panes) != 1 {
+		t.Errorf("DecodeTemplate(json) = %+v, %v", template, err)
+	}
+
+	for _, bad := range []string{
+		"name: api\npanes: vim",
+		"name: api\npanes:\n  - id: one",
+		"  name: api\nroot: x",
+		"<<: [a]",
+	} {
+		if _, err := DecodeTemplate([]byte(bad)); err == nil {
+			t.Errorf("DecodeTemplate(%q) gave no error", bad)
+		}
+	}
+}
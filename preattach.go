@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// pre_attach runs in the terminal that is about to attach, right before
+// tmux takes it over, so it can prompt (ssh-add asking for a passphrase)
+// and its output is seen. Printed and copied attach commands leave it to
+// whoever runs them.
+
+func preAttachBlocks() bool {
+	return config.PreAttachBlocking == "on"
+}
+
+// runPreAttach runs pre_attach for a session in this terminal. A failure
+// is an error when it blocks the attach and a warning otherwise.
+func runPreAttach(name string) error {
+	command := strings.TrimSpace(config.PreAttach)
+	if command == "" {
+		return nil
+	}
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(), "LAZYTMUX_SESSION="+name)
+	err := cmd.Run()
+	if err == nil {
+		return nil
+	}
+	if preAttachBlocks() {
+		return fmt.Errorf("pre_attach failed: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "Warning: pre_attach failed: %v\n", err)
+	return nil
+}
+
+// withPreAttach has a terminal run pre_attach before the tmux (or ssh)
+// command it was given. A failure waits for Enter, so it can be read before
+// the window closes or tmux covers it.
+func withPreAttach(args []string, name string) []string {
+	command := strings.TrimSpace(config.PreAttach)
+	if command == "" {
+		return args
+	}
+	onFailure := `echo "pre_attach failed, press Enter to attach anyway"; read _`
+	if preAttachBlocks() {
+		onFailure = `echo "pre_attach failed, not attaching. Press Enter to close"; read _; exit 1`
+	}
+	script := fmt.Sprintf("export LAZYTMUX_SESSION=%s; { %s\n} || { %s; }; exec \"$@\"", shellQuote(name), command, onFailure)
+	for i, arg := range args {
+		if arg == "tmux" || arg == "ssh" {
+			out := append([]string{}, args[:i]...)
+			out = append(out, "sh", "-c", script, "sh")
+			return append(out, args[i:]...)
+		}
+	}
+	return args
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Presets are named sets of variable values kept in a template, for
+// combinations you start again and again ("billing-service" filling in
+// project_dir and port). Ctrl+P in the variable form cycles through them,
+// Ctrl+S saves the values in the form as one, and `start --preset` picks
+// one from the command line.
+
+// presetNames lists the template's presets in a stable order.
+func (t SessionTemplate) presetNames() []string {
+	names := make([]string, 0, len(t.Presets))
+	for name := range t.Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// presetValues returns the values of a preset, overridden by values.
+func (t SessionTemplate) presetValues(name string, values map[string]string) (map[string]string, error) {
+	preset, ok := t.Presets[name]
+	if !ok {
+		if names := t.presetNames(); len(names) > 0 {
+			return nil, fmt.Errorf("template '%s' has no preset '%s' (has %s)", t.Name, name, strings.Join(names, ", "))
+		}
+		return nil, fmt.Errorf("template '%s' has no presets", t.Name)
+	}
+	out := make(map[string]string, len(preset)+len(values))
+	for k, v := range preset {
+		out[k] = v
+	}
+	for k, v := range values {
+		out[k] = v
+	}
+	return out, nil
+}
+
+// cyclePreset fills the variable form with the next preset, or with the
+// template defaults after the last one.
+func (m *model) cyclePreset() {
+	names := m.pendingTemplate.presetNames()
+	if len(names) == 0 {
+		m.setMessage("Template has no presets, Ctrl+S saves one", "info")
+		return
+	}
+	m.varPreset++
+	if m.varPreset >= len(names) {
+		m.varPreset = -1
+	}
+	values := m.pendingTemplate.Variables
+	if m.varPreset >= 0 {
+		values = m.pendingTemplate.Presets[names[m.varPreset]]
+	}
+	for i, name := range m.varNames {
+		value, ok := values[name]
+		if !ok {
+			value = m.pendingTemplate.Variables[name]
+		}
+		m.varInputs[i].SetValue(value)
+	}
+}
+
+// startSavingPreset asks for the name to save the form's values under,
+// suggesting the preset they came from.
+func (m *model) startSavingPreset() {
+	ti := textinput.New()
+	ti.Placeholder = "preset name"
+	ti.CharLimit = 50
+	if names := m.pendingTemplate.presetNames(); m.varPreset >= 0 && m.varPreset < len(names) {
+		ti.SetValue(names[m.varPreset])
+	}
+	ti.Focus()
+	m.varInputs[m.varCursor].Blur()
+	m.presetInput = ti
+	m.savingPreset = true
+}
+
+func (m *model) stopSavingPreset() {
+	m.savingPreset = false
+	m.varInputs[m.varCursor].Focus()
+}
+
+// updatePresetInput handles keys while the form asks for a preset name.
+func (m *model) updatePresetInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		m.stopSavingPreset()
+		return nil
+	case "enter":
+		name := strings.TrimSpace(m.presetInput.Value())
+		if name == "" {
+			return nil
+		}
+		values := map[string]string{}
+		for i, v := range m.varNames {
+			values[v] = m.varInputs[i].Value()
+		}
+		if err := m.savePreset(name, values); err != nil {
+			m.setMessage(fmt.Sprintf("Failed to save preset: %v", err), "error")
+		} else {
+			m.setMessage(fmt.Sprintf("Saved preset '%s'", name), "success")
+		}
+		m.stopSavingPreset()
+		return nil
+	}
+	var cmd tea.Cmd
+	m.presetInput, cmd = m.presetInput.Update(msg)
+	return cmd
+}
+
+// savePreset stores values as a preset of the pending template and writes
+// the saved template it came from.
+func (m *model) savePreset(name string, values map[string]string) error {
+	index := -1
+	for i, t := range m.templates {
+		if t.Name == m.pendingTemplate.Name {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("template '%s' is not a saved template", m.pendingTemplate.Name)
+	}
+	templates := append([]SessionTemplate(nil), m.templates...)
+	t := templates[index].clone()
+	if t.Presets == nil {
+		t.Presets = map[string]map[string]string{}
+	}
+	t.Presets[name] = values
+	templates[index] = t
+	if err := saveTemplates(templates); err != nil {
+		return err
+	}
+	m.templates = templates
+	m.pendingTemplate.Presets = t.Presets
+	for i, n := range t.presetNames() {
+		if n == name {
+			m.varPreset = i
+		}
+	}
+	return nil
+}
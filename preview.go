@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// With the preview on (p) and a terminal wide enough for both, the template
+// browser shows the highlighted template next to the list: its layout and
+// what it sets up. < and > move the split between the two, and the share of
+// the list is remembered in view.json, since how much preview helps differs
+// a lot between a laptop screen and an ultrawide.
+
+const (
+	// Narrower terminals show the list alone.
+	previewMinWidth = 100
+	// Percent of the width the list takes next to the preview.
+	defaultListShare = 55
+	minListShare     = 30
+	maxListShare     = 75
+	listShareStep    = 5
+)
+
+// viewState is how the views were last laid out.
+type viewState struct {
+	ListShare int `json:"list_share,omitempty"`
+}
+
+func getViewStateFile() string {
+	return filepath.Join(getConfigDir(), "view.json")
+}
+
+// loadViewState reads the layout of the views. A missing or broken file
+// just means the defaults.
+func loadViewState() viewState {
+	var v viewState
+	if data, err := ioutil.ReadFile(getViewStateFile()); err == nil {
+		json.Unmarshal(data, &v)
+	}
+	return v
+}
+
+func saveViewState(v viewState) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(getConfigDir(), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(getViewStateFile(), data)
+}
+
+// clampListShare keeps a list share in bounds, 0 being the default.
+func clampListShare(share int) int {
+	if share == 0 {
+		return defaultListShare
+	}
+	return min(max(share, minListShare), maxListShare)
+}
+
+// templateSplit returns the widths of the template list and of the preview
+// next to it, which is 0 when the list is shown alone.
+func (m model) templateSplit(tableWidth int) (listWidth, previewWidth int) {
+	if !m.previewMode || m.width < previewMinWidth || len(m.templates) == 0 {
+		return tableWidth, 0
+	}
+	total := m.width - 4
+	listWidth = total * clampListShare(m.listShare) / 100
+	return listWidth, total - listWidth - 1
+}
+
+// resizePreview moves the split between the template list and the preview
+// by step percent of the width, positive widening the list.
+func (m *model) resizePreview(step int) {
+	if _, previewWidth := m.templateSplit(0); previewWidth == 0 {
+		m.setMessage(fmt.Sprintf("The preview shows next to the list with p on, in %d columns or more", previewMinWidth), "warning")
+		return
+	}
+	m.listShare = clampListShare(clampListShare(m.listShare) + step)
+	if err := saveViewState(viewState{ListShare: m.listShare}); err != nil {
+		m.setMessage(fmt.Sprintf("Failed to remember the split: %v", err), "error")
+		return
+	}
+	m.setMessage(fmt.Sprintf("List %d%% • preview %d%%", m.listShare, 100-m.listShare), "info")
+}
+
+// renderTemplatePreview draws the highlighted template in a box width
+// columns wide and at most height lines high.
+func (m model) renderTemplatePreview(width, height int) string {
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(templateColor).
+		Padding(0, 1)
+	inner := width - box.GetHorizontalFrameSize()
+	rows := height - box.GetVerticalFrameSize()
+	if m.templateCursor < 0 || m.templateCursor >= len(m.templates) || inner < 10 || rows < 1 {
+		return ""
+	}
+	template := m.templates[m.templateCursor]
+
+	var lines []string
+	lines = append(lines, lipgloss.NewStyle().Foreground(templateColor).Bold(true).Render(rightTruncate(template.Name, inner)))
+	if template.Description != "" {
+		lines = append(lines, rightTruncate(template.Description, inner))
+	}
+	if template.Root != "" {
+		lines = append(lines, "📁 "+leftTruncate(template.Root, inner-3))
+	}
+	if len(template.Windows) > 0 {
+		var names []string
+		for k := 0; k <= len(template.Windows); k++ {
+			names = append(names, windowLabel(template, k))
+		}
+		lines = append(lines, "🪟 "+rightTruncate(strings.Join(names, " • "), inner-3))
+	}
+	if vars := template.Placeholders(); len(vars) > 0 {
+		lines = append(lines, "🧩 "+rightTruncate(strings.Join(vars, ", "), inner-3))
+	}
+	if template.OnCreate != "" {
+		lines = append(lines, rightTruncate("on_create: "+template.OnCreate, inner))
+	}
+	lines = append(lines, "")
+
+	// Then the layout of the first window, from its splits, as hand
+	// written templates often leave out where the panes are on the grid.
+	// It is drawn in the proportions of the editor's layout preview.
+	if canvasRows := min(rows-len(lines), max(3, inner*editorCanvasRows/editorCanvasCols)); canvasRows >= 3 && len(template.Panes) > 0 {
+		panes := append([]Pane(nil), template.Panes...)
+		layoutGeometry(panes)
+		lines = append(lines, drawLayout(panes, canvasRows, inner)...)
+	}
+	if len(lines) > rows {
+		lines = lines[:rows]
+	}
+	return box.Width(width - box.GetHorizontalBorderSize()).Render(strings.Join(lines, "\n"))
+}
+
+// drawLayout draws panes as boxes scaled to rows by cols characters, each
+// labelled with its title or command.
+func drawLayout(panes []Pane, rows, cols int) []string {
+	grid := make([][]rune, rows)
+	for r := range grid {
+		grid[r] = []rune(strings.Repeat(" ", cols))
+	}
+	set := func(r, c int, ch rune) {
+		if r >= 0 && r < rows && c >= 0 && c < cols {
+			grid[r][c] = ch
+		}
+	}
+	maxRow, maxCol := editorBounds(panes)
+	for _, pane := range panes {
+		r0, r1, c0, c1 := scaledRect(pane, maxRow, maxCol, rows, cols)
+		for c := c0 + 1; c < c1-1; c++ {
+			set(r0, c, '─')
+			set(r1-1, c, '─')
+		}
+		for r := r0 + 1; r < r1-1; r++ {
+			set(r, c0, '│')
+			set(r, c1-1, '│')
+		}
+		set(r0, c0, '┌')
+		set(r0, c1-1, '┐')
+		set(r1-1, c0, '└')
+		set(r1-1, c1-1, '┘')
+
+		label := strings.TrimSpace(pane.Command)
+		if pane.Type == paneTypeEditor {
+			label = strings.TrimSpace("$EDITOR " + label)
+		}
+		if pane.Title != "" {
+			label = pane.Title
+		}
+		if label == "" {
+			label = "(empty)"
+		}
+		for i, ch := range []rune(label) {
+			if c0+1+i >= c1-1 {
+				break
+			}
+			if r0+1 < r1-1 {
+				set(r0+1, c0+1+i, ch)
+			}
+		}
+	}
+	out := make([]string, rows)
+	for r := range grid {
+		out[r] = string(grid[r])
+	}
+	return out
+}
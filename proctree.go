@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// P shows what really runs in the highlighted session: the processes under
+// the shell of each pane, from #{pane_pid} and the process table ps prints,
+// so a "bash" pane turns out to be running cargo build or psql before you
+// decide to kill it.
+
+// process is one line of ps output.
+type process struct {
+	PID     int
+	PPID    int
+	State   string
+	Elapsed string
+	CPUTime time.Duration // used so far
+	RSS     int64         // resident memory in KiB
+	Args    string
+}
+
+// procLine is one row of the process tree: a pane or a process under it.
+type procLine struct {
+	Depth   int
+	Prefix  string // tree drawing in front of the process
+	Pane    string // "window.pane" for the rows of panes
+	Process process
+}
+
+// listProcesses returns every process on this machine by PID, and the
+// children of each.
+func listProcesses() (map[int]process, map[int][]int, error) {
+	out, err := exec.Command("ps", "-A", "-o", "pid=,ppid=,stat=,etime=,time=,rss=,args=").Output()
+	if err != nil {
+		return nil, nil, fmt.Errorf("ps failed: %v", err)
+	}
+	procs := map[int]process{}
+	children := map[int][]int{}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 7 {
+			continue
+		}
+		pid, err1 := strconv.Atoi(fields[0])
+		ppid, err2 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		rss, _ := strconv.ParseInt(fields[5], 10, 64)
+		procs[pid] = process{
+			PID:     pid,
+			PPID:    ppid,
+			State:   fields[2],
+			Elapsed: fields[3],
+			CPUTime: parseCPUTime(fields[4]),
+			RSS:     rss,
+			Args:    strings.Join(fields[6:], " "),
+		}
+		children[ppid] = append(children[ppid], pid)
+	}
+	for _, pids := range children {
+		sort.Ints(pids)
+	}
+	return procs, children, nil
+}
+
+// parseCPUTime reads the time column of ps: [[dd-]hh:]mm:ss on Linux,
+// mm:ss.ss on macOS.
+func parseCPUTime(s string) time.Duration {
+	var d time.Duration
+	if days, rest, ok := strings.Cut(s, "-"); ok {
+		n, _ := strconv.Atoi(days)
+		d += time.Duration(n) * 24 * time.Hour
+		s = rest
+	}
+	parts := strings.Split(s, ":")
+	unit := time.Second
+	for i := len(parts) - 1; i >= 0; i-- {
+		f, _ := strconv.ParseFloat(parts[i], 64)
+		d += time.Duration(f * float64(unit))
+		unit *= 60
+	}
+	return d
+}
+
+// panePIDs returns the pid of the process each pane of the session runs,
+// by "window.pane".
+func panePIDs(server Server, session string) ([]string, map[string]int, error) {
+	out, err := server.command("list-panes", "-s", "-t", "="+session, "-F", "#{window_index}.#{pane_index}\t#{pane_pid}").Output()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list panes of '%s'", session)
+	}
+	var panes []string
+	pids := map[string]int{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		index, pid, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(pid); err == nil {
+			panes = append(panes, index)
+			pids[index] = n
+		}
+	}
+	return panes, pids, nil
+}
+
+// sessionProcessTree lays out the processes of every pane of the session,
+// each pane's own process first and its descendants indented below it.
+func sessionProcessTree(server Server, session string) ([]procLine, error) {
+	panes, pids, err := panePIDs(server, session)
+	if err != nil {
+		return nil, err
+	}
+	procs, children, err := listProcesses()
+	if err != nil {
+		return nil, err
+	}
+	var lines []procLine
+	var walk func(pid, depth int, prefix string)
+	walk = func(pid, depth int, prefix string) {
+		kids := children[pid]
+		for i, kid := range kids {
+			branch, next := "├─ ", "│  "
+			if i == len(kids)-1 {
+				branch, next = "└─ ", "   "
+			}
+			lines = append(lines, procLine{Depth: depth, Prefix: prefix + branch, Process: procs[kid]})
+			walk(kid, depth+1, prefix+next)
+		}
+	}
+	for _, pane := range panes {
+		pid := pids[pane]
+		p, ok := procs[pid]
+		if !ok {
+			p = process{PID: pid, Args: "(exited)"}
+		}
+		lines = append(lines, procLine{Pane: pane, Process: p})
+		walk(pid, 1, "")
+	}
+	return lines, nil
+}
+
+// showProcesses opens the process tree of the highlighted session.
+func (m *model) showProcesses() tea.Cmd {
+	if len(m.sessions) == 0 {
+		return nil
+	}
+	s := m.sessions[m.cursor]
+	server := serverNamed(s.Server)
+	if server.remote() {
+		m.setMessage("The processes run on another machine", "warning")
+		return nil
+	}
+	var lines []procLine
+	return inBackground(func() (err error) {
+		lines, err = sessionProcessTree(server, s.Name)
+		return err
+	}, func(m *model, err error) tea.Cmd {
+		if err != nil {
+			m.setMessage(fmt.Sprintf("Failed to read processes: %v", err), "error")
+			return nil
+		}
+		if m.mode != browsing {
+			return nil
+		}
+		m.procSession = s
+		m.procLines = lines
+		m.procCursor = 0
+		m.mode = processBrowsing
+		return nil
+	})
+}
+
+// reloadProcesses reads the tree of the shown session again.
+func (m *model) reloadProcesses() tea.Cmd {
+	s := m.procSession
+	var lines []procLine
+	return inBackground(func() (err error) {
+		lines, err = sessionProcessTree(serverNamed(s.Server), s.Name)
+		return err
+	}, func(m *model, err error) tea.Cmd {
+		if m.mode != processBrowsing || m.procSession.Name != s.Name {
+			return nil
+		}
+		if err != nil {
+			m.setMessage(fmt.Sprintf("Failed to read processes: %v", err), "error")
+			return nil
+		}
+		m.procLines = lines
+		if m.procCursor >= len(lines) {
+			m.procCursor = max(0, len(lines)-1)
+		}
+		return nil
+	})
+}
+
+func (m model) renderProcessView(tableWidth int) string {
+	var content strings.Builder
+	title := tableHeaderStyle.Width(tableWidth).Render(fmt.Sprintf("🌳 PROCESSES OF '%s'", m.procSession.Name))
+	content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, title))
+	content.WriteString("\n\n")
+
+	// Keep the cursor in sight when the tree is taller than the screen.
+	rows := max(5, m.height-10)
+	start := 0
+	if m.procCursor >= rows {
+		start = m.procCursor - rows + 1
+	}
+	end := min(len(m.procLines), start+rows)
+
+	muted := lipgloss.NewStyle().Foreground(mutedColor)
+	for i := start; i < end; i++ {
+		line := m.procLines[i]
+		p := line.Process
+		text := line.Prefix + p.Args
+		if line.Pane != "" {
+			text = lipgloss.NewStyle().Bold(true).Render("pane "+line.Pane) + "  " + p.Args
+		}
+		meta := muted.Render(fmt.Sprintf("%d  %s  %s", p.PID, p.State, p.Elapsed))
+		text = rightTruncate(text, max(10, tableWidth-lipgloss.Width(meta)-6))
+		gap := max(1, tableWidth-lipgloss.Width(text)-lipgloss.Width(meta)-4)
+		row := "  " + text + strings.Repeat(" ", gap) + meta
+		if i == m.procCursor {
+			row = selectedRowStyle.Copy().Padding(0, 1).Render("▶ " + text + strings.Repeat(" ", gap) + meta)
+		}
+		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, row))
+		content.WriteString("\n")
+	}
+	if len(m.procLines) == 0 {
+		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, muted.Italic(true).Render("No processes found. The session may have been closed.")))
+		content.WriteString("\n")
+	}
+	content.WriteString("\n")
+	help := muted.Render("[j/k] Move • [r] Refresh • [d] Delete session • [Esc] Back")
+	content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, help))
+	return content.String()
+}
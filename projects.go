@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Projects are directories matched by the project_roots globs that have no
+// session yet. They are listed after the live sessions, and starting one
+// creates a session named after the directory in it, built from
+// project_template if there is one.
+
+// projectSessions returns a row for every project without a session, by
+// name, the directory being the project.
+func projectSessions(live []Session) []Session {
+	var projects []Session
+	taken := map[string]bool{}
+	for _, s := range live {
+		taken[s.Name] = true
+	}
+	server := ""
+	if len(dashboardServers) > 0 {
+		server = dashboardServers[0].Name
+	}
+	for _, dir := range projectDirs(config.ProjectRoots) {
+		name := projectSessionName(dir)
+		if nameExists(name, live, nil) {
+			continue
+		}
+		if taken[name] {
+			// Another root has a directory of the same name.
+			name = projectSessionName(filepath.Dir(dir)) + "_" + name
+			if taken[name] {
+				continue
+			}
+		}
+		taken[name] = true
+		projects = append(projects, Session{Name: name, Dir: dir, Server: server, Owner: currentUser, Project: true})
+	}
+	sort.SliceStable(projects, func(i, j int) bool { return projects[i].Name < projects[j].Name })
+	return projects
+}
+
+// projectDirs returns the directories the globs match, leaving out hidden
+// ones and those matched twice.
+func projectDirs(roots []string) []string {
+	var dirs []string
+	seen := map[string]bool{}
+	for _, root := range roots {
+		matches, err := filepath.Glob(expandHome(root))
+		if err != nil {
+			continue
+		}
+		for _, dir := range matches {
+			if strings.HasPrefix(filepath.Base(dir), ".") || seen[dir] {
+				continue
+			}
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				continue
+			}
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// projectSessionName names the session of a project directory.
+func projectSessionName(dir string) string {
+	return sanitizeSessionName(filepath.Base(dir))
+}
+
+// projectProblems lists what is wrong with the project settings, for doctor.
+func projectProblems(cfg Config, templates []SessionTemplate) []string {
+	var problems []string
+	for _, root := range cfg.ProjectRoots {
+		if _, err := filepath.Glob(expandHome(root)); err != nil {
+			problems = append(problems, fmt.Sprintf("project_roots: %q is not a valid pattern", root))
+		}
+	}
+	if cfg.ProjectTemplate != "" && findTemplateByPrefix(cfg.ProjectTemplate, templates) == nil {
+		problems = append(problems, fmt.Sprintf("project_template: no template named '%s'", cfg.ProjectTemplate))
+	}
+	return problems
+}
+
+// onProject reports whether Enter starts a project: one is highlighted and
+// no sessions are marked.
+func (m model) onProject() bool {
+	return len(m.marked) == 0 && m.cursor < len(m.sessions) && m.sessions[m.cursor].Project
+}
+
+// openProject starts the highlighted project.
+func (m model) openProject(key string) (tea.Model, tea.Cmd) {
+	cmd := m.startProject(m.sessions[m.cursor], key)
+	return m, cmd
+}
+
+// startProject creates the session of a project in the background, from
+// its own .lazytmux.json if it has one, and attaches to it unless
+// m.detachNew is set.
+func (m *model) startProject(project Session, key string) tea.Cmd {
+	if path, err := filepath.Abs(filepath.Join(project.Dir, localTemplateFile)); err == nil {
+		if _, err := os.Stat(path); err == nil {
+			t, err := readLocalTemplate(path)
+			if err != nil {
+				m.setMessage(err.Error(), "error")
+				return nil
+			}
+			m.detachNew = createDetached(key, t.afterCreate())
+			return m.startTemplateSession(project.Name, t)
+		}
+	}
+	if config.ProjectTemplate != "" {
+		template := findTemplateByPrefix(config.ProjectTemplate, m.templates)
+		if template == nil {
+			m.setMessage(fmt.Sprintf("No template named '%s' for projects", config.ProjectTemplate), "error")
+			return nil
+		}
+		t := *template
+		t.Root = project.Dir
+		m.detachNew = createDetached(key, t.afterCreate())
+		return m.startTemplateSession(project.Name, t)
+	}
+
+	m.detachNew = createDetached(key, config.AfterCreate)
+	return m.createInBackground(project.Name, project.Dir, fmt.Sprintf("Created session '%s' in %s", project.Name, shortDir(project.Dir)))
+}
+
+// projectKeys act on a session that has to exist, so they are refused on
+// project rows, as are custom actions.
+var projectKeys = map[string]bool{
+	"m": true, "r": true, "d": true, "w": true, "p": true, "N": true,
+	"S": true, "W": true, "B": true, "M": true, "*": true, "i": true, "P": true,
+	"T": true,
+}
+
+// refuseOnProject reports whether key needs a session the highlighted
+// project doesn't have yet, and tells the user so if it does.
+func (m *model) refuseOnProject(key string) bool {
+	if m.cursor >= len(m.sessions) || !m.sessions[m.cursor].Project {
+		return false
+	}
+	if _, ok := findAction(actionViewSessions, key); !ok && !projectKeys[key] {
+		return false
+	}
+	m.setMessage(fmt.Sprintf("'%s' has no session yet, Enter starts it", m.sessions[m.cursor].Name), "warning")
+	return true
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Auto-refresh reloads the session list every 5 seconds, or every 30 while
+// the event hooks report changes as they happen. refresh_interval sets how
+// often instead, like "1m", and "0" leaves reloading to Ctrl+R, for servers
+// with so many sessions that reading them all is felt. The status bar says
+// how long ago the list was read.
+
+// How often sessions are reloaded without the event hooks.
+const defaultRefreshInterval = 5 * time.Second
+
+// parseRefreshInterval reads refresh_interval: a Go duration of at least a
+// second, or "0" for manual refresh only.
+func parseRefreshInterval(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d != 0 && d < time.Second {
+		return 0, fmt.Errorf("%s is shorter than a second", s)
+	}
+	return d, nil
+}
+
+// manualRefresh reports whether refresh_interval leaves reloading to
+// Ctrl+R.
+func manualRefresh() bool {
+	d, err := parseRefreshInterval(config.RefreshInterval)
+	return config.RefreshInterval != "" && err == nil && d == 0
+}
+
+// refreshInterval is how often the session list is reloaded when auto
+// refresh is on, 0 for never.
+func (m model) refreshInterval() time.Duration {
+	if config.RefreshInterval != "" {
+		if d, err := parseRefreshInterval(config.RefreshInterval); err == nil {
+			return d
+		}
+	}
+	if eventHooksEnabled() && m.hooksRegistered {
+		return hookedRefreshInterval
+	}
+	return defaultRefreshInterval
+}
+
+// refreshDue reports whether auto refresh should reload the list now.
+func (m model) refreshDue(now time.Time) bool {
+	interval := m.refreshInterval()
+	return m.autoRefresh && interval > 0 && now.Sub(m.lastRefresh) > interval
+}
+
+// refreshStatus tells in the status bar how long ago the list was read,
+// and whether it reloads by itself.
+func (m model) refreshStatus() string {
+	ago := formatUptime(time.Since(m.lastRefresh)) + " ago"
+	if !m.autoRefresh {
+		return "⏸ Refreshed " + ago + ", manually"
+	}
+	return "🔄 Refreshed " + ago
+}
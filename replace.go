@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// replaceMatch is one template field containing the search string.
+type replaceMatch struct {
+	template int
+	field    int
+	label    string
+	before   string
+	after    string
+	selected bool
+}
+
+type templateField struct {
+	label string
+	value *string
+}
+
+// clone returns a copy of the template that shares no panes, windows or
+// maps with the original.
+func (t SessionTemplate) clone() SessionTemplate {
+	t.Template = t.Template.Clone()
+	return t
+}
+
+// commandFields returns the commands and paths of the template that
+// search-and-replace works on, in a stable order.
+func (t *SessionTemplate) commandFields() []templateField {
+	fields := []templateField{
+		{"root", &t.Root},
+		{"on_create", &t.OnCreate},
+		{"on_attach", &t.OnAttach},
+	}
+	addPanes := func(window string, panes []Pane) {
+		for i := range panes {
+			prefix := fmt.Sprintf("%spane %d", window, panes[i].ID)
+			fields = append(fields,
+				templateField{prefix + " command", &panes[i].Command},
+				templateField{prefix + " dir", &panes[i].Dir},
+			)
+		}
+	}
+	addPanes("", t.Panes)
+	for i := range t.Windows {
+		name := t.Windows[i].Name
+		if name == "" {
+			name = fmt.Sprintf("window %d", i+2)
+		}
+		addPanes(name+" / ", t.Windows[i].Panes)
+	}
+	return fields
+}
+
+// findReplaceMatches lists every field of every template that contains
+// find, with the value it would have after replacing it. All matches start
+// out selected.
+func findReplaceMatches(templates []SessionTemplate, find, replace string) []replaceMatch {
+	if find == "" {
+		return nil
+	}
+	var matches []replaceMatch
+	for ti := range templates {
+		for fi, f := range templates[ti].commandFields() {
+			if !strings.Contains(*f.value, find) {
+				continue
+			}
+			matches = append(matches, replaceMatch{
+				template: ti,
+				field:    fi,
+				label:    f.label,
+				before:   *f.value,
+				after:    strings.ReplaceAll(*f.value, find, replace),
+				selected: true,
+			})
+		}
+	}
+	return matches
+}
+
+// applyReplaceMatches returns a copy of templates with the selected matches
+// applied, along with the number of fields changed.
+func applyReplaceMatches(templates []SessionTemplate, matches []replaceMatch) ([]SessionTemplate, int) {
+	out := make([]SessionTemplate, len(templates))
+	for i, t := range templates {
+		out[i] = t.clone()
+	}
+	changed := 0
+	for _, match := range matches {
+		if !match.selected {
+			continue
+		}
+		fields := out[match.template].commandFields()
+		*fields[match.field].value = match.after
+		changed++
+	}
+	return out, changed
+}
+
+func (m *model) startReplace() {
+	find := textinput.New()
+	find.Placeholder = "Text to find, e.g. ~/work"
+	find.CharLimit = 200
+	find.Focus()
+	m.findInput = find
+
+	replace := textinput.New()
+	replace.Placeholder = "Replacement, e.g. ~/code"
+	replace.CharLimit = 200
+	m.replaceInput = replace
+
+	m.replaceMatches = nil
+	m.replaceCursor = 0
+	m.mode = templateReplacing
+}
+
+func (m model) renderReplaceForm() string {
+	form := "🔁 Replace in all templates\n\nFind: " + m.findInput.View() +
+		"\nReplace: " + m.replaceInput.View() +
+		"\n\n[Tab] Switch fields • [Enter] Show matches • [Esc] Cancel"
+	inputView := inputBoxStyle.Render(form)
+	return lipgloss.Place(m.width, 8, lipgloss.Center, lipgloss.Top, inputView)
+}
+
+func (m model) renderReplaceMatches(tableWidth int) string {
+	var list strings.Builder
+	selected := 0
+	for _, match := range m.replaceMatches {
+		if match.selected {
+			selected++
+		}
+	}
+	list.WriteString(fmt.Sprintf("🔁 %d of %d match(es) selected\n\n", selected, len(m.replaceMatches)))
+
+	valueWidth := max(10, tableWidth-16)
+	clip := func(s string) string {
+		if len(s) > valueWidth {
+			return s[:valueWidth] + "..."
+		}
+		return s
+	}
+	// Each match takes three lines; keep the cursor in view on long lists.
+	visible := max(3, (m.height-20)/3)
+	start := 0
+	if m.replaceCursor >= visible {
+		start = m.replaceCursor - visible + 1
+	}
+	end := min(len(m.replaceMatches), start+visible)
+	if start > 0 {
+		list.WriteString(fmt.Sprintf("  ↑ %d more\n", start))
+	}
+	for i := start; i < end; i++ {
+		match := m.replaceMatches[i]
+		check := "[ ]"
+		if match.selected {
+			check = "[x]"
+		}
+		cursor := "  "
+		if i == m.replaceCursor {
+			cursor = "▶ "
+		}
+		header := fmt.Sprintf("%s%s %s: %s", cursor, check, m.templates[match.template].Name, match.label)
+		if i == m.replaceCursor {
+			header = lipgloss.NewStyle().Bold(true).Foreground(templateColor).Render(header)
+		}
+		list.WriteString(header + "\n")
+		list.WriteString(lipgloss.NewStyle().Foreground(dangerColor).Render("      - "+clip(match.before)) + "\n")
+		list.WriteString(lipgloss.NewStyle().Foreground(successColor).Render("      + "+clip(match.after)) + "\n")
+	}
+	if end < len(m.replaceMatches) {
+		list.WriteString(fmt.Sprintf("  ↓ %d more\n", len(m.replaceMatches)-end))
+	}
+	list.WriteString("\n[Space] Toggle • [a] Toggle all • [Enter] Replace and save • [Esc] Back")
+	return lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, inputBoxStyle.Copy().Width(tableWidth).Render(list.String()))
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// --record and --replay put a "tmux" first on PATH that is lazytmux itself.
+// Started under that name it either runs the real tmux and appends what it
+// printed to a fixture, or answers from the fixture without running tmux at
+// all, so a UI can be shown exactly as someone else saw it. Replayed commands
+// print what they printed when recorded, without changing anything, and
+// commands missing from the fixture succeed without output.
+
+// Fixture modes, passed to the shim in LAZYTMUX_FIXTURE_MODE.
+const (
+	fixtureRecord = "record"
+	fixtureReplay = "replay"
+)
+
+// fixtureEntry is one tmux command and what it printed, a line of JSON in
+// the fixture file.
+type fixtureEntry struct {
+	Args   []string `json:"args"`
+	Stdout string   `json:"stdout,omitempty"`
+	Stderr string   `json:"stderr,omitempty"`
+	Exit   int      `json:"exit,omitempty"`
+}
+
+// isTmuxShim reports whether lazytmux was started as the tmux of a fixture.
+func isTmuxShim() bool {
+	return filepath.Base(os.Args[0]) == "tmux" && os.Getenv("LAZYTMUX_FIXTURE") != ""
+}
+
+// useFixture makes the tmux commands of this process and its children go
+// through the shim.
+func useFixture(mode, fixture string) error {
+	fixture, err := filepath.Abs(expandHome(fixture))
+	if err != nil {
+		return err
+	}
+	switch mode {
+	case fixtureRecord:
+		real, err := exec.LookPath("tmux")
+		if err != nil {
+			return fmt.Errorf("tmux not found: %v", err)
+		}
+		os.Setenv("LAZYTMUX_REAL_TMUX", real)
+		if err := ioutil.WriteFile(fixture, nil, 0644); err != nil {
+			return err
+		}
+	case fixtureReplay:
+		if _, err := loadFixture(fixture); err != nil {
+			return err
+		}
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(getConfigDir(), "fixture-bin")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	shim := filepath.Join(dir, "tmux")
+	os.Remove(shim)
+	if err := os.Symlink(self, shim); err != nil {
+		return err
+	}
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	os.Setenv("LAZYTMUX_FIXTURE", fixture)
+	os.Setenv("LAZYTMUX_FIXTURE_MODE", mode)
+	return nil
+}
+
+func loadFixture(path string) ([]fixtureEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []fixtureEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var e fixtureEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// runTmuxShim stands in for tmux and returns its exit status.
+func runTmuxShim(args []string) int {
+	fixture := os.Getenv("LAZYTMUX_FIXTURE")
+	if os.Getenv("LAZYTMUX_FIXTURE_MODE") == fixtureRecord {
+		return recordTmux(fixture, args)
+	}
+
+	entries, err := loadFixture(fixture)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lazytmux replay: %v\n", err)
+		return 1
+	}
+	// A command run more than once answers with what it printed last.
+	for i := len(entries) - 1; i >= 0; i-- {
+		if reflect.DeepEqual(entries[i].Args, args) {
+			fmt.Fprint(os.Stdout, entries[i].Stdout)
+			fmt.Fprint(os.Stderr, entries[i].Stderr)
+			return entries[i].Exit
+		}
+	}
+	return 0
+}
+
+// recordTmux runs the real tmux and appends its output to the fixture.
+// Commands taking over the terminal, like attach-session, run untouched.
+func recordTmux(fixture string, args []string) int {
+	cmd := exec.Command(os.Getenv("LAZYTMUX_REAL_TMUX"), args...)
+	cmd.Stdin = os.Stdin
+	if isTerminal(os.Stdout) {
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		return exitStatus(cmd.Run())
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = io.MultiWriter(os.Stdout, &stdout)
+	cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+	status := exitStatus(cmd.Run())
+
+	line, err := json.Marshal(fixtureEntry{Args: args, Stdout: stdout.String(), Stderr: stderr.String(), Exit: status})
+	if err == nil {
+		if f, err := os.OpenFile(fixture, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644); err == nil {
+			f.Write(append(line, '\n'))
+			f.Close()
+		}
+	}
+	return status
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func exitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exit, ok := err.(*exec.ExitError); ok {
+		return exit.ExitCode()
+	}
+	fmt.Fprintf(os.Stderr, "lazytmux record: %v\n", err)
+	return 1
+}
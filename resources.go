@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// u adds a column with the CPU and memory used by everything running in
+// each session: the processes of its panes and all their descendants. CPU
+// is what they used since the previous look, a few seconds ago, so a
+// forgotten build or dev server stands out. Sessions on other machines are
+// left out.
+
+// How long the usage is shown before it is measured again.
+const resourcesTTL = 3 * time.Second
+
+// resourceUsage is what the processes of a session use.
+type resourceUsage struct {
+	CPU    float64 // percent of one core, -1 until measured twice
+	Memory int64   // resident, in KiB
+}
+
+// resourcesMsg carries the usage by session name, and the CPU time of
+// every process seen, which the next measurement is compared with.
+type resourcesMsg struct {
+	usage   map[string]resourceUsage
+	cpuTime map[int]time.Duration
+	at      time.Time
+}
+
+// sessionPanePIDs returns the pids of the panes of every session on a
+// server, by session name.
+func sessionPanePIDs(server Server) map[string][]int {
+	out, err := server.command("list-panes", "-a", "-F", "#{session_name}\t#{pane_pid}").Output()
+	if err != nil {
+		return nil
+	}
+	pids := map[string][]int{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		session, pid, ok := strings.Cut(line, "\t")
+		if n, err := strconv.Atoi(pid); ok && err == nil {
+			pids[session] = append(pids[session], n)
+		}
+	}
+	return pids
+}
+
+// measureResources adds up the usage of the sessions in the background.
+// CPU is the CPU time used since the processes were last seen, over the
+// time passed since then.
+func measureResources(sessions []Session, seen map[int]time.Duration, seenAt time.Time) tea.Cmd {
+	return func() tea.Msg {
+		now := time.Now()
+		msg := resourcesMsg{usage: map[string]resourceUsage{}, cpuTime: map[int]time.Duration{}, at: now}
+		procs, children, err := listProcesses()
+		if err != nil {
+			return msg
+		}
+		for pid, p := range procs {
+			msg.cpuTime[pid] = p.CPUTime
+		}
+		panes := map[string]map[string][]int{}
+		wall := now.Sub(seenAt)
+		for _, s := range sessions {
+			if panes[s.Server] == nil {
+				panes[s.Server] = sessionPanePIDs(serverNamed(s.Server))
+			}
+			var used time.Duration
+			usage := resourceUsage{CPU: -1}
+			var add func(pid int)
+			add = func(pid int) {
+				p, ok := procs[pid]
+				if !ok {
+					return
+				}
+				usage.Memory += p.RSS
+				// Processes started since the last look count from zero.
+				used += p.CPUTime - seen[pid]
+				for _, kid := range children[pid] {
+					add(kid)
+				}
+			}
+			for _, pid := range panes[s.Server][s.Name] {
+				add(pid)
+			}
+			if len(seen) > 0 && wall > 0 && used > 0 {
+				usage.CPU = 100 * used.Seconds() / wall.Seconds()
+			} else if len(seen) > 0 {
+				usage.CPU = 0
+			}
+			msg.usage[s.Name] = usage
+		}
+		return msg
+	}
+}
+
+// refreshResources starts measuring the listed sessions if the column is
+// shown and what it shows has gone stale. It returns nil otherwise.
+func (m *model) refreshResources() tea.Cmd {
+	if !m.showResources || m.measuring || time.Since(m.resourcesAt) < resourcesTTL {
+		return nil
+	}
+	var sessions []Session
+	for _, s := range m.sessions {
+		if !s.Project && !serverNamed(s.Server).remote() {
+			sessions = append(sessions, s)
+		}
+	}
+	m.measuring = true
+	return measureResources(sessions, m.cpuSeen, m.resourcesAt)
+}
+
+// format shows the usage as "12% 340M".
+func (u resourceUsage) format() string {
+	cpu := "…"
+	if u.CPU >= 0 {
+		cpu = fmt.Sprintf("%.0f%%", u.CPU)
+	}
+	return cpu + " " + formatKiB(u.Memory)
+}
+
+// formatKiB shows an amount of KiB in the largest unit that keeps it
+// above one.
+func formatKiB(kib int64) string {
+	switch {
+	case kib >= 1024*1024:
+		return fmt.Sprintf("%.1fG", float64(kib)/(1024*1024))
+	case kib >= 1024:
+		return fmt.Sprintf("%dM", kib/1024)
+	}
+	return fmt.Sprintf("%dK", kib)
+}
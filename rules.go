@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// SessionRule labels the sessions it matches. Every condition that is set
+// has to match; a rule without conditions matches every session.
+type SessionRule struct {
+	// Regular expression the session name has to match.
+	Name string `json:"name,omitempty"`
+	// Directory the active pane has to be in, or below. ~ is expanded.
+	Dir string `json:"dir,omitempty"`
+	// Template the session was created from.
+	Template string `json:"template,omitempty"`
+
+	Tag     string `json:"tag,omitempty"`
+	Color   string `json:"color,omitempty"` // lipgloss color: "1", "#ff5f87", ...
+	Protect bool   `json:"protect,omitempty"`
+}
+
+// Compiled name patterns, keyed by the pattern itself. The API server
+// applies rules from its own goroutines, hence the lock.
+var (
+	ruleNameRes   = map[string]*regexp.Regexp{}
+	ruleNameResMu sync.Mutex
+)
+
+func ruleNameRe(pattern string) (*regexp.Regexp, error) {
+	ruleNameResMu.Lock()
+	defer ruleNameResMu.Unlock()
+	if re, ok := ruleNameRes[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	ruleNameRes[pattern] = re
+	return re, nil
+}
+
+// matches reports whether the rule applies to the session. A broken name
+// pattern never matches; doctor points it out.
+func (r SessionRule) matches(s Session) bool {
+	if r.Name != "" {
+		re, err := ruleNameRe(r.Name)
+		if err != nil || !re.MatchString(s.Name) {
+			return false
+		}
+	}
+	if r.Dir != "" {
+		dir := filepath.Clean(expandHome(r.Dir))
+		if s.Dir != dir && !strings.HasPrefix(s.Dir, strings.TrimSuffix(dir, "/")+"/") {
+			return false
+		}
+	}
+	if r.Template != "" && r.Template != s.Template {
+		return false
+	}
+	return true
+}
+
+// applyRules sets the labels of every session from the rules, in order:
+// tags add up, a later color wins and any matching rule can protect.
+func applyRules(sessions []Session, rules []SessionRule) {
+	for i := range sessions {
+		s := &sessions[i]
+		s.Tags, s.Color, s.Protected = nil, "", false
+		for _, r := range rules {
+			if !r.matches(*s) {
+				continue
+			}
+			if r.Tag != "" && !containsString(s.Tags, r.Tag) {
+				s.Tags = append(s.Tags, r.Tag)
+			}
+			if r.Color != "" {
+				s.Color = r.Color
+			}
+			s.Protected = s.Protected || r.Protect
+		}
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, have := range list {
+		if have == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleProblems lists what is wrong with the configured rules, for doctor.
+func ruleProblems(rules []SessionRule) []string {
+	var problems []string
+	for i, r := range rules {
+		if r.Name != "" {
+			if _, err := regexp.Compile(r.Name); err != nil {
+				problems = append(problems, fmt.Sprintf("rule %d: name %q is not a valid regular expression: %v", i+1, r.Name, err))
+			}
+		}
+		if r.Tag == "" && r.Color == "" && !r.Protect {
+			problems = append(problems, fmt.Sprintf("rule %d sets neither tag, color nor protect", i+1))
+		}
+	}
+	return problems
+}
+
+// killableSessions splits sessions into those "kill all" may kill and a
+// description of the ones it has to keep: other users' and protected ones.
+func killableSessions(sessions []Session) ([]Session, []string) {
+	var kill []Session
+	others, protected := 0, 0
+	for _, s := range sessions {
+		switch {
+		case !s.mine():
+			others++
+		case s.Protected:
+			protected++
+		default:
+			kill = append(kill, s)
+		}
+	}
+	var kept []string
+	if others > 0 {
+		kept = append(kept, fmt.Sprintf("%d of other users", others))
+	}
+	if protected > 0 {
+		kept = append(kept, fmt.Sprintf("%d protected", protected))
+	}
+	return kill, kept
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/newcharhuso/tmux-navigator/pkg/lazytmux"
+)
+
+// scriptWriter builds a POSIX shell script line by line.
+type scriptWriter struct {
+	b strings.Builder
+}
+
+func (w *scriptWriter) line(format string, args ...interface{}) {
+	fmt.Fprintf(&w.b, format+"\n", args...)
+}
+
+// scriptDir is resolveDir for scripts: the path is resolved when the script
+// runs, so ~ and relative roots work on other machines too.
+func scriptDir(root, dir string) string {
+	switch {
+	case dir == "":
+		dir = root
+	case !strings.HasPrefix(dir, "/") && !strings.HasPrefix(dir, "~") && root != "":
+		dir = path.Join(root, dir)
+	}
+	switch {
+	case dir == "":
+		return ""
+	case dir == "~":
+		return `"$HOME"`
+	case strings.HasPrefix(dir, "~/"):
+		return `"$HOME"/` + shellQuote(dir[2:])
+	case !strings.HasPrefix(dir, "/"):
+		return `"$PWD"/` + shellQuote(dir)
+	}
+	return shellQuote(dir)
+}
+
+// paneVar names the shell variable holding the tmux id of a template pane.
+func paneVar(window, id int) string {
+	return fmt.Sprintf("pane%d_%d", window, id)
+}
+
+// doubleQuote wraps s in double quotes for POSIX shells.
+func doubleQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`").Replace(s) + `"`
+}
+
+// waitLoop returns the pane command of a waiting pane as a quoted shell
+// word. It polls like `lazytmux wait` does, but only with tmux and nc, and
+// refers to the dependency through its shell variable.
+func waitLoop(p Pane, depVar string) (string, bool) {
+	// Stands in for the variable until the rest has been quoted.
+	const dep = "\x00dep\x00"
+	var conds []string
+	if p.WaitFor != "" {
+		conds = append(conds, "tmux capture-pane -p -J -S - -t "+dep+" | grep -qF "+shellQuote(p.WaitFor))
+	}
+	usesNc := false
+	if p.WaitForPort > 0 {
+		conds = append(conds, "nc -z localhost "+strconv.Itoa(p.WaitForPort))
+		usesNc = true
+	}
+	timeout := p.WaitTimeout
+	if timeout <= 0 {
+		timeout = defaultWaitTimeout
+	}
+	cond := strings.Join(conds, " && ")
+	loop := fmt.Sprintf("i=0; until %s || [ $((i+=1)) -gt %d ]; do sleep 1; done; %s && %s",
+		cond, timeout, cond, strings.TrimSpace(p.Command))
+	return strings.ReplaceAll(doubleQuote(loop), dep, "$"+depVar), usesNc
+}
+
+// templateScript converts a template into a standalone shell script of tmux
+// commands. Placeholders must already be filled in. It also returns warnings
+// about what the script needs beyond tmux.
+func templateScript(template SessionTemplate) (string, []string) {
+	var warnings []string
+	w := &scriptWriter{}
+	w.line("#!/bin/sh")
+	w.line("# Creates the tmux session of the lazytmux template %s.", shellQuote(template.Name))
+	w.line("# Usage: %s [session-name]", "sh "+template.Name+".sh")
+	w.line("set -e")
+	w.line("")
+	w.line("session=${1:-%s}", shellQuote(template.Name))
+	w.line(`if tmux has-session -t "=$session" 2>/dev/null; then`)
+	w.line(`	echo "session '$session' already exists" >&2`)
+	w.line("	exit 1")
+	w.line("fi")
+	w.line("")
+
+	hook := func(name, command string) {
+		if strings.TrimSpace(command) == "" {
+			return
+		}
+		w.line("# %s hook", name)
+		var env []string
+		for _, k := range sortedKeys(template.Env) {
+			env = append(env, k+"="+shellQuote(template.Env[k]))
+		}
+		env = append(env, `LAZYTMUX_SESSION="$session"`, "LAZYTMUX_TEMPLATE="+shellQuote(template.Name))
+		if dir := scriptDir(template.Root, firstDir(template.Panes)); dir != "" {
+			w.line("(cd %s && %s sh -c %s)", dir, strings.Join(env, " "), shellQuote(command))
+		} else {
+			w.line("%s sh -c %s", strings.Join(env, " "), shellQuote(command))
+		}
+		w.line("")
+	}
+	hook("on_create", template.OnCreate)
+
+	args := `tmux new-session -d -s "$session"`
+	if dir := scriptDir(template.Root, firstDir(template.Panes)); dir != "" {
+		args += " -c " + dir
+	}
+	w.line("%s", args)
+	w.line(`tmux set-option -t "$session" @owner "$(id -un)"`)
+	w.line(`tmux set-option -t "$session" @template %s`, shellQuote(template.Name))
+	first := paneVar(1, 1)
+	if len(template.Panes) > 0 {
+		first = paneVar(1, template.Panes[0].ID)
+	}
+	w.line(`%s=$(tmux display-message -p -t "$session" '#{pane_id}')`, first)
+	if len(template.Env) > 0 {
+		var exports []string
+		for _, k := range sortedKeys(template.Env) {
+			w.line(`tmux set-environment -t "$session" %s %s`, k, shellQuote(template.Env[k]))
+			exports = append(exports, fmt.Sprintf("export %s=%s", k, shellQuote(template.Env[k])))
+		}
+		w.line(`tmux send-keys -t "$%s" %s C-m`, first, shellQuote(strings.Join(exports, "; ")))
+	}
+	if template.WindowName != "" {
+		w.line(`tmux rename-window -t "$%s" %s`, first, shellQuote(template.WindowName))
+	}
+
+	borderStatus := template.BorderStatus()
+	needsNc := false
+	windows := append([]TemplateWindow{{Name: template.WindowName, Panes: template.Panes}}, template.Windows...)
+	for i, win := range windows {
+		n := i + 1
+		base := first
+		if i > 0 {
+			w.line("")
+			w.line("# window %d", n)
+			base = paneVar(n, 1)
+			if len(win.Panes) > 0 {
+				base = paneVar(n, win.Panes[0].ID)
+			}
+			args := `tmux new-window -d -t "$session:" -P -F '#{pane_id}'`
+			if win.Name != "" {
+				args += " -n " + shellQuote(win.Name)
+			}
+			if dir := scriptDir(template.Root, firstDir(win.Panes)); dir != "" {
+				args += " -c " + dir
+			}
+			w.line("%s=$(%s)", base, args)
+		}
+		panes := lazytmux.WithEditors(win.Panes, template.Root, template.Env)
+		if scriptPanes(w, template.Root, n, base, panes, borderStatus) {
+			needsNc = true
+		}
+	}
+
+	w.line("")
+	hook("on_attach", template.OnAttach)
+	w.line(`tmux select-window -t "$%s"`, first)
+	w.line(`tmux select-pane -t "$%s"`, first)
+	w.line(`if [ -n "$TMUX" ]; then`)
+	w.line(`	tmux switch-client -t "=$session"`)
+	w.line("else")
+	w.line(`	tmux attach-session -t "=$session"`)
+	w.line("fi")
+
+	if needsNc {
+		warnings = append(warnings, "wait_for_port is checked with nc, which has to be installed where the script runs")
+	}
+	if warning := editorExportWarning(template); warning != "" {
+		warnings = append(warnings, warning)
+	}
+	return w.b.String(), warnings
+}
+
+func firstDir(panes []Pane) string {
+	if len(panes) > 0 {
+		return panes[0].Dir
+	}
+	return ""
+}
+
+// scriptPanes writes the commands lazytmux.Builder would run for one window. It
+// reports whether any pane waits on a port.
+func scriptPanes(w *scriptWriter, root string, window int, base string, panes []Pane, borderStatus string) bool {
+	if borderStatus != "" {
+		w.line(`tmux set-option -w -t "$%s" pane-border-status %s`, base, borderStatus)
+	}
+	if len(panes) == 0 {
+		return false
+	}
+
+	known := map[int]bool{panes[0].ID: true}
+	var waiting []Pane
+	for i, p := range panes {
+		v := paneVar(window, p.ID)
+		if i > 0 {
+			parent := base
+			if known[p.Parent] {
+				parent = paneVar(window, p.Parent)
+			}
+			args := []string{"tmux", "split-window", "-t", `"$` + parent + `"`}
+			switch p.Position {
+			case "left":
+				args = append(args, "-h", "-b")
+			case "up":
+				args = append(args, "-v", "-b")
+			case "down":
+				args = append(args, "-v")
+			default:
+				args = append(args, "-h")
+			}
+			if p.SplitPercent > 0 && p.SplitPercent != 50 {
+				args = append(args, "-p", strconv.Itoa(p.SplitPercent))
+			}
+			if dir := scriptDir(root, p.Dir); dir != "" {
+				args = append(args, "-c", dir)
+			}
+			args = append(args, "-P", "-F", "'#{pane_id}'")
+			w.line("%s=$(%s)", v, strings.Join(args, " "))
+			known[p.ID] = true
+		}
+		if p.Title != "" {
+			w.line(`tmux select-pane -t "$%s" -T %s`, v, shellQuote(p.Title))
+		}
+		if p.BorderStyle != "" {
+			w.line(`tmux set-option -p -t "$%s" pane-border-style %s`, v, shellQuote(p.BorderStyle))
+			w.line(`tmux set-option -p -t "$%s" pane-active-border-style %s`, v, shellQuote(p.BorderStyle))
+		}
+		if p.Waits() {
+			waiting = append(waiting, p)
+			continue
+		}
+		scriptCommand(w, v, p, shellQuote(strings.TrimSpace(p.Command)))
+	}
+
+	usesNc := false
+	for _, p := range waiting {
+		if strings.TrimSpace(p.Command) == "" {
+			continue
+		}
+		loop, nc := waitLoop(p, paneVar(window, p.WaitForPane))
+		usesNc = usesNc || nc
+		scriptCommand(w, paneVar(window, p.ID), p, loop)
+	}
+	return usesNc
+}
+
+// scriptCommand writes what runPaneCommand does; cmd is already quoted.
+func scriptCommand(w *scriptWriter, v string, p Pane, cmd string) {
+	if strings.TrimSpace(p.Command) == "" {
+		return
+	}
+	if p.RemainOnExit {
+		w.line(`tmux set-option -p -t "$%s" remain-on-exit on`, v)
+		w.line(`tmux respawn-pane -k -t "$%s" %s`, v, cmd)
+		return
+	}
+	w.line(`tmux send-keys -t "$%s" %s C-m`, v, cmd)
+}
+
+// runExportScript prints a saved template as a shell script of tmux commands.
+func runExportScript(args []string) int {
+	fs := flag.NewFlagSet("export-script", flag.ContinueOnError)
+	vars := varFlags{}
+	fs.Var(vars, "var", "Value for a template `name=value` placeholder (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s export-script [--var name=value]... <template>\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	args, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(args) != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	template := findTemplateByPrefix(args[0], loadTemplates())
+	if template == nil {
+		fmt.Fprintf(os.Stderr, "Error: template '%s' not found\n", args[0])
+		return 1
+	}
+	filled, err := template.withVars(vars)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v (pass --var name=value)\n", err)
+		return 1
+	}
+	script, warnings := templateScript(filled)
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+	fmt.Print(script)
+	return 0
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// F searches what every pane of the listed sessions shows and has scrolled
+// by, for "which session had that stack trace?". The matching lines are
+// listed with their pane, and Enter attaches to the session with that pane
+// selected. The query is matched case-insensitively unless it has capitals.
+
+// How many lines of history above the visible ones are searched per pane.
+const searchHistory = 5000
+
+// At most this many matching lines are listed.
+const searchLimit = 500
+
+// searchMatch is a line of a pane that matches the query.
+type searchMatch struct {
+	Session string
+	Server  string // name of the managed server, "" for the current one
+	Pane    string // "window.pane"
+	PaneID  string
+	Line    string
+}
+
+type searchMsg struct {
+	query     string
+	matches   []searchMatch
+	truncated bool
+	panes     int
+}
+
+// queryMatcher returns whether a line matches the query, ignoring case
+// unless the query has capitals in it.
+func queryMatcher(query string) func(string) bool {
+	for _, r := range query {
+		if unicode.IsUpper(r) {
+			return func(line string) bool { return strings.Contains(line, query) }
+		}
+	}
+	query = strings.ToLower(query)
+	return func(line string) bool { return strings.Contains(strings.ToLower(line), query) }
+}
+
+// searchPanes captures every pane of the sessions in the background and
+// collects the lines matching query.
+func searchPanes(sessions []Session, query string) tea.Cmd {
+	return func() tea.Msg {
+		msg := searchMsg{query: query}
+		match := queryMatcher(query)
+		wanted := map[string]map[string]bool{}
+		for _, s := range sessions {
+			if wanted[s.Server] == nil {
+				wanted[s.Server] = map[string]bool{}
+			}
+			wanted[s.Server][s.Name] = true
+		}
+		for _, server := range managedServers() {
+			names := wanted[server.Name]
+			if len(names) == 0 {
+				continue
+			}
+			out, err := server.command("list-panes", "-a", "-F", "#{session_name}\t#{window_index}.#{pane_index}\t#{pane_id}").Output()
+			if err != nil {
+				continue
+			}
+			for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+				fields := strings.Split(line, "\t")
+				if len(fields) != 3 || !names[fields[0]] {
+					continue
+				}
+				text, err := server.command("capture-pane", "-p", "-J", "-S", fmt.Sprintf("-%d", searchHistory), "-t", fields[2]).Output()
+				if err != nil {
+					continue
+				}
+				msg.panes++
+				for _, l := range strings.Split(string(text), "\n") {
+					if !match(l) {
+						continue
+					}
+					if len(msg.matches) == searchLimit {
+						msg.truncated = true
+						return msg
+					}
+					msg.matches = append(msg.matches, searchMatch{
+						Session: fields[0],
+						Server:  server.Name,
+						Pane:    fields[1],
+						PaneID:  fields[2],
+						Line:    strings.TrimSpace(l),
+					})
+				}
+			}
+		}
+		return msg
+	}
+}
+
+// startSearch asks for what to look for in the panes.
+func (m *model) startSearch() {
+	ti := textinput.New()
+	ti.Placeholder = "Text to find in every pane, e.g. panic:"
+	ti.CharLimit = 200
+	ti.SetValue(m.searchQuery)
+	ti.Focus()
+	m.input = ti
+	m.mode = searchEntering
+}
+
+// runSearch starts searching the sessions listed for the query typed.
+func (m *model) runSearch() tea.Cmd {
+	query := m.input.Value()
+	m.mode = browsing
+	if strings.TrimSpace(query) == "" {
+		return nil
+	}
+	var sessions []Session
+	for _, s := range m.allSessions {
+		if !s.Project {
+			sessions = append(sessions, s)
+		}
+	}
+	m.searchQuery = query
+	m.setMessage(fmt.Sprintf("Searching the panes of %s for '%s'...", plural(len(sessions), "session"), query), "info")
+	return searchPanes(sessions, query)
+}
+
+// showSearchResults lists the matches of a finished search.
+func (m *model) showSearchResults(msg searchMsg) {
+	if len(msg.matches) == 0 {
+		m.setMessage(fmt.Sprintf("'%s' is in none of %s", msg.query, plural(msg.panes, "pane")), "info")
+		return
+	}
+	m.setMessage("", "")
+	if msg.truncated {
+		m.setMessage(fmt.Sprintf("Showing the first %d matching lines", searchLimit), "warning")
+	}
+	m.searchMatches = msg.matches
+	m.searchCursor = 0
+	m.mode = searchBrowsing
+}
+
+// jumpToMatch selects the pane of the highlighted match, then attaches to
+// its session and quits.
+func (m *model) jumpToMatch() tea.Cmd {
+	match := m.searchMatches[m.searchCursor]
+	srv := serverNamed(match.Server)
+	return inBackground(func() error {
+		return srv.selectPane(match.PaneID)
+	}, func(m *model, err error) tea.Cmd {
+		if err != nil {
+			m.setMessage(fmt.Sprintf("Failed to select pane: %v", err), "error")
+			return nil
+		}
+		attachSession(srv, match.Session)
+		return tea.Quit
+	})
+}
+
+func (m model) renderSearchPrompt() string {
+	prompt := fmt.Sprintf("🔎 Search the panes of every session\n%s\n", m.input.View())
+	prompt += lipgloss.NewStyle().Foreground(mutedColor).Render("[Enter] Search • [Esc] Cancel")
+	inputView := inputBoxStyle.Render(prompt)
+	return lipgloss.Place(m.width, 5, lipgloss.Center, lipgloss.Top, inputView)
+}
+
+func (m model) renderSearchView(tableWidth int) string {
+	var content strings.Builder
+	title := tableHeaderStyle.Width(tableWidth).Render(fmt.Sprintf("🔎 '%s' IN %s", m.searchQuery, strings.ToUpper(plural(len(m.searchMatches), "line"))))
+	content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, title))
+	content.WriteString("\n\n")
+
+	rows := max(5, m.height-10)
+	start := 0
+	if m.searchCursor >= rows {
+		start = m.searchCursor - rows + 1
+	}
+	end := min(len(m.searchMatches), start+rows)
+
+	muted := lipgloss.NewStyle().Foreground(mutedColor)
+	where := lipgloss.NewStyle().Bold(true)
+	for i := start; i < end; i++ {
+		match := m.searchMatches[i]
+		location := match.Session + ":" + match.Pane
+		if label := serverNamed(match.Server).label(); label != "" {
+			location = label + "/" + location
+		}
+		text := rightTruncate(match.Line, max(10, tableWidth-lipgloss.Width(location)-8))
+		row := "  " + where.Render(location) + "  " + text
+		if i == m.searchCursor {
+			row = selectedRowStyle.Copy().Padding(0, 1).Render("▶ " + location + "  " + text)
+		}
+		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, row))
+		content.WriteString("\n")
+	}
+	content.WriteString("\n")
+	if m.message != "" {
+		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, m.renderMessage()))
+		content.WriteString("\n")
+	}
+	help := muted.Render("[j/k] Move • [Enter] Attach to the pane • [/] Search again • [Esc] Back")
+	content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, help))
+	return content.String()
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Server is a tmux server lazytmux can manage: the default one, one on
+// another socket, or one on another machine reached over ssh.
+type Server struct {
+	// Shown in the SERVER column when several servers are listed.
+	Name       string `json:"name,omitempty"`
+	SocketName string `json:"socket_name,omitempty"` // tmux -L
+	SocketPath string `json:"socket_path,omitempty"` // tmux -S
+	// ssh destination the server runs on, e.g. "me@build-box"; empty for
+	// this machine.
+	Host string `json:"host,omitempty"`
+}
+
+// Profile names a server lazytmux can be pointed at with --profile.
+type Profile = Server
+
+var (
+	serverMu sync.RWMutex
+	// server is the one tmux commands go to.
+	server Server
+	// dashboardServers are listed together, set from the servers config
+	// option; empty when only server is managed.
+	dashboardServers []Server
+)
+
+func currentServer() Server {
+	serverMu.RLock()
+	defer serverMu.RUnlock()
+	return server
+}
+
+// useServer sends the following tmux commands to s.
+func useServer(s Server) {
+	serverMu.Lock()
+	server = s
+	serverMu.Unlock()
+}
+
+// serverNamed returns the dashboard server called name, or the current
+// server when there is no dashboard or no such server.
+func serverNamed(name string) Server {
+	for _, s := range dashboardServers {
+		if s.Name == name {
+			return s
+		}
+	}
+	return currentServer()
+}
+
+// managedServers returns the dashboard servers, or the current one alone.
+func managedServers() []Server {
+	if len(dashboardServers) == 0 {
+		return []Server{currentServer()}
+	}
+	return dashboardServers
+}
+
+func (s Server) remote() bool {
+	return s.Host != ""
+}
+
+// label names the server for the status bar and SERVER column, or "" for
+// the default local server.
+func (s Server) label() string {
+	switch {
+	case s.Name != "":
+		return s.Name
+	case s.Host != "" && s.SocketName != "":
+		return s.Host + ":" + s.SocketName
+	case s.Host != "":
+		return s.Host
+	case s.SocketName != "":
+		return s.SocketName
+	}
+	return s.SocketPath
+}
+
+func (s Server) socketArgs() []string {
+	switch {
+	case s.SocketName != "":
+		return []string{"-L", s.SocketName}
+	case s.SocketPath != "":
+		return []string{"-S", expandHome(s.SocketPath)}
+	}
+	return nil
+}
+
+// argv is the command line running tmux with args on the server. Remote
+// servers get it through ssh, which hands it to a shell.
+func (s Server) argv(args ...string) []string {
+	argv := append(append([]string{"tmux"}, s.socketArgs()...), args...)
+	if !s.remote() {
+		return argv
+	}
+	quoted := make([]string, len(argv))
+	for i, arg := range argv {
+		quoted[i] = shellQuote(arg)
+	}
+	return []string{"ssh", "-o", "BatchMode=yes", s.Host, strings.Join(quoted, " ")}
+}
+
+// command runs tmux with args on the server.
+func (s Server) command(args ...string) *exec.Cmd {
+	argv := s.argv(args...)
+	return exec.Command(argv[0], argv[1:]...)
+}
+
+// tmuxCommand runs tmux with args on the current server.
+func tmuxCommand(args ...string) *exec.Cmd {
+	return currentServer().command(args...)
+}
+
+// run runs tmux with args on the server and returns its error message, if
+// any.
+func (s Server) run(args ...string) error {
+	var stderr bytes.Buffer
+	cmd := s.command(args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return errors.New(msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// CommandContext is command, killed when ctx is done. It makes s the
+// lazytmux.Tmux the template engine builds sessions on.
+func (s Server) CommandContext(ctx context.Context, args ...string) *exec.Cmd {
+	argv := s.argv(args...)
+	return exec.CommandContext(ctx, argv[0], argv[1:]...)
+}
+
+// withTmuxSocket points the "tmux" of a command line meant for another
+// program, such as a terminal emulator, at s.
+func (s Server) withTmuxSocket(argv []string) []string {
+	for i, arg := range argv {
+		if arg != "tmux" {
+			continue
+		}
+		out := append([]string{}, argv[:i]...)
+		if s.remote() {
+			out = append(out, "ssh", "-t", s.Host)
+		}
+		out = append(out, "tmux")
+		out = append(out, s.socketArgs()...)
+		return append(out, argv[i+1:]...)
+	}
+	return argv
+}
+
+// remoteQuote quotes an argument that ssh hands to the remote shell when
+// s is on another machine.
+func (s Server) remoteQuote(arg string) string {
+	if s.remote() {
+		return shellQuote(arg)
+	}
+	return arg
+}
+
+// tmuxShellPrefix is "tmux" pointed at s, for commands shown to the user
+// or run by a shell.
+func (s Server) tmuxShellPrefix() string {
+	var words []string
+	if s.remote() {
+		words = append(words, "ssh", "-t", shellQuote(s.Host))
+	}
+	words = append(words, "tmux")
+	for _, arg := range s.socketArgs() {
+		if strings.HasPrefix(arg, "-") {
+			words = append(words, arg)
+		} else {
+			words = append(words, shellQuote(arg))
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// selectServer picks the server to manage. Flags win over the profile,
+// which wins over socket_name and socket_path in the config file; any of
+// them turns the servers dashboard off.
+func selectServer(name, path, profile string) error {
+	if name != "" && path != "" {
+		return fmt.Errorf("--socket-name and --socket-path can't be used together")
+	}
+	s := Server{SocketName: config.SocketName, SocketPath: config.SocketPath}
+	chosen := s.SocketName != "" || s.SocketPath != ""
+	if profile != "" {
+		var ok bool
+		if s, ok = config.Profiles[profile]; !ok {
+			return fmt.Errorf("no profile named '%s' in %s", profile, getConfigFile())
+		}
+		chosen = true
+	}
+	if name != "" || path != "" {
+		s = Server{SocketName: name, SocketPath: path}
+		chosen = true
+	}
+	if s.SocketName != "" && s.SocketPath != "" {
+		return fmt.Errorf("socket_name and socket_path can't both be set")
+	}
+	useServer(s)
+
+	if !chosen && len(config.Servers) > 0 {
+		dashboardServers = config.Servers
+		for i := range dashboardServers {
+			if dashboardServers[i].Name == "" {
+				dashboardServers[i].Name = dashboardServers[i].label()
+			}
+			if dashboardServers[i].Name == "" {
+				dashboardServers[i].Name = "local"
+			}
+		}
+		useServer(dashboardServers[0])
+	}
+	return nil
+}
+
+// targetServer names the server of the session the current view works
+// on: the one whose windows, panes or client are shown, else the
+// highlighted one.
+func (m model) targetServer() string {
+	session := ""
+	switch {
+	case m.showWindows:
+		session = m.windowSession
+	case m.showPanes:
+		session = m.paneSession
+	case m.showClients:
+		if m.clientCursor < len(m.clients) {
+			return m.clients[m.clientCursor].Server
+		}
+	case m.cursor < len(m.sessions):
+		return m.sessions[m.cursor].Server
+	}
+	for _, s := range m.allSessions {
+		if s.Name == session {
+			return s.Server
+		}
+	}
+	return ""
+}
+
+// server is the server of the session the current view works on, which
+// the tmux commands it runs go to.
+func (m model) server() Server {
+	return serverNamed(m.targetServer())
+}
+
+// serverLabel names what the status bar should show as managed, or "" for
+// the default server alone.
+func serverLabel() string {
+	if len(dashboardServers) > 0 {
+		return fmt.Sprintf("%d servers", len(dashboardServers))
+	}
+	return currentServer().label()
+}
+
+// insideServer reports whether lazytmux runs inside a client of s, which
+// can then simply switch sessions.
+func (s Server) insideServer() bool {
+	client := os.Getenv("TMUX")
+	if client == "" || s.remote() {
+		return false
+	}
+	if s.SocketName == "" && s.SocketPath == "" {
+		return true
+	}
+	out, err := s.command("display-message", "-p", "#{socket_path}").Output()
+	return err == nil && strings.TrimSpace(string(out)) == strings.SplitN(client, ",", 2)[0]
+}
+
+// serverProblems lists what is wrong with the server settings, for doctor.
+func serverProblems(cfg Config) []string {
+	var problems []string
+	if cfg.SocketName != "" && cfg.SocketPath != "" {
+		problems = append(problems, "socket_name and socket_path can't both be set")
+	}
+	for _, name := range sortedProfileNames(cfg.Profiles) {
+		p := cfg.Profiles[name]
+		if p.SocketName != "" && p.SocketPath != "" {
+			problems = append(problems, fmt.Sprintf("profile %s: socket_name and socket_path can't both be set", name))
+		}
+		if p.SocketName == "" && p.SocketPath == "" && p.Host == "" {
+			problems = append(problems, fmt.Sprintf("profile %s: set socket_name, socket_path or host", name))
+		}
+	}
+	seen := map[string]bool{}
+	for i, s := range cfg.Servers {
+		label := s.Name
+		if label == "" {
+			label = s.label()
+		}
+		if s.SocketName != "" && s.SocketPath != "" {
+			problems = append(problems, fmt.Sprintf("server %d: socket_name and socket_path can't both be set", i+1))
+		}
+		if seen[label] {
+			problems = append(problems, fmt.Sprintf("server %d: name %q is used twice", i+1, label))
+		}
+		seen[label] = true
+	}
+	return problems
+}
+
+func sortedProfileNames(profiles map[string]Profile) []string {
+	names := map[string]string{}
+	for name := range profiles {
+		names[name] = ""
+	}
+	return sortedKeys(names)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// shortDir abbreviates the home directory to ~.
+func shortDir(dir string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return dir
+	}
+	if dir == home {
+		return "~"
+	}
+	if strings.HasPrefix(dir, home+"/") {
+		return "~" + dir[len(home):]
+	}
+	return dir
+}
+
+// leftTruncate shortens s to width runes, keeping the end, which is the
+// telling part of a path.
+func leftTruncate(s string, width int) string {
+	r := []rune(s)
+	if len(r) <= width || width < 2 {
+		return s
+	}
+	return "…" + string(r[len(r)-width+1:])
+}
+
+// rightTruncate is leftTruncate for text whose start matters.
+func rightTruncate(s string, width int) string {
+	r := []rune(s)
+	if len(r) <= width || width < 2 {
+		return s
+	}
+	return string(r[:width-1]) + "…"
+}
+
+// matchesFilter reports whether the session name or the directory of its
+// active pane contains filter, ignoring case. ~ works for the home directory.
+func (s Session) matchesFilter(filter string) bool {
+	filter = strings.ToLower(filter)
+	for _, field := range []string{s.Name, s.Dir, shortDir(s.Dir)} {
+		if strings.Contains(strings.ToLower(field), filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// showSessions derives the visible session list from allSessions: only our
+// own if asked, matching the filter, sorted by directory if asked.
+func (m *model) showSessions() {
+	var current *Session
+	if m.cursor < len(m.sessions) {
+		s := m.sessions[m.cursor]
+		current = &s
+	}
+	sessions := m.allSessions
+	if m.mineOnly {
+		sessions = ownSessions(sessions)
+	}
+	// Projects follow the live sessions, filtered and sorted on their own.
+	m.sessions = append(pinSessions(m.shownSessions(sessions), m.favorites.Sessions), m.shownSessions(m.projects)...)
+	// The cursor stays on the session it was on wherever the new list puts
+	// it, so a refresh in the middle of moving around can't land a key on
+	// another session. If it is gone the cursor stays where it was, on the
+	// session that came after it.
+	if current != nil {
+		for i, s := range m.sessions {
+			if s.Name == current.Name && s.Server == current.Server && s.Project == current.Project {
+				m.cursor = i
+				return
+			}
+		}
+	}
+	if m.cursor >= len(m.sessions) {
+		m.cursor = max(0, len(m.sessions)-1)
+	}
+}
+
+// shownSessions returns the sessions matching the filter, sorted by
+// directory if asked.
+func (m model) shownSessions(sessions []Session) []Session {
+	if m.sessionFilter != "" {
+		var shown []Session
+		for _, s := range sessions {
+			if s.matchesFilter(m.sessionFilter) {
+				shown = append(shown, s)
+			}
+		}
+		sessions = shown
+	}
+	if m.sortByDir {
+		sessions = append([]Session(nil), sessions...)
+		sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].Dir < sessions[j].Dir })
+	}
+	return sessions
+}
+
+// pathView reports whether the session table shows directories instead of
+// window counts and creation times.
+func (m model) pathView() bool {
+	return m.sortByDir || m.sessionFilter != "" || m.mode == sessionFiltering
+}
+
+func (m *model) startSessionFilter() {
+	ti := textinput.New()
+	ti.Placeholder = "Part of a name or directory, e.g. code/billing"
+	ti.CharLimit = 100
+	ti.SetValue(m.sessionFilter)
+	ti.CursorEnd()
+	ti.Focus()
+	m.input = ti
+	m.previousFilter = m.sessionFilter
+	m.mode = sessionFiltering
+}
+
+func (m model) renderSessionFilter() string {
+	form := "🔍 Filter sessions\n\n" + m.input.View() + "\n\n" +
+		lipgloss.NewStyle().Foreground(mutedColor).Render("[Enter] Keep • [Esc] Cancel")
+	inputView := inputBoxStyle.Render(form)
+	return lipgloss.Place(m.width, 7, lipgloss.Center, lipgloss.Top, inputView)
+}
@@ -0,0 +1,575 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// attachHere attaches the current terminal to the session on srv, or switches the
+// client when already inside tmux. Outside tmux it replaces this process.
+// Switching keeps the client as it is: making it read-only would lock the
+// user out of their own tmux.
+func attachHere(srv Server, name string) error {
+	if err := runPreAttach(name); err != nil {
+		return err
+	}
+	if srv.insideServer() {
+		return srv.command("switch-client", "-t", "="+name).Run()
+	}
+	argv := srv.withTmuxSocket(append([]string{"tmux", "attach-session", "-t", srv.remoteQuote("=" + name)}, attachFlags(name)...))
+	tmux, err := exec.LookPath(argv[0])
+	if err != nil {
+		return err
+	}
+	// Attaching to another server from inside tmux nests a client, which
+	// tmux only allows once told it isn't inside itself.
+	env := []string{}
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, "TMUX=") {
+			env = append(env, kv)
+		}
+	}
+	return syscall.Exec(tmux, argv, env)
+}
+
+// cliSessions lists the sessions with the config rules applied, honoring
+// the global -mine flag.
+func cliSessions(mineOnly bool) []Session {
+	sessions := listTmuxSessions()
+	applyRules(sessions, config.Rules)
+	applyMetadata(sessions, loadMetadata())
+	if mineOnly {
+		sessions = ownSessions(sessions)
+	}
+	return sessions
+}
+
+// matchSession picks the session query refers to: an exact name, else the
+// only name starting with it, containing it, or containing its letters in
+// order. It is an error if a step matches more than one session.
+func matchSession(query string, sessions []Session) (Session, error) {
+	steps := []func(name string) bool{
+		func(name string) bool { return name == query },
+		func(name string) bool { return strings.HasPrefix(strings.ToLower(name), strings.ToLower(query)) },
+		func(name string) bool { return strings.Contains(strings.ToLower(name), strings.ToLower(query)) },
+		func(name string) bool { return subsequence(strings.ToLower(query), strings.ToLower(name)) },
+	}
+	for _, matches := range steps {
+		var found []Session
+		var names []string
+		for _, s := range sessions {
+			if matches(s.Name) {
+				found = append(found, s)
+				names = append(names, s.Name)
+			}
+		}
+		switch len(found) {
+		case 0:
+			continue
+		case 1:
+			return found[0], nil
+		default:
+			return Session{}, fmt.Errorf("'%s' matches %s", query, strings.Join(names, ", "))
+		}
+	}
+	return Session{}, fmt.Errorf("no session matches '%s'", query)
+}
+
+// subsequence reports whether the runes of sub appear in s in order.
+func subsequence(sub, s string) bool {
+	rs := []rune(sub)
+	if len(rs) == 0 {
+		return false
+	}
+	for _, r := range s {
+		if r == rs[0] {
+			rs = rs[1:]
+			if len(rs) == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sessionJSON is a session as printed by list --format json.
+type sessionJSON struct {
+	Name      string     `json:"name"`
+	Windows   int        `json:"windows"`
+	Created   *time.Time `json:"created,omitempty"`
+	Attached  bool       `json:"attached"`
+	Owner     string     `json:"owner,omitempty"`
+	Dir       string     `json:"dir,omitempty"`
+	Command   string     `json:"command,omitempty"`
+	Template  string     `json:"template,omitempty"`
+	Tags      []string   `json:"tags,omitempty"`
+	Note      string     `json:"note,omitempty"`
+	Bell      bool       `json:"bell,omitempty"`
+	Activity  bool       `json:"activity,omitempty"`
+	Silence   bool       `json:"silence,omitempty"`
+	Protected bool       `json:"protected,omitempty"`
+}
+
+// templateJSON is a template as printed by list --templates --format json.
+type templateJSON struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	Windows     int        `json:"windows"`
+	Panes       int        `json:"panes"`
+	File        string     `json:"file,omitempty"`
+	LastUsed    *time.Time `json:"last_used,omitempty"`
+	Uses        int        `json:"uses"`
+}
+
+func sessionsJSON(sessions []Session) []sessionJSON {
+	out := []sessionJSON{}
+	for _, s := range sessions {
+		j := sessionJSON{
+			Name:      s.Name,
+			Windows:   s.Windows,
+			Attached:  s.Attached,
+			Owner:     s.Owner,
+			Dir:       s.Dir,
+			Command:   s.Command,
+			Template:  s.Template,
+			Tags:      s.Tags,
+			Note:      s.Note,
+			Bell:      s.Bell,
+			Activity:  s.Activity,
+			Silence:   s.Silence,
+			Protected: s.Protected,
+		}
+		if !s.CreatedAt.IsZero() {
+			created := s.CreatedAt
+			j.Created = &created
+		}
+		out = append(out, j)
+	}
+	return out
+}
+
+func printJSON(v interface{}) int {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runList prints the sessions, or the templates, as a table, as JSON or
+// just their names.
+func runList(args []string, mineOnly bool) int {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	format := fs.String("format", "text", "Output format: text, json or names")
+	asJSON := fs.Bool("json", false, "Same as --format json")
+	namesOnly := fs.Bool("names", false, "Same as --format names")
+	templates := fs.Bool("templates", false, "List templates instead of sessions")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s list [--templates] [--format text|json|names] [--json] [--names]\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+	switch {
+	case *asJSON:
+		*format = "json"
+	case *namesOnly:
+		*format = "names"
+	}
+	switch *format {
+	case "text", "json", "names":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q (want text, json or names)\n", *format)
+		return 2
+	}
+
+	if *templates {
+		return listTemplates(*format)
+	}
+	return listSessions(*format, mineOnly)
+}
+
+func listSessions(format string, mineOnly bool) int {
+	sessions := cliSessions(mineOnly)
+	switch format {
+	case "names":
+		for _, s := range sessions {
+			fmt.Println(s.Name)
+		}
+		return 0
+	case "json":
+		return printJSON(sessionsJSON(sessions))
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tWINDOWS\tSTATUS\tOWNER\tCREATED\tCOMMAND\tLABELS")
+	for _, s := range sessions {
+		status := "detached"
+		if s.Attached {
+			status = "attached"
+		}
+		var labels []string
+		if s.Protected {
+			labels = append(labels, "protected")
+		}
+		for _, tag := range s.Tags {
+			labels = append(labels, "#"+tag)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\n", s.Name, s.Windows, status, s.Owner, s.Created, s.Command, strings.Join(labels, " "))
+	}
+	w.Flush()
+	return 0
+}
+
+func listTemplates(format string) int {
+	templates := loadTemplates()
+	usage := loadUsage()
+	switch format {
+	case "names":
+		for _, t := range templates {
+			fmt.Println(t.Name)
+		}
+		return 0
+	case "json":
+		out := []templateJSON{}
+		for _, t := range templates {
+			panes := len(t.Panes)
+			for _, win := range t.Windows {
+				panes += len(win.Panes)
+			}
+			j := templateJSON{
+				Name:        t.Name,
+				Description: t.Description,
+				Tags:        t.Tags,
+				Windows:     1 + len(t.Windows),
+				Panes:       panes,
+				File:        t.file,
+				Uses:        usage[t.Name].Count,
+			}
+			if u, ok := usage[t.Name]; ok && !u.LastUsed.IsZero() {
+				lastUsed := u.LastUsed
+				j.LastUsed = &lastUsed
+			}
+			out = append(out, j)
+		}
+		return printJSON(out)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tWINDOWS\tUSES\tTAGS\tDESCRIPTION")
+	for _, t := range templates {
+		var tags []string
+		for _, tag := range t.Tags {
+			tags = append(tags, "#"+tag)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\n", t.Name, 1+len(t.Windows), usage[t.Name].Count, strings.Join(tags, " "), t.Description)
+	}
+	w.Flush()
+	return 0
+}
+
+// runNew creates an empty session and prints its name.
+func runNew(args []string) int {
+	fs := flag.NewFlagSet("new", flag.ContinueOnError)
+	dir := fs.String("dir", "", "Start directory of the first pane")
+	attach := fs.Bool("attach", false, "Attach to the session after creating it")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s new [--dir dir] [--attach] [name]\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	args, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(args) > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	name := generateNumericName(listTmuxSessions())
+	if len(args) == 1 {
+		name = sanitizeSessionName(args[0])
+	}
+	if err := checkSessionName(name, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := currentServer().createSessionIn(name, expandHome(*dir)); err != nil {
+		if errors.Is(err, errSessionExists) {
+			err = fmt.Errorf("%w: %s", err, name)
+		}
+		return claimFailed(err)
+	}
+	fmt.Println(name)
+	return attachAfter(currentServer(), name, *attach)
+}
+
+// runStart creates a session from a saved template, or from the local
+// template of a directory, and prints its name.
+func runStart(args []string) int {
+	fs := flag.NewFlagSet("start", flag.ContinueOnError)
+	exists := fs.String("exists", existsFail, "What to do if the session already exists: attach, fail or suffix")
+	attach := fs.Bool("attach", false, "Attach to the session after creating it")
+	preset := fs.String("preset", "", "Fill in the template's variables from this `preset`; --var overrides single values")
+	vars := varFlags{}
+	fs.Var(vars, "var", "Value for a template `name=value` placeholder (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s start [--exists=attach|fail|suffix] [--preset name] [--var name=value]... [--attach] [<template>|<dir>] [session-name]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Without a template, or given a directory like ., the %s of the directory or its closest parent is used.\n\n", localTemplateFile)
+		fs.PrintDefaults()
+	}
+	args, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(args) > 2 {
+		fs.Usage()
+		return 2
+	}
+
+	var template SessionTemplate
+	local := len(args) == 0 || isDirArg(args[0])
+	if local {
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+		path, ok := findLocalTemplate(dir)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: no %s in %s or its parents\n", localTemplateFile, dir)
+			return 1
+		}
+		if template, err = readLocalTemplate(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	} else {
+		saved := findTemplateByPrefix(args[0], loadTemplates())
+		if saved == nil {
+			fmt.Fprintf(os.Stderr, "Error: template '%s' not found\n", args[0])
+			return 1
+		}
+		template = *saved
+	}
+	values := map[string]string(vars)
+	if *preset != "" {
+		if values, err = template.presetValues(*preset, vars); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	template, err = template.withVars(values)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v (pass --var name=value)\n", err)
+		return 1
+	}
+	sessionName := sanitizeSessionName(template.Name)
+	if len(args) == 2 {
+		sessionName = sanitizeSessionName(args[1])
+	}
+	if err := checkSessionName(sessionName, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	srv := currentServer()
+	sessionName, created, err := srv.claimSession(sessionName, template.StartDir(), *exists)
+	if err != nil {
+		return claimFailed(err)
+	}
+	if created {
+		if err := srv.applyTemplate(sessionName, template); err != nil {
+			_ = srv.killSession(sessionName)
+			fmt.Fprintf(os.Stderr, "Error: failed to create session from template: %v\n", err)
+			return 1
+		}
+		if !local {
+			recordTemplateUse(template.Name)
+		}
+	}
+	fmt.Println(sessionName)
+	if *attach {
+		if template.afterCreate() == "read-only" {
+			attachVariants[sessionName] = attachReadOnly
+		}
+		if _, err := runHook(template.OnAttach, sessionName, template); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: on_attach hook failed: %v\n", err)
+		}
+	}
+	return attachAfter(srv, sessionName, *attach)
+}
+
+// isDirArg reports whether a start argument names a directory rather than
+// a saved template: . and anything that looks like a path.
+func isDirArg(arg string) bool {
+	return arg == "." || arg == ".." || arg == "~" || strings.ContainsRune(arg, filepath.Separator)
+}
+
+// attachAfter attaches to a session just created on srv if asked to.
+func attachAfter(srv Server, name string, attach bool) int {
+	if !attach {
+		return 0
+	}
+	if err := attachHere(srv, name); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not attach to '%s': %v\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+// runAttach attaches the current terminal to the session best matching
+// the query.
+func runAttach(args []string, mineOnly bool) int {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s attach <session>\n", os.Args[0])
+		return 2
+	}
+	session, err := matchSession(args[0], cliSessions(mineOnly))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := attachHere(serverNamed(session.Server), session.Name); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not attach to '%s': %v\n", session.Name, err)
+		return 1
+	}
+	return 0
+}
+
+// runKill kills sessions by exact name. Like the TUI it refuses in
+// read-only mode and for protected sessions, and logs to the audit log.
+func runKill(args []string, mineOnly bool) int {
+	fs := flag.NewFlagSet("kill", flag.ContinueOnError)
+	match := fs.String("match", "", "Kill every session whose name matches this glob, e.g. 'scratch-*'")
+	useRegex := fs.Bool("regex", false, "Treat --match as a regular expression")
+	dryRun := fs.Bool("dry-run", false, "Only list the sessions --match would kill")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s kill <session>...\n       %s kill --match pattern [--regex] [--dry-run]\n\n", os.Args[0], os.Args[0])
+		fs.PrintDefaults()
+	}
+	args, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if (*match == "") == (len(args) == 0) {
+		fs.Usage()
+		return 2
+	}
+	if readOnly && !*dryRun {
+		fmt.Fprintln(os.Stderr, "Error: read-only mode: killing sessions is disabled")
+		return 1
+	}
+
+	sessions := cliSessions(mineOnly)
+	if *match != "" {
+		return killMatching(*match, *useRegex, *dryRun, sessions)
+	}
+	status := 0
+	for _, name := range args {
+		if err := killByName(name, sessions); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			status = 1
+		}
+	}
+	return status
+}
+
+// killMatching kills the sessions whose name matches pattern, listing each
+// one as it goes. Protected sessions are listed but kept.
+func killMatching(pattern string, useRegex, dryRun bool, sessions []Session) int {
+	matches, err := sessionMatcher(pattern, useRegex)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	found, status := 0, 0
+	for _, s := range sessions {
+		if !matches(s.Name) {
+			continue
+		}
+		found++
+		switch {
+		case s.Protected:
+			fmt.Printf("kept %s (protected by a rule)\n", s.Name)
+		case dryRun:
+			fmt.Printf("would kill %s\n", s.Name)
+		default:
+			if err := killByName(s.Name, sessions); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				status = 1
+				continue
+			}
+			fmt.Printf("killed %s\n", s.Name)
+		}
+	}
+	if found == 0 {
+		fmt.Fprintf(os.Stderr, "No sessions match '%s'\n", pattern)
+	}
+	return status
+}
+
+// sessionMatcher returns a test for session names: a glob, where * doesn't
+// stop at '/', or with useRegex a regular expression matching anywhere in
+// the name.
+func sessionMatcher(pattern string, useRegex bool) (func(string) bool, error) {
+	if useRegex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %v", err)
+		}
+		return re.MatchString, nil
+	}
+	// Session names aren't paths, so match them with / as an ordinary character.
+	const slash = "\x00"
+	pattern = strings.ReplaceAll(pattern, "/", slash)
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern '%s'", strings.ReplaceAll(pattern, slash, "/"))
+	}
+	return func(name string) bool {
+		ok, _ := path.Match(pattern, strings.ReplaceAll(name, "/", slash))
+		return ok
+	}, nil
+}
+
+// killByName kills the session with exactly that name, unless a rule
+// protects it, and records the attempt in the audit log.
+func killByName(name string, sessions []Session) error {
+	var session *Session
+	for i := range sessions {
+		if sessions[i].Name == name {
+			session = &sessions[i]
+		}
+	}
+	switch {
+	case session == nil:
+		return fmt.Errorf("no session named '%s'", name)
+	case session.Protected:
+		return fmt.Errorf("session '%s' is protected by a rule", name)
+	}
+	err := serverNamed(session.Server).killSession(name)
+	recordAudit("kill-session", name, err)
+	if err != nil {
+		return fmt.Errorf("failed to kill '%s': %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Shared templates are small; anything bigger is most likely the wrong URL.
+const maxTemplateSize = 1 << 20
+
+var templateFetchClient = &http.Client{Timeout: 15 * time.Second}
+
+// readSource returns the contents of a local file, stdin ("-") or an HTTPS
+// URL. Plain HTTP is refused since a template runs commands on our machine.
+func readSource(src string) ([]byte, error) {
+	switch {
+	case src == "-":
+		return io.ReadAll(io.LimitReader(os.Stdin, maxTemplateSize+1))
+	case strings.HasPrefix(src, "http://"):
+		return nil, errors.New("refusing to fetch a template over plain http, use https")
+	case strings.HasPrefix(src, "https://"):
+		return fetchURL(src)
+	}
+	return ioutil.ReadFile(expandHome(src))
+}
+
+func fetchURL(src string) ([]byte, error) {
+	resp, err := templateFetchClient.Get(src)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", src, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxTemplateSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxTemplateSize {
+		return nil, fmt.Errorf("%s: larger than %d bytes", src, maxTemplateSize)
+	}
+	return data, nil
+}
+
+// sourceName derives a template name from a file path or URL for templates
+// that don't carry one.
+func sourceName(src string) string {
+	base := filepath.Base(src)
+	if u, err := url.Parse(src); err == nil && u.Scheme != "" {
+		base = path.Base(u.Path)
+	}
+	if base == "-" || base == "/" || base == "." {
+		return ""
+	}
+	return strings.TrimSuffix(base, path.Ext(base))
+}
+
+// fetchTemplate reads and validates a template for importing. A non-empty
+// name overrides the one stored in the template.
+func fetchTemplate(src, name string) (SessionTemplate, error) {
+	data, err := readSource(src)
+	if err != nil {
+		return SessionTemplate{}, err
+	}
+	if len(data) > maxTemplateSize {
+		return SessionTemplate{}, fmt.Errorf("template is larger than %d bytes", maxTemplateSize)
+	}
+	template, err := decodeTemplate(data)
+	if err != nil {
+		return SessionTemplate{}, fmt.Errorf("invalid template: %v", err)
+	}
+	if name != "" {
+		template.Name = name
+	}
+	if strings.TrimSpace(template.Name) == "" {
+		template.Name = sourceName(src)
+	}
+	if template.Name == "" {
+		return SessionTemplate{}, errors.New("invalid template: no name, pass --name")
+	}
+	if problems := templateProblems(template); len(problems) > 0 {
+		return SessionTemplate{}, fmt.Errorf("invalid template: %s", strings.Join(problems, "; "))
+	}
+	return template, nil
+}
+
+// uniqueTemplateName returns name, or the first free name-2, name-3, ...
+// if a template or session already uses it.
+func uniqueTemplateName(name string, sessions []Session, templates []SessionTemplate) string {
+	candidate := name
+	for n := 2; nameExists(candidate, sessions, templates); n++ {
+		candidate = fmt.Sprintf("%s-%d", name, n)
+	}
+	return candidate
+}
+
+const existsReplace = "replace"
+
+// addImportedTemplate adds template to templates, resolving a name clash
+// according to policy: fail, suffix or replace. It returns the updated list
+// and the name the template was stored under.
+func addImportedTemplate(template SessionTemplate, templates []SessionTemplate, sessions []Session, policy string) ([]SessionTemplate, string, error) {
+	template.file = ""
+	for i, t := range templates {
+		if t.Name != template.Name {
+			continue
+		}
+		switch policy {
+		case existsFail:
+			return nil, "", fmt.Errorf("template '%s' already exists", template.Name)
+		case existsReplace:
+			template.file = t.file
+			out := append([]SessionTemplate(nil), templates...)
+			out[i] = template
+			return out, template.Name, nil
+		}
+	}
+	if policy == existsSuffix {
+		template.Name = uniqueTemplateName(template.Name, sessions, templates)
+	}
+	return append(templates, template), template.Name, nil
+}
+
+// runImportTemplate stores a template read from a file, stdin or an HTTPS
+// URL: lazytmux import [--name N] [--exists=fail|suffix|replace] <file|url|->
+func runImportTemplate(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	name := fs.String("name", "", "Store the template under this name instead of its own")
+	exists := fs.String("exists", existsSuffix, "What to do if the name is taken: fail, suffix or replace")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s import [--name name] [--exists=fail|suffix|replace] <file|url|->\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	args, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(args) != 1 {
+		fs.Usage()
+		return 2
+	}
+	switch *exists {
+	case existsFail, existsSuffix, existsReplace:
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown --exists value %q (want fail, suffix or replace)\n", *exists)
+		return 2
+	}
+
+	template, err := fetchTemplate(args[0], strings.TrimSpace(*name))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	templates, stored, err := addImportedTemplate(template, loadTemplates(), listTmuxSessions(), *exists)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v (use --exists=suffix or --exists=replace)\n", err)
+		return 1
+	}
+	if err := saveTemplates(templates); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to save templates: %v\n", err)
+		return 1
+	}
+	if stored != template.Name {
+		fmt.Fprintf(os.Stderr, "template '%s' already exists, stored as '%s'\n", template.Name, stored)
+	}
+	fmt.Println(stored)
+	return 0
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// serverStats sums up the managed tmux servers for the footer of the
+// session table.
+type serverStats struct {
+	Windows int
+	Panes   int
+	Clients int
+	Started []time.Time // of each server that answered
+}
+
+// statsQuery asks a server for everything the footer shows in a single
+// tmux invocation, one line per window, pane and client and one for the
+// server itself.
+var statsQuery = []string{
+	"list-windows", "-a", "-F", "w",
+	";", "list-panes", "-a", "-F", "p",
+	";", "list-clients", "-F", "c",
+	";", "display-message", "-p", "s #{start_time}",
+}
+
+func loadServerStats() serverStats {
+	var stats serverStats
+	for _, s := range managedServers() {
+		// A server without sessions fails the last command; what the
+		// others printed still counts.
+		out, _ := s.command(statsQuery...).Output()
+		for _, line := range strings.Split(string(out), "\n") {
+			kind, value, _ := strings.Cut(line, " ")
+			switch kind {
+			case "w":
+				stats.Windows++
+			case "p":
+				stats.Panes++
+			case "c":
+				stats.Clients++
+			case "s":
+				if ts, err := strconv.ParseInt(value, 10, 64); err == nil {
+					stats.Started = append(stats.Started, time.Unix(ts, 0))
+				}
+			}
+		}
+	}
+	return stats
+}
+
+// formatUptime describes how long a server has been running.
+func formatUptime(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%dd %dh", int(d.Hours()/24), int(d.Hours())%24)
+}
+
+// uptime describes how long the servers have been running, the shortest
+// and longest when there are several.
+func (s serverStats) uptime() string {
+	var ups []time.Duration
+	for _, started := range s.Started {
+		ups = append(ups, time.Since(started))
+	}
+	sort.Slice(ups, func(i, j int) bool { return ups[i] < ups[j] })
+	switch len(ups) {
+	case 0:
+		return "no tmux server running"
+	case 1:
+		return "server up " + formatUptime(ups[0])
+	}
+	return fmt.Sprintf("%d servers up %s to %s", len(ups), formatUptime(ups[0]), formatUptime(ups[len(ups)-1]))
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, word)
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
+
+// renderStatsFooter renders the totals below the session table.
+func (m model) renderStatsFooter() string {
+	text := "Σ " + m.stats.uptime()
+	if len(m.stats.Started) > 0 {
+		text = fmt.Sprintf("Σ %s • %s • %s attached • %s",
+			plural(m.stats.Windows, "window"), plural(m.stats.Panes, "pane"),
+			plural(m.stats.Clients, "client"), m.stats.uptime())
+	}
+	footer := lipgloss.NewStyle().Foreground(mutedColor).Render(text)
+	return lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, footer)
+}
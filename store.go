@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/newcharhuso/tmux-navigator/pkg/lazytmux"
+)
+
+// Templates live in getTemplatesDir, one file each, so they can be kept in
+// git, shared and edited by hand without touching each other. Files may be
+// JSON or YAML; new templates are written as JSON.
+
+func getTemplatesDir() string {
+	return filepath.Join(getConfigDir(), "templates")
+}
+
+func isYAMLFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// decodeTemplate reads a template in either format, see
+// lazytmux.DecodeTemplate.
+func decodeTemplate(data []byte) (SessionTemplate, error) {
+	template, err := lazytmux.DecodeTemplate(data)
+	return SessionTemplate{Template: template}, err
+}
+
+func encodeTemplate(template SessionTemplate, yaml bool) ([]byte, error) {
+	if yaml {
+		return []byte(marshalYAML(template)), nil
+	}
+	data, err := json.MarshalIndent(template, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// readTemplateFile loads one template file, see lazytmux.ReadTemplateFile.
+func readTemplateFile(path string) (SessionTemplate, error) {
+	template, err := lazytmux.ReadTemplateFile(path)
+	if err != nil {
+		return SessionTemplate{}, err
+	}
+	return SessionTemplate{Template: template, file: path}, nil
+}
+
+func loadTemplates() []SessionTemplate {
+	templates, _ := loadTemplateDir()
+	return templates
+}
+
+// loadTemplateDir loads every template file, migrating the old single
+// templates.json first if needed. Files that cannot be read are reported
+// and left alone.
+func loadTemplateDir() ([]SessionTemplate, []error) {
+	var errs []error
+	if err := migrateTemplatesFile(); err != nil {
+		errs = append(errs, fmt.Errorf("migrating %s: %v", getTemplatesFile(), err))
+	}
+
+	templates := []SessionTemplate{}
+	seen := map[string]string{}
+	for _, path := range lazytmux.TemplateFiles(getTemplatesDir()) {
+		template, err := readTemplateFile(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if other, dup := seen[template.Name]; dup {
+			errs = append(errs, fmt.Errorf("%s: template '%s' is already defined in %s", filepath.Base(path), template.Name, filepath.Base(other)))
+			continue
+		}
+		seen[template.Name] = path
+		templates = append(templates, template)
+	}
+	return templates, errs
+}
+
+// migrateTemplatesFile splits the pre-directory templates.json into one file
+// per template and keeps the original as templates.json.migrated.
+func migrateTemplatesFile() error {
+	legacy := getTemplatesFile()
+	if _, err := os.Stat(legacy); err != nil {
+		return nil
+	}
+	if _, err := os.Stat(getTemplatesDir()); err == nil {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(legacy)
+	if err != nil {
+		return err
+	}
+	var templates []SessionTemplate
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return err
+	}
+	if err := saveTemplates(templates); err != nil {
+		return err
+	}
+	return os.Rename(legacy, legacy+".migrated")
+}
+
+// saveTemplates writes every template that changed to its own file. New
+// templates get a file named after them; the chosen path is recorded in
+// the slice so later saves and deletes find it again.
+func saveTemplates(templates []SessionTemplate) error {
+	dir := getTemplatesDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	used := map[string]bool{}
+	for _, t := range templates {
+		if t.file != "" {
+			used[t.file] = true
+		}
+	}
+	for i := range templates {
+		t := &templates[i]
+		if t.file == "" {
+			t.file = newTemplatePath(dir, t.Name, used)
+			used[t.file] = true
+		}
+		// Leave files alone unless their content changes, which keeps
+		// diffs small and comments in hand-written YAML intact.
+		if existing, err := readTemplateFile(t.file); err == nil && sameTemplate(existing, *t) {
+			continue
+		}
+		data, err := encodeTemplate(*t, isYAMLFile(t.file))
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(t.file, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteTemplateFile removes the file a template was loaded from.
+func deleteTemplateFile(template SessionTemplate) error {
+	if template.file == "" {
+		return nil
+	}
+	if err := os.Remove(template.file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// checkTemplateName reports why name can't be given to a template: it is
+// empty, tmux would reject it, or a session or other template has it.
+func checkTemplateName(name string, sessions []Session, others []SessionTemplate) error {
+	switch {
+	case name == "":
+		return errors.New("template name cannot be empty")
+	case strings.ContainsAny(name, ".:"):
+		return errors.New("names cannot contain '.' or ':'")
+	case nameExists(name, sessions, others):
+		return fmt.Errorf("'%s' already exists", name)
+	}
+	return nil
+}
+
+// renameTemplate gives templates[i] a new name and moves it to a file named
+// after it. The new file is written before the old one is removed, so a
+// failure never loses the template.
+func renameTemplate(templates []SessionTemplate, i int, name string) ([]SessionTemplate, error) {
+	out := append([]SessionTemplate(nil), templates...)
+	out[i].Name = name
+	out[i].file = ""
+	if err := saveTemplates(out); err != nil {
+		return templates, err
+	}
+	if err := deleteTemplateFile(templates[i]); err != nil {
+		return out, fmt.Errorf("renamed, but the old file is still there: %v", err)
+	}
+	return out, nil
+}
+
+// duplicateTemplate stores a copy of templates[i] under name right after
+// the original.
+func duplicateTemplate(templates []SessionTemplate, i int, name string) ([]SessionTemplate, error) {
+	dup := templates[i].clone()
+	dup.Name = name
+	dup.file = ""
+	out := append([]SessionTemplate(nil), templates[:i+1]...)
+	out = append(out, dup)
+	out = append(out, templates[i+1:]...)
+	if err := saveTemplates(out); err != nil {
+		return templates, err
+	}
+	return out, nil
+}
+
+func sameTemplate(a, b SessionTemplate) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+// newTemplatePath picks a file name for a template that clashes with
+// neither another template nor a file already on disk.
+func newTemplatePath(dir, name string, used map[string]bool) string {
+	base := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '-'
+	}, name)
+	base = strings.TrimLeft(base, ".")
+	if base == "" {
+		base = "template"
+	}
+
+	path := filepath.Join(dir, base+".json")
+	for n := 2; ; n++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) && !used[path] {
+			return path
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d.json", base, n))
+	}
+}
+
+// writeFileAtomic writes to a temporary file and renames it over path, so a
+// crash mid-write never leaves a truncated file behind.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
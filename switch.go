@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// lazytmux switch is a bare session picker for tmux's display-popup: type to
+// narrow the list, Enter switches the client to the session, Esc closes. It
+// skips everything the full TUI does on start-up and never animates.
+
+type switcher struct {
+	sessions []Session
+	current  string // the session of the client the popup belongs to
+	input    textinput.Model
+	matches  []Session
+	cursor   int
+	chosen   *Session
+	height   int
+}
+
+// switchMatches returns the sessions matching query, best first: exact
+// names, then names starting with it, containing it, containing its letters
+// in order, and last sessions whose directory contains it. The current
+// session goes to the end, so Enter right away switches to another one.
+func switchMatches(query string, sessions []Session, current string) []Session {
+	q := strings.ToLower(strings.TrimSpace(query))
+	rank := func(s Session) int {
+		name := strings.ToLower(s.Name)
+		switch {
+		case q == "":
+			return 0
+		case name == q:
+			return 0
+		case strings.HasPrefix(name, q):
+			return 1
+		case strings.Contains(name, q):
+			return 2
+		case subsequence(q, name):
+			return 3
+		case strings.Contains(strings.ToLower(shortDir(s.Dir)), q):
+			return 4
+		}
+		return -1
+	}
+	var matches []Session
+	ranks := map[string]int{}
+	for _, s := range sessions {
+		if r := rank(s); r >= 0 {
+			if s.Name == current {
+				r += 10
+			}
+			ranks[s.Name] = r
+			matches = append(matches, s)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return ranks[matches[i].Name] < ranks[matches[j].Name] })
+	return matches
+}
+
+func (m switcher) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m switcher) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc", "ctrl+c":
+			return m, tea.Quit
+		case "enter":
+			if m.cursor < len(m.matches) {
+				m.chosen = &m.matches[m.cursor]
+			}
+			return m, tea.Quit
+		case "up", "ctrl+p", "ctrl+k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+			return m, nil
+		case "down", "ctrl+n", "ctrl+j", "tab":
+			if m.cursor < len(m.matches)-1 {
+				m.cursor++
+			}
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.input, cmd = m.input.Update(msg)
+		m.matches = switchMatches(m.input.Value(), m.sessions, m.current)
+		m.cursor = 0
+		return m, cmd
+	}
+	return m, nil
+}
+
+func (m switcher) View() string {
+	var b strings.Builder
+	b.WriteString(m.input.View() + "\n")
+	muted := lipgloss.NewStyle().Foreground(mutedColor)
+	if len(m.matches) == 0 {
+		b.WriteString(muted.Render("  no session matches"))
+		return b.String()
+	}
+	// Keep the cursor in view when the popup is short.
+	rows := len(m.matches)
+	if m.height > 1 {
+		rows = min(rows, m.height-1)
+	}
+	start := max(0, m.cursor-rows+1)
+	nameWidth := 0
+	for _, s := range m.matches {
+		nameWidth = max(nameWidth, len([]rune(s.Name)))
+	}
+	for i := start; i < start+rows && i < len(m.matches); i++ {
+		s := m.matches[i]
+		marker := "  "
+		if s.Attached {
+			marker = attachedIndicator + " "
+		}
+		name := fmt.Sprintf("%-*s", nameWidth, s.Name)
+		if s.Server != "" {
+			name += " @" + s.Server
+		}
+		detail := fmt.Sprintf("  %dw  %s", s.Windows, shortDir(s.Dir))
+		if s.Name == m.current {
+			detail += "  (current)"
+		}
+		line := marker + name
+		if i == m.cursor {
+			line = lipgloss.NewStyle().Foreground(primaryColor).Bold(true).Render("▶ " + name)
+		}
+		b.WriteString(line + muted.Render(detail))
+		if i < start+rows-1 && i < len(m.matches)-1 {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// runSwitch picks a session and switches to it: lazytmux switch [query]
+func runSwitch(args []string, mineOnly bool) int {
+	if len(args) > 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s switch [query]\n", os.Args[0])
+		return 2
+	}
+	sessions := cliSessions(mineOnly)
+	if len(sessions) == 0 {
+		fmt.Fprintln(os.Stderr, "No tmux sessions")
+		return 1
+	}
+	current := ""
+	if os.Getenv("TMUX") != "" {
+		if out, err := tmuxCommand("display-message", "-p", "#S").Output(); err == nil {
+			current = strings.TrimSpace(string(out))
+		}
+	}
+
+	ti := textinput.New()
+	ti.Prompt = "> "
+	ti.Placeholder = "session"
+	ti.Focus()
+	if len(args) == 1 {
+		ti.SetValue(args[0])
+		ti.CursorEnd()
+	}
+	m := switcher{sessions: sessions, current: current, input: ti}
+	m.matches = switchMatches(ti.Value(), sessions, current)
+
+	final, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	chosen := final.(switcher).chosen
+	if chosen == nil || chosen.Name == current {
+		return 0
+	}
+	if err := attachHere(serverNamed(chosen.Server), chosen.Name); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not switch to '%s': %v\n", chosen.Name, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// parseTags splits a comma or space separated list of tags, dropping
+// duplicates and the leading # people tend to type.
+func parseTags(s string) []string {
+	var tags []string
+	seen := map[string]bool{}
+	for _, tag := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		tag = strings.TrimPrefix(tag, "#")
+		if tag != "" && !seen[strings.ToLower(tag)] {
+			seen[strings.ToLower(tag)] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// hasTag reports whether the template carries tag, ignoring case.
+func (t SessionTemplate) hasTag(tag string) bool {
+	for _, have := range t.Tags {
+		if strings.EqualFold(have, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// allTags lists every tag used by the templates, sorted.
+func allTags(templates []SessionTemplate) []string {
+	var tags []string
+	seen := map[string]bool{}
+	for _, t := range templates {
+		for _, tag := range t.Tags {
+			if !seen[strings.ToLower(tag)] {
+				seen[strings.ToLower(tag)] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return strings.ToLower(tags[i]) < strings.ToLower(tags[j]) })
+	return tags
+}
+
+// templateVisible reports whether template i passes the tag filter.
+func (m model) templateVisible(i int) bool {
+	return m.tagFilter == "" || m.templates[i].hasTag(m.tagFilter)
+}
+
+// selectedTemplateVisible reports whether the cursor is on a template the
+// tag filter shows, for the keys acting on the selected template.
+func (m model) selectedTemplateVisible() bool {
+	return m.templateCursor < len(m.templates) && m.templateVisible(m.templateCursor)
+}
+
+// visibleTemplates counts the templates passing the tag filter.
+func (m model) visibleTemplates() int {
+	n := 0
+	for i := range m.templates {
+		if m.templateVisible(i) {
+			n++
+		}
+	}
+	return n
+}
+
+// moveTemplateCursor moves to the next template in direction (+1 or -1)
+// that passes the tag filter, staying put if there is none.
+func (m *model) moveTemplateCursor(direction int) bool {
+	for i := m.templateCursor + direction; i >= 0 && i < len(m.templates); i += direction {
+		if m.templateVisible(i) {
+			m.templateCursor = i
+			return true
+		}
+	}
+	return false
+}
+
+// clampTemplateCursor puts the cursor back on a visible template after the
+// list or the filter changed.
+func (m *model) clampTemplateCursor() {
+	if m.templateCursor >= len(m.templates) {
+		m.templateCursor = max(0, len(m.templates)-1)
+	}
+	if len(m.templates) == 0 || m.templateVisible(m.templateCursor) {
+		return
+	}
+	if !m.moveTemplateCursor(1) {
+		m.moveTemplateCursor(-1)
+	}
+}
+
+func (m *model) startTagFilter() {
+	ti := textinput.New()
+	ti.Placeholder = "Tag to show, empty for all"
+	ti.CharLimit = 30
+	ti.SetValue(m.tagFilter)
+	ti.CursorEnd()
+	ti.Focus()
+	m.input = ti
+	m.mode = templateFiltering
+}
+
+func (m model) renderTagFilter() string {
+	form := "🏷️ Filter templates by tag\n\nTag: " + m.input.View()
+	if tags := allTags(m.templates); len(tags) > 0 {
+		form += "\n\n" + lipgloss.NewStyle().Foreground(mutedColor).Render("Tags: "+strings.Join(tags, ", "))
+	}
+	form += "\n\n[Enter] Apply • [Esc] Cancel"
+	inputView := inputBoxStyle.Render(form)
+	return lipgloss.Place(m.width, 8, lipgloss.Center, lipgloss.Top, inputView)
+}
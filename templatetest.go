@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// T in the template editor tries the template out before it is saved: it
+// builds it into a detached scratch session, lazytmux-test-<name>, and shows
+// what tmux made of the window shown in the editor, every pane captured
+// where it is, to hold up against the mock-up. r captures the panes again
+// once the commands have drawn more. Leaving the preview kills the session,
+// unless "keep_test_sessions" is "on". Placeholders take their default
+// values.
+
+// Prefix of the names of test sessions.
+const testSessionPrefix = "lazytmux-test-"
+
+// How long the commands of a test session get to draw before the first
+// capture.
+const testSettle = 500 * time.Millisecond
+
+// testSessionName is the name of the test session of a template.
+func testSessionName(template string) string {
+	return sanitizeSessionName(testSessionPrefix + template)
+}
+
+// livePane is where a pane is in its window, in cells.
+type livePane struct {
+	id                        string
+	left, top, width, height  int
+	windowWidth, windowHeight int
+}
+
+// captureWindow draws a window as tmux shows it: the text of each pane in
+// its place, and lines where the borders are.
+func (s Server) captureWindow(id string) ([]string, error) {
+	format := "#{pane_id}\t#{pane_left}\t#{pane_top}\t#{pane_width}\t#{pane_height}\t#{window_width}\t#{window_height}"
+	out, err := s.command("list-panes", "-t", id, "-F", format).Output()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list the panes of %s: %w", id, err)
+	}
+	var panes []livePane
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		parts := strings.Split(line, "\t")
+		if len(parts) < 7 {
+			continue
+		}
+		p := livePane{id: parts[0]}
+		for i, n := range []*int{&p.left, &p.top, &p.width, &p.height, &p.windowWidth, &p.windowHeight} {
+			*n, _ = strconv.Atoi(parts[i+1])
+		}
+		panes = append(panes, p)
+	}
+	if len(panes) == 0 {
+		return nil, fmt.Errorf("%s has no panes", id)
+	}
+
+	w, h := panes[0].windowWidth, panes[0].windowHeight
+	grid := make([][]rune, h)
+	covered := make([][]bool, h)
+	for y := range grid {
+		grid[y] = []rune(strings.Repeat(" ", w))
+		covered[y] = make([]bool, w)
+	}
+	for _, p := range panes {
+		text, _ := s.command("capture-pane", "-p", "-t", p.id).Output()
+		lines := strings.Split(string(text), "\n")
+		for y := p.top; y < min(p.top+p.height, h); y++ {
+			var line []rune
+			if y-p.top < len(lines) {
+				line = []rune(printable(lines[y-p.top]))
+			}
+			for x := p.left; x < min(p.left+p.width, w); x++ {
+				covered[y][x] = true
+				if x-p.left < len(line) {
+					grid[y][x] = line[x-p.left]
+				}
+			}
+		}
+	}
+
+	// What no pane covers is a border.
+	at := func(x, y int) bool { return x >= 0 && x < w && y >= 0 && y < h && covered[y][x] }
+	rows := make([]string, h)
+	for y := range grid {
+		for x := range grid[y] {
+			if covered[y][x] {
+				continue
+			}
+			switch {
+			case at(x-1, y) && at(x+1, y):
+				grid[y][x] = '│'
+			case at(x, y-1) && at(x, y+1):
+				grid[y][x] = '─'
+			default:
+				grid[y][x] = '┼'
+			}
+		}
+		rows[y] = string(grid[y])
+	}
+	return rows, nil
+}
+
+// keepTestSessions reports whether test sessions outlive their preview.
+func keepTestSessions() bool {
+	return config.KeepTestSessions == "on"
+}
+
+// testTemplate builds the edited template into its test session, replacing
+// the one left from before, and captures the window shown in the editor.
+func (m *model) testTemplate() tea.Cmd {
+	template, err := m.editedTemplate().withVars(nil)
+	if err != nil {
+		m.setMessage(fmt.Sprintf("Give the placeholders default values to test the template: %v", err), "error")
+		return nil
+	}
+	name, k, srv := testSessionName(template.Name), m.editorWindow, m.server()
+	m.testSession = name
+	m.testCapture = nil
+	m.mode = templateTesting
+	m.setMessage(fmt.Sprintf("Building the test session '%s'...", name), "info")
+	var window string
+	var capture []string
+	return inBackground(func() error {
+		_ = srv.killSession(name)
+		if err := srv.createSessionFromTemplate(name, template); err != nil {
+			return err
+		}
+		windows := srv.listSessionWindows(name)
+		if k >= len(windows) {
+			return fmt.Errorf("%s has no window %d", name, k+1)
+		}
+		window = windows[k].ID
+		time.Sleep(testSettle)
+		capture, err = srv.captureWindow(window)
+		return err
+	}, func(m *model, err error) tea.Cmd {
+		if m.mode != templateTesting || m.testSession != name {
+			return nil
+		}
+		if err != nil {
+			m.setMessage(fmt.Sprintf("Failed to test the template: %v", err), "error")
+			return nil
+		}
+		m.testWindow, m.testCapture = window, capture
+		m.setMessage(fmt.Sprintf("Built '%s' from the template, r captures it again", name), "success")
+		return nil
+	})
+}
+
+// recaptureTest captures the test session again.
+func (m *model) recaptureTest() tea.Cmd {
+	srv, name, window := m.server(), m.testSession, m.testWindow
+	var capture []string
+	return inBackground(func() (err error) {
+		capture, err = srv.captureWindow(window)
+		return err
+	}, func(m *model, err error) tea.Cmd {
+		if m.mode != templateTesting || m.testSession != name {
+			return nil
+		}
+		if err != nil {
+			m.setMessage(fmt.Sprintf("Failed to capture the test session: %v", err), "error")
+			return nil
+		}
+		m.testCapture = capture
+		m.setMessage("Captured the test session again", "info")
+		return nil
+	})
+}
+
+// endTest goes back to the editor, killing the test session unless it is
+// to be kept.
+func (m *model) endTest() tea.Cmd {
+	name := m.testSession
+	m.testSession, m.testWindow = "", ""
+	m.testCapture = nil
+	m.mode = templateEditing
+	if keepTestSessions() {
+		m.setMessage(fmt.Sprintf("Kept the test session '%s'", name), "info")
+		m.loadSessions()
+		return nil
+	}
+	srv := m.server()
+	return inBackground(func() error {
+		return srv.killSession(name)
+	}, func(m *model, err error) tea.Cmd {
+		if err != nil {
+			m.setMessage(fmt.Sprintf("Failed to kill the test session '%s': %v", name, err), "error")
+		}
+		return nil
+	})
+}
+
+// renderTemplateTest shows the capture of the test session.
+func (m model) renderTemplateTest() string {
+	var content strings.Builder
+	content.WriteString(fmt.Sprintf("🧪 Test session '%s', window %d\n\n", m.testSession, m.editorWindow+1))
+	if m.testCapture == nil {
+		content.WriteString("Building...\n")
+	} else {
+		// Leave room for the box around it.
+		width := max(10, m.width-8)
+		for _, line := range m.testCapture {
+			if runes := []rune(line); len(runes) > width {
+				line = string(runes[:width])
+			}
+			content.WriteString(line + "\n")
+		}
+	}
+	hint := "[r] Capture again • [Esc] Back to the editor, killing the session"
+	if keepTestSessions() {
+		hint = "[r] Capture again • [Esc] Back to the editor"
+	}
+	content.WriteString("\n" + hint)
+	return lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, inputBoxStyle.Render(content.String())) + "\n"
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/newcharhuso/tmux-navigator/pkg/lazytmux"
+)
+
+// tmuxinatorDirs returns the directories tmuxinator reads projects from.
+func tmuxinatorDirs() []string {
+	var dirs []string
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		dirs = append(dirs, filepath.Join(xdg, "tmuxinator"))
+	}
+	homeDir, _ := os.UserHomeDir()
+	return append(dirs,
+		filepath.Join(homeDir, ".config", "tmuxinator"),
+		filepath.Join(homeDir, ".tmuxinator"),
+	)
+}
+
+// findTmuxinatorProjects lists all project files in the tmuxinator dirs.
+func findTmuxinatorProjects() []string {
+	return findProjectFiles(tmuxinatorDirs(), "*.yml", "*.yaml")
+}
+
+// findProjectFiles lists the files matching any of patterns in dirs.
+func findProjectFiles(dirs []string, patterns ...string) []string {
+	var files []string
+	seen := map[string]bool{}
+	for _, dir := range dirs {
+		for _, pattern := range patterns {
+			matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+			for _, m := range matches {
+				if !seen[m] {
+					seen[m] = true
+					files = append(files, m)
+				}
+			}
+		}
+	}
+	return files
+}
+
+// parseTmuxinator converts a tmuxinator project into a template. Windows,
+// panes, layouts, roots and the project/window pre commands carry over;
+// ERB and tmuxinator-only settings are ignored.
+func parseTmuxinator(data []byte) (SessionTemplate, error) {
+	doc, err := parseYAML(data)
+	if err != nil {
+		return SessionTemplate{}, err
+	}
+	project, ok := doc.(*yamlMap)
+	if !ok {
+		return SessionTemplate{}, errors.New("project is not a mapping")
+	}
+
+	get := func(key string) interface{} {
+		v, _ := project.get(key)
+		return v
+	}
+
+	template := SessionTemplate{Template: lazytmux.Template{
+		Name:        yamlString(get("name")),
+		Description: "Imported from tmuxinator",
+		Root:        yamlString(get("root")),
+	}}
+	if template.Root == "" {
+		template.Root = yamlString(get("project_root"))
+	}
+	if template.Name == "" {
+		template.Name = yamlString(get("project_name"))
+	}
+
+	var onCreate []string
+	for _, key := range []string{"on_project_start", "pre"} {
+		onCreate = append(onCreate, yamlStrings(get(key))...)
+	}
+	template.OnCreate = strings.Join(onCreate, "; ")
+
+	preWindow := strings.Join(yamlStrings(get("pre_window")), "; ")
+	if preWindow == "" {
+		preWindow = strings.Join(yamlStrings(get("pre_tab")), "; ")
+	}
+
+	windowList, _ := get("windows").([]interface{})
+	if windowList == nil {
+		windowList, _ = get("tabs").([]interface{})
+	}
+	if len(windowList) == 0 {
+		return SessionTemplate{}, errors.New("project has no windows")
+	}
+
+	for i, item := range windowList {
+		name, window, err := tmuxinatorWindow(item, preWindow)
+		if err != nil {
+			return SessionTemplate{}, fmt.Errorf("window %d: %v", i+1, err)
+		}
+		if i == 0 {
+			template.WindowName = name
+			template.Panes = window
+			continue
+		}
+		template.Windows = append(template.Windows, TemplateWindow{Name: name, Panes: window})
+	}
+	return template, nil
+}
+
+// tmuxinatorWindow converts one "- name: definition" entry of windows.
+func tmuxinatorWindow(item interface{}, preWindow string) (string, []Pane, error) {
+	entry, ok := item.(*yamlMap)
+	if !ok || len(entry.keys) == 0 {
+		return "", nil, errors.New("expected 'name: command' or 'name: {panes: ...}'")
+	}
+	name := entry.keys[0]
+	def := entry.values[name]
+
+	var commands [][]string
+	var titles []string
+	layout := ""
+	root := ""
+	windowPre := ""
+
+	switch d := def.(type) {
+	case nil:
+		commands = [][]string{nil}
+		titles = []string{""}
+	case string:
+		commands = [][]string{{d}}
+		titles = []string{""}
+	case *yamlMap:
+		layout = yamlString(d.values["layout"])
+		root = yamlString(d.values["root"])
+		windowPre = strings.Join(yamlStrings(d.values["pre"]), "; ")
+		panes, _ := d.values["panes"].([]interface{})
+		if len(panes) == 0 {
+			panes = []interface{}{nil}
+		}
+		for _, pane := range panes {
+			switch pv := pane.(type) {
+			case *yamlMap:
+				// Named pane: "- title: [cmd, cmd]"
+				if len(pv.keys) > 0 {
+					titles = append(titles, pv.keys[0])
+					commands = append(commands, yamlStrings(pv.values[pv.keys[0]]))
+					continue
+				}
+				titles = append(titles, "")
+				commands = append(commands, nil)
+			default:
+				titles = append(titles, "")
+				commands = append(commands, yamlStrings(pv))
+			}
+		}
+	default:
+		return "", nil, errors.New("unsupported window definition")
+	}
+
+	if layout == "" && len(commands) > 1 {
+		layout = "tiled"
+	}
+
+	contents := make([]Pane, len(commands))
+	for i := range contents {
+		var parts []string
+		for _, pre := range []string{preWindow, windowPre} {
+			if pre != "" {
+				parts = append(parts, pre)
+			}
+		}
+		parts = append(parts, commands[i]...)
+		contents[i] = Pane{Command: strings.Join(parts, "; "), Title: titles[i], Dir: root}
+	}
+	return name, arrangePanes(layout, contents), nil
+}
+
+// importProjects converts project files of another tmux manager into
+// templates using parse, skipping names that are already taken. It returns
+// the updated list plus the names imported and a description of everything
+// skipped.
+func importProjects(files []string, parse func([]byte) (SessionTemplate, error), templates []SessionTemplate) ([]SessionTemplate, []string, []string) {
+	var imported, skipped []string
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s: %v", filepath.Base(file), err))
+			continue
+		}
+		template, err := parse(data)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s: %v", filepath.Base(file), err))
+			continue
+		}
+		if template.Name == "" {
+			template.Name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		}
+		if nameExists(template.Name, nil, templates) {
+			skipped = append(skipped, fmt.Sprintf("%s: template '%s' already exists", filepath.Base(file), template.Name))
+			continue
+		}
+		templates = append(templates, template)
+		imported = append(imported, template.Name)
+	}
+	return templates, imported, skipped
+}
+
+// runImport imports project files given on the command line, or all
+// projects found by find, and saves them as templates.
+func runImport(args []string, tool string, find func() []string, dirs []string, parse func([]byte) (SessionTemplate, error)) int {
+	files := args
+	if len(files) == 0 {
+		files = find()
+	}
+	if len(files) == 0 {
+		fmt.Fprintf(os.Stderr, "No %s projects found in %s\n", tool, strings.Join(dirs, ", "))
+		return 1
+	}
+
+	templates, imported, skipped := importProjects(files, parse, loadTemplates())
+	for _, s := range skipped {
+		fmt.Fprintf(os.Stderr, "skipped %s\n", s)
+	}
+	if len(imported) == 0 {
+		fmt.Fprintln(os.Stderr, "Nothing imported")
+		return 1
+	}
+	if err := saveTemplates(templates); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to save templates: %v\n", err)
+		return 1
+	}
+	for _, name := range imported {
+		fmt.Printf("imported %s\n", name)
+	}
+	return 0
+}
+
+func runImportTmuxinator(args []string) int {
+	return runImport(args, "tmuxinator", findTmuxinatorProjects, tmuxinatorDirs(), parseTmuxinator)
+}
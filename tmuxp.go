@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/newcharhuso/tmux-navigator/pkg/lazytmux"
+)
+
+// tmuxpDirs returns the directories tmuxp reads workspace files from.
+func tmuxpDirs() []string {
+	var dirs []string
+	if dir := os.Getenv("TMUXP_CONFIGDIR"); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		dirs = append(dirs, filepath.Join(xdg, "tmuxp"))
+	}
+	homeDir, _ := os.UserHomeDir()
+	return append(dirs,
+		filepath.Join(homeDir, ".config", "tmuxp"),
+		filepath.Join(homeDir, ".tmuxp"),
+	)
+}
+
+// findTmuxpProjects lists all workspace files in the tmuxp dirs.
+func findTmuxpProjects() []string {
+	return findProjectFiles(tmuxpDirs(), "*.yaml", "*.yml", "*.json")
+}
+
+// parseTmuxp converts a tmuxp workspace file, YAML or JSON, into a template.
+func parseTmuxp(data []byte) (SessionTemplate, error) {
+	var doc interface{}
+	var err error
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		doc, err = parseJSONNode(data)
+	} else {
+		doc, err = parseYAML(data)
+	}
+	if err != nil {
+		return SessionTemplate{}, err
+	}
+	workspace, ok := doc.(*yamlMap)
+	if !ok {
+		return SessionTemplate{}, errors.New("workspace is not a mapping")
+	}
+
+	template := SessionTemplate{Template: lazytmux.Template{
+		Name:        yamlString(workspace.values["session_name"]),
+		Description: "Imported from tmuxp",
+		Root:        yamlString(workspace.values["start_directory"]),
+		OnCreate:    yamlString(workspace.values["before_script"]),
+	}}
+	if env, ok := workspace.values["environment"].(*yamlMap); ok {
+		template.Env = map[string]string{}
+		for _, k := range env.keys {
+			template.Env[k] = yamlString(env.values[k])
+		}
+	}
+	sessionBefore := tmuxpCommands(workspace.values["shell_command_before"])
+
+	windowList, _ := workspace.values["windows"].([]interface{})
+	if len(windowList) == 0 {
+		return SessionTemplate{}, errors.New("workspace has no windows")
+	}
+	for i, item := range windowList {
+		window, ok := item.(*yamlMap)
+		if !ok {
+			return SessionTemplate{}, fmt.Errorf("window %d: expected a mapping", i+1)
+		}
+		name := yamlString(window.values["window_name"])
+		panes := tmuxpPanes(window, sessionBefore)
+		if i == 0 {
+			template.WindowName = name
+			template.Panes = panes
+			continue
+		}
+		template.Windows = append(template.Windows, TemplateWindow{Name: name, Panes: panes})
+	}
+	return template, nil
+}
+
+// tmuxpPanes converts the panes of one tmuxp window.
+func tmuxpPanes(window *yamlMap, sessionBefore []string) []Pane {
+	before := append(append([]string(nil), sessionBefore...), tmuxpCommands(window.values["shell_command_before"])...)
+	windowDir := yamlString(window.values["start_directory"])
+
+	items, _ := window.values["panes"].([]interface{})
+	if len(items) == 0 {
+		items = []interface{}{nil}
+	}
+	contents := make([]Pane, len(items))
+	for i, item := range items {
+		var commands []string
+		dir := windowDir
+		switch p := item.(type) {
+		case *yamlMap:
+			commands = tmuxpCommands(p.values["shell_command"])
+			if d := yamlString(p.values["start_directory"]); d != "" {
+				dir = d
+			}
+		default:
+			commands = tmuxpCommands(p)
+		}
+		contents[i] = Pane{
+			Command: strings.Join(append(append([]string(nil), before...), commands...), "; "),
+			Dir:     dir,
+		}
+	}
+	return arrangePanes(yamlString(window.values["layout"]), contents)
+}
+
+// tmuxpCommands flattens a tmuxp command list. Entries may be plain strings
+// or {cmd: ...} mappings; "blank" and "pane" stand for an empty pane.
+func tmuxpCommands(v interface{}) []string {
+	var out []string
+	add := func(item interface{}) {
+		switch c := item.(type) {
+		case string:
+			if c != "" && c != "blank" && c != "pane" {
+				out = append(out, c)
+			}
+		case *yamlMap:
+			if cmd := yamlString(c.values["cmd"]); cmd != "" {
+				out = append(out, cmd)
+			}
+		}
+	}
+	if list, ok := v.([]interface{}); ok {
+		for _, item := range list {
+			add(item)
+		}
+	} else {
+		add(v)
+	}
+	return out
+}
+
+// tmuxpWorkspace converts a template into a tmuxp workspace. Settings tmuxp
+// has no equivalent for are reported as warnings.
+func tmuxpWorkspace(template SessionTemplate) (*yamlMap, []string) {
+	var warnings []string
+	workspace := &yamlMap{}
+	workspace.set("session_name", template.Name)
+	if template.Root != "" {
+		workspace.set("start_directory", template.Root)
+	}
+	if len(template.Env) > 0 {
+		env := &yamlMap{}
+		for _, k := range sortedKeys(template.Env) {
+			env.set(k, template.Env[k])
+		}
+		workspace.set("environment", env)
+	}
+	if template.OnCreate != "" {
+		// tmuxp runs before_script as a program, not through a shell.
+		if strings.ContainsAny(template.OnCreate, " ;&|<>$`'\"") {
+			warnings = append(warnings, "on_create is a shell command; tmuxp before_script only runs a script, so it was left out")
+		} else {
+			workspace.set("before_script", template.OnCreate)
+		}
+	}
+	if template.OnAttach != "" {
+		warnings = append(warnings, "on_attach has no tmuxp equivalent and was left out")
+	}
+	if names := template.Placeholders(); len(names) > 0 {
+		warnings = append(warnings, fmt.Sprintf("variables %s are exported as literal {{placeholders}}", strings.Join(names, ", ")))
+	}
+	if warning := editorExportWarning(template); warning != "" {
+		warnings = append(warnings, warning)
+	}
+
+	windows := []TemplateWindow{{Name: template.WindowName, Panes: template.Panes}}
+	windows = append(windows, template.Windows...)
+	var windowList []interface{}
+	titled := false
+	for i, w := range windows {
+		window := &yamlMap{}
+		if w.Name != "" {
+			window.set("window_name", w.Name)
+		}
+
+		panes := lazytmux.WithEditors(w.Panes, template.Root, template.Env)
+		layoutGeometry(panes)
+		if len(panes) > 1 {
+			layout := detectLayout(panes)
+			if layout == "" {
+				layout = "tiled"
+				warnings = append(warnings, fmt.Sprintf("window %d has no matching tmux layout and was exported as tiled", i+1))
+			}
+			window.set("layout", layout)
+		}
+
+		var paneList []interface{}
+		for _, idx := range readingOrder(panes) {
+			p := panes[idx]
+			if p.Title != "" {
+				titled = true
+			}
+			if p.Dir == "" {
+				if p.Command == "" {
+					paneList = append(paneList, "blank")
+				} else {
+					paneList = append(paneList, p.Command)
+				}
+				continue
+			}
+			pane := &yamlMap{}
+			if p.Command != "" {
+				pane.set("shell_command", []interface{}{p.Command})
+			}
+			pane.set("start_directory", p.Dir)
+			paneList = append(paneList, pane)
+		}
+		window.set("panes", paneList)
+		windowList = append(windowList, window)
+	}
+	workspace.set("windows", windowList)
+	if titled {
+		warnings = append(warnings, "pane titles have no tmuxp equivalent and were left out")
+	}
+	return workspace, warnings
+}
+
+func runImportTmuxp(args []string) int {
+	return runImport(args, "tmuxp", findTmuxpProjects, tmuxpDirs(), parseTmuxp)
+}
+
+// runExportTmuxp prints a saved template as a tmuxp workspace file.
+func runExportTmuxp(args []string) int {
+	fs := flag.NewFlagSet("export-tmuxp", flag.ContinueOnError)
+	format := fs.String("format", "yaml", "Output format: yaml or json")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s export-tmuxp [--format yaml|json] <template>\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	args, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(args) != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	template := findTemplateByPrefix(args[0], loadTemplates())
+	if template == nil {
+		fmt.Fprintf(os.Stderr, "Error: template '%s' not found\n", args[0])
+		return 1
+	}
+	workspace, warnings := tmuxpWorkspace(*template)
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+
+	switch *format {
+	case "yaml", "yml":
+		fmt.Print(formatYAML(workspace))
+	case "json":
+		data, err := json.MarshalIndent(workspace, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(data))
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q (want yaml or json)\n", *format)
+		return 2
+	}
+	return 0
+}
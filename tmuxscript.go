@@ -0,0 +1,582 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A shell variable holding the id of a pane we created expands to a
+// reference "\x00pane:window:index:name\x00".
+const paneRefPrefix = "\x00pane:"
+
+var (
+	paneRefRe     = regexp.MustCompile("\x00pane:([0-9]+):([0-9]+):([A-Za-z0-9_]+)\x00")
+	assignRe      = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
+	defaultArgRe  = regexp.MustCompile(`^\$\{[0-9]+:-(.*)\}$`)
+	tmuxGlobalArg = map[string]bool{"-L": true, "-S": true, "-f": true, "-c": true, "-T": true}
+)
+
+// shellCommands splits one line of a shell script into simple commands made
+// of words. Quotes and backslashes are removed, known variables are expanded
+// and the rest of the shell language is ignored. A command substitution
+// that makes up a whole assignment, var=$(...), comes back as the words of
+// the inner command preceded by "var=$(".
+func shellCommands(line string, vars map[string]string) [][]string {
+	line = strings.TrimSpace(line)
+	if m := assignRe.FindStringSubmatch(line); m != nil && strings.HasPrefix(m[2], "$(") && strings.HasSuffix(m[2], ")") {
+		var cmds [][]string
+		for _, inner := range splitCommands(m[2][2:len(m[2])-1], vars) {
+			cmds = append(cmds, append([]string{m[1] + "=$("}, inner...))
+		}
+		return cmds
+	}
+	return splitCommands(line, vars)
+}
+
+// splitCommands tokenizes one line, starting a new command at every
+// unquoted ;, && or ||.
+func splitCommands(line string, vars map[string]string) [][]string {
+	var cmds [][]string
+	var words []string
+	var word strings.Builder
+	inWord := false
+	flushWord := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	flushCmd := func() {
+		flushWord()
+		if len(words) > 0 {
+			cmds = append(cmds, words)
+			words = nil
+		}
+	}
+	// expand reads a $name, ${name} or ${1:-default} at line[i:] and
+	// returns its value and the length consumed.
+	expand := func(i int) (string, int) {
+		rest := line[i:]
+		if strings.HasPrefix(rest, "${") {
+			end := strings.Index(rest, "}")
+			if end < 0 {
+				return "$", 1
+			}
+			ref := rest[:end+1]
+			if m := defaultArgRe.FindStringSubmatch(ref); m != nil {
+				words := splitCommands(m[1], vars)
+				if len(words) == 1 && len(words[0]) == 1 {
+					return words[0][0], len(ref)
+				}
+			}
+			if v, ok := vars[rest[2:end]]; ok {
+				return v, len(ref)
+			}
+			return ref, len(ref)
+		}
+		n := 1
+		for n < len(rest) && (rest[n] == '_' || rest[n] >= 'a' && rest[n] <= 'z' || rest[n] >= 'A' && rest[n] <= 'Z' || rest[n] >= '0' && rest[n] <= '9') {
+			n++
+		}
+		if v, ok := vars[rest[1:n]]; ok && n > 1 {
+			return v, n
+		}
+		return rest[:n], n
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\'':
+			inWord = true
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				end = len(line) - i - 1
+			}
+			word.WriteString(line[i+1 : i+1+end])
+			i += end + 1
+		case c == '"':
+			inWord = true
+			for i++; i < len(line) && line[i] != '"'; i++ {
+				switch {
+				case line[i] == '\\' && i+1 < len(line) && strings.IndexByte("\\\"$`", line[i+1]) >= 0:
+					i++
+					word.WriteByte(line[i])
+				case line[i] == '$':
+					v, n := expand(i)
+					word.WriteString(v)
+					i += n - 1
+				default:
+					word.WriteByte(line[i])
+				}
+			}
+		case c == '\\' && i+1 < len(line):
+			inWord = true
+			i++
+			word.WriteByte(line[i])
+		case c == '$':
+			inWord = true
+			v, n := expand(i)
+			word.WriteString(v)
+			i += n - 1
+		case c == ';':
+			flushCmd()
+		case (c == '&' || c == '|') && i+1 < len(line) && line[i+1] == c:
+			flushCmd()
+			i++
+		case c == ' ' || c == '\t':
+			flushWord()
+		case c == '#' && !inWord:
+			flushCmd()
+			return cmds
+		default:
+			inWord = true
+			word.WriteByte(c)
+		}
+	}
+	flushCmd()
+	return cmds
+}
+
+// scriptPane tells where a pane ended up in the template being built.
+type scriptPane struct {
+	window, index int
+}
+
+// scriptImporter rebuilds a template from the tmux commands of a script.
+type scriptImporter struct {
+	template SessionTemplate
+	windows  []*TemplateWindow
+	layouts  []string
+	current  scriptPane
+	vars     map[string]string
+	skipped  []string
+}
+
+func (s *scriptImporter) pane(ref scriptPane) *Pane {
+	return &s.windows[ref.window].Panes[ref.index]
+}
+
+// resolve finds the pane a -t target refers to: a variable set from our own
+// -P output, window.pane indexes or a window name. Anything else is taken
+// to mean the current pane.
+func (s *scriptImporter) resolve(target string) scriptPane {
+	if m := paneRefRe.FindStringSubmatch(target); m != nil {
+		w, _ := strconv.Atoi(m[1])
+		i, _ := strconv.Atoi(m[2])
+		return scriptPane{w, i}
+	}
+	if target == "" || len(s.windows) == 0 {
+		return s.current
+	}
+	if _, after, ok := strings.Cut(target, ":"); ok {
+		target = after
+	}
+	windowPart, panePart, hasPane := strings.Cut(target, ".")
+	ref := scriptPane{window: -1}
+	if n, err := strconv.Atoi(windowPart); err == nil {
+		// Scripts number windows from 0 or 1 depending on base-index.
+		switch {
+		case n < len(s.windows):
+			ref.window = n
+		case n-1 < len(s.windows):
+			ref.window = n - 1
+		}
+	} else {
+		for i, w := range s.windows {
+			if w.Name == windowPart && windowPart != "" {
+				ref.window = i
+			}
+		}
+	}
+	if ref.window < 0 {
+		return s.current
+	}
+	if hasPane {
+		if n, err := strconv.Atoi(panePart); err == nil {
+			panes := len(s.windows[ref.window].Panes)
+			switch {
+			case n < panes:
+				ref.index = n
+			case n-1 < panes:
+				ref.index = n - 1
+			}
+		}
+	}
+	return ref
+}
+
+// dir turns a -c argument back into a template path relative to the root.
+func (s *scriptImporter) dir(dir string) string {
+	for _, home := range []string{"$HOME", "${HOME}"} {
+		if dir == home || strings.HasPrefix(dir, home+"/") {
+			dir = "~" + strings.TrimPrefix(dir, home)
+		}
+	}
+	for _, pwd := range []string{"$PWD/", "${PWD}/"} {
+		dir = strings.TrimPrefix(dir, pwd)
+	}
+	if dir == s.template.Root {
+		return ""
+	}
+	if s.template.Root != "" && strings.HasPrefix(dir, s.template.Root+"/") {
+		return strings.TrimPrefix(dir, s.template.Root+"/")
+	}
+	return dir
+}
+
+// options splits the arguments of a tmux command into flags and the
+// remaining positional words. valued lists the flags taking an argument.
+func options(args []string, valued string) (map[string]string, []string) {
+	flags := map[string]string{}
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			return flags, args[i+1:]
+		}
+		if len(a) < 2 || a[0] != '-' {
+			return flags, args[i:]
+		}
+		for j := 1; j < len(a); j++ {
+			f := a[j : j+1]
+			if !strings.Contains(valued, f) {
+				flags[f] = ""
+				continue
+			}
+			switch {
+			case j+1 < len(a):
+				flags[f] = a[j+1:]
+			case i+1 < len(args):
+				i++
+				flags[f] = args[i]
+			}
+			break
+		}
+	}
+	return flags, nil
+}
+
+// exportsEnv reports whether text only exports variables already set with
+// set-environment, as export-script does for the first pane.
+func (s *scriptImporter) exportsEnv(text string) bool {
+	if len(s.template.Env) == 0 {
+		return false
+	}
+	for _, words := range splitCommands(text, nil) {
+		if len(words) != 2 || words[0] != "export" {
+			return false
+		}
+		name, _, _ := strings.Cut(words[1], "=")
+		if _, ok := s.template.Env[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *scriptImporter) addWindow(name, dir string, command []string) scriptPane {
+	s.windows = append(s.windows, &TemplateWindow{Name: name})
+	s.layouts = append(s.layouts, "")
+	w := len(s.windows) - 1
+	s.windows[w].Panes = []Pane{{ID: 1, Position: "main", SplitPercent: 50, Dir: s.dir(dir), Command: strings.Join(command, " ")}}
+	s.current = scriptPane{w, 0}
+	return s.current
+}
+
+// run applies one tmux command. It returns the pane it created, if any.
+func (s *scriptImporter) run(name string, args []string) (scriptPane, bool) {
+	switch name {
+	case "new-session", "new":
+		flags, rest := options(args, "cefFnstxy")
+		if v, ok := flags["s"]; ok && s.template.Name == "" {
+			s.template.Name = v
+		}
+		if len(s.windows) == 0 {
+			s.template.Root = s.dir(flags["c"])
+		}
+		return s.addWindow(flags["n"], flags["c"], rest), true
+	case "new-window", "neww":
+		flags, rest := options(args, "ceFnt")
+		if len(s.windows) == 0 {
+			s.template.Root = s.dir(flags["c"])
+		}
+		return s.addWindow(flags["n"], flags["c"], rest), true
+	case "split-window", "splitw":
+		flags, rest := options(args, "ceFlpt")
+		if len(s.windows) == 0 {
+			s.addWindow("", "", nil)
+		}
+		parent := s.resolve(flags["t"])
+		_, vertical := flags["v"]
+		_, before := flags["b"]
+		position := "right"
+		switch {
+		case vertical && before:
+			position = "up"
+		case vertical:
+			position = "down"
+		case before:
+			position = "left"
+		}
+		percent := 50
+		if v, err := strconv.Atoi(flags["p"]); err == nil {
+			percent = v
+		} else if v, err := strconv.Atoi(strings.TrimSuffix(flags["l"], "%")); err == nil && strings.HasSuffix(flags["l"], "%") {
+			percent = v
+		}
+		win := s.windows[parent.window]
+		id := 1
+		for _, p := range win.Panes {
+			if p.ID >= id {
+				id = p.ID + 1
+			}
+		}
+		win.Panes = append(win.Panes, Pane{
+			ID:           id,
+			Parent:       s.pane(parent).ID,
+			Position:     position,
+			SplitPercent: percent,
+			Dir:          s.dir(flags["c"]),
+			Command:      strings.Join(rest, " "),
+		})
+		s.current = scriptPane{parent.window, len(win.Panes) - 1}
+		return s.current, true
+	case "send-keys", "send":
+		flags, keys := options(args, "tNc")
+		if len(s.windows) == 0 {
+			return scriptPane{}, false
+		}
+		if len(keys) > 0 {
+			switch keys[len(keys)-1] {
+			case "C-m", "Enter", "KPEnter":
+				keys = keys[:len(keys)-1]
+			}
+		}
+		_, literal := flags["l"]
+		text := strings.Join(keys, " ")
+		if literal {
+			text = strings.Join(keys, "")
+		}
+		if text == "" || s.exportsEnv(text) {
+			return scriptPane{}, false
+		}
+		p := s.pane(s.resolve(flags["t"]))
+		if p.Command != "" {
+			p.Command += "; "
+		}
+		p.Command += text
+	case "respawn-pane", "respawnp":
+		flags, rest := options(args, "tce")
+		if len(s.windows) > 0 && len(rest) > 0 {
+			p := s.pane(s.resolve(flags["t"]))
+			p.Command = strings.Join(rest, " ")
+			p.RemainOnExit = true
+		}
+	case "select-pane", "selectp":
+		flags, _ := options(args, "tT")
+		if title, ok := flags["T"]; ok && len(s.windows) > 0 {
+			s.pane(s.resolve(flags["t"])).Title = title
+		}
+	case "rename-window", "renamew":
+		flags, rest := options(args, "t")
+		if len(s.windows) > 0 && len(rest) > 0 {
+			s.windows[s.resolve(flags["t"]).window].Name = rest[0]
+		}
+	case "select-layout", "selectl":
+		flags, rest := options(args, "t")
+		if len(s.windows) > 0 && len(rest) > 0 {
+			s.layouts[s.resolve(flags["t"]).window] = rest[0]
+		}
+	case "set-environment", "setenv":
+		_, rest := options(args, "tF")
+		if len(rest) == 2 {
+			if s.template.Env == nil {
+				s.template.Env = map[string]string{}
+			}
+			s.template.Env[rest[0]] = rest[1]
+		}
+	case "set-option", "set":
+		flags, rest := options(args, "t")
+		if len(rest) == 2 && rest[0] == "pane-border-style" && len(s.windows) > 0 {
+			if _, ok := flags["p"]; ok {
+				s.pane(s.resolve(flags["t"])).BorderStyle = rest[1]
+			}
+		}
+		if len(rest) == 2 && rest[0] == "pane-border-status" {
+			s.template.PaneBorderStatus = rest[1]
+		}
+	case "display-message", "display", "has-session", "has", "attach-session", "attach", "a",
+		"switch-client", "switchc", "select-window", "selectw", "kill-session", "set-window-option", "setw",
+		"set-hook", "source-file", "source":
+		// Nothing to keep: these only look around, attach or tune options.
+	default:
+		for _, seen := range s.skipped {
+			if seen == name {
+				return scriptPane{}, false
+			}
+		}
+		s.skipped = append(s.skipped, name)
+	}
+	return scriptPane{}, false
+}
+
+// unwrapWait turns the polling loop export-script writes for waiting panes
+// back into wait_for, wait_for_pane and wait_for_port.
+func (s *scriptImporter) unwrapWait(p *Pane, window int) {
+	rest, ok := strings.CutPrefix(p.Command, "i=0; until ")
+	if !ok {
+		return
+	}
+	cond, rest, ok := strings.Cut(rest, " || [ $((i+=1)) -gt ")
+	if !ok {
+		return
+	}
+	timeout, rest, ok := strings.Cut(rest, " ]; do sleep 1; done; "+cond+" && ")
+	seconds, err := strconv.Atoi(timeout)
+	if !ok || err != nil {
+		return
+	}
+
+	wait := Pane{}
+	for _, part := range strings.Split(cond, " && ") {
+		words := shellCommands(part, nil)
+		if len(words) != 1 {
+			return
+		}
+		w := words[0]
+		switch {
+		case len(w) == 12 && w[1] == "capture-pane" && w[8] == "|" && w[10] == "-qF":
+			ref := paneRefRe.FindStringSubmatch(w[7])
+			if ref == nil || ref[1] != strconv.Itoa(window) {
+				return
+			}
+			idx, _ := strconv.Atoi(ref[2])
+			wait.WaitForPane = s.windows[window].Panes[idx].ID
+			wait.WaitFor = w[11]
+		case len(w) == 4 && w[0] == "nc" && w[1] == "-z":
+			if wait.WaitForPort, err = strconv.Atoi(w[3]); err != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+	p.WaitForPane, p.WaitFor, p.WaitForPort = wait.WaitForPane, wait.WaitFor, wait.WaitForPort
+	if seconds != defaultWaitTimeout {
+		p.WaitTimeout = seconds
+	}
+	p.Command = rest
+}
+
+// command applies one simple command of the script: a variable assignment
+// or a tmux invocation, possibly several chained with ";".
+func (s *scriptImporter) command(words []string) {
+	assignTo := ""
+	if strings.HasSuffix(words[0], "=$(") {
+		assignTo = strings.TrimSuffix(words[0], "=$(")
+		words = words[1:]
+	} else if m := assignRe.FindStringSubmatch(words[0]); m != nil && len(words) == 1 {
+		s.vars[m[1]] = m[2]
+		return
+	}
+	if len(words) == 0 || words[0] != "tmux" {
+		return
+	}
+	words = words[1:]
+	for len(words) > 0 && strings.HasPrefix(words[0], "-") {
+		if tmuxGlobalArg[words[0]] && len(words) > 1 {
+			words = words[1:]
+		}
+		words = words[1:]
+	}
+
+	// tmux chains commands with a standalone ";".
+	var created scriptPane
+	var ok bool
+	for len(words) > 0 {
+		end := len(words)
+		for i, w := range words {
+			if w == ";" {
+				end = i
+				break
+			}
+		}
+		if end > 0 {
+			if p, made := s.run(words[0], words[1:end]); made {
+				created, ok = p, true
+			}
+		}
+		words = words[min(end+1, len(words)):]
+	}
+	if assignTo != "" && len(s.windows) > 0 {
+		if !ok {
+			created = s.current
+		}
+		s.vars[assignTo] = fmt.Sprintf("%s%d:%d:%s\x00", paneRefPrefix, created.window, created.index, assignTo)
+	}
+}
+
+// parseTmuxScript builds a best-effort template from a shell script made of
+// tmux new-session/new-window/split-window/send-keys commands. Commands it
+// does not understand are reported as warnings.
+func parseTmuxScript(data []byte) (SessionTemplate, []string, error) {
+	s := &scriptImporter{vars: map[string]string{}}
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\\\n", ""), "\n") {
+		// Each line sees the variables assigned by the ones before it.
+		for _, words := range shellCommands(line, s.vars) {
+			s.command(words)
+		}
+	}
+
+	if len(s.windows) == 0 {
+		return SessionTemplate{}, nil, errors.New("no new-session, new-window or split-window commands found")
+	}
+	template := s.template
+	template.Description = "Imported from a shell script"
+	for i, w := range s.windows {
+		panes := w.Panes
+		for j := range panes {
+			s.unwrapWait(&panes[j], i)
+			// Pane references left in commands go back to the variable name.
+			panes[j].Command = paneRefRe.ReplaceAllString(panes[j].Command, "$$$3")
+		}
+		if isKnownLayout(s.layouts[i]) && len(panes) > 1 {
+			panes = arrangePanes(s.layouts[i], panes)
+		} else {
+			layoutGeometry(panes)
+		}
+		if i == 0 {
+			template.WindowName = w.Name
+			template.Panes = panes
+			continue
+		}
+		template.Windows = append(template.Windows, TemplateWindow{Name: w.Name, Panes: panes})
+	}
+
+	var warnings []string
+	for _, name := range s.skipped {
+		warnings = append(warnings, fmt.Sprintf("ignored tmux %s", name))
+	}
+	return template, warnings, nil
+}
+
+// runImportScript converts shell scripts of tmux commands into templates.
+func runImportScript(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: %s import-script <file>...\n", os.Args[0])
+		return 2
+	}
+	parse := func(data []byte) (SessionTemplate, error) {
+		template, warnings, err := parseTmuxScript(data)
+		for _, w := range warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+		}
+		return template, err
+	}
+	return runImport(args, "tmux script", nil, nil, parse)
+}
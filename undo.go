@@ -0,0 +1,113 @@
+package main
+
+import "fmt"
+
+// Ctrl+Z in the template editor takes back the last change: adding,
+// duplicating, deleting or resizing a pane, a layout, a new command or
+// label, or adding, naming or deleting a window. Ctrl+Y puts it back. The
+// history starts afresh each time a template is opened for editing.
+
+// How many changes the editor can take back.
+const undoLimit = 100
+
+// editorState is the editor as it was before a change.
+type editorState struct {
+	panes      []Pane
+	cursor     int
+	windowName string
+	windows    []TemplateWindow
+	window     int
+	what       string
+}
+
+// copyWindows copies windows and their panes.
+func copyWindows(windows []TemplateWindow) []TemplateWindow {
+	out := make([]TemplateWindow, len(windows))
+	for i, w := range windows {
+		out[i] = TemplateWindow{Name: w.Name, Panes: append([]Pane(nil), w.Panes...)}
+	}
+	return out
+}
+
+// saveEditorState copies the panes, the windows and the cursor.
+func (m model) saveEditorState(what string) editorState {
+	return editorState{
+		panes:      append([]Pane(nil), m.currentTemplate.Panes...),
+		cursor:     m.paneCursor,
+		windowName: m.currentTemplate.WindowName,
+		windows:    copyWindows(m.currentTemplate.Windows),
+		window:     m.editorWindow,
+		what:       what,
+	}
+}
+
+// restoreEditorState puts the panes, the windows and the cursor back as
+// they were.
+func (m *model) restoreEditorState(s editorState) {
+	m.currentTemplate.Panes = append([]Pane(nil), s.panes...)
+	m.currentTemplate.WindowName = s.windowName
+	m.currentTemplate.Windows = copyWindows(s.windows)
+	m.editorWindow = s.window
+	m.paneCursor = max(0, min(s.cursor, len(s.panes)-1))
+	m.calculatePaneLayout()
+}
+
+// startEditHistory forgets the changes of the template edited before.
+func (m *model) startEditHistory() {
+	m.undoStack = nil
+	m.redoStack = nil
+}
+
+// recordEdit remembers the editor before a change described by what, so
+// it can be taken back.
+func (m *model) recordEdit(what string) {
+	m.pushEdit(m.saveEditorState(what))
+}
+
+// pushEdit remembers a state the editor was in before a change.
+func (m *model) pushEdit(s editorState) {
+	m.undoStack = append(m.undoStack, s)
+	if len(m.undoStack) > undoLimit {
+		m.undoStack = m.undoStack[1:]
+	}
+	m.redoStack = nil
+}
+
+// samePanes reports whether the panes are alike in every setting.
+func samePanes(a, b []Pane) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// undoEdit takes back the last change.
+func (m *model) undoEdit() {
+	if len(m.undoStack) == 0 {
+		m.setMessage("Nothing to undo", "info")
+		return
+	}
+	last := m.undoStack[len(m.undoStack)-1]
+	m.undoStack = m.undoStack[:len(m.undoStack)-1]
+	m.redoStack = append(m.redoStack, m.saveEditorState(last.what))
+	m.restoreEditorState(last)
+	m.setMessage(fmt.Sprintf("Undid %s (Ctrl+Y to redo)", last.what), "info")
+}
+
+// redoEdit puts back the last change taken back.
+func (m *model) redoEdit() {
+	if len(m.redoStack) == 0 {
+		m.setMessage("Nothing to redo", "info")
+		return
+	}
+	next := m.redoStack[len(m.redoStack)-1]
+	m.redoStack = m.redoStack[:len(m.redoStack)-1]
+	m.undoStack = append(m.undoStack, m.saveEditorState(next.what))
+	m.restoreEditorState(next)
+	m.setMessage(fmt.Sprintf("Redid %s", next.what), "info")
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const version = "0.0.1"
+
+const latestReleaseURL = "https://api.github.com/repos/newcharhuso/lazytmux/releases/latest"
+
+// GitHub is asked at most this often; in between the last answer is reused.
+const updateCheckInterval = 24 * time.Hour
+
+// release is the part of a GitHub release we show.
+type release struct {
+	Tag  string `json:"tag_name"`
+	Name string `json:"name"`
+	Body string `json:"body"` // the changelog, in markdown
+	URL  string `json:"html_url"`
+}
+
+// updateState is what update.json remembers between runs.
+type updateState struct {
+	Checked   time.Time `json:"checked"`
+	Latest    release   `json:"latest"`
+	Dismissed string    `json:"dismissed,omitempty"` // tag whose banner was dismissed
+}
+
+// updateMsg carries a release newer than this one that wasn't dismissed.
+type updateMsg release
+
+func getUpdateFile() string {
+	return filepath.Join(getConfigDir(), "update.json")
+}
+
+func loadUpdateState() updateState {
+	var state updateState
+	if data, err := ioutil.ReadFile(getUpdateFile()); err == nil {
+		json.Unmarshal(data, &state)
+	}
+	return state
+}
+
+func saveUpdateState(state updateState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(getUpdateFile(), data)
+}
+
+// updateCheckEnabled reports whether the user opted in to update checks.
+func updateCheckEnabled() bool {
+	return config.UpdateCheck == "on"
+}
+
+func fetchLatestRelease() (release, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(latestReleaseURL)
+	if err != nil {
+		return release{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return release{}, fmt.Errorf("%s: %s", latestReleaseURL, resp.Status)
+	}
+	var r release
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&r); err != nil {
+		return release{}, err
+	}
+	return r, nil
+}
+
+// checkForUpdate looks for a newer release in the background. Failures are
+// silent: an update check is never worth an error message.
+func checkForUpdate() tea.Cmd {
+	return func() tea.Msg {
+		state := loadUpdateState()
+		if time.Since(state.Checked) >= updateCheckInterval {
+			if latest, err := fetchLatestRelease(); err == nil {
+				state.Checked, state.Latest = time.Now(), latest
+				saveUpdateState(state)
+			}
+		}
+		if state.Latest.Tag == "" || state.Latest.Tag == state.Dismissed || !newerVersion(state.Latest.Tag, version) {
+			return nil
+		}
+		return updateMsg(state.Latest)
+	}
+}
+
+// dismissUpdate hides the banner until an even newer release comes out.
+func (m *model) dismissUpdate() {
+	state := loadUpdateState()
+	state.Dismissed = m.update.Tag
+	if err := saveUpdateState(state); err != nil {
+		m.setMessage(fmt.Sprintf("Failed to remember the dismissal: %v", err), "error")
+	}
+	m.update = release{}
+}
+
+// newerVersion reports whether tag ("v1.2.3") is a later version than
+// current. Parts that aren't numbers compare as 0.
+func newerVersion(tag, current string) bool {
+	a := strings.Split(strings.TrimPrefix(tag, "v"), ".")
+	b := strings.Split(strings.TrimPrefix(current, "v"), ".")
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x, _ = strconv.Atoi(a[i])
+		}
+		if i < len(b) {
+			y, _ = strconv.Atoi(b[i])
+		}
+		if x != y {
+			return x > y
+		}
+	}
+	return false
+}
+
+func (m model) renderUpdateBanner() string {
+	text := fmt.Sprintf("⬆️  lazytmux %s is out (you have %s) • [U] What's new • [X] Dismiss", m.update.Tag, version)
+	return lipgloss.NewStyle().
+		Foreground(lipgloss.Color("15")).
+		Background(secondaryColor).
+		Padding(0, 2).
+		Render(text)
+}
+
+func (m model) changelogLines() []string {
+	body := strings.TrimSpace(strings.ReplaceAll(m.update.Body, "\r\n", "\n"))
+	if body == "" {
+		return []string{"No changelog was published with this release."}
+	}
+	return strings.Split(body, "\n")
+}
+
+// changelogHeight is how many changelog lines fit on the screen.
+func (m model) changelogHeight() int {
+	return max(5, m.height-12)
+}
+
+// scrollChangelog moves the changelog by delta lines, stopping at either end.
+func (m *model) scrollChangelog(delta int) {
+	last := max(0, len(m.changelogLines())-m.changelogHeight())
+	m.changelogScroll = min(max(0, m.changelogScroll+delta), last)
+}
+
+func (m model) renderChangelogView(tableWidth int) string {
+	var content strings.Builder
+
+	title := m.update.Name
+	if title == "" {
+		title = m.update.Tag
+	}
+	header := tableHeaderStyle.Width(tableWidth).Render("📰 WHAT'S NEW IN " + strings.ToUpper(title))
+	content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, header))
+	content.WriteString("\n\n")
+
+	lines := m.changelogLines()
+	start := m.changelogScroll
+	end := min(len(lines), start+m.changelogHeight())
+	var body []string
+	for _, line := range lines[start:end] {
+		body = append(body, rightTruncate(printable(line), tableWidth-4))
+	}
+	box := lipgloss.NewStyle().Width(tableWidth).Padding(0, 2).Render(strings.Join(body, "\n"))
+	content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, box))
+	content.WriteString("\n\n")
+
+	hints := "[↑/↓] Scroll • [X] Dismiss this release • [Esc] Back"
+	if m.update.URL != "" {
+		hints = m.update.URL + " • " + hints
+	}
+	statusBar := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("16")).
+		Padding(0, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderTop(true).
+		BorderForeground(primaryColor).
+		Render(hints)
+	content.WriteString(lipgloss.Place(tableWidth, 1, lipgloss.Center, lipgloss.Top, statusBar))
+
+	return baseStyle.Render(content.String())
+}
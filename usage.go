@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// templateUsage is what we remember about how a template gets used.
+type templateUsage struct {
+	LastUsed time.Time `json:"last_used"`
+	Count    int       `json:"count"`
+}
+
+// Orders the template browser can show templates in.
+const (
+	orderName     = "name"
+	orderRecent   = "recent"
+	orderFrequent = "frequent"
+)
+
+var templateOrders = []string{orderName, orderRecent, orderFrequent}
+
+// Usage lives next to the templates rather than in them, so using a
+// template never rewrites a file that may be kept in git.
+func getUsageFile() string {
+	return filepath.Join(getConfigDir(), "usage.json")
+}
+
+// loadUsage reads the usage of every template by name. A missing or broken
+// file just means nothing has been recorded yet.
+func loadUsage() map[string]templateUsage {
+	usage := map[string]templateUsage{}
+	if data, err := ioutil.ReadFile(getUsageFile()); err == nil {
+		json.Unmarshal(data, &usage)
+	}
+	return usage
+}
+
+func saveUsage(usage map[string]templateUsage) error {
+	data, err := json.MarshalIndent(usage, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(getUsageFile(), data)
+}
+
+// recordTemplateUse notes that a session was just created from the template.
+// Like the audit log, failing to record it never fails the operation.
+func recordTemplateUse(name string) {
+	usage := loadUsage()
+	u := usage[name]
+	u.LastUsed = time.Now()
+	u.Count++
+	usage[name] = u
+	saveUsage(usage)
+}
+
+// renameUsage carries the usage of a template over to its new name.
+func renameUsage(oldName, newName string) {
+	usage := loadUsage()
+	if u, ok := usage[oldName]; ok {
+		delete(usage, oldName)
+		usage[newName] = u
+		saveUsage(usage)
+	}
+}
+
+// sortTemplates orders templates in place. Templates that were never used
+// keep their relative order below the ones that were.
+func sortTemplates(templates []SessionTemplate, order string, usage map[string]templateUsage) {
+	switch order {
+	case orderRecent:
+		sort.SliceStable(templates, func(i, j int) bool {
+			return usage[templates[i].Name].LastUsed.After(usage[templates[j].Name].LastUsed)
+		})
+	case orderFrequent:
+		sort.SliceStable(templates, func(i, j int) bool {
+			a, b := usage[templates[i].Name], usage[templates[j].Name]
+			if a.Count != b.Count {
+				return a.Count > b.Count
+			}
+			return a.LastUsed.After(b.LastUsed)
+		})
+	default:
+		sort.SliceStable(templates, func(i, j int) bool {
+			return templates[i].file < templates[j].file
+		})
+	}
+}
+
+// sortTemplates applies the browser's order, keeping the cursor on the
+// template it was on.
+func (m *model) sortTemplates() {
+	var selected string
+	if m.templateCursor < len(m.templates) {
+		selected = m.templates[m.templateCursor].Name
+	}
+	sortTemplates(m.templates, m.templateOrder, loadUsage())
+	pinTemplates(m.templates, m.favorites.Templates)
+	for i, t := range m.templates {
+		if t.Name == selected {
+			m.templateCursor = i
+		}
+	}
+}
+
+// nextTemplateOrder cycles through templateOrders.
+func nextTemplateOrder(order string) string {
+	for i, o := range templateOrders {
+		if o == order {
+			return templateOrders[(i+1)%len(templateOrders)]
+		}
+	}
+	return templateOrders[1]
+}
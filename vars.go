@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// withVars returns a copy of the template with every placeholder replaced,
+// see lazytmux.Template.WithVars.
+func (t SessionTemplate) withVars(values map[string]string) (SessionTemplate, error) {
+	template, err := t.Template.WithVars(values)
+	if err != nil {
+		return SessionTemplate{}, err
+	}
+	t.Template = template
+	return t, nil
+}
+
+// startTemplateSession creates sessionName from template in the background
+// and attaches to it unless m.detachNew is set, asking for the template's
+// variables first if it has any. It returns nil while they are asked for.
+func (m *model) startTemplateSession(sessionName string, template SessionTemplate) tea.Cmd {
+	if names := template.Placeholders(); len(names) > 0 {
+		m.pendingTemplate = template
+		m.pendingSession = sessionName
+		m.varNames = names
+		m.varInputs = make([]textinput.Model, len(names))
+		for i, name := range names {
+			ti := textinput.New()
+			ti.Placeholder = name
+			ti.SetValue(template.Variables[name])
+			ti.CharLimit = 200
+			if i == 0 {
+				ti.Focus()
+			}
+			m.varInputs[i] = ti
+		}
+		m.varCursor = 0
+		m.varPreset = -1
+		m.savingPreset = false
+		m.mode = templateVariables
+		return nil
+	}
+
+	detach, srv := m.detachNew, m.server()
+	m.setMessage(fmt.Sprintf("Creating session '%s' from template '%s'...", sessionName, template.Name), "info")
+	return inBackground(func() error {
+		return srv.createSessionFromTemplate(sessionName, template)
+	}, func(m *model, err error) tea.Cmd {
+		if err != nil {
+			m.setMessage(fmt.Sprintf("Failed to create session from template: %v", err), "error")
+			return nil
+		}
+		recordTemplateUse(template.Name)
+		m.setMessage(fmt.Sprintf("Created session '%s' from template '%s'", sessionName, template.Name), "success")
+		if detach {
+			m.selectLoaded(sessionName)
+			return nil
+		}
+		if _, err := runHook(template.OnAttach, sessionName, template); err != nil {
+			m.setMessage(fmt.Sprintf("Created session '%s', but on_attach hook failed: %v", sessionName, err), "error")
+			return nil
+		}
+		if template.afterCreate() == "read-only" {
+			attachVariants[sessionName] = attachReadOnly
+		}
+		attachSession(srv, sessionName)
+		return tea.Quit
+	})
+}
+
+func (m *model) focusVariable(i int) {
+	if i < 0 || i >= len(m.varInputs) {
+		return
+	}
+	m.varInputs[m.varCursor].Blur()
+	m.varCursor = i
+	m.varInputs[m.varCursor].Focus()
+}
+
+func (m model) renderVariableForm() string {
+	var form strings.Builder
+	form.WriteString(fmt.Sprintf("🧩 Variables for '%s'\n", m.pendingTemplate.Name))
+	if names := m.pendingTemplate.presetNames(); m.varPreset >= 0 && m.varPreset < len(names) {
+		form.WriteString(fmt.Sprintf("Preset: %s\n", names[m.varPreset]))
+	}
+	form.WriteString("\n")
+	for i, name := range m.varNames {
+		label := "  " + name
+		if i == m.varCursor {
+			label = "▶ " + name
+		}
+		form.WriteString(fmt.Sprintf("%s: %s\n", label, m.varInputs[i].View()))
+	}
+	if m.savingPreset {
+		form.WriteString(fmt.Sprintf("\nSave as preset: %s\n", m.presetInput.View()))
+		form.WriteString("\n[Enter] Save • [Esc] Back")
+	} else {
+		form.WriteString("\n[Tab] Switch • [Ctrl+P] Preset • [Ctrl+S] Save preset • [Enter] Next/Create • [Esc] Cancel")
+	}
+	inputView := inputBoxStyle.Render(form.String())
+	return lipgloss.Place(m.width, len(m.varNames)+9, lipgloss.Center, lipgloss.Top, inputView)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The session and template lists scroll once they are longer than the
+// screen: only the rows that fit are drawn, the cursor always among them,
+// with a line under them saying which are shown. PgUp and PgDn move the
+// cursor a page, Ctrl+U and Ctrl+D half a page.
+
+// listChrome is about how many lines the list views take besides the rows:
+// the title or column headers, the footer, the message and the status bar.
+const listChrome = 13
+
+// promptRoom is left free under the list while a prompt or form is open.
+const promptRoom = 10
+
+// sessionRows is how many sessions fit on the screen.
+func (m model) sessionRows() int {
+	room := m.height - listChrome
+	if m.mode != browsing {
+		room -= promptRoom
+	}
+	if m.compact {
+		return max(3, room)
+	}
+	// Detailed rows are boxed, three lines each.
+	return max(3, room/3)
+}
+
+// templateRows is how many templates fit on the screen.
+func (m model) templateRows() int {
+	room := m.height - listChrome
+	if m.mode != templateBrowsing {
+		room -= promptRoom
+	}
+	return max(3, room/3)
+}
+
+// scrollOffset returns the first of rows lines to draw from total so that
+// the cursor is among them, moving offset as little as it can.
+func scrollOffset(offset, cursor, rows, total int) int {
+	if cursor < offset {
+		offset = cursor
+	}
+	if cursor >= offset+rows {
+		offset = cursor - rows + 1
+	}
+	return max(0, min(offset, total-rows))
+}
+
+// templatePosition is the place of the template cursor among the visible
+// templates.
+func (m model) templatePosition() int {
+	n := 0
+	for i := 0; i < m.templateCursor && i < len(m.templates); i++ {
+		if m.templateVisible(i) {
+			n++
+		}
+	}
+	return n
+}
+
+// followCursor scrolls the lists to keep their cursors in view.
+func (m *model) followCursor() {
+	m.sessionOffset = scrollOffset(m.sessionOffset, m.cursor, m.sessionRows(), len(m.sessions))
+	m.templateOffset = scrollOffset(m.templateOffset, m.templatePosition(), m.templateRows(), m.visibleTemplates())
+}
+
+// pageSessions moves the session cursor by n rows, stopping at the ends.
+func (m *model) pageSessions(n int) {
+	if len(m.sessions) == 0 {
+		return
+	}
+	cursor := max(0, min(m.cursor+n, len(m.sessions)-1))
+	if cursor != m.cursor {
+		m.lastCursor = m.cursor
+		m.cursor = cursor
+		m.popAnimation = 0.5
+	}
+}
+
+// pageTemplates moves the template cursor by n visible templates,
+// stopping at the ends.
+func (m *model) pageTemplates(n int) {
+	direction := 1
+	if n < 0 {
+		direction, n = -1, -n
+	}
+	moved := false
+	for ; n > 0 && m.moveTemplateCursor(direction); n-- {
+		moved = true
+	}
+	if moved {
+		m.popAnimation = 0.5
+	}
+}
+
+// renderScrollPosition tells which rows of a scrolled list are shown, or
+// returns "" when they all are.
+func (m model) renderScrollPosition(offset, rows, total int) string {
+	if total <= rows {
+		return ""
+	}
+	text := fmt.Sprintf("%d–%d of %d", offset+1, min(offset+rows, total), total)
+	if offset > 0 {
+		text = "↑ " + text
+	}
+	if offset+rows < total {
+		text += " ↓"
+	}
+	text += " • [PgUp/PgDn] Page"
+	position := lipgloss.NewStyle().Foreground(mutedColor).Render(text)
+	return lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, position) + "\n"
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How long a pane waits for its dependency when the template doesn't say.
+const defaultWaitTimeout = 60
+
+// paneShows reports whether text appears anywhere in the pane's scrollback.
+func paneShows(paneID, text string) (bool, error) {
+	out, err := tmuxCommand("capture-pane", "-p", "-J", "-S", "-", "-t", paneID).Output()
+	if err != nil {
+		return false, fmt.Errorf("pane %s is gone", paneID)
+	}
+	return strings.Contains(string(out), text), nil
+}
+
+func portOpen(port int) bool {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort("localhost", strconv.Itoa(port)), time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// waitReady polls until the pane shows text and the port accepts
+// connections, whichever of the two are set.
+func waitReady(paneID, text string, port int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		ready := true
+		if text != "" {
+			shown, err := paneShows(paneID, text)
+			if err != nil {
+				return err
+			}
+			ready = shown
+		}
+		if ready && port > 0 {
+			ready = portOpen(port)
+		}
+		if ready {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.New("timed out")
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// runWait blocks until a pane prints a string and/or a local port is open.
+// Template panes with wait_for or wait_for_port run their command through it.
+func runWait(args []string) int {
+	fs := flag.NewFlagSet("wait", flag.ContinueOnError)
+	pane := fs.String("pane", "", "tmux id of the pane to watch, e.g. %3")
+	text := fs.String("text", "", "Wait until this text appears in the pane")
+	port := fs.Int("port", 0, "Wait until this TCP port on localhost accepts connections")
+	timeout := fs.Int("timeout", defaultWaitTimeout, "Give up after this many seconds")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s wait [--pane id --text text] [--port port] [--timeout seconds]\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if (*text == "") != (*pane == "") || (*text == "" && *port <= 0) {
+		fs.Usage()
+		return 2
+	}
+
+	var what []string
+	if *text != "" {
+		what = append(what, fmt.Sprintf("pane %s to show %q", *pane, *text))
+	}
+	if *port > 0 {
+		what = append(what, fmt.Sprintf("port %d", *port))
+	}
+	fmt.Printf("Waiting for %s...\n", strings.Join(what, " and "))
+	if err := waitReady(*pane, *text, *port, time.Duration(*timeout)*time.Second); err != nil {
+		fmt.Fprintf(os.Stderr, "Gave up waiting for %s: %v\n", strings.Join(what, " and "), err)
+		return 1
+	}
+	return 0
+}
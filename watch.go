@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// T watches the active pane of the highlighted session, or the highlighted
+// pane in the pane view, for "tell me when this build finishes" without
+// staying attached: once the command running in it gives the pane back to
+// the shell, or the pane closes, the status bar says so, and with
+// "watch_notify": "on" the desktop does too. Watched sessions are marked 👀.
+
+// paneWatch is a pane waited on.
+type paneWatch struct {
+	Session string
+	Server  string
+	Pane    string // "window.pane"
+	PaneID  string
+	Command string // what was running when the watch started
+	Started time.Time
+}
+
+// paneState is how a watched pane looks now.
+type paneState struct {
+	Command    string
+	Dead       bool
+	ExitStatus string
+}
+
+// watchMsg carries the state of the panes on the servers of the watches,
+// by server name and pane id. A watched pane missing from it has closed.
+type watchMsg map[string]map[string]paneState
+
+// shells are the commands a pane shows while nothing else runs in it.
+var shells = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "fish": true, "dash": true, "ksh": true,
+	"mksh": true, "tcsh": true, "csh": true, "nu": true, "elvish": true, "xonsh": true, "pwsh": true,
+}
+
+// isShell reports whether a pane's current command is an idle shell.
+func isShell(command string) bool {
+	command = strings.TrimPrefix(command, "-")
+	return shells[command] || command == filepath.Base(os.Getenv("SHELL"))
+}
+
+// readPaneStates lists the panes of the servers in the background.
+func readPaneStates(servers []string) tea.Cmd {
+	return func() tea.Msg {
+		msg := watchMsg{}
+		for _, name := range servers {
+			out, err := serverNamed(name).command("list-panes", "-a", "-F", "#{pane_id}\t#{pane_current_command}\t#{pane_dead}\t#{pane_dead_status}").Output()
+			if err != nil {
+				continue
+			}
+			panes := map[string]paneState{}
+			// The exit status is empty while the pane is alive.
+			for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+				fields := strings.Split(line, "\t")
+				if len(fields) == 4 {
+					panes[fields[0]] = paneState{Command: fields[1], Dead: fields[2] == "1", ExitStatus: fields[3]}
+				}
+			}
+			msg[name] = panes
+		}
+		return msg
+	}
+}
+
+// refreshWatches starts reading the watched panes unless it already runs.
+// It returns nil when nothing is watched.
+func (m *model) refreshWatches() tea.Cmd {
+	if len(m.watches) == 0 || m.checkingWatches {
+		return nil
+	}
+	var servers []string
+	for _, w := range m.watches {
+		if !containsString(servers, w.Server) {
+			servers = append(servers, w.Server)
+		}
+	}
+	m.checkingWatches = true
+	return readPaneStates(servers)
+}
+
+// finishWatches ends the watches whose pane is back at the shell or gone,
+// and tells about them.
+func (m *model) finishWatches(msg watchMsg) {
+	m.checkingWatches = false
+	var done []string
+	for key, w := range m.watches {
+		panes, ok := msg[w.Server]
+		if !ok {
+			// The server couldn't be read this time.
+			continue
+		}
+		state, alive := panes[w.PaneID]
+		var how string
+		switch {
+		case !alive:
+			how = "and its pane closed"
+		case state.Dead:
+			how = "with exit status " + state.ExitStatus
+		case isShell(state.Command):
+			// Back at the prompt.
+		default:
+			continue
+		}
+		text := strings.TrimSpace(fmt.Sprintf("%s finished in %s:%s after %s %s", w.Command, w.Session, w.Pane, formatUptime(time.Since(w.Started)), how))
+		done = append(done, text)
+		delete(m.watches, key)
+		if config.WatchNotify == "on" {
+			go notifyDesktop(text)
+		}
+	}
+	if len(done) > 0 {
+		m.setMessage("✅ "+strings.Join(done, "; "), "success")
+	}
+}
+
+// notifyDesktop shows a desktop notification, with notify-send or, on
+// macOS, osascript. Failures are ignored.
+func notifyDesktop(text string) {
+	if runtime.GOOS == "darwin" {
+		script := fmt.Sprintf("display notification %q with title \"lazytmux\"", text)
+		exec.Command("osascript", "-e", script).Run()
+		return
+	}
+	exec.Command("notify-send", "lazytmux", text).Run()
+}
+
+// toggleWatch starts or stops watching a pane of a session. Pane is a
+// pane id, or "" for the active pane of the session.
+func (m *model) toggleWatch(s Session, pane string) tea.Cmd {
+	server := serverNamed(s.Server)
+	target := pane
+	if target == "" {
+		target = "=" + s.Name + ":"
+	}
+	var fields []string
+	return inBackground(func() error {
+		out, err := server.command("display-message", "-p", "-t", target, "#{pane_id}\t#{window_index}.#{pane_index}\t#{pane_current_command}").Output()
+		fields = strings.Split(strings.TrimSpace(string(out)), "\t")
+		return err
+	}, func(m *model, err error) tea.Cmd {
+		if err != nil || len(fields) != 3 {
+			m.setMessage(fmt.Sprintf("Failed to find the pane of '%s'", s.Name), "error")
+			return nil
+		}
+		m.watchPane(s, fields[0], fields[1], fields[2])
+		return nil
+	})
+}
+
+// watchPane starts or stops watching the pane id, numbered pane in its
+// session, which runs command.
+func (m *model) watchPane(s Session, id, pane, command string) {
+	key := s.Server + id
+	if w, ok := m.watches[key]; ok {
+		delete(m.watches, key)
+		m.setMessage(fmt.Sprintf("Stopped watching %s in %s:%s", w.Command, s.Name, w.Pane), "info")
+		return
+	}
+	if isShell(command) {
+		m.setMessage(fmt.Sprintf("Nothing runs in %s:%s but the shell", s.Name, pane), "warning")
+		return
+	}
+	if m.watches == nil {
+		m.watches = map[string]paneWatch{}
+	}
+	m.watches[key] = paneWatch{
+		Session: s.Name,
+		Server:  s.Server,
+		Pane:    pane,
+		PaneID:  id,
+		Command: command,
+		Started: time.Now(),
+	}
+	m.setMessage(fmt.Sprintf("Watching %s in %s:%s, you'll be told when it finishes", command, s.Name, pane), "info")
+}
+
+// watched reports whether a pane of the session is watched.
+func (m model) watched(session string) bool {
+	for _, w := range m.watches {
+		if w.Session == session {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// LiveWindow is a window of a running session.
+type LiveWindow struct {
+	ID      string // tmux window id, e.g. "@3"
+	Session string
+	Index   string
+	Name    string
+	Panes   int
+	Active  bool
+}
+
+func (s Server) listSessionWindows(session string) []LiveWindow {
+	format := strings.Join([]string{
+		"#{window_id}",
+		"#{window_index}",
+		"#{window_panes}",
+		"#{window_active}",
+		"#{window_name}",
+	}, "\t")
+	out, err := s.command("list-windows", "-t", "="+session, "-F", format).Output()
+	if err != nil {
+		return []LiveWindow{}
+	}
+
+	windows := []LiveWindow{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		parts := strings.SplitN(line, "\t", 5)
+		if len(parts) < 5 {
+			continue
+		}
+		panes, _ := strconv.Atoi(parts[2])
+		windows = append(windows, LiveWindow{
+			ID:      parts[0],
+			Session: session,
+			Index:   parts[1],
+			Panes:   panes,
+			Active:  parts[3] == "1",
+			Name:    parts[4],
+		})
+	}
+	return windows
+}
+
+// listAllWindows returns the windows of every session, for picking a
+// window to join a pane into.
+func (s Server) listAllWindows() []LiveWindow {
+	format := "#{window_id}\t#{session_name}\t#{window_index}\t#{window_panes}\t#{window_name}"
+	out, err := s.command("list-windows", "-a", "-F", format).Output()
+	if err != nil {
+		return []LiveWindow{}
+	}
+
+	windows := []LiveWindow{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		parts := strings.SplitN(line, "\t", 5)
+		if len(parts) < 5 {
+			continue
+		}
+		panes, _ := strconv.Atoi(parts[3])
+		windows = append(windows, LiveWindow{
+			ID:      parts[0],
+			Session: parts[1],
+			Index:   parts[2],
+			Panes:   panes,
+			Name:    parts[4],
+		})
+	}
+	return windows
+}
+
+// newWindow adds a window at the end of a session, named name unless it is
+// empty, without switching to it.
+func (s Server) newWindow(session, name string) error {
+	args := []string{"new-window", "-d", "-t", "=" + session + ":"}
+	if name != "" {
+		args = append(args, "-n", name)
+	}
+	return s.run(args...)
+}
+
+func (s Server) renameWindow(id, name string) error {
+	return s.run("rename-window", "-t", id, name)
+}
+
+func (s Server) killWindow(id string) error {
+	return s.run("kill-window", "-t", id)
+}
+
+// moveWindow moves a window to the first free index of another session.
+func (s Server) moveWindow(id, session string) error {
+	return s.run("move-window", "-d", "-s", id, "-t", "="+session+":")
+}
+
+// swapWindow swaps a window with target, given as "index" for a window of
+// the same session or "session:index" for one of another session.
+func (s Server) swapWindow(id, session, target string) error {
+	if !strings.Contains(target, ":") {
+		target = session + ":" + target
+	}
+	return s.run("swap-window", "-d", "-s", id, "-t", "="+target)
+}
+
+// selectWindow makes a window the current one of its session, so attaching
+// afterwards lands on it.
+func (s Server) selectWindow(id string) error {
+	return s.run("select-window", "-t", id)
+}
+
+// readWindows reads the windows of a session for refreshView.
+func readWindows(srv Server, session string) func(m *model) {
+	windows := srv.listSessionWindows(session)
+	return func(m *model) {
+		if !m.showWindows || m.windowSession != session {
+			return
+		}
+		m.liveWindows = windows
+		if m.windowCursor >= len(m.liveWindows) && len(m.liveWindows) > 0 {
+			m.windowCursor = len(m.liveWindows) - 1
+		}
+	}
+}
+
+// startWindowInput asks for a window name or target in one of the window
+// input modes, starting with value.
+func (m *model) startWindowInput(next mode, placeholder, value string) {
+	ti := textinput.New()
+	ti.Placeholder = placeholder
+	ti.SetValue(value)
+	ti.CharLimit = 50
+	ti.Focus()
+	m.input = ti
+	m.mode = next
+}
+
+// submitWindowInput carries out what the window input mode asked for, in
+// the background.
+func (m *model) submitWindowInput() tea.Cmd {
+	value := strings.TrimSpace(m.input.Value())
+	var w LiveWindow
+	if len(m.liveWindows) > 0 {
+		w = m.liveWindows[m.windowCursor]
+	}
+	session, srv := m.windowSession, m.server()
+	target := fmt.Sprintf("%s:%s", session, w.Index)
+	mode := m.mode
+	m.mode = windowBrowsing
+
+	switch mode {
+	case windowCreating:
+		return changeInBackground(func() error { return srv.newWindow(session, value) },
+			"new-window", session, "Failed to create window", fmt.Sprintf("Created a window in '%s'", session))
+	case windowRenaming:
+		if value == "" || value == w.Name {
+			break
+		}
+		return changeInBackground(func() error { return srv.renameWindow(w.ID, value) },
+			"rename-window", fmt.Sprintf("%s -> %s", target, value),
+			"Failed to rename window", fmt.Sprintf("Renamed window %s to '%s'", w.Index, value))
+	case windowMoving:
+		if value == "" || value == session {
+			break
+		}
+		return changeInBackground(func() error { return srv.moveWindow(w.ID, value) },
+			"move-window", fmt.Sprintf("%s -> %s", target, value),
+			"Failed to move window", fmt.Sprintf("Moved window '%s' to '%s'", w.Name, value))
+	case windowSwapping:
+		if value == "" {
+			break
+		}
+		return changeInBackground(func() error { return srv.swapWindow(w.ID, session, value) },
+			"swap-window", fmt.Sprintf("%s <-> %s", target, value),
+			"Failed to swap windows", fmt.Sprintf("Swapped window '%s' with %s", w.Name, value))
+	}
+	return nil
+}
+
+func (m model) renderWindowView(tableWidth int) string {
+	var content strings.Builder
+
+	title := tableHeaderStyle.Width(tableWidth).Render(fmt.Sprintf("🪟 WINDOWS OF '%s'", m.windowSession))
+	content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, title))
+	content.WriteString("\n\n")
+
+	if len(m.liveWindows) == 0 {
+		text := "No windows found. The session may have been closed."
+		if m.viewLoading() {
+			text = "Reading the windows..."
+		}
+		emptyMsg := lipgloss.NewStyle().
+			Foreground(mutedColor).
+			Italic(true).
+			Render(text)
+		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, emptyMsg))
+		content.WriteString("\n\n")
+	} else {
+		for i, w := range m.liveWindows {
+			isSelected := m.windowCursor == i && m.mode == windowBrowsing
+
+			rowStyle := selectedRowStyle.Copy().Padding(0, 1)
+			if !isSelected {
+				rowStyle = lipgloss.NewStyle().
+					Foreground(lipgloss.Color("16")).
+					Padding(0, 1).
+					Border(lipgloss.RoundedBorder()).
+					BorderForeground(mutedColor)
+			}
+
+			indexText := "  " + w.Index
+			if isSelected {
+				indexText = "▶ " + w.Index
+			}
+			status := ""
+			if w.Active {
+				status = lipgloss.NewStyle().Foreground(successColor).Render("current")
+			}
+
+			indexCell := rowStyle.Copy().Width(tableWidth / 6).Render(indexText)
+			nameCell := rowStyle.Copy().Width(tableWidth * 2 / 5).Render(w.Name)
+			panesCell := rowStyle.Copy().Width(tableWidth / 8).Render(fmt.Sprintf("%d pane(s)", w.Panes))
+			statusCell := rowStyle.Copy().Width(tableWidth / 4).Render(status)
+
+			row := lipgloss.JoinHorizontal(lipgloss.Top, indexCell, nameCell, panesCell, statusCell)
+			content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, row))
+			content.WriteString("\n")
+		}
+		content.WriteString("\n")
+	}
+
+	var prompt string
+	switch m.mode {
+	case windowCreating:
+		prompt = fmt.Sprintf("🪟 New window in '%s'\n\n%s\n\n[Enter] Create • [Esc] Cancel", m.windowSession, m.input.View())
+	case windowRenaming:
+		prompt = fmt.Sprintf("✏️ Rename window %s\n\n%s\n\n[Enter] Rename • [Esc] Cancel", m.liveWindows[m.windowCursor].Index, m.input.View())
+	case windowMoving:
+		prompt = fmt.Sprintf("📦 Move '%s' to session\n\n%s\n\n[Enter] Move • [Esc] Cancel", m.liveWindows[m.windowCursor].Name, m.input.View())
+	case windowSwapping:
+		prompt = fmt.Sprintf("🔀 Swap '%s' with window (index or session:index)\n\n%s\n\n[Enter] Swap • [Esc] Cancel", m.liveWindows[m.windowCursor].Name, m.input.View())
+	}
+	if prompt != "" {
+		content.WriteString(lipgloss.Place(m.width, 7, lipgloss.Center, lipgloss.Top, inputBoxStyle.Render(prompt)))
+		content.WriteString("\n")
+	}
+
+	if m.mode == confirming && m.confirmAction == actionKillWindow {
+		w := m.liveWindows[m.windowCursor]
+		confirmText := fmt.Sprintf("⚠️  KILL WINDOW %s (%s)?\n\nAll its panes will be closed!\n\n[y] Yes  [n] No", w.Index, w.Name)
+		if len(m.liveWindows) == 1 {
+			confirmText = fmt.Sprintf("⚠️  KILL WINDOW %s (%s)?\n\nIt is the last one, so '%s' will end too!\n\n[y] Yes  [n] No", w.Index, w.Name, m.windowSession)
+		}
+		confirmView := confirmBoxStyle.Render(confirmText)
+		content.WriteString(lipgloss.Place(m.width, 7, lipgloss.Center, lipgloss.Center, confirmView))
+	}
+
+	if m.message != "" {
+		content.WriteString(lipgloss.Place(m.width, 1, lipgloss.Center, lipgloss.Top, m.renderMessage()))
+		content.WriteString("\n")
+	}
+
+	hints := "[Enter] Attach at window • [n] New • [r] Rename • [x] Kill • [m] Move • [s] Swap • [M] Bookmark • [Esc] Back"
+	if readOnly {
+		hints = "[Enter] Attach at window • [n] New • [M] Bookmark • [Esc] Back • 🔒 Read-only"
+	}
+	statusBar := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("16")).
+		Padding(0, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderTop(true).
+		BorderForeground(primaryColor).
+		Render(hints)
+	content.WriteString("\n")
+	content.WriteString(lipgloss.Place(tableWidth, 1, lipgloss.Center, lipgloss.Top, statusBar))
+
+	return baseStyle.Render(content.String())
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// detectWindowManager returns the window manager we can talk to over IPC,
+// or "" if there is none.
+func detectWindowManager() string {
+	switch config.WindowManager {
+	case "", "auto":
+	case "none":
+		return ""
+	default:
+		return config.WindowManager
+	}
+
+	switch {
+	case os.Getenv("HYPRLAND_INSTANCE_SIGNATURE") != "":
+		return "hyprland"
+	case os.Getenv("SWAYSOCK") != "":
+		return "sway"
+	case os.Getenv("I3SOCK") != "":
+		return "i3"
+	}
+	return ""
+}
+
+// terminalCommand returns the argv that opens a terminal attached to t.
+func terminalCommand(t attachTarget) []string {
+	args := append([]string{terminalCmd}, getTerminalArgs(t.server, terminalCmd)...)
+	args = append(args, t.server.remoteQuote(t.name))
+	return append(args, attachFlags(t.name)...)
+}
+
+// attachSessions opens a terminal for every session, asking the window
+// manager to place each one according to its configured Placement.
+func attachSessions(targets []attachTarget) error {
+	if !spawnsTerminals() {
+		for _, t := range targets {
+			attachSession(t.server, t.name)
+		}
+		return nil
+	}
+	wm := detectWindowManager()
+	var errs []string
+	for _, t := range targets {
+		placement, ok := config.Placements[t.name]
+		if wm == "" || !ok {
+			attachSession(t.server, t.name)
+			continue
+		}
+		if err := placeTerminal(wm, placement, terminalCommand(t)); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", t.name, err))
+			attachSession(t.server, t.name)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func placeTerminal(wm string, p Placement, argv []string) error {
+	quoted := make([]string, len(argv))
+	for i, a := range argv {
+		quoted[i] = shellQuote(a)
+	}
+	cmdline := strings.Join(quoted, " ")
+
+	switch wm {
+	case "hyprland":
+		var rules []string
+		if p.Workspace != "" {
+			rules = append(rules, "workspace "+p.Workspace+" silent")
+		}
+		if p.Floating {
+			rules = append(rules, "float")
+			if p.Width > 0 && p.Height > 0 {
+				rules = append(rules, fmt.Sprintf("size %d %d", p.Width, p.Height))
+			}
+			rules = append(rules, fmt.Sprintf("move %d %d", p.X, p.Y))
+		}
+		dispatch := "dispatch exec "
+		if len(rules) > 0 {
+			dispatch += "[" + strings.Join(rules, ";") + "] "
+		}
+		return hyprlandRequest(dispatch + cmdline)
+
+	case "sway", "i3":
+		var cmds []string
+		if p.Workspace != "" {
+			cmds = append(cmds, "workspace "+p.Workspace)
+		}
+		switch p.Split {
+		case "h", "v":
+			cmds = append(cmds, "split "+p.Split)
+		}
+		cmds = append(cmds, "exec "+cmdline)
+		if err := i3Command(wm, strings.Join(cmds, "; ")); err != nil {
+			return err
+		}
+		if p.Floating {
+			// exec is asynchronous, so give the window a moment to appear
+			// and take focus before floating it.
+			time.Sleep(300 * time.Millisecond)
+			move := "floating enable"
+			if p.Width > 0 && p.Height > 0 {
+				move += fmt.Sprintf("; resize set %d %d", p.Width, p.Height)
+			}
+			move += fmt.Sprintf("; move position %d %d", p.X, p.Y)
+			return i3Command(wm, move)
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported window manager %q", wm)
+}
+
+func hyprlandRequest(request string) error {
+	sig := os.Getenv("HYPRLAND_INSTANCE_SIGNATURE")
+	if sig == "" {
+		return errors.New("HYPRLAND_INSTANCE_SIGNATURE is not set")
+	}
+	candidates := []string{filepath.Join("/tmp", "hypr", sig, ".socket.sock")}
+	if runtime := os.Getenv("XDG_RUNTIME_DIR"); runtime != "" {
+		candidates = append([]string{filepath.Join(runtime, "hypr", sig, ".socket.sock")}, candidates...)
+	}
+
+	var conn net.Conn
+	var err error
+	for _, path := range candidates {
+		if conn, err = net.DialTimeout("unix", path, time.Second); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(request)); err != nil {
+		return err
+	}
+	reply, err := io.ReadAll(conn)
+	if err != nil {
+		return err
+	}
+	if r := strings.TrimSpace(string(reply)); r != "ok" {
+		return fmt.Errorf("hyprland: %s", r)
+	}
+	return nil
+}
+
+func i3SocketPath(wm string) (string, error) {
+	if wm == "sway" {
+		if path := os.Getenv("SWAYSOCK"); path != "" {
+			return path, nil
+		}
+		return "", errors.New("SWAYSOCK is not set")
+	}
+	if path := os.Getenv("I3SOCK"); path != "" {
+		return path, nil
+	}
+	out, err := exec.Command("i3", "--get-socketpath").Output()
+	if err != nil {
+		return "", fmt.Errorf("could not find i3 socket: %v", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// i3Command sends a RUN_COMMAND message over the i3/sway IPC protocol.
+func i3Command(wm, command string) error {
+	path, err := i3SocketPath(wm)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	const magic = "i3-ipc"
+	const runCommand = 0
+	header := make([]byte, len(magic)+8)
+	copy(header, magic)
+	binary.LittleEndian.PutUint32(header[len(magic):], uint32(len(command)))
+	binary.LittleEndian.PutUint32(header[len(magic)+4:], runCommand)
+	if _, err := conn.Write(append(header, command...)); err != nil {
+		return err
+	}
+
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	payload := make([]byte, binary.LittleEndian.Uint32(header[len(magic):]))
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return err
+	}
+
+	var results []struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+	}
+	if err := json.Unmarshal(payload, &results); err != nil {
+		return err
+	}
+	for _, r := range results {
+		if !r.Success {
+			return fmt.Errorf("%s: %s", wm, r.Error)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// worktree is a checkout of a git repository, as listed by git worktree.
+type worktree struct {
+	Path   string
+	Branch string // branch name, or the short commit of a detached HEAD
+}
+
+// listWorktrees returns the worktrees of the repository dir belongs to, the
+// main one first. Bare repositories have no checkout and are left out.
+func listWorktrees(dir string) ([]worktree, error) {
+	out, err := exec.Command("git", "-C", dir, "worktree", "list", "--porcelain").Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s is not in a git repository", dir)
+	}
+	var trees []worktree
+	for _, block := range strings.Split(strings.TrimSpace(string(out)), "\n\n") {
+		var wt worktree
+		bare := false
+		for _, line := range strings.Split(block, "\n") {
+			key, value, _ := strings.Cut(line, " ")
+			switch key {
+			case "worktree":
+				wt.Path = value
+			case "branch":
+				wt.Branch = strings.TrimPrefix(value, "refs/heads/")
+			case "HEAD":
+				if wt.Branch == "" && len(value) >= 7 {
+					wt.Branch = value[:7]
+				}
+			case "bare":
+				bare = true
+			}
+		}
+		if wt.Path != "" && !bare {
+			trees = append(trees, wt)
+		}
+	}
+	return trees, nil
+}
+
+// worktreeSessionName names the session of a worktree repo@branch. tmux
+// doesn't allow '.' or ':' in names, so they become '_' as tmux itself does.
+func worktreeSessionName(repo, branch string) string {
+	return sanitizeSessionName(repo + "@" + branch)
+}
+
+// worktreeSessions returns the sessions to create for the worktrees of the
+// repository dir is in, keyed by name, leaving out names already taken.
+func worktreeSessions(dir string, sessions []Session) (map[string]string, error) {
+	if dir == "" {
+		return nil, errors.New("the session has no directory")
+	}
+	trees, err := listWorktrees(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(trees) == 0 {
+		return nil, fmt.Errorf("%s has no worktrees", dir)
+	}
+	repo := filepath.Base(trees[0].Path)
+	missing := map[string]string{}
+	for _, wt := range trees {
+		name := worktreeSessionName(repo, wt.Branch)
+		if !nameExists(name, sessions, nil) {
+			missing[name] = wt.Path
+		}
+	}
+	return missing, nil
+}
+
+// createWorktreeSessions starts a detached session in each worktree and
+// returns the names of those it created.
+func (s Server) createWorktreeSessions(trees map[string]string) ([]string, error) {
+	var created, failed []string
+	for _, name := range sortedKeys(trees) {
+		err := s.createSessionIn(name, trees[name])
+		recordAudit("new-session", name, err)
+		if err != nil {
+			failed = append(failed, name)
+			continue
+		}
+		created = append(created, name)
+	}
+	if len(failed) > 0 {
+		return created, fmt.Errorf("could not create %s", strings.Join(failed, ", "))
+	}
+	return created, nil
+}
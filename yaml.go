@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAML support for templates and the project files of other tmux managers
+// (tmuxinator, tmuxp). Documents are read with gopkg.in/yaml.v3 and turned
+// into plain nodes: mappings become *yamlMap (keeping key order), sequences
+// []interface{}, scalars string and null nil. Aliases are followed and
+// "<<" merge keys are applied. Only the first document of a stream is read.
+
+// yamlMap is a mapping that remembers the order its keys appeared in.
+type yamlMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (m *yamlMap) get(key string) (interface{}, bool) {
+	v, ok := m.values[key]
+	return v, ok
+}
+
+func parseYAML(data []byte) (interface{}, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var doc yaml.Node
+	if err := dec.Decode(&doc); err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	// Decoding stops after the first document; reading on catches what
+	// follows it but isn't a document, such as a line indented less than
+	// the first.
+	var next yaml.Node
+	if err := dec.Decode(&next); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return yamlValue(&doc)
+}
+
+// yamlValue converts a parsed node into the plain node types.
+func yamlValue(n *yaml.Node) (interface{}, error) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil, nil
+		}
+		return yamlValue(n.Content[0])
+	case yaml.AliasNode:
+		return yamlValue(n.Alias)
+	case yaml.SequenceNode:
+		items := []interface{}{}
+		for _, item := range n.Content {
+			v, err := yamlValue(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		return items, nil
+	case yaml.MappingNode:
+		m := &yamlMap{values: map[string]interface{}{}}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if key.Kind == yaml.ScalarNode && key.Tag == "!!merge" {
+				if err := mergeYAML(m, value); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if key.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: only plain keys are supported", key.Line)
+			}
+			v, err := yamlValue(value)
+			if err != nil {
+				return nil, err
+			}
+			m.set(key.Value, v)
+		}
+		return m, nil
+	case yaml.ScalarNode:
+		if n.Tag == "!!null" {
+			return nil, nil
+		}
+		return n.Value, nil
+	}
+	return nil, fmt.Errorf("line %d: unsupported YAML node", n.Line)
+}
+
+// mergeYAML applies a "<<" merge key: the keys of the merged mapping, or
+// of each mapping in a list, that m doesn't set itself. Keys m sets later
+// still win, as set replaces values.
+func mergeYAML(m *yamlMap, n *yaml.Node) error {
+	v, err := yamlValue(n)
+	if err != nil {
+		return err
+	}
+	sources := []interface{}{v}
+	if list, ok := v.([]interface{}); ok {
+		sources = list
+	}
+	for _, source := range sources {
+		merged, ok := source.(*yamlMap)
+		if !ok {
+			return fmt.Errorf("line %d: << has to merge a mapping", n.Line)
+		}
+		for _, k := range merged.keys {
+			if _, set := m.values[k]; !set {
+				m.set(k, merged.values[k])
+			}
+		}
+	}
+	return nil
+}
+
+// yamlString renders a scalar node as a string ("" for null or non-scalars).
+func yamlString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return ""
+}
+
+// yamlStrings flattens a scalar or a sequence of scalars into a list.
+func yamlStrings(v interface{}) []string {
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var out []string
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// set adds or replaces key, keeping the position of existing keys.
+func (m *yamlMap) set(key string, value interface{}) {
+	if m.values == nil {
+		m.values = map[string]interface{}{}
+	}
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// MarshalJSON writes the mapping with its keys in order.
+func (m *yamlMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		value, err := json.Marshal(m.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// parseJSONNode decodes JSON into the same node types parseYAML returns,
+// so one converter handles both formats. Numbers and booleans become strings.
+func parseJSONNode(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	v, err := decodeJSONNode(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+	return v, nil
+}
+
+func decodeJSONNode(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '[' {
+			items := []interface{}{}
+			for dec.More() {
+				v, err := decodeJSONNode(dec)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, v)
+			}
+			_, err := dec.Token()
+			return items, err
+		}
+		m := &yamlMap{values: map[string]interface{}{}}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := decodeJSONNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			m.set(keyTok.(string), v)
+		}
+		_, err := dec.Token()
+		return m, err
+	case string:
+		return t, nil
+	case json.Number:
+		return t.String(), nil
+	case bool:
+		return fmt.Sprint(t), nil
+	}
+	return nil, nil
+}
+
+// formatYAML renders a node tree as block-style YAML.
+func formatYAML(v interface{}) string {
+	var buf strings.Builder
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	// Nodes built from strings, maps and lists always encode.
+	_ = enc.Encode(yamlDocument(v))
+	enc.Close()
+	return buf.String()
+}
+
+// yamlDocument turns a node tree back into a yaml.v3 node.
+func yamlDocument(v interface{}) *yaml.Node {
+	switch t := v.(type) {
+	case *yamlMap:
+		n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range t.keys {
+			n.Content = append(n.Content, yamlDocument(k), yamlDocument(t.values[k]))
+		}
+		return n
+	case []interface{}:
+		n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range t {
+			n.Content = append(n.Content, yamlDocument(item))
+		}
+		return n
+	case string:
+		n := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: t}
+		switch strings.ToLower(t) {
+		case "y", "yes", "n", "no", "on", "off":
+			// YAML 1.1 readers such as tmuxp's take these for booleans.
+			n.Style = yaml.DoubleQuotedStyle
+		}
+		return n
+	case yamlRaw:
+		n := &yaml.Node{Kind: yaml.ScalarNode, Value: string(t)}
+		n.Tag = n.ShortTag()
+		return n
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+}
+
+// yamlRaw is a scalar written without quotes, such as a number or boolean.
+type yamlRaw string
+
+// marshalYAML renders a struct as YAML, naming fields after their json tags
+// and honouring omitempty, so templates read the same in both formats.
+func marshalYAML(v interface{}) string {
+	return formatYAML(yamlNode(reflect.ValueOf(v)))
+}
+
+func yamlNode(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return yamlNode(v.Elem())
+	case reflect.Struct:
+		m := &yamlMap{}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Anonymous && field.Tag.Get("json") == "" && field.Type.Kind() == reflect.Struct {
+				// Embedded structs are flattened, as encoding/json does.
+				embedded := yamlNode(v.Field(i)).(*yamlMap)
+				for _, k := range embedded.keys {
+					m.set(k, embedded.values[k])
+				}
+				continue
+			}
+			name, omitEmpty, ok := jsonFieldName(field)
+			if !ok {
+				continue
+			}
+			fv := v.Field(i)
+			if omitEmpty && isEmptyValue(fv) {
+				continue
+			}
+			m.set(name, yamlNode(fv))
+		}
+		return m
+	case reflect.Map:
+		m := &yamlMap{}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(a, b int) bool { return keys[a].String() < keys[b].String() })
+		for _, k := range keys {
+			m.set(k.String(), yamlNode(v.MapIndex(k)))
+		}
+		return m
+	case reflect.Slice, reflect.Array:
+		items := []interface{}{}
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, yamlNode(v.Index(i)))
+		}
+		return items
+	case reflect.String:
+		return v.String()
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return yamlRaw(fmt.Sprint(v.Interface()))
+	}
+	return nil
+}
+
+// jsonFieldName returns the key a struct field is stored under.
+func jsonFieldName(field reflect.StructField) (name string, omitEmpty, ok bool) {
+	if field.PkgPath != "" {
+		return "", false, false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, strings.Contains(","+opts+",", ",omitempty,"), true
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/newcharhuso/tmux-navigator/pkg/lazytmux"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string // the parsed node as JSON, keys in order
+	}{
+		{"empty", "", `null`},
+		{"plain scalar", "hello world", `"hello world"`},
+		{"nulls", "a: ~\nb: null\nc:", `{"a":null,"b":null,"c":null}`},
+		{"map keeps key order", "b: 1\na: 2\nc: three", `{"b":"1","a":"2","c":"three"}`},
+		{"nested maps", "root:\n  child:\n    leaf: x\n  other: y\ntop: z",
+			`{"root":{"child":{"leaf":"x"},"other":"y"},"top":"z"}`},
+		{"block sequence", "- a\n- b\n-\n- c", `["a","b",null,"c"]`},
+		{"sequence at the indentation of its key", "panes:\n- one\n- two\nname: x",
+			`{"panes":["one","two"],"name":"x"}`},
+		{"sequence of maps", "windows:\n  - name: editor\n    root: ~/src\n  - name: shell",
+			`{"windows":[{"name":"editor","root":"~/src"},{"name":"shell"}]}`},
+		{"nested sequence", "- - a\n  - b\n- c", `[["a","b"],"c"]`},
+		{"flow sequence", "panes: [vim, 'git status', \"make, test\"]",
+			`{"panes":["vim","git status","make, test"]}`},
+		{"quotes escaped in a flow sequence", "a: ['it''s, ok', b]", `{"a":["it's, ok","b"]}`},
+		{"empty flow collections", "a: []\nb: {}", `{"a":[],"b":{}}`},
+		{"double quoted", `a: "say \"hi\"\tthere\\"`, `{"a":"say \"hi\"\tthere\\"}`},
+		{"single quoted", `a: 'it''s # not a comment'`, `{"a":"it's # not a comment"}`},
+		{"quoted key", `"a: b": c`, `{"a: b":"c"}`},
+		{"colon without space", "url: http://localhost:3000", `{"url":"http://localhost:3000"}`},
+		{"comments", "# header\na: 1 # trailing\n\n  # indented\nb: x#y", `{"a":"1","b":"x#y"}`},
+		{"document markers", "---\na: 1\n...", `{"a":"1"}`},
+		{"literal block", "cmd: |\n  echo one\n\n  echo two\nnext: x",
+			`{"cmd":"echo one\n\necho two\n","next":"x"}`},
+		{"folded block", "cmd: >\n  make\n  test\n", `{"cmd":"make test\n"}`},
+		{"stripped block", "cmd: |-\n  make\n", `{"cmd":"make"}`},
+		{"flow mapping", "pane: {dir: src, cmd: 'make, test'}", `{"pane":{"dir":"src","cmd":"make, test"}}`},
+		{"anchor and alias", "base: &b make\ncmd: *b", `{"base":"make","cmd":"make"}`},
+		{"merge key", "base: &b {root: ~/src, name: x}\nwin:\n  <<: *b\n  name: y",
+			`{"base":{"root":"~/src","name":"x"},"win":{"root":"~/src","name":"y"}}`},
+		{"merge key after its keys", "base: &b {a: 1, b: 2}\nwin: {b: 3, <<: *b}", `{"base":{"a":"1","b":"2"},"win":{"b":"3","a":"1"}}`},
+		{"tagged string", "a: !!str 12", `{"a":"12"}`},
+		{"only the first document", "a: 1\n---\nb: 2", `{"a":"1"}`},
+		{"crlf", "a: 1\r\nb: 2\r\n", `{"a":"1","b":"2"}`},
+		{"duplicate key keeps first position", "a: 1\nb: 2\na: 3", `{"a":"3","b":"2"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := parseYAML([]byte(tt.in))
+			if err != nil {
+				t.Fatalf("parseYAML(%q): %v", tt.in, err)
+			}
+			got, err := json.Marshal(node)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("parseYAML(%q) = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"tab indentation", "a:\n\tb: 1", "line 2"},
+		{"unterminated double quote", `a: "open`, "unexpected end of stream"},
+		{"unterminated single quote", "- 'open", "unexpected end of stream"},
+		{"unterminated flow sequence", "a: [x, y", "line 1"},
+		{"unterminated quote in flow sequence", `a: ["x, y]`, "unexpected end of stream"},
+		{"deeper line in a map", "a: 1\n  b: 2", "line 2"},
+		{"scalar among keys", "a: 1\nb", "line 2"},
+		{"shallower line after the root", "  a: 1\nb: 2", "did not find expected <document start>"},
+		{"unknown alias", "a: *nope", "unknown anchor"},
+		{"merge of a scalar", "a: {<<: x}", "<< has to merge a mapping"},
+		{"mapping as a key", "? {a: 1}\n: b", "only plain keys"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := parseYAML([]byte(tt.in))
+			if err == nil {
+				got, _ := json.Marshal(node)
+				t.Fatalf("parseYAML(%q) = %s, want an error", tt.in, got)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseYAML(%q) error %q, want it to contain %q", tt.in, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatYAMLReadsBack(t *testing.T) {
+	in := "name: \"a: b\"\npanes:\n  - vim\n  - \"\"\n  - command: make\n    split: \"50\"\nempty: []\nnone: {}\nflag: \"on\"\n"
+	node, err := parseYAML([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := parseYAML([]byte(formatYAML(node)))
+	if err != nil {
+		t.Fatalf("formatYAML wrote what parseYAML can't read: %v\n%s", err, formatYAML(node))
+	}
+	want, _ := json.Marshal(node)
+	got, _ := json.Marshal(again)
+	if string(got) != string(want) {
+		t.Errorf("read back %s, want %s", got, want)
+	}
+}
+
+// TestFormatYAMLQuotesOldBooleans keeps strings that YAML 1.1 readers,
+// which tmuxp uses, would take for booleans or numbers quoted.
+func TestFormatYAMLQuotesOldBooleans(t *testing.T) {
+	m := &yamlMap{}
+	for _, v := range []string{"on", "Off", "yes", "n", "true", "12"} {
+		m.set(v, v)
+	}
+	out := formatYAML(m)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if _, value, _ := strings.Cut(line, ": "); !strings.HasPrefix(value, `"`) && !strings.HasPrefix(value, "'") {
+			t.Errorf("%q is not quoted in:\n%s", value, out)
+		}
+	}
+}
+
+// TestTemplateYAMLReadsBack checks that templates saved as YAML keep the
+// fields of the embedded lazytmux.Template at the top level.
+func TestTemplateYAMLReadsBack(t *testing.T) {
+	template := SessionTemplate{Template: lazytmux.Template{
+		Name:      "api",
+		Root:      "~/src/api",
+		Env:       map[string]string{"PORT": "8080"},
+		Variables: map[string]string{"branch": "main"},
+		Panes: []Pane{
+			{ID: 1, Position: "main", Command: "make run", SplitPercent: 50},
+			{ID: 2, Position: "right", Parent: 1, SplitPercent: 30, RemainOnExit: true},
+		},
+		Windows: []TemplateWindow{{Name: "logs", Panes: []Pane{{ID: 1, Position: "main", Command: "tail -f log"}}}},
+	}}
+	out, err := encodeTemplate(template, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(out), "name: api\n") {
+		t.Errorf("template fields are not at the top level:\n%s", out)
+	}
+	again, err := decodeTemplate(out)
+	if err != nil {
+		t.Fatalf("can't read back what was written: %v\n%s", err, out)
+	}
+	if !sameTemplate(again, template) {
+		t.Errorf("read back %+v, want %+v", again, template)
+	}
+}
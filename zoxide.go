@@ -0,0 +1,144 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// With zoxide installed, the new session prompt gets a directory field that
+// picks from the directories zoxide knows, most used first, so the session
+// starts there instead of wherever tmux would put it.
+
+// How many matching directories the picker shows.
+const dirPickerRows = 5
+
+// zoxideDirs returns the directories zoxide knows, best first, or nil when
+// it isn't installed.
+func zoxideDirs() []string {
+	if _, err := exec.LookPath("zoxide"); err != nil {
+		return nil
+	}
+	out, err := exec.Command("zoxide", "query", "-l").Output()
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line != "" {
+			dirs = append(dirs, line)
+		}
+	}
+	return dirs
+}
+
+// startDirPicker readies the directory field of the new session prompt.
+func (m *model) startDirPicker() {
+	m.dirChoices = zoxideDirs()
+	m.dirCursor = 0
+	ti := textinput.New()
+	ti.Placeholder = "Part of a directory zoxide knows"
+	ti.CharLimit = 200
+	m.dirInput = ti
+}
+
+// dirMatches returns the known directories containing what was typed,
+// ignoring case; ~ works for the home directory.
+func (m model) dirMatches() []string {
+	query := strings.ToLower(strings.TrimSpace(m.dirInput.Value()))
+	var matches []string
+	for _, dir := range m.dirChoices {
+		if strings.Contains(strings.ToLower(dir), query) || strings.Contains(strings.ToLower(shortDir(dir)), query) {
+			matches = append(matches, dir)
+		}
+	}
+	return matches
+}
+
+// pickedDir returns the directory the new session starts in: the
+// highlighted match, else what was typed if it is a directory, or "" to let
+// tmux decide when the field was never used.
+func (m model) pickedDir() string {
+	if m.dirInput.Value() == "" && !m.dirInput.Focused() {
+		return ""
+	}
+	if matches := m.dirMatches(); len(matches) > 0 {
+		return matches[min(m.dirCursor, len(matches)-1)]
+	}
+	dir := expandHome(strings.TrimSpace(m.dirInput.Value()))
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		return dir
+	}
+	return ""
+}
+
+// dirKey handles the keys of the directory field in the new session prompt
+// and reports whether it took the key. Enter and Esc are left to the prompt.
+func (m *model) dirKey(msg tea.KeyMsg) (bool, tea.Cmd) {
+	if len(m.dirChoices) == 0 {
+		return false, nil
+	}
+	key := msg.String()
+	if key == "tab" || key == "shift+tab" {
+		if m.dirInput.Focused() {
+			m.dirInput.Blur()
+			m.input.Focus()
+		} else {
+			m.input.Blur()
+			m.dirInput.Focus()
+		}
+		return true, nil
+	}
+	if !m.dirInput.Focused() {
+		return false, nil
+	}
+	switch key {
+	case "enter", "alt+enter", "esc":
+		return false, nil
+	case "up", "ctrl+p":
+		if m.dirCursor > 0 {
+			m.dirCursor--
+		}
+		return true, nil
+	case "down", "ctrl+n":
+		if m.dirCursor < len(m.dirMatches())-1 {
+			m.dirCursor++
+		}
+		return true, nil
+	}
+	var cmd tea.Cmd
+	m.dirInput, cmd = m.dirInput.Update(msg)
+	m.dirCursor = 0
+	return true, cmd
+}
+
+// renderDirPicker renders the directory field and its matches for the new
+// session prompt.
+func (m model) renderDirPicker() string {
+	var b strings.Builder
+	if !m.dirInput.Focused() && m.dirInput.Value() == "" {
+		return "\n📂 Directory: " + lipgloss.NewStyle().Foreground(mutedColor).Render("[Tab] pick one zoxide knows")
+	}
+	b.WriteString("\n📂 Directory: " + m.dirInput.View())
+	if !m.dirInput.Focused() {
+		return b.String()
+	}
+	matches := m.dirMatches()
+	if len(matches) == 0 {
+		b.WriteString("\n" + lipgloss.NewStyle().Foreground(mutedColor).Render("  no match, a path typed in full is used as is"))
+	}
+	// Keep the highlighted match in view.
+	start := max(0, m.dirCursor-dirPickerRows+1)
+	for i := start; i < len(matches) && i < start+dirPickerRows; i++ {
+		line := "  " + shortDir(matches[i])
+		if i == m.dirCursor {
+			line = lipgloss.NewStyle().Foreground(primaryColor).Bold(true).Render("▶ " + shortDir(matches[i]))
+		}
+		b.WriteString("\n" + line)
+	}
+	return b.String()
+}